@@ -262,6 +262,10 @@ func (api *adminApi) create(c echo.Context) error {
 					return NewBadRequestError("Failed to create admin.", err)
 				}
 
+				if after, err := adminAuditState(e.Admin); err == nil {
+					recordAudit(api.app, c, models.AuditActionCreate, models.AuditResourceAdmin, e.Admin.Id, nil, after, adminAuditSecrets...)
+				}
+
 				return api.app.OnAdminAfterCreateRequest().Trigger(event, func(e *core.AdminCreateEvent) error {
 					if e.HttpContext.Response().Committed {
 						return nil
@@ -287,6 +291,12 @@ func (api *adminApi) update(c echo.Context) error {
 		return NewNotFoundError("", err)
 	}
 
+	// snapshot the original state for the audit log
+	before, err := adminAuditState(admin)
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
 	form := forms.NewAdminUpsert(api.app, admin)
 
 	// load request
@@ -308,6 +318,10 @@ func (api *adminApi) update(c echo.Context) error {
 					return NewBadRequestError("Failed to update admin.", err)
 				}
 
+				if after, err := adminAuditState(e.Admin); err == nil {
+					recordAudit(api.app, c, models.AuditActionUpdate, models.AuditResourceAdmin, e.Admin.Id, before, after, adminAuditSecrets...)
+				}
+
 				return api.app.OnAdminAfterUpdateRequest().Trigger(event, func(e *core.AdminUpdateEvent) error {
 					if e.HttpContext.Response().Committed {
 						return nil
@@ -342,6 +356,10 @@ func (api *adminApi) delete(c echo.Context) error {
 			return NewBadRequestError("Failed to delete admin.", err)
 		}
 
+		if before, err := adminAuditState(e.Admin); err == nil {
+			recordAudit(api.app, c, models.AuditActionDelete, models.AuditResourceAdmin, e.Admin.Id, before, nil, adminAuditSecrets...)
+		}
+
 		return api.app.OnAdminAfterDeleteRequest().Trigger(event, func(e *core.AdminDeleteEvent) error {
 			if e.HttpContext.Response().Committed {
 				return nil
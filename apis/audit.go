@@ -0,0 +1,261 @@
+package apis
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/settings"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/labstack/echo/v5"
+)
+
+// bindAuditApi registers the admin audit trail api endpoints.
+func bindAuditApi(app core.App, rg *echo.Group) {
+	api := auditApi{app: app}
+
+	subGroup := rg.Group("/audit", RequireAdminAuth())
+	subGroup.GET("", api.list)
+	subGroup.GET("/:id", api.view)
+}
+
+type auditApi struct {
+	app core.App
+}
+
+var auditFilterFields = []string{
+	"id", "created", "action", "resourceType", "resourceId",
+	"actorId", "actorEmail", "ip",
+}
+
+func (api *auditApi) list(c echo.Context) error {
+	fieldResolver := search.NewSimpleFieldResolver(auditFilterFields...)
+
+	result, err := search.NewProvider(fieldResolver).
+		Query(api.app.Dao().AuditLogQuery()).
+		ParseAndExec(c.QueryParams().Encode(), &[]*models.AuditLog{})
+
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func (api *auditApi) view(c echo.Context) error {
+	id := c.PathParam("id")
+	if id == "" {
+		return NewNotFoundError("", nil)
+	}
+
+	entry, err := api.app.Dao().FindAuditLogById(id)
+	if err != nil || entry == nil {
+		return NewNotFoundError("", err)
+	}
+
+	return c.JSON(http.StatusOK, entry)
+}
+
+// -------------------------------------------------------------------
+
+// recordAudit appends a new audit trail entry for the already
+// performed resource change with the request admin as actor.
+//
+// before and after are the JSON serializable resource states
+// (nil for the create and delete actions respectively).
+// The diff values of the secrets paths (and their descendants) are masked.
+//
+// The change has already happened, so a write failure is only logged.
+func recordAudit(
+	app core.App,
+	c echo.Context,
+	action string,
+	resourceType string,
+	resourceId string,
+	before any,
+	after any,
+	secrets ...string,
+) {
+	entry := &models.AuditLog{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceId:   resourceId,
+	}
+
+	if admin, _ := c.Get(ContextAdminKey).(*models.Admin); admin != nil {
+		entry.ActorId = admin.Id
+		entry.ActorEmail = admin.Email
+	}
+
+	ip, _, _ := net.SplitHostPort(c.Request().RemoteAddr)
+	entry.Ip = realUserIp(c.Request(), ip)
+
+	diff, err := auditDiff(before, after, secrets...)
+	if err == nil {
+		entry.Diff = diff
+		err = app.Dao().CreateAuditLog(entry)
+	}
+
+	if err != nil {
+		app.Logger().Error(
+			"Failed to write audit log",
+			slog.String("action", action),
+			slog.String("resourceType", resourceType),
+			slog.String("resourceId", resourceId),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// auditDiff returns the changed fields between the before and after
+// states as flattened dot-notation paths mapped to their old and new values.
+//
+// Arrays are compared and stored as a whole.
+func auditDiff(before any, after any, secrets ...string) (map[string]any, error) {
+	oldFields, err := flattenAuditState(before)
+	if err != nil {
+		return nil, err
+	}
+
+	newFields, err := flattenAuditState(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]any{}
+
+	addChange := func(path string, oldVal any, newVal any) {
+		if reflect.DeepEqual(oldVal, newVal) {
+			return
+		}
+
+		if isAuditSecret(path, secrets) {
+			oldVal = maskAuditValue(oldVal)
+			newVal = maskAuditValue(newVal)
+		}
+
+		diff[path] = map[string]any{"old": oldVal, "new": newVal}
+	}
+
+	for path, oldVal := range oldFields {
+		addChange(path, oldVal, newFields[path])
+	}
+
+	for path, newVal := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			addChange(path, nil, newVal)
+		}
+	}
+
+	return diff, nil
+}
+
+// flattenAuditState normalizes the provided state through a JSON
+// serialization roundtrip and flattens its nested objects.
+func flattenAuditState(state any) (map[string]any, error) {
+	result := map[string]any{}
+
+	if state == nil || reflect.ValueOf(state).IsZero() {
+		return result, nil
+	}
+
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized any
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, err
+	}
+
+	flattenAuditValue("", normalized, result)
+
+	return result, nil
+}
+
+func flattenAuditValue(path string, value any, result map[string]any) {
+	obj, ok := value.(map[string]any)
+	if !ok || len(obj) == 0 {
+		result[path] = value
+		return
+	}
+
+	for k, v := range obj {
+		if path != "" {
+			k = path + "." + k
+		}
+
+		flattenAuditValue(k, v, result)
+	}
+}
+
+func isAuditSecret(path string, secrets []string) bool {
+	for _, s := range secrets {
+		if path == s || strings.HasPrefix(path, s+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func maskAuditValue(value any) any {
+	if value == nil || value == "" {
+		return value
+	}
+
+	return settings.SecretMask
+}
+
+// settingsAuditSecrets returns the paths of the settings fields that
+// are masked in the redacted settings (see [settings.Settings.RedactClone]).
+func settingsAuditSecrets(s *settings.Settings) ([]string, error) {
+	redacted, err := s.RedactClone()
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := flattenAuditState(redacted)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := []string{}
+
+	for path, v := range fields {
+		if v == settings.SecretMask {
+			secrets = append(secrets, path)
+		}
+	}
+
+	return secrets, nil
+}
+
+// adminAuditState returns the admin audit state including the
+// otherwise hidden password hash and token key fields
+// so that the password changes and token keys rotations are tracked.
+func adminAuditState(admin *models.Admin) (map[string]any, error) {
+	raw, err := json.Marshal(admin)
+	if err != nil {
+		return nil, err
+	}
+
+	state := map[string]any{}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, err
+	}
+
+	state["passwordHash"] = admin.PasswordHash
+	state["tokenKey"] = admin.TokenKey
+
+	return state, nil
+}
+
+// adminAuditSecrets are the admin audit state fields that are always masked.
+var adminAuditSecrets = []string{"passwordHash", "tokenKey"}
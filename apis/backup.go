@@ -172,6 +172,8 @@ func (api *backupApi) restore(c echo.Context) error {
 		return NewBadRequestError("Missing or invalid backup file.", err)
 	}
 
+	recordAudit(api.app, c, models.AuditActionRestore, models.AuditResourceBackup, key, nil, nil)
+
 	go func() {
 		// wait max 15 minutes to fetch the backup
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
@@ -42,6 +42,7 @@ func InitApi(app core.App) (*echo.Echo, error) {
 	})
 
 	// default middlewares
+	e.Pre(tracingMiddleware())
 	e.Pre(middleware.RemoveTrailingSlashWithConfig(middleware.RemoveTrailingSlashConfig{
 		Skipper: func(c echo.Context) bool {
 			// enable by default only for the API routes
@@ -58,6 +59,7 @@ func InitApi(app core.App) (*echo.Echo, error) {
 			return next(c)
 		}
 	})
+	e.Use(metricsMiddleware(app))
 
 	// custom error handler
 	e.HTTPErrorHandler = func(c echo.Context, err error) {
@@ -126,8 +128,12 @@ func InitApi(app core.App) (*echo.Echo, error) {
 	bindFileApi(app, api)
 	bindRealtimeApi(app, api)
 	bindLogsApi(app, api)
+	bindAuditApi(app, api)
 	bindHealthApi(app, api)
 	bindBackupApi(app, api)
+	bindWebhookApi(app, api)
+	bindJobsApi(app, api)
+	bindMetricsApi(app, api)
 
 	// catch all any route
 	api.Any("/*", func(c echo.Context) error {
@@ -1,14 +1,20 @@
 package apis
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/daos"
 	"github.com/hylarucoder/rocketbase/forms"
 	"github.com/hylarucoder/rocketbase/models"
 	"github.com/hylarucoder/rocketbase/tools/search"
 	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/spf13/cast"
 )
 
 // bindCollectionApi registers the collection api endpoints and the corresponding handlers.
@@ -100,6 +106,8 @@ func (api *collectionApi) create(c echo.Context) error {
 					return NewBadRequestError("Failed to create the collection.", err)
 				}
 
+				recordAudit(api.app, c, models.AuditActionCreate, models.AuditResourceCollection, e.Collection.Id, nil, e.Collection)
+
 				return api.app.OnCollectionAfterCreateRequest().Trigger(event, func(e *core.CollectionCreateEvent) error {
 					if e.HttpContext.Response().Committed {
 						return nil
@@ -118,6 +126,12 @@ func (api *collectionApi) update(c echo.Context) error {
 		return NewNotFoundError("", err)
 	}
 
+	// snapshot the original state for the audit log
+	before, err := json.Marshal(collection)
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
 	form := forms.NewCollectionUpsert(api.app, collection)
 
 	// load request
@@ -125,6 +139,10 @@ func (api *collectionApi) update(c echo.Context) error {
 		return NewBadRequestError("Failed to load the submitted data due to invalid formatting.", err)
 	}
 
+	if cast.ToBool(c.QueryParam("dryRun")) {
+		return api.updateDryRun(c, form, collection)
+	}
+
 	event := new(core.CollectionUpdateEvent)
 	event.HttpContext = c
 	event.Collection = collection
@@ -140,6 +158,8 @@ func (api *collectionApi) update(c echo.Context) error {
 					return NewBadRequestError("Failed to update the collection.", err)
 				}
 
+				recordAudit(api.app, c, models.AuditActionUpdate, models.AuditResourceCollection, e.Collection.Id, json.RawMessage(before), e.Collection)
+
 				return api.app.OnCollectionAfterUpdateRequest().Trigger(event, func(e *core.CollectionUpdateEvent) error {
 					if e.HttpContext.Response().Committed {
 						return nil
@@ -152,6 +172,51 @@ func (api *collectionApi) update(c echo.Context) error {
 	})
 }
 
+// updateDryRun submits the collection update form in a rolled back
+// transaction and responds with the updated collection and the
+// executed SQL statements (aka. the changes that would be applied).
+//
+// The collection request hooks are not triggered and no audit log is recorded.
+//
+// The statements are always captured from an in-place (transactional)
+// update, so when the online schema changes are enabled the response
+// is flagged with a warning that the actual update will differ.
+func (api *collectionApi) updateDryRun(c echo.Context, form *forms.CollectionUpsert, collection *models.Collection) error {
+	statements, err := requestDao(api.app, c).DryRun(func(txDao *daos.Dao) error {
+		form.SetDao(txDao)
+
+		return form.Submit()
+	})
+	if err != nil {
+		if _, ok := err.(validation.Errors); ok {
+			return err
+		}
+
+		return NewBadRequestError("Failed to update the collection.", err)
+	}
+
+	sql := ""
+	if len(statements) > 0 {
+		sql = strings.Join(statements, ";\n") + ";"
+	}
+
+	result := map[string]any{
+		"collection":    collection,
+		"statements":    statements,
+		"sql":           sql,
+		"onlineChanges": false,
+	}
+
+	if api.app.Settings().Schema.OnlineChanges && !collection.IsView() {
+		result["onlineChanges"] = true
+		result["warning"] = "The online schema changes are enabled and the actual update will differ from the previewed statements: " +
+			"the indexes are (re)built with CREATE INDEX CONCURRENTLY after the commit " +
+			"and the single/multiple field conversions are deferred to batched backfills."
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
 func (api *collectionApi) delete(c echo.Context) error {
 	collection, err := api.app.Dao().FindCollectionByNameOrId(c.PathParam("collection"))
 	if err != nil || collection == nil {
@@ -167,6 +232,8 @@ func (api *collectionApi) delete(c echo.Context) error {
 			return NewBadRequestError("Failed to delete collection due to existing dependency.", err)
 		}
 
+		recordAudit(api.app, c, models.AuditActionDelete, models.AuditResourceCollection, e.Collection.Id, e.Collection, nil)
+
 		return api.app.OnCollectionAfterDeleteRequest().Trigger(event, func(e *core.CollectionDeleteEvent) error {
 			if e.HttpContext.Response().Committed {
 				return nil
@@ -185,6 +252,12 @@ func (api *collectionApi) bulkImport(c echo.Context) error {
 		return NewBadRequestError("Failed to load the submitted data due to invalid formatting.", err)
 	}
 
+	// snapshot the original collections state for the audit log
+	before, err := collectionsAuditState(api.app.Dao().CollectionQuery())
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
 	event := new(core.CollectionsImportEvent)
 	event.HttpContext = c
 	event.Collections = form.Collections
@@ -199,6 +272,10 @@ func (api *collectionApi) bulkImport(c echo.Context) error {
 					return NewBadRequestError("Failed to import the submitted collections.", err)
 				}
 
+				if after, err := collectionsAuditState(api.app.Dao().CollectionQuery()); err == nil {
+					recordAudit(api.app, c, models.AuditActionImport, models.AuditResourceCollection, "", before, after)
+				}
+
 				return api.app.OnCollectionsAfterImportRequest().Trigger(event, func(e *core.CollectionsImportEvent) error {
 					if e.HttpContext.Response().Committed {
 						return nil
@@ -210,3 +287,19 @@ func (api *collectionApi) bulkImport(c echo.Context) error {
 		}
 	})
 }
+
+// collectionsAuditState returns the audit state of all collections
+// returned by the provided query keyed by their name.
+func collectionsAuditState(query *dbx.SelectQuery) (map[string]*models.Collection, error) {
+	collections := []*models.Collection{}
+	if err := query.All(&collections); err != nil {
+		return nil, err
+	}
+
+	state := make(map[string]*models.Collection, len(collections))
+	for _, c := range collections {
+		state[c.Name] = c
+	}
+
+	return state, nil
+}
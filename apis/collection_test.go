@@ -1,6 +1,7 @@
 package apis_test
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"os"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/hylarucoder/rocketbase/core"
 	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/schema"
 	"github.com/hylarucoder/rocketbase/tests"
 	"github.com/hylarucoder/rocketbase/tools/list"
 	"github.com/labstack/echo/v5"
@@ -1248,6 +1250,124 @@ func (suite *CollectionTestSuite) TestCollectionUpdate() {
 	}
 }
 
+func (suite *CollectionTestSuite) TestCollectionUpdateDryRun() {
+	t := suite.T()
+	app := suite.App
+
+	// extend the existing demo1 schema with a new field
+	demo1, err := app.Dao().FindCollectionByNameOrId("demo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	demo1.Schema.AddField(&schema.SchemaField{
+		Name: "dry_run_field",
+		Type: schema.FieldTypeText,
+	})
+	rawSchema, err := json.Marshal(demo1.Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "authorized as admin + invalid data",
+			Method: http.MethodPatch,
+			Url:    "/api/collections/demo1?dryRun=1",
+			Body:   strings.NewReader(`{"name":"demo2"}`),
+			RequestHeaders: map[string]string{
+				"Authorization": suite.AdminAuthToken,
+			},
+			ExpectedStatus: 400,
+			ExpectedContent: []string{
+				`"data":{`,
+				`"name":{"code":"validation_collection_name_exists"`,
+			},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return app
+			},
+		},
+		{
+			Name:   "authorized as admin + new field",
+			Method: http.MethodPatch,
+			Url:    "/api/collections/demo1?dryRun=1",
+			Body:   strings.NewReader(`{"schema":` + string(rawSchema) + `}`),
+			RequestHeaders: map[string]string{
+				"Authorization": suite.AdminAuthToken,
+			},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"collection":{`,
+				`"name":"demo1"`,
+				`"dry_run_field"`,
+				`"statements":[`,
+				`ALTER TABLE`,
+				`"sql":"`,
+				`"onlineChanges":false`,
+			},
+			NotExpectedContent: []string{
+				`"warning"`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnModelBeforeUpdate": 1,
+			},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return app
+			},
+			AfterTestFunc: func(t *testing.T, app *tests.TestApp, res *http.Response) {
+				collection, err := app.Dao().FindCollectionByNameOrId("demo1")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if collection.Schema.GetFieldByName("dry_run_field") != nil {
+					t.Fatal("Expected the dry run changes to not be persisted")
+				}
+
+				columns, err := app.Dao().TableColumns("demo1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, c := range columns {
+					if c == "dry_run_field" {
+						t.Fatal("Expected the dry run column to be rolled back")
+					}
+				}
+			},
+		},
+		{
+			Name:   "authorized as admin + enabled online changes",
+			Method: http.MethodPatch,
+			Url:    "/api/collections/demo1?dryRun=1",
+			Body:   strings.NewReader(`{"schema":` + string(rawSchema) + `}`),
+			RequestHeaders: map[string]string{
+				"Authorization": suite.AdminAuthToken,
+			},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"statements":[`,
+				`"onlineChanges":true`,
+				`"warning":"`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnModelBeforeUpdate": 1,
+			},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return app
+			},
+			BeforeTestFunc: func(t *testing.T, app *tests.TestApp, e *echo.Echo) {
+				app.Settings().Schema.OnlineChanges = true
+			},
+			AfterTestFunc: func(t *testing.T, app *tests.TestApp, res *http.Response) {
+				app.Settings().Schema.OnlineChanges = false
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
 func (suite *CollectionTestSuite) TestCollectionsImport() {
 	t := suite.T()
 	app := suite.App
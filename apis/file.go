@@ -155,6 +155,10 @@ func (api *fileApi) download(c echo.Context) error {
 	}
 	defer fsys.Close()
 
+	// trace the filesystem operations as part of the request
+	// (without propagating the request cancellation to the thumbs generation)
+	fsys.SetContext(context.WithoutCancel(c.Request().Context()))
+
 	originalPath := baseFilesPath + "/" + filename
 	servedPath := originalPath
 	servedName := filename
@@ -265,7 +269,13 @@ func (api *fileApi) createThumb(
 		}
 		defer api.thumbGenSem.Release(1)
 
-		return nil, fsys.CreateThumb(originalPath, thumbPath, thumbSize)
+		start := time.Now()
+
+		err := fsys.CreateThumb(originalPath, thumbPath, thumbSize)
+
+		observeThumbGeneration(api.app, time.Since(start), err)
+
+		return nil, err
 	})
 
 	res := <-ch
@@ -274,3 +284,17 @@ func (api *fileApi) createThumb(
 
 	return res.Err
 }
+
+// observeThumbGeneration records the metrics of a single thumb generation.
+func observeThumbGeneration(app core.App, duration time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+
+	app.Metrics().Counter(metricThumbsGenerated, "Total number of generated thumbs.", "status").
+		Inc(status)
+
+	app.Metrics().Histogram(metricThumbDuration, "Thumbs generation duration in seconds.", nil).
+		Observe(duration.Seconds())
+}
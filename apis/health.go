@@ -1,23 +1,85 @@
 package apis
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/tools/migrate"
 	"github.com/labstack/echo/v5"
 )
 
+// healthCheckTimeout is the max duration of a single readiness check.
+const healthCheckTimeout = 5 * time.Second
+
+// healthCheckCacheTTL is the duration for which the optional readiness
+// checks results are reused for the non-admin requests, so that anonymous
+// callers can't make the server open outbound connections on every request.
+const healthCheckCacheTTL = 30 * time.Second
+
+// Readiness check statuses.
+const (
+	healthCheckStatusOk      = "ok"
+	healthCheckStatusFailure = "failure"
+	healthCheckStatusSkipped = "skipped"
+)
+
+// Readiness check names.
+const (
+	healthCheckDB         = "db"
+	healthCheckLogsDB     = "logsDb"
+	healthCheckMigrations = "migrations"
+	healthCheckFilesystem = "filesystem"
+	healthCheckSmtp       = "smtp"
+	healthCheckJobs       = "jobs"
+	healthCheckCron       = "cron"
+)
+
+// errHealthCheckSkipped is returned by the readiness checks
+// whose dependency is not configured (eg. disabled smtp).
+var errHealthCheckSkipped = errors.New("skipped")
+
+// optionalHealthChecks lists the readiness checks that
+// are performed only if explicitly requested with the "checks" query parameter.
+var optionalHealthChecks = []string{
+	healthCheckFilesystem,
+	healthCheckSmtp,
+	healthCheckJobs,
+	healthCheckCron,
+}
+
 // bindHealthApi registers the health api endpoint.
 func bindHealthApi(app core.App, rg *echo.Group) {
-	api := healthApi{app: app}
+	api := healthApi{app: app, cache: map[string]*cachedReadyCheck{}}
 
 	subGroup := rg.Group("/health")
 	subGroup.HEAD("", api.healthCheck)
 	subGroup.GET("", api.healthCheck)
+	subGroup.HEAD("/live", api.liveCheck)
+	subGroup.GET("/live", api.liveCheck)
+	subGroup.HEAD("/ready", api.readyCheck)
+	subGroup.GET("/ready", api.readyCheck)
 }
 
 type healthApi struct {
 	app core.App
+
+	cacheMux sync.Mutex
+	cache    map[string]*cachedReadyCheck
+}
+
+// cachedReadyCheck holds the last result of a single optional readiness check.
+type cachedReadyCheck struct {
+	mux       sync.Mutex
+	result    *readyCheckResult
+	checkedAt time.Time
 }
 
 type healthCheckResponse struct {
@@ -28,6 +90,24 @@ type healthCheckResponse struct {
 	} `json:"data"`
 }
 
+type readyCheckResponse struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	Data    struct {
+		Checks map[string]*readyCheckResult `json:"checks"`
+	} `json:"data"`
+}
+
+type readyCheckResult struct {
+	Status string `json:"status"`
+
+	// Latency is the check duration in ms.
+	Latency float64 `json:"latency"`
+
+	// Error is the check failure reason (returned only to admins).
+	Error string `json:"error,omitempty"`
+}
+
 // healthCheck returns a 200 OK response if the server is healthy.
 func (api *healthApi) healthCheck(c echo.Context) error {
 	if c.Request().Method == http.MethodHead {
@@ -41,3 +121,264 @@ func (api *healthApi) healthCheck(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, resp)
 }
+
+// liveCheck returns a 200 OK response if the server process is able to handle requests
+// (it doesn't check any of the app dependencies).
+func (api *healthApi) liveCheck(c echo.Context) error {
+	if c.Request().Method == http.MethodHead {
+		return c.NoContent(http.StatusOK)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"code":    http.StatusOK,
+		"message": "API is alive.",
+		"data":    map[string]any{},
+	})
+}
+
+// readyCheck probes the app dependencies and returns 200 OK response
+// if all of them are available or 503 Service Unavailable otherwise.
+//
+// The dbs and migrations are always checked. The filesystem, smtp, jobs and cron
+// checks could be enabled with the "checks" query parameter, eg. "?checks=filesystem,smtp".
+//
+// The checks failure reasons are returned only to admins. For the non-admin
+// requests the filesystem and smtp checks results are cached for [healthCheckCacheTTL].
+func (api *healthApi) readyCheck(c echo.Context) error {
+	isAdmin := RequestInfo(c).Admin != nil
+
+	checks := map[string]func(ctx context.Context) *readyCheckResult{
+		healthCheckDB: probeReadyCheck(func(ctx context.Context) error {
+			return api.app.DB().DB().PingContext(ctx)
+		}),
+		healthCheckLogsDB: probeReadyCheck(func(ctx context.Context) error {
+			return api.app.LogsDB().DB().PingContext(ctx)
+		}),
+		healthCheckMigrations: probeReadyCheck(api.checkMigrations),
+	}
+
+	for _, name := range strings.Split(c.QueryParam("checks"), ",") {
+		name = strings.TrimSpace(name)
+
+		switch name {
+		case healthCheckFilesystem:
+			checks[name] = api.cachedReadyCheck(name, !isAdmin, probeReadyCheck(api.checkFilesystem))
+		case healthCheckSmtp:
+			checks[name] = api.cachedReadyCheck(name, !isAdmin, probeReadyCheck(api.checkSmtp))
+		case healthCheckJobs:
+			checks[name] = probeReadyCheck(api.checkJobs)
+		case healthCheckCron:
+			checks[name] = probeReadyCheck(api.checkCron)
+		case "":
+			// ignore
+		default:
+			return NewBadRequestError(
+				fmt.Sprintf("Unknown health check %q (supported: %s).", name, strings.Join(optionalHealthChecks, ", ")),
+				nil,
+			)
+		}
+	}
+
+	results := runReadyChecks(c.Request().Context(), checks)
+
+	if !isAdmin {
+		// hide the failure details (hosts, ports, driver messages, etc.)
+		for name, r := range results {
+			redacted := *r
+			redacted.Error = ""
+			results[name] = &redacted
+		}
+	}
+
+	status := http.StatusOK
+	for _, r := range results {
+		if r.Status == healthCheckStatusFailure {
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	if c.Request().Method == http.MethodHead {
+		return c.NoContent(status)
+	}
+
+	resp := new(readyCheckResponse)
+	resp.Code = status
+	resp.Data.Checks = results
+	if status == http.StatusOK {
+		resp.Message = "API is ready."
+	} else {
+		resp.Message = "API is not ready."
+	}
+
+	return c.JSON(status, resp)
+}
+
+// runReadyChecks concurrently executes the provided checks
+// (each with its own timeout) and returns their results.
+func runReadyChecks(ctx context.Context, checks map[string]func(ctx context.Context) *readyCheckResult) map[string]*readyCheckResult {
+	results := make(map[string]*readyCheckResult, len(checks))
+
+	var mux sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, check := range checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			result := check(checkCtx)
+
+			mux.Lock()
+			results[name] = result
+			mux.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// probeReadyCheck wraps the provided check function into
+// a readiness check that measures its latency.
+func probeReadyCheck(check func(ctx context.Context) error) func(ctx context.Context) *readyCheckResult {
+	return func(ctx context.Context) *readyCheckResult {
+		start := time.Now()
+
+		err := check(ctx)
+
+		result := &readyCheckResult{
+			Status:  healthCheckStatusOk,
+			Latency: float64(time.Since(start)) / float64(time.Millisecond),
+		}
+
+		if errors.Is(err, errHealthCheckSkipped) {
+			result.Status = healthCheckStatusSkipped
+		} else if err != nil {
+			result.Status = healthCheckStatusFailure
+			result.Error = err.Error()
+		}
+
+		return result
+	}
+}
+
+// cachedReadyCheck wraps the provided readiness check so that its
+// last result is stored and, if useCache is set, reused for [healthCheckCacheTTL].
+//
+// The concurrent calls of the same check wait for the running one
+// so that there is at most one execution per check at a time.
+func (api *healthApi) cachedReadyCheck(
+	name string,
+	useCache bool,
+	check func(ctx context.Context) *readyCheckResult,
+) func(ctx context.Context) *readyCheckResult {
+	api.cacheMux.Lock()
+	cached, ok := api.cache[name]
+	if !ok {
+		cached = &cachedReadyCheck{}
+		api.cache[name] = cached
+	}
+	api.cacheMux.Unlock()
+
+	return func(ctx context.Context) *readyCheckResult {
+		cached.mux.Lock()
+		defer cached.mux.Unlock()
+
+		if useCache && cached.result != nil && time.Since(cached.checkedAt) < healthCheckCacheTTL {
+			return cached.result
+		}
+
+		cached.result = check(ctx)
+		cached.checkedAt = time.Now()
+
+		return cached.result
+	}
+}
+
+// checkMigrations reports an error if there are unapplied app or logs db migrations.
+//
+// Note that the check is read-only and it doesn't create the migrations table if missing.
+func (api *healthApi) checkMigrations(ctx context.Context) error {
+	var total int
+
+	for _, conn := range appMigrationsConnections(api.app) {
+		pending, err := migrate.PendingMigrations(conn.DB.WithContext(ctx), conn.MigrationsList)
+		if err != nil {
+			return err
+		}
+
+		total += len(pending)
+	}
+
+	if total > 0 {
+		return fmt.Errorf("%d pending migration(s)", total)
+	}
+
+	return nil
+}
+
+// checkFilesystem checks whether the app storage (local or S3) is reachable.
+func (api *healthApi) checkFilesystem(ctx context.Context) error {
+	fsys, err := api.app.NewFilesystem()
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+
+	fsys.SetContext(ctx)
+
+	// a missing file is not an error - we only care that the storage could be queried
+	_, err = fsys.Exists(".health")
+
+	return err
+}
+
+// checkSmtp checks whether a connection with the configured SMTP server could be established.
+func (api *healthApi) checkSmtp(ctx context.Context) error {
+	config := api.app.Settings().Smtp
+	if !config.Enabled {
+		return errHealthCheckSkipped
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(config.Host, strconv.Itoa(config.Port)))
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// checkJobs checks whether the background jobs queue workers are started.
+func (api *healthApi) checkJobs(ctx context.Context) error {
+	if !api.app.Jobs().IsRunning() {
+		return fmt.Errorf("the jobs workers are not running")
+	}
+
+	return nil
+}
+
+// checkCron checks whether the enabled app cron schedulers
+// (autobackups, collection partitions, etc.) are running.
+func (api *healthApi) checkCron(ctx context.Context) error {
+	var stopped []string
+
+	for _, state := range api.app.CronStates() {
+		if state.Enabled && !state.Running {
+			stopped = append(stopped, state.Name)
+		}
+	}
+
+	if len(stopped) > 0 {
+		return fmt.Errorf("the %s cron schedulers are not running", strings.Join(stopped, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package apis
+
+import (
+	"net/http"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/labstack/echo/v5"
+)
+
+// bindJobsApi registers the background jobs api endpoints and the corresponding handlers.
+//
+// @todo add hooks once the app hooks api restructuring is finalized
+func bindJobsApi(app core.App, rg *echo.Group) {
+	api := jobsApi{app: app}
+
+	subGroup := rg.Group("/jobs", ActivityLogger(app), RequireAdminAuth())
+	subGroup.GET("", api.list)
+	subGroup.GET("/:id", api.view)
+	subGroup.POST("/:id/retry", api.retry)
+	subGroup.DELETE("/:id", api.delete)
+}
+
+type jobsApi struct {
+	app core.App
+}
+
+func (api *jobsApi) list(c echo.Context) error {
+	fieldResolver := search.NewSimpleFieldResolver(
+		"id", "created", "updated", "name", "status", "priority",
+		"attempts", "maxAttempts", "uniqueKey", "runAt", "lastError",
+	)
+
+	jobs := []*models.Job{}
+
+	result, err := search.NewProvider(fieldResolver).
+		Query(api.app.Dao().JobQuery()).
+		ParseAndExec(c.QueryParams().Encode(), &jobs)
+
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func (api *jobsApi) view(c echo.Context) error {
+	job, err := api.app.Dao().FindJobById(c.PathParam("id"))
+	if err != nil || job == nil {
+		return NewNotFoundError("", err)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// retry moves a dead (or completed) job back to the queue
+// with reset attempts counter.
+func (api *jobsApi) retry(c echo.Context) error {
+	job, err := api.app.Dao().FindJobById(c.PathParam("id"))
+	if err != nil || job == nil {
+		return NewNotFoundError("", err)
+	}
+
+	if job.Status == models.JobStatusPending || job.Status == models.JobStatusRunning {
+		return NewBadRequestError("The job is already queued.", nil)
+	}
+
+	job.Status = models.JobStatusPending
+	job.Attempts = 0
+	job.RunAt = types.NowDateTime()
+	job.LastError = ""
+
+	if err := api.app.Dao().SaveJob(job); err != nil {
+		return NewBadRequestError("Failed to retry the job.", err)
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+func (api *jobsApi) delete(c echo.Context) error {
+	job, err := api.app.Dao().FindJobById(c.PathParam("id"))
+	if err != nil || job == nil {
+		return NewNotFoundError("", err)
+	}
+
+	if job.Status == models.JobStatusRunning {
+		return NewBadRequestError("The job is currently running.", nil)
+	}
+
+	if err := api.app.Dao().DeleteJob(job); err != nil {
+		return NewBadRequestError("Failed to delete job.", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package apis
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/labstack/echo/v5"
+)
+
+const (
+	metricHttpRequests        = "pb_http_requests_total"
+	metricHttpRequestDuration = "pb_http_request_duration_seconds"
+	metricThumbsGenerated     = "pb_thumbs_generated_total"
+	metricThumbDuration       = "pb_thumb_generation_duration_seconds"
+)
+
+// bindMetricsApi registers the metrics api endpoint.
+func bindMetricsApi(app core.App, rg *echo.Group) {
+	api := metricsApi{app: app}
+
+	rg.GET("/metrics", api.metrics)
+}
+
+type metricsApi struct {
+	app core.App
+}
+
+// metrics exports the app metrics in the Prometheus text exposition format.
+//
+// The endpoint is available only if enabled in the app settings and
+// requires either an admin auth token or the configured metrics bearer token.
+func (api *metricsApi) metrics(c echo.Context) error {
+	config := api.app.Settings().Metrics
+
+	if !config.Enabled {
+		return NewNotFoundError("", nil)
+	}
+
+	if !api.canAccess(c, config.Token) {
+		return NewUnauthorizedError("The request requires valid admin authorization token or metrics token to be set.", nil)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+
+	return api.app.Metrics().WriteText(c.Response())
+}
+
+func (api *metricsApi) canAccess(c echo.Context, token string) bool {
+	if admin, _ := c.Get(ContextAdminKey).(*models.Admin); admin != nil {
+		return true
+	}
+
+	if token == "" {
+		return false
+	}
+
+	// the "Bearer " prefix is optional similar to the auth tokens
+	reqToken := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(reqToken), []byte(token)) == 1
+}
+
+// metricsMiddleware records the http requests count and duration metrics
+// per route and response status (if the app metrics are enabled).
+func metricsMiddleware(app core.App) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !app.Settings().Metrics.Enabled {
+				return next(c)
+			}
+
+			start := time.Now()
+
+			if err := next(c); err != nil {
+				// handle the error here to be able to resolve the final response status
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unknown"
+			}
+
+			method := c.Request().Method
+			status := strconv.Itoa(c.Response().Status)
+
+			app.Metrics().Counter(
+				metricHttpRequests,
+				"Total number of http requests.",
+				"method", "route", "status",
+			).Inc(method, route, status)
+
+			app.Metrics().Histogram(
+				metricHttpRequestDuration,
+				"Http requests duration in seconds.",
+				nil,
+				"method", "route", "status",
+			).Observe(time.Since(start).Seconds(), method, route, status)
+
+			return nil
+		}
+	}
+}
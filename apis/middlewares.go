@@ -15,6 +15,7 @@ import (
 	"github.com/hylarucoder/rocketbase/tools/list"
 	"github.com/hylarucoder/rocketbase/tools/routine"
 	"github.com/hylarucoder/rocketbase/tools/security"
+	"github.com/hylarucoder/rocketbase/tools/tracing"
 	"github.com/labstack/echo/v5"
 	"github.com/spf13/cast"
 )
@@ -226,26 +227,12 @@ func LoadAuthContext(app core.App) echo.MiddlewareFunc {
 			// compatibility with the defaults of some HTTP clients
 			token = strings.TrimPrefix(token, "Bearer ")
 
-			claims, _ := security.ParseUnverifiedJWT(token)
-			tokenType := cast.ToString(claims["type"])
-
-			switch tokenType {
-			case tokens.TypeAdmin:
-				admin, err := app.Dao().FindAdminByToken(
-					token,
-					app.Settings().AdminAuthToken.Secret,
-				)
-				if err == nil && admin != nil {
-					c.Set(ContextAdminKey, admin)
-				}
-			case tokens.TypeAuthRecord:
-				record, err := app.Dao().FindAuthRecordByToken(
-					token,
-					app.Settings().RecordAuthToken.Secret,
-				)
-				if err == nil && record != nil {
-					c.Set(ContextAuthRecordKey, record)
-				}
+			admin, record := findAuthByToken(app, token)
+			if admin != nil {
+				c.Set(ContextAdminKey, admin)
+			}
+			if record != nil {
+				c.Set(ContextAuthRecordKey, record)
 			}
 
 			return next(c)
@@ -253,6 +240,34 @@ func LoadAuthContext(app core.App) echo.MiddlewareFunc {
 	}
 }
 
+// findAuthByToken returns the admin or the auth record
+// associated with the provided auth token (if any).
+func findAuthByToken(app core.App, token string) (*models.Admin, *models.Record) {
+	claims, _ := security.ParseUnverifiedJWT(token)
+	tokenType := cast.ToString(claims["type"])
+
+	switch tokenType {
+	case tokens.TypeAdmin:
+		admin, err := app.Dao().FindAdminByToken(
+			token,
+			app.Settings().AdminAuthToken.Secret,
+		)
+		if err == nil && admin != nil {
+			return admin, nil
+		}
+	case tokens.TypeAuthRecord:
+		record, err := app.Dao().FindAuthRecordByToken(
+			token,
+			app.Settings().RecordAuthToken.Secret,
+		)
+		if err == nil && record != nil {
+			return nil, record
+		}
+	}
+
+	return nil, nil
+}
+
 // LoadCollectionContext middleware finds the collection with related
 // path identifier and loads it into the request context.
 //
@@ -282,7 +297,7 @@ func LoadCollectionContext(app core.App, optCollectionTypes ...string) echo.Midd
 // into the logs database.
 //
 // The middleware does nothing if the app logs retention period is zero
-// (aka. app.Settings().Logs.MaxDays = 0).
+// and there are no logs sinks (aka. app.Settings().Logs.IsEnabled() = false).
 func ActivityLogger(app core.App) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -298,8 +313,8 @@ func ActivityLogger(app core.App) echo.MiddlewareFunc {
 }
 
 func logRequest(app core.App, c echo.Context, err *ApiError) {
-	// no logs retention
-	if app.Settings().Logs.MaxDays == 0 {
+	// no logs retention or sinks
+	if !app.Settings().Logs.IsEnabled() {
 		return
 	}
 
@@ -345,6 +360,14 @@ func logRequest(app core.App, c echo.Context, err *ApiError) {
 		slog.String("userAgent", httpRequest.UserAgent()),
 	)
 
+	if traceId := tracing.TraceId(httpRequest.Context()); traceId != "" {
+		attrs = append(
+			attrs,
+			slog.String("traceId", traceId),
+			slog.String("spanId", tracing.SpanId(httpRequest.Context())),
+		)
+	}
+
 	if app.Settings().Logs.LogIp {
 		ip, _, _ := net.SplitHostPort(httpRequest.RemoteAddr)
 		attrs = append(
@@ -6,35 +6,62 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hylarucoder/rocketbase/core"
 	"github.com/hylarucoder/rocketbase/forms"
 	"github.com/hylarucoder/rocketbase/models"
-	"github.com/hylarucoder/rocketbase/resolvers"
+	"github.com/hylarucoder/rocketbase/tools/list"
 	"github.com/hylarucoder/rocketbase/tools/rest"
 	"github.com/hylarucoder/rocketbase/tools/routine"
-	"github.com/hylarucoder/rocketbase/tools/search"
 	"github.com/hylarucoder/rocketbase/tools/subscriptions"
 	"github.com/labstack/echo/v5"
-	"github.com/pocketbase/dbx"
-	"github.com/spf13/cast"
 )
 
+// realtimeDefaultResumeTimeout is the default max time the disconnected
+// clients subscriptions are kept for resuming the connection.
+const realtimeDefaultResumeTimeout = 2 * time.Minute
+
 // bindRealtimeApi registers the realtime api endpoints.
 func bindRealtimeApi(app core.App, rg *echo.Group) {
-	api := realtimeApi{app: app}
+	api := realtimeApi{app: app, detached: map[string]*detachedClient{}}
 
 	subGroup := rg.Group("/realtime")
 	subGroup.GET("", api.connect)
+	subGroup.GET("/ws", api.connectWS)
 	subGroup.POST("", api.setSubscriptions, ActivityLogger(app))
+	subGroup.POST("/channels/:channel", api.publish, ActivityLogger(app))
+	subGroup.POST("/presence/:channel", api.updatePresence, ActivityLogger(app))
 
 	api.bindEvents()
 }
 
 type realtimeApi struct {
 	app core.App
+
+	mux      sync.Mutex
+	detached map[string]*detachedClient
+
+	// presenceMux serializes the channel presence membership changes.
+	presenceMux sync.Mutex
+}
+
+// detachedClient keeps track of a disconnected client that is waiting to be resumed.
+type detachedClient struct {
+	stop func()
+}
+
+// realtimeResyncData represents the PB_RESYNC message data.
+type realtimeResyncData struct {
+	ClientId string `json:"clientId"`
+
+	// Subscriptions lists the subscriptions with missed messages that
+	// couldn't be replayed (empty if the previous connection couldn't be
+	// resumed at all, aka. all previous subscriptions must be resynced).
+	Subscriptions []string `json:"subscriptions"`
 }
 
 func (api *realtimeApi) connect(c echo.Context) error {
@@ -42,9 +69,18 @@ func (api *realtimeApi) connect(c echo.Context) error {
 	defer cancelRequest()
 	c.SetRequest(c.Request().Clone(cancelCtx))
 
-	// register new subscription client
-	client := subscriptions.NewDefaultClient()
-	api.app.SubscriptionsBroker().Register(client)
+	// resume the previous client connection (if any)
+	// or register a new subscription client
+	lastEventId := c.Request().Header.Get("Last-Event-ID")
+	client, lastId := api.resumeClient(c, lastEventId)
+	resumed := client != nil
+	if !resumed {
+		client = subscriptions.NewDefaultClient()
+		api.app.SubscriptionsBroker().Register(client)
+	}
+
+	var resumeTimeout time.Duration
+
 	defer func() {
 		disconnectEvent := &core.RealtimeDisconnectEvent{
 			HttpContext: c,
@@ -59,7 +95,12 @@ func (api *realtimeApi) connect(c echo.Context) error {
 			)
 		}
 
-		api.app.SubscriptionsBroker().Unregister(client.Id())
+		// keep the client subscriptions for a while in case of reconnect
+		if resumeTimeout > 0 && len(client.Subscriptions()) > 0 {
+			api.detachClient(client, resumeTimeout)
+		} else {
+			api.unregisterClient(client)
+		}
 	}()
 
 	c.Response().Header().Set("Content-Type", "text/event-stream")
@@ -69,35 +110,33 @@ func (api *realtimeApi) connect(c echo.Context) error {
 	c.Response().Header().Set("X-Accel-Buffering", "no")
 
 	connectEvent := &core.RealtimeConnectEvent{
-		HttpContext: c,
-		Client:      client,
-		IdleTimeout: 5 * time.Minute,
+		HttpContext:   c,
+		Client:        client,
+		IdleTimeout:   5 * time.Minute,
+		ResumeTimeout: realtimeDefaultResumeTimeout,
 	}
 
 	if err := api.app.OnRealtimeConnectRequest().Trigger(connectEvent); err != nil {
 		return err
 	}
 
-	api.app.Logger().Debug("Realtime connection established.", slog.String("clientId", client.Id()))
+	resumeTimeout = connectEvent.ResumeTimeout
+
+	api.app.Logger().Debug(
+		"Realtime connection established.",
+		slog.String("clientId", client.Id()),
+		slog.Bool("resumed", resumed),
+	)
 
 	// signalize established connection (aka. fire "connect" message)
-	connectMsgEvent := &core.RealtimeMessageEvent{
-		HttpContext: c,
-		Client:      client,
-		Message: &subscriptions.Message{
-			Name: "PB_CONNECT",
-			Data: []byte(`{"clientId":"` + client.Id() + `"}`),
-		},
+	connectData := `{"clientId":"` + client.Id() + `"}`
+	if resumed {
+		connectData = `{"clientId":"` + client.Id() + `","resumed":true}`
 	}
-	connectMsgErr := api.app.OnRealtimeBeforeMessageSend().Trigger(connectMsgEvent, func(e *core.RealtimeMessageEvent) error {
-		w := e.HttpContext.Response()
-		w.Write([]byte("id:" + client.Id() + "\n"))
-		w.Write([]byte("event:" + e.Message.Name + "\n"))
-		w.Write([]byte("data:"))
-		w.Write(e.Message.Data)
-		w.Write([]byte("\n\n"))
-		w.Flush()
-		return api.app.OnRealtimeAfterMessageSend().Trigger(e)
+	connectMsgErr := api.sendMessage(c, client, &subscriptions.Message{
+		Id:   lastId,
+		Name: "PB_CONNECT",
+		Data: []byte(connectData),
 	})
 	if connectMsgErr != nil {
 		api.app.Logger().Debug(
@@ -108,6 +147,55 @@ func (api *realtimeApi) connect(c echo.Context) error {
 		return nil
 	}
 
+	// replay the messages missed while reconnecting
+	var replayedId uint64
+	if lastEventId != "" {
+		resync := realtimeResyncData{ClientId: client.Id(), Subscriptions: []string{}}
+
+		var missed []subscriptions.Message
+		if resumed {
+			var overflowed []string
+			missed, overflowed = client.MessagesSince(lastId)
+			resync.Subscriptions = append(resync.Subscriptions, overflowed...)
+		}
+
+		if !resumed || len(resync.Subscriptions) > 0 {
+			resyncData, _ := json.Marshal(resync)
+
+			resyncErr := api.sendMessage(c, client, &subscriptions.Message{
+				Id:   lastId,
+				Name: "PB_RESYNC",
+				Data: resyncData,
+			})
+			if resyncErr != nil {
+				api.app.Logger().Debug(
+					"Realtime connection closed (failed to deliver PB_RESYNC)",
+					slog.String("clientId", client.Id()),
+					slog.String("error", resyncErr.Error()),
+				)
+				return nil
+			}
+		}
+
+		for i := range missed {
+			if err := api.sendMessage(c, client, &missed[i]); err != nil {
+				api.app.Logger().Debug(
+					"Realtime connection closed (failed to replay message)",
+					slog.String("clientId", client.Id()),
+					slog.String("error", err.Error()),
+				)
+				return nil
+			}
+
+			replayedId = missed[i].Id
+		}
+	}
+
+	// rejoin the custom channels presence left on detach
+	if resumed {
+		api.syncChannelSubscriptions(client)
+	}
+
 	// start an idle timer to keep track of inactive/forgotten connections
 	idleTimeout := connectEvent.IdleTimeout
 	idleTimer := time.NewTimer(idleTimeout)
@@ -127,26 +215,15 @@ func (api *realtimeApi) connect(c echo.Context) error {
 				return nil
 			}
 
-			msgEvent := &core.RealtimeMessageEvent{
-				HttpContext: c,
-				Client:      client,
-				Message:     &msg,
+			if msg.Id <= replayedId {
+				continue // already replayed
 			}
-			msgErr := api.app.OnRealtimeBeforeMessageSend().Trigger(msgEvent, func(e *core.RealtimeMessageEvent) error {
-				w := e.HttpContext.Response()
-				w.Write([]byte("id:" + e.Client.Id() + "\n"))
-				w.Write([]byte("event:" + e.Message.Name + "\n"))
-				w.Write([]byte("data:"))
-				w.Write(e.Message.Data)
-				w.Write([]byte("\n\n"))
-				w.Flush()
-				return api.app.OnRealtimeAfterMessageSend().Trigger(msgEvent)
-			})
-			if msgErr != nil {
+
+			if err := api.sendMessage(c, client, &msg); err != nil {
 				api.app.Logger().Debug(
 					"Realtime connection closed (failed to deliver message)",
 					slog.String("clientId", client.Id()),
-					slog.String("error", msgErr.Error()),
+					slog.String("error", err.Error()),
 				)
 				return nil
 			}
@@ -164,7 +241,129 @@ func (api *realtimeApi) connect(c echo.Context) error {
 	}
 }
 
-// note: in case of reconnect, clients will have to resubmit all subscriptions again
+// sendMessage writes a single SSE message to the client connection.
+//
+// The SSE message id has the format "clientId:messageId" so that
+// the client could be resumed on reconnect with the Last-Event-ID header.
+func (api *realtimeApi) sendMessage(c echo.Context, client subscriptions.Client, msg *subscriptions.Message) error {
+	msgEvent := &core.RealtimeMessageEvent{
+		HttpContext: c,
+		Client:      client,
+		Message:     msg,
+	}
+
+	return api.app.OnRealtimeBeforeMessageSend().Trigger(msgEvent, func(e *core.RealtimeMessageEvent) error {
+		w := e.HttpContext.Response()
+		w.Write([]byte("id:" + e.Client.Id() + ":" + strconv.FormatUint(e.Message.Id, 10) + "\n"))
+		w.Write([]byte("event:" + e.Message.Name + "\n"))
+		w.Write([]byte("data:"))
+		w.Write(e.Message.Data)
+		w.Write([]byte("\n\n"))
+		w.Flush()
+		return api.app.OnRealtimeAfterMessageSend().Trigger(e)
+	})
+}
+
+// resumeClient returns the disconnected client matching the provided
+// Last-Event-ID header value and the id of the last received message.
+//
+// Similar to the subscriptions update, a previously authorized client
+// can be resumed only with the same request authorization.
+//
+// Returns nil client if there is no resumable client.
+func (api *realtimeApi) resumeClient(c echo.Context, lastEventId string) (subscriptions.Client, uint64) {
+	clientId, rawId, ok := strings.Cut(lastEventId, ":")
+	if !ok {
+		return nil, 0
+	}
+
+	lastId, err := strconv.ParseUint(rawId, 10, 64)
+	if err != nil {
+		return nil, 0
+	}
+
+	client, err := api.app.SubscriptionsBroker().ClientById(clientId)
+	if err != nil {
+		return nil, 0
+	}
+
+	// check if the previous request was authorized
+	oldAuthId := extractAuthIdFromGetter(client)
+	newAuthId := extractAuthIdFromGetter(c)
+	if oldAuthId != "" && oldAuthId != newAuthId {
+		return nil, 0
+	}
+
+	api.mux.Lock()
+	detached := api.detached[clientId]
+	delete(api.detached, clientId)
+	api.mux.Unlock()
+
+	if detached == nil {
+		return nil, 0
+	}
+
+	detached.stop()
+
+	if client.IsDiscarded() {
+		return nil, 0
+	}
+
+	return client, lastId
+}
+
+// detachClient keeps the disconnected client registered for up to timeout
+// so that it can be resumed with [realtimeApi.resumeClient].
+//
+// While detached, the client messages are only stored in its replay buffer.
+func (api *realtimeApi) detachClient(client subscriptions.Client, timeout time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	detached := &detachedClient{
+		stop: func() {
+			cancel()
+			<-done
+		},
+	}
+
+	api.mux.Lock()
+	api.detached[client.Id()] = detached
+	api.mux.Unlock()
+
+	// the client is no longer present (it will rejoin on resume)
+	api.presenceLeaveAll(client)
+
+	go func() {
+		defer close(done)
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				api.mux.Lock()
+				expired := api.detached[client.Id()] == detached
+				if expired {
+					delete(api.detached, client.Id())
+				}
+				api.mux.Unlock()
+
+				if expired {
+					api.unregisterClient(client)
+				}
+				return
+			case <-client.Channel():
+				// discard (the message is already stored in the replay buffer)
+			}
+		}
+	}()
+}
+
+// note: in case of a new (not resumed) connection, clients will have to resubmit all subscriptions again
 func (api *realtimeApi) setSubscriptions(c echo.Context) error {
 	form := forms.NewRealtimeSubscribe()
 
@@ -197,30 +396,57 @@ func (api *realtimeApi) setSubscriptions(c echo.Context) error {
 		Subscriptions: form.Subscriptions,
 	}
 
+	return api.applySubscriptions(event, func(e *core.RealtimeSubscribeEvent) error {
+		if e.HttpContext.Response().Committed {
+			return nil
+		}
+
+		return e.HttpContext.NoContent(http.StatusNoContent)
+	})
+}
+
+// applySubscriptions replaces the event client subscriptions and auth state
+// with the event ones, wrapped in the realtime subscribe request hooks.
+func (api *realtimeApi) applySubscriptions(
+	event *core.RealtimeSubscribeEvent,
+	finalizer func(e *core.RealtimeSubscribeEvent) error,
+) error {
 	return api.app.OnRealtimeBeforeSubscribeRequest().Trigger(event, func(e *core.RealtimeSubscribeEvent) error {
 		// update auth state
 		e.Client.Set(ContextAdminKey, e.HttpContext.Get(ContextAdminKey))
 		e.Client.Set(ContextAuthRecordKey, e.HttpContext.Get(ContextAuthRecordKey))
 
-		// unsubscribe from any previous existing subscriptions
-		e.Client.Unsubscribe()
+		// unsubscribe from the previous subscriptions that are no longer present
+		// (the others are kept as they are to preserve their replay buffer)
+		removed := []string{}
+		for sub := range e.Client.Subscriptions() {
+			if !list.ExistInSlice(sub, e.Subscriptions) {
+				removed = append(removed, sub)
+			}
+		}
+		if len(removed) > 0 {
+			e.Client.Unsubscribe(removed...)
+			for _, sub := range removed {
+				e.Client.Unset(realtimeFilterPrefix + sub)
+			}
+		}
 
 		// subscribe to the new subscriptions
 		e.Client.Subscribe(e.Subscriptions...)
 
+		// refresh the custom channels access and presence
+		api.syncChannelSubscriptions(e.Client)
+
+		// (re)compile the record subscriptions filters
+		api.syncRecordSubscriptionFilters(e.Client)
+
 		api.app.Logger().Debug(
 			"Realtime subscriptions updated.",
 			slog.String("clientId", e.Client.Id()),
 			slog.Any("subscriptions", e.Subscriptions),
 		)
 
-		return api.app.OnRealtimeAfterSubscribeRequest().Trigger(event, func(e *core.RealtimeSubscribeEvent) error {
-			if e.HttpContext.Response().Committed {
-				return nil
-			}
-
-			return e.HttpContext.NoContent(http.StatusNoContent)
-		})
+		return api.app.OnRealtimeAfterSubscribeRequest().Trigger(event, finalizer)
 	})
 }
 
@@ -232,6 +458,9 @@ func (api *realtimeApi) updateClientsAuthModel(contextKey string, newModel model
 			clientModel.TableName() == newModel.TableName() &&
 			clientModel.GetId() == newModel.GetId() {
 			client.Set(contextKey, newModel)
+
+			// the filters could reference the auth model fields
+			api.syncRecordSubscriptionFilters(client)
 		}
 	}
 
@@ -245,7 +474,7 @@ func (api *realtimeApi) unregisterClientsByAuthModel(contextKey string, model mo
 		if clientModel != nil &&
 			clientModel.TableName() == model.TableName() &&
 			clientModel.GetId() == model.GetId() {
-			api.app.SubscriptionsBroker().Unregister(client.Id())
+			api.unregisterClient(client)
 		}
 	}
 
@@ -362,6 +591,91 @@ func (api *realtimeApi) resolveRecordCollection(model models.Model) (collection
 	return collection
 }
 
+// syncRecordSubscriptionFilters compiles the client-side filters of the
+// client record subscriptions so that they don't have to be compiled
+// on every record change.
+//
+// It is expected to be called after each client subscriptions or auth state change.
+func (api *realtimeApi) syncRecordSubscriptionFilters(client subscriptions.Client) {
+	for sub, options := range client.Subscriptions() {
+		if strings.HasPrefix(sub, realtimeChannelTopicPrefix) ||
+			strings.HasPrefix(sub, realtimePresenceTopicPrefix) {
+			continue
+		}
+
+		topic, _, _ := strings.Cut(sub, "?")
+		collectionNameOrId, _, _ := strings.Cut(topic, "/")
+
+		collection, err := api.app.Dao().FindCollectionByNameOrId(collectionNameOrId)
+		if err != nil {
+			client.Unset(realtimeFilterPrefix + sub)
+			continue
+		}
+
+		filter := compileRealtimeSubscriptionFilter(
+			api.app.Dao(),
+			collection,
+			realtimeRecordRequestInfo(client, options),
+		)
+		if filter == nil {
+			client.Unset(realtimeFilterPrefix + sub)
+		} else {
+			client.Set(realtimeFilterPrefix+sub, filter)
+		}
+	}
+}
+
+// recordSubscriptionFilter returns the compiled client-side filter of
+// the client record subscription (or nil if the subscription doesn't have one).
+//
+// The filter is recompiled if it is missing (eg. the client subscribed
+// without the subscribe request) or the collection has changed since its compilation.
+func (api *realtimeApi) recordSubscriptionFilter(
+	client subscriptions.Client,
+	sub string,
+	collection *models.Collection,
+	requestInfo *models.RequestInfo,
+) *realtimeFilter {
+	filter, _ := client.Get(realtimeFilterPrefix + sub).(*realtimeFilter)
+	if filter != nil && filter.collectionUpdated.Time().Equal(collection.Updated.Time()) {
+		return filter
+	}
+
+	filter = compileRealtimeSubscriptionFilter(api.app.Dao(), collection, requestInfo)
+	if filter != nil {
+		client.Set(realtimeFilterPrefix+sub, filter)
+	}
+
+	return filter
+}
+
+// realtimeRecordRequestInfo returns a mocked request info for the
+// record subscription access checks.
+func realtimeRecordRequestInfo(
+	client subscriptions.Client,
+	options subscriptions.SubscriptionOptions,
+) *models.RequestInfo {
+	requestInfo := &models.RequestInfo{
+		Method:  "GET",
+		Query:   options.Query,
+		Headers: options.Headers,
+	}
+	requestInfo.Admin, _ = client.Get(ContextAdminKey).(*models.Admin)
+	requestInfo.AuthRecord, _ = client.Get(ContextAuthRecordKey).(*models.Record)
+
+	return requestInfo
+}
+
+// realtimeDelivery represents a single pending record subscription message.
+type realtimeDelivery struct {
+	client      subscriptions.Client
+	sub         string
+	options     subscriptions.SubscriptionOptions
+	requestInfo *models.RequestInfo
+	access      *realtimeAccessCheck
+	manage      *realtimeAccessCheck
+}
+
 // recordData represents the broadcasted record subscrition message data.
 type recordData struct {
 	Record any    `json:"record"` /* map or models.Record */
@@ -389,13 +703,14 @@ func (api *realtimeApi) broadcastRecord(action string, record *models.Record, dr
 		(collection.Id + "?"):   collection.ListRule,
 	}
 
-	dryCacheKey := action + "/" + record.Id
+	// collect the access checks of all matching subscriptions
+	// so that they could be evaluated together
+	// (note: not executed concurrently to avoid races and to ensure
+	// that the access checks are applied for the current record db state)
+	batch := newRealtimeAccessBatch(api.app.Dao(), record)
+	deliveries := []*realtimeDelivery{}
 
 	for _, client := range clients {
-		client := client
-
-		// note: not executed concurrently to avoid races and to ensure
-		// that the access checks are applied for the current record db state
 		for prefix, rule := range subscriptionRuleMap {
 			subs := client.Subscriptions(prefix)
 			if len(subs) == 0 {
@@ -403,102 +718,129 @@ func (api *realtimeApi) broadcastRecord(action string, record *models.Record, dr
 			}
 
 			for sub, options := range subs {
-				// create a clean record copy without expand and unknown fields
-				// because we don't know yet which exact fields the client subscription has permissions to access
-				cleanRecord := record.CleanCopy()
-
-				// mock request data
-				requestInfo := &models.RequestInfo{
-					Method:  "GET",
-					Query:   options.Query,
-					Headers: options.Headers,
-				}
-				requestInfo.Admin, _ = client.Get(ContextAdminKey).(*models.Admin)
-				requestInfo.AuthRecord, _ = client.Get(ContextAuthRecordKey).(*models.Record)
+				requestInfo := realtimeRecordRequestInfo(client, options)
 
-				if !api.canAccessRecord(cleanRecord, requestInfo, rule) {
-					continue
-				}
+				filter := api.recordSubscriptionFilter(client, sub, collection, requestInfo)
 
-				rawExpand := cast.ToString(options.Query[expandQueryParam])
-				if rawExpand != "" {
-					expandErrs := api.app.Dao().ExpandRecord(cleanRecord, strings.Split(rawExpand, ","), expandFetch(api.app.Dao(), requestInfo))
-					if len(expandErrs) > 0 {
-						api.app.Logger().Debug(
-							"[broadcastRecord] expand errors",
-							slog.String("id", cleanRecord.Id),
-							slog.String("collectionName", cleanRecord.Collection().Name),
-							slog.String("sub", sub),
-							slog.String("expand", rawExpand),
-							slog.Any("errors", expandErrs),
-						)
-					}
+				delivery := &realtimeDelivery{
+					client:      client,
+					sub:         sub,
+					options:     options,
+					requestInfo: requestInfo,
+					access:      batch.add(requestInfo, rule, filter),
 				}
 
 				// ignore the auth record email visibility checks
 				// for auth owner, admin or manager
-				if collection.IsAuth() {
-					authId := extractAuthIdFromGetter(client)
-					if authId == cleanRecord.Id {
-						if api.canAccessRecord(cleanRecord, requestInfo, collection.AuthOptions().ManageRule) {
-							cleanRecord.IgnoreEmailVisibility(true)
-						}
-					}
+				if collection.IsAuth() && extractAuthIdFromGetter(client) == record.Id {
+					delivery.manage = batch.add(requestInfo, collection.AuthOptions().ManageRule, filter)
 				}
 
-				data := &recordData{
-					Action: action,
-					Record: cleanRecord,
-				}
+				deliveries = append(deliveries, delivery)
+			}
+		}
+	}
 
-				// check fields
-				rawFields := cast.ToString(options.Query[fieldsQueryParam])
-				if rawFields != "" {
-					decoded, err := rest.PickFields(cleanRecord, rawFields)
-					if err == nil {
-						data.Record = decoded
-					} else {
-						api.app.Logger().Debug(
-							"[broadcastRecord] pick fields error",
-							slog.String("id", cleanRecord.Id),
-							slog.String("collectionName", cleanRecord.Collection().Name),
-							slog.String("sub", sub),
-							slog.String("fields", rawFields),
-							slog.String("error", err.Error()),
-						)
-					}
-				}
+	if len(deliveries) == 0 {
+		return nil // no matching subscriptions
+	}
 
-				dataBytes, err := json.Marshal(data)
-				if err != nil {
-					api.app.Logger().Debug(
-						"[broadcastRecord] data marshal error",
-						slog.String("id", cleanRecord.Id),
-						slog.String("collectionName", cleanRecord.Collection().Name),
-						slog.String("error", err.Error()),
-					)
-					continue
-				}
+	if err := batch.eval(); err != nil {
+		// the failed subscriptions are denied but the others are still delivered
+		api.app.Logger().Debug(
+			"[broadcastRecord] failed to evaluate some of the subscriptions access checks",
+			slog.String("id", record.Id),
+			slog.String("collectionName", collection.Name),
+			slog.String("error", err.Error()),
+		)
+	}
 
-				msg := subscriptions.Message{
-					Name: sub,
-					Data: dataBytes,
-				}
+	dryCacheKey := action + "/" + record.Id
 
-				if dryCache {
-					messages, ok := client.Get(dryCacheKey).([]subscriptions.Message)
-					if !ok {
-						messages = []subscriptions.Message{msg}
-					} else {
-						messages = append(messages, msg)
-					}
-					client.Set(dryCacheKey, messages)
-				} else {
-					routine.FireAndForget(func() {
-						client.Send(msg)
-					})
-				}
+	for _, delivery := range deliveries {
+		if !delivery.access.Allowed() {
+			continue
+		}
+
+		client := delivery.client
+		sub := delivery.sub
+
+		// create a clean record copy without expand and unknown fields
+		// because we don't know yet which exact fields the client subscription has permissions to access
+		cleanRecord := record.CleanCopy()
+
+		if delivery.options.Expand != "" {
+			expandErrs := api.app.Dao().ExpandRecord(
+				cleanRecord,
+				strings.Split(delivery.options.Expand, ","),
+				expandFetch(api.app.Dao(), delivery.requestInfo),
+			)
+			if len(expandErrs) > 0 {
+				api.app.Logger().Debug(
+					"[broadcastRecord] expand errors",
+					slog.String("id", cleanRecord.Id),
+					slog.String("collectionName", cleanRecord.Collection().Name),
+					slog.String("sub", sub),
+					slog.String("expand", delivery.options.Expand),
+					slog.Any("errors", expandErrs),
+				)
+			}
+		}
+
+		if delivery.manage != nil && delivery.manage.Allowed() {
+			cleanRecord.IgnoreEmailVisibility(true)
+		}
+
+		data := &recordData{
+			Action: action,
+			Record: cleanRecord,
+		}
+
+		// check fields
+		if delivery.options.Fields != "" {
+			decoded, err := rest.PickFields(cleanRecord, delivery.options.Fields)
+			if err == nil {
+				data.Record = decoded
+			} else {
+				api.app.Logger().Debug(
+					"[broadcastRecord] pick fields error",
+					slog.String("id", cleanRecord.Id),
+					slog.String("collectionName", cleanRecord.Collection().Name),
+					slog.String("sub", sub),
+					slog.String("fields", delivery.options.Fields),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+
+		dataBytes, err := json.Marshal(data)
+		if err != nil {
+			api.app.Logger().Debug(
+				"[broadcastRecord] data marshal error",
+				slog.String("id", cleanRecord.Id),
+				slog.String("collectionName", cleanRecord.Collection().Name),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+
+		msg := subscriptions.Message{
+			Name: sub,
+			Data: dataBytes,
+		}
+
+		if dryCache {
+			messages, ok := client.Get(dryCacheKey).([]subscriptions.Message)
+			if !ok {
+				messages = []subscriptions.Message{msg}
+			} else {
+				messages = append(messages, msg)
 			}
+			client.Set(dryCacheKey, messages)
+		} else {
+			routine.FireAndForget(func() {
+				client.Send(msg)
+			})
 		}
 	}
 
@@ -548,45 +890,3 @@ func extractAuthIdFromGetter(val getter) string {
 
 	return ""
 }
-
-// canAccessRecord checks if the subscription client has access to the specified record model.
-func (api *realtimeApi) canAccessRecord(
-	record *models.Record,
-	requestInfo *models.RequestInfo,
-	accessRule *string,
-) bool {
-	// check the access rule
-	// ---
-	if ok, _ := api.app.Dao().CanAccessRecord(record, requestInfo, accessRule); !ok {
-		return false
-	}
-
-	// check the subscription client-side filter (if any)
-	// ---
-	filter := cast.ToString(requestInfo.Query[search.FilterQueryParam])
-	if filter == "" {
-		return true // no further checks needed
-	}
-
-	if err := checkForAdminOnlyRuleFields(requestInfo); err != nil {
-		return false
-	}
-
-	ruleFunc := func(q *dbx.SelectQuery) error {
-		resolver := resolvers.NewRecordFieldResolver(api.app.Dao(), record.Collection(), requestInfo, false)
-
-		expr, err := search.FilterData(filter).BuildExpr(resolver)
-		if err != nil {
-			return err
-		}
-		q.AndWhere(expr)
-
-		resolver.UpdateQuery(q)
-
-		return nil
-	}
-
-	_, err := api.app.Dao().FindRecordById(record.Collection().Id, record.Id, ruleFunc)
-
-	return err == nil
-}
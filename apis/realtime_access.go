@@ -0,0 +1,302 @@
+package apis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/resolvers"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+	"github.com/spf13/cast"
+)
+
+// realtimeAccessBatchSize is the max number of record conditions
+// evaluated with a single db query.
+const realtimeAccessBatchSize = 50
+
+var realtimePlaceholderRegex = regexp.MustCompile(`\{:(\w+)\}`)
+
+// realtimeFilterPrefix is the client storage key prefix
+// of the compiled record subscriptions client-side filters.
+const realtimeFilterPrefix = "@filter/"
+
+// realtimeAccessBatch collects the realtime subscriptions access checks
+// for a single record and evaluates them together.
+//
+// Each check consists of an access rule and an optional subscription filter.
+// The rule and filter expressions are compiled only once per unique
+// request info and all of them are evaluated with a single query
+// (per [realtimeAccessBatchSize] conditions) instead of one query per subscription.
+type realtimeAccessBatch struct {
+	dao        *daos.Dao
+	record     *models.Record
+	conditions map[string]*realtimeCondition
+}
+
+// realtimeAccessCheck is a single record access check registered in a realtimeAccessBatch.
+type realtimeAccessCheck struct {
+	conditions []*realtimeCondition
+
+	// denied is set if the check could be resolved without db query.
+	denied bool
+}
+
+// Allowed reports whether the check passed.
+//
+// Should be called only after [realtimeAccessBatch.eval].
+func (c *realtimeAccessCheck) Allowed() bool {
+	if c.denied {
+		return false
+	}
+
+	for _, cond := range c.conditions {
+		if !cond.result {
+			return false
+		}
+	}
+
+	return true
+}
+
+// realtimeCondition is a single record filter expression query.
+type realtimeCondition struct {
+	query  *dbx.SelectQuery
+	result bool
+}
+
+// realtimeFilter is a filter expression compiled for a specific
+// collection and request info (aka. with resolved fields and joins)
+// that could be applied to any of the collection records.
+type realtimeFilter struct {
+	// collectionUpdated is the collection state the filter was compiled for.
+	collectionUpdated types.DateTime
+
+	// nil if the filter expression cannot be compiled
+	resolver *resolvers.RecordFieldResolver
+	expr     dbx.Expression
+}
+
+// compileRealtimeFilter compiles the provided filter expression
+// for the collection records.
+//
+// Invalid filter expressions are also returned but they always deny the access.
+func compileRealtimeFilter(
+	dao *daos.Dao,
+	collection *models.Collection,
+	requestInfo *models.RequestInfo,
+	filter string,
+	allowHiddenFields bool,
+) *realtimeFilter {
+	compiled := &realtimeFilter{collectionUpdated: collection.Updated}
+
+	resolver := resolvers.NewRecordFieldResolver(dao, collection, requestInfo, allowHiddenFields)
+	expr, err := search.FilterData(filter).BuildExpr(resolver)
+	if err == nil {
+		compiled.resolver = resolver
+		compiled.expr = expr
+	}
+
+	return compiled
+}
+
+// compileRealtimeSubscriptionFilter compiles the record subscription
+// client-side filter (if any) from the request info query.
+//
+// Returns nil if the subscription doesn't have a filter.
+func compileRealtimeSubscriptionFilter(
+	dao *daos.Dao,
+	collection *models.Collection,
+	requestInfo *models.RequestInfo,
+) *realtimeFilter {
+	filter := cast.ToString(requestInfo.Query[search.FilterQueryParam])
+	if filter == "" {
+		return nil
+	}
+
+	if err := checkForAdminOnlyRuleFields(requestInfo); err != nil {
+		return &realtimeFilter{collectionUpdated: collection.Updated}
+	}
+
+	return compileRealtimeFilter(dao, collection, requestInfo, filter, false)
+}
+
+func newRealtimeAccessBatch(dao *daos.Dao, record *models.Record) *realtimeAccessBatch {
+	return &realtimeAccessBatch{
+		dao:        dao,
+		record:     record,
+		conditions: map[string]*realtimeCondition{},
+	}
+}
+
+// add registers a new record access check consisting of the access
+// rule and the already compiled subscription client-side filter (if any).
+func (b *realtimeAccessBatch) add(
+	requestInfo *models.RequestInfo,
+	accessRule *string,
+	filter *realtimeFilter,
+) *realtimeAccessCheck {
+	check := &realtimeAccessCheck{}
+
+	infoKey := realtimeRequestInfoKey(requestInfo)
+
+	// check the access rule
+	// ---
+	if requestInfo.Admin == nil {
+		if accessRule == nil {
+			check.denied = true // only admins can access the record
+			return check
+		}
+
+		if *accessRule != "" {
+			cond := b.condition("rule:"+*accessRule+":"+infoKey, func() *realtimeFilter {
+				return compileRealtimeFilter(b.dao, b.record.Collection(), requestInfo, *accessRule, true)
+			})
+			if cond == nil {
+				check.denied = true
+				return check
+			}
+			check.conditions = append(check.conditions, cond)
+		}
+	}
+
+	// check the subscription client-side filter (if any)
+	// ---
+	if filter != nil {
+		rawFilter := cast.ToString(requestInfo.Query[search.FilterQueryParam])
+
+		cond := b.condition("filter:"+rawFilter+":"+infoKey, func() *realtimeFilter {
+			return filter
+		})
+		if cond == nil {
+			check.denied = true
+			return check
+		}
+		check.conditions = append(check.conditions, cond)
+	}
+
+	return check
+}
+
+// condition returns the condition associated with the key
+// (creating and caching a new one from the compiled filter if missing).
+//
+// Returns nil if the filter expression cannot be compiled.
+func (b *realtimeAccessBatch) condition(key string, compile func() *realtimeFilter) *realtimeCondition {
+	if cond, ok := b.conditions[key]; ok {
+		return cond
+	}
+
+	filter := compile()
+	if filter == nil || filter.expr == nil {
+		return nil
+	}
+
+	collection := b.record.Collection()
+
+	query := b.dao.RecordQuery(collection).
+		Select("(1)").
+		AndWhere(dbx.HashExp{collection.Name + ".id": b.record.Id})
+
+	filter.resolver.UpdateQuery(query)
+	query.AndWhere(filter.expr)
+
+	cond := &realtimeCondition{query: query.Limit(1)}
+	b.conditions[key] = cond
+
+	return cond
+}
+
+// eval evaluates all registered checks conditions.
+//
+// A condition that fails to evaluate (eg. comparing a date field with
+// a non-date value) is resolved as denied without affecting the other
+// conditions. The returned error (if any) is the joined conditions errors.
+func (b *realtimeAccessBatch) eval() error {
+	conditions := make([]*realtimeCondition, 0, len(b.conditions))
+	for _, cond := range b.conditions {
+		conditions = append(conditions, cond)
+	}
+
+	var errs []error
+
+	for i := 0; i < len(conditions); i += realtimeAccessBatchSize {
+		end := i + realtimeAccessBatchSize
+		if end > len(conditions) {
+			end = len(conditions)
+		}
+
+		chunk := conditions[i:end]
+
+		if err := b.evalChunk(chunk); err == nil || len(chunk) == 1 {
+			errs = append(errs, err)
+			continue
+		}
+
+		// a single failing condition fails the entire chunk query
+		// so fallback to evaluating the chunk conditions one by one
+		for _, cond := range chunk {
+			errs = append(errs, b.evalChunk([]*realtimeCondition{cond}))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// evalChunk evaluates the provided conditions with a single
+// "SELECT EXISTS(cond1) AS c0, EXISTS(cond2) AS c1, ..." query.
+//
+// On error all chunk conditions are resolved as denied.
+func (b *realtimeAccessBatch) evalChunk(conditions []*realtimeCondition) error {
+	selects := make([]string, len(conditions))
+	params := dbx.Params{}
+
+	for i, cond := range conditions {
+		cond.result = false
+
+		built := cond.query.Build()
+
+		// prefix the subquery placeholders to avoid conflicts with the other subqueries
+		prefix := fmt.Sprintf("c%d_", i)
+		sql := realtimePlaceholderRegex.ReplaceAllString(built.SQL(), "{:"+prefix+"$1}")
+		for k, v := range built.Params() {
+			params[prefix+k] = v
+		}
+
+		selects[i] = fmt.Sprintf("EXISTS(%s) AS [[c%d]]", sql, i)
+	}
+
+	row := dbx.NullStringMap{}
+
+	err := b.dao.DB().NewQuery("SELECT " + strings.Join(selects, ", ")).Bind(params).One(row)
+	if err != nil {
+		return err
+	}
+
+	for i, cond := range conditions {
+		cond.result = cast.ToBool(row[fmt.Sprintf("c%d", i)].String)
+	}
+
+	return nil
+}
+
+// realtimeRequestInfoKey returns a key that identifies the
+// request info data that could be referenced in a filter expression.
+func realtimeRequestInfoKey(requestInfo *models.RequestInfo) string {
+	var adminId, authId string
+	if requestInfo.Admin != nil {
+		adminId = requestInfo.Admin.Id
+	}
+	if requestInfo.AuthRecord != nil {
+		authId = requestInfo.AuthRecord.Collection().Id + "/" + requestInfo.AuthRecord.Id
+	}
+
+	raw, _ := json.Marshal([]any{adminId, authId, requestInfo.Query, requestInfo.Headers})
+
+	return string(raw)
+}
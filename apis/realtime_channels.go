@@ -0,0 +1,549 @@
+package apis
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/settings"
+	"github.com/hylarucoder/rocketbase/tools/routine"
+	"github.com/hylarucoder/rocketbase/tools/security"
+	"github.com/hylarucoder/rocketbase/tools/subscriptions"
+	"github.com/labstack/echo/v5"
+	"github.com/spf13/cast"
+)
+
+// Realtime channels subscription topic prefixes
+// (eg. "@channels/rooms:1" and "@presence/rooms:1").
+const (
+	realtimeChannelTopicPrefix  = "@channels/"
+	realtimePresenceTopicPrefix = "@presence/"
+)
+
+// Realtime channels client store keys.
+const (
+	realtimeClientRefKey        = "@ref"
+	realtimePresenceKey         = "@presence"
+	realtimeChannelAccessPrefix = "@channelAccess/"
+)
+
+// Realtime presence message events.
+const (
+	realtimePresenceEventSync   = "sync"
+	realtimePresenceEventJoin   = core.RealtimePresenceJoin
+	realtimePresenceEventUpdate = core.RealtimePresenceUpdate
+	realtimePresenceEventLeave  = core.RealtimePresenceLeave
+)
+
+var realtimeChannelNameRegex = regexp.MustCompile(`^[\w\-\.\:]+$`)
+
+// realtimeChannelMessage represents the broadcasted channel subscription message data.
+type realtimeChannelMessage struct {
+	Channel string `json:"channel"`
+
+	// Sender is the public reference of the publisher client
+	// (empty if published with a plain HTTP request).
+	Sender string `json:"sender"`
+
+	Data any `json:"data"`
+}
+
+// realtimePresenceMessage represents the broadcasted presence subscription message data.
+type realtimePresenceMessage struct {
+	Channel string                    `json:"channel"`
+	Event   string                    `json:"event"`
+	Member  *realtimePresenceMember   `json:"member,omitempty"`
+	Members []*realtimePresenceMember `json:"members,omitempty"`
+}
+
+// realtimePresenceMember represents a single channel presence member.
+type realtimePresenceMember struct {
+	// Ref is the public client reference
+	// (the client id is not exposed because it is used as a secret).
+	Ref    string         `json:"ref"`
+	AuthId string         `json:"authId"`
+	State  map[string]any `json:"state"`
+}
+
+// publish handles the channel message publish HTTP request.
+//
+// The request body could contain an optional "clientId" to identify
+// the publisher client (it is excluded from the message recipients).
+func (api *realtimeApi) publish(c echo.Context) error {
+	requestInfo := RequestInfo(c)
+
+	var client subscriptions.Client
+	if clientId := cast.ToString(requestInfo.Data["clientId"]); clientId != "" {
+		var err error
+		if client, err = api.findRequestClient(c, clientId); err != nil {
+			return err
+		}
+	}
+
+	err := api.publishToChannel(c, client, requestInfo, c.PathParam("channel"), requestInfo.Data["data"])
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// updatePresence handles the channel presence state update HTTP request.
+func (api *realtimeApi) updatePresence(c echo.Context) error {
+	requestInfo := RequestInfo(c)
+
+	client, err := api.findRequestClient(c, cast.ToString(requestInfo.Data["clientId"]))
+	if err != nil {
+		return err
+	}
+
+	state, _ := requestInfo.Data["state"].(map[string]any)
+
+	if err := api.presenceUpdate(client, c.PathParam("channel"), state); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findRequestClient returns the subscription client with the specified id
+// ensuring that its auth state matches with the one of the current request.
+func (api *realtimeApi) findRequestClient(c echo.Context, clientId string) (subscriptions.Client, error) {
+	client, err := api.app.SubscriptionsBroker().ClientById(clientId)
+	if err != nil {
+		return nil, NewNotFoundError("Missing or invalid client id.", err)
+	}
+
+	clientAuthId := extractAuthIdFromGetter(client)
+	if clientAuthId != "" && clientAuthId != extractAuthIdFromGetter(c) {
+		return nil, NewForbiddenError("The current and the previous request authorization don't match.", nil)
+	}
+
+	return client, nil
+}
+
+// publishToChannel checks the channel publish rule and broadcasts
+// the provided message data to the channel subscribers.
+func (api *realtimeApi) publishToChannel(
+	c echo.Context,
+	client subscriptions.Client,
+	requestInfo *models.RequestInfo,
+	channel string,
+	data any,
+) error {
+	config := api.findChannel(channel)
+	if config == nil {
+		return NewNotFoundError("Missing or invalid channel.", nil)
+	}
+
+	info := *requestInfo
+	info.Context = models.RequestInfoContextRealtime
+	info.Data = map[string]any{"channel": channel, "data": data}
+
+	if !api.checkChannelRule(&info, config.PublishRule) {
+		return NewForbiddenError("You are not allowed to publish to the channel.", nil)
+	}
+
+	event := &core.RealtimeChannelEvent{
+		HttpContext: c,
+		Client:      client,
+		Channel:     channel,
+		Data:        data,
+	}
+
+	return api.app.OnRealtimeBeforeChannelPublish().Trigger(event, func(e *core.RealtimeChannelEvent) error {
+		msg := &realtimeChannelMessage{
+			Channel: e.Channel,
+			Data:    e.Data,
+		}
+
+		var senderId string
+		if e.Client != nil {
+			msg.Sender = realtimeClientRef(e.Client)
+			senderId = e.Client.Id()
+		}
+
+		if err := api.broadcastChannel(realtimeChannelTopicPrefix, e.Channel, msg, senderId); err != nil {
+			return NewBadRequestError("Failed to publish the channel message.", err)
+		}
+
+		return api.app.OnRealtimeAfterChannelPublish().Trigger(e)
+	})
+}
+
+// broadcastChannel sends the provided data to all clients with an
+// accessible topic subscription for the specified channel
+// (except the client with excludeClientId).
+func (api *realtimeApi) broadcastChannel(topicPrefix string, channel string, data any, excludeClientId string) error {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	for _, client := range api.app.SubscriptionsBroker().Clients() {
+		if client.Id() == excludeClientId {
+			continue
+		}
+
+		for sub := range client.Subscriptions(topicPrefix + channel + "?") {
+			if !realtimeHasChannelAccess(client, sub) {
+				continue
+			}
+
+			client := client
+			msg := subscriptions.Message{
+				Name: sub,
+				Data: rawData,
+			}
+
+			routine.FireAndForget(func() {
+				client.Send(msg)
+			})
+		}
+	}
+
+	return nil
+}
+
+// syncChannelSubscriptions evaluates the subscribe rule of the client
+// channel subscriptions and updates accordingly its presence memberships.
+//
+// It is expected to be called after each client subscriptions or auth state change.
+func (api *realtimeApi) syncChannelSubscriptions(client subscriptions.Client) {
+	presenceSubs := map[string]string{} // channel -> sub
+
+	subs := client.Subscriptions(realtimeChannelTopicPrefix, realtimePresenceTopicPrefix)
+
+	for sub, options := range subs {
+		topicPrefix := realtimeChannelTopicPrefix
+		if strings.HasPrefix(sub, realtimePresenceTopicPrefix) {
+			topicPrefix = realtimePresenceTopicPrefix
+		}
+
+		channel := realtimeTopicChannel(sub, topicPrefix)
+
+		config := api.findChannel(channel)
+
+		allowed := config != nil && api.checkChannelRule(
+			realtimeClientRequestInfo(client, options, channel),
+			config.SubscribeRule,
+		)
+
+		client.Set(realtimeChannelAccessPrefix+sub, allowed)
+
+		if allowed && topicPrefix == realtimePresenceTopicPrefix && config.Presence {
+			presenceSubs[channel] = sub
+		}
+	}
+
+	api.presenceMux.Lock()
+	defer api.presenceMux.Unlock()
+
+	// leave the no longer subscribed or accessible presence channels
+	for channel := range realtimePresenceStates(client) {
+		if _, ok := presenceSubs[channel]; !ok {
+			api.presenceLeave(client, channel)
+		}
+	}
+
+	// join the new presence channels
+	for channel, sub := range presenceSubs {
+		if _, ok := realtimePresenceStates(client)[channel]; !ok {
+			api.presenceJoin(client, channel, sub)
+		}
+	}
+}
+
+// findChannel returns the realtime channel config matching the provided channel name.
+//
+// Returns nil if the channel name is invalid or there is no such channel.
+func (api *realtimeApi) findChannel(channel string) *settings.RealtimeChannelConfig {
+	if !realtimeChannelNameRegex.MatchString(channel) {
+		return nil
+	}
+
+	return api.app.Settings().Realtime.FindChannel(channel)
+}
+
+// checkChannelRule checks whether the provided request info satisfies the channel rule.
+//
+// Non-empty rules are evaluated against the request auth record
+// (guests are allowed to access only channels with empty rule).
+func (api *realtimeApi) checkChannelRule(requestInfo *models.RequestInfo, rule *string) bool {
+	if requestInfo.Admin != nil {
+		return true // admins can access everything
+	}
+
+	if rule == nil {
+		return false // only admins can access the channel
+	}
+
+	if *rule == "" {
+		return true // public channel
+	}
+
+	if requestInfo.AuthRecord == nil {
+		return false
+	}
+
+	ok, err := api.app.Dao().CanAccessRecord(requestInfo.AuthRecord, requestInfo, rule)
+	if err != nil {
+		api.app.Logger().Debug(
+			"Failed to check the realtime channel rule",
+			slog.String("rule", *rule),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return ok
+}
+
+// presenceUpdate replaces the presence state of a channel member client.
+func (api *realtimeApi) presenceUpdate(client subscriptions.Client, channel string, state map[string]any) error {
+	api.presenceMux.Lock()
+	defer api.presenceMux.Unlock()
+
+	if _, ok := realtimePresenceStates(client)[channel]; !ok {
+		return NewBadRequestError("The client is not a member of the channel presence.", nil)
+	}
+
+	if state == nil {
+		state = map[string]any{}
+	}
+
+	event := &core.RealtimePresenceEvent{
+		Client:  client,
+		Channel: channel,
+		Action:  core.RealtimePresenceUpdate,
+		State:   state,
+	}
+
+	return api.app.OnRealtimePresenceChange().Trigger(event, func(e *core.RealtimePresenceEvent) error {
+		realtimeSetPresenceState(e.Client, e.Channel, e.State)
+
+		return api.broadcastChannel(realtimePresenceTopicPrefix, e.Channel, &realtimePresenceMessage{
+			Channel: e.Channel,
+			Event:   realtimePresenceEventUpdate,
+			Member:  realtimeNewPresenceMember(e.Client, e.State),
+		}, e.Client.Id())
+	})
+}
+
+// presenceJoin adds the client to the channel presence members
+// and sends it the current members state.
+//
+// The caller must hold the api.presenceMux lock.
+func (api *realtimeApi) presenceJoin(client subscriptions.Client, channel string, sub string) {
+	event := &core.RealtimePresenceEvent{
+		Client:  client,
+		Channel: channel,
+		Action:  core.RealtimePresenceJoin,
+		State:   map[string]any{},
+	}
+
+	err := api.app.OnRealtimePresenceChange().Trigger(event, func(e *core.RealtimePresenceEvent) error {
+		if e.State == nil {
+			e.State = map[string]any{}
+		}
+
+		realtimeSetPresenceState(e.Client, e.Channel, e.State)
+
+		err := api.broadcastChannel(realtimePresenceTopicPrefix, e.Channel, &realtimePresenceMessage{
+			Channel: e.Channel,
+			Event:   realtimePresenceEventJoin,
+			Member:  realtimeNewPresenceMember(e.Client, e.State),
+		}, e.Client.Id())
+		if err != nil {
+			return err
+		}
+
+		// sync the current members state with the joined client
+		syncData, err := json.Marshal(&realtimePresenceMessage{
+			Channel: e.Channel,
+			Event:   realtimePresenceEventSync,
+			Members: api.presenceMembers(e.Channel),
+		})
+		if err != nil {
+			return err
+		}
+
+		msg := subscriptions.Message{Name: sub, Data: syncData}
+		routine.FireAndForget(func() {
+			client.Send(msg)
+		})
+
+		return nil
+	})
+	if err != nil {
+		api.app.Logger().Debug(
+			"Failed to join the realtime channel presence",
+			slog.String("clientId", client.Id()),
+			slog.String("channel", channel),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// presenceLeave removes the client from the channel presence members.
+//
+// The caller must hold the api.presenceMux lock.
+func (api *realtimeApi) presenceLeave(client subscriptions.Client, channel string) {
+	event := &core.RealtimePresenceEvent{
+		Client:  client,
+		Channel: channel,
+		Action:  core.RealtimePresenceLeave,
+		State:   realtimePresenceStates(client)[channel],
+	}
+
+	hookErr := api.app.OnRealtimePresenceChange().Trigger(event)
+	if hookErr != nil {
+		api.app.Logger().Debug(
+			"OnRealtimePresenceChange leave error",
+			slog.String("clientId", client.Id()),
+			slog.String("channel", channel),
+			slog.String("error", hookErr.Error()),
+		)
+	}
+
+	// the leave cannot be prevented
+	realtimeSetPresenceState(client, channel, nil)
+
+	err := api.broadcastChannel(realtimePresenceTopicPrefix, channel, &realtimePresenceMessage{
+		Channel: channel,
+		Event:   realtimePresenceEventLeave,
+		Member:  realtimeNewPresenceMember(client, event.State),
+	}, client.Id())
+	if err != nil {
+		api.app.Logger().Debug(
+			"Failed to broadcast the realtime channel presence leave",
+			slog.String("clientId", client.Id()),
+			slog.String("channel", channel),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// presenceLeaveAll removes the client from all of its channel presences.
+func (api *realtimeApi) presenceLeaveAll(client subscriptions.Client) {
+	api.presenceMux.Lock()
+	defer api.presenceMux.Unlock()
+
+	for channel := range realtimePresenceStates(client) {
+		api.presenceLeave(client, channel)
+	}
+}
+
+// presenceMembers returns the current members of the channel presence.
+func (api *realtimeApi) presenceMembers(channel string) []*realtimePresenceMember {
+	members := []*realtimePresenceMember{}
+
+	for _, client := range api.app.SubscriptionsBroker().Clients() {
+		if state, ok := realtimePresenceStates(client)[channel]; ok {
+			members = append(members, realtimeNewPresenceMember(client, state))
+		}
+	}
+
+	return members
+}
+
+// unregisterClient leaves all client channel presences
+// and removes the client from the subscriptions broker.
+func (api *realtimeApi) unregisterClient(client subscriptions.Client) {
+	api.presenceLeaveAll(client)
+
+	api.app.SubscriptionsBroker().Unregister(client.Id())
+}
+
+// realtimeTopicChannel extracts the channel name from a channel topic subscription.
+func realtimeTopicChannel(sub string, topicPrefix string) string {
+	channel := strings.TrimPrefix(sub, topicPrefix)
+
+	if i := strings.Index(channel, "?"); i >= 0 {
+		channel = channel[:i]
+	}
+
+	return channel
+}
+
+// realtimeClientRequestInfo returns a mocked request info for the
+// channel subscription access checks.
+func realtimeClientRequestInfo(
+	client subscriptions.Client,
+	options subscriptions.SubscriptionOptions,
+	channel string,
+) *models.RequestInfo {
+	requestInfo := &models.RequestInfo{
+		Context: models.RequestInfoContextRealtime,
+		Method:  "GET",
+		Query:   options.Query,
+		Headers: options.Headers,
+		Data:    map[string]any{"channel": channel},
+	}
+	requestInfo.Admin, _ = client.Get(ContextAdminKey).(*models.Admin)
+	requestInfo.AuthRecord, _ = client.Get(ContextAuthRecordKey).(*models.Record)
+
+	return requestInfo
+}
+
+// realtimeHasChannelAccess reports whether the client subscription
+// has passed the channel subscribe rule check.
+func realtimeHasChannelAccess(client subscriptions.Client, sub string) bool {
+	allowed, _ := client.Get(realtimeChannelAccessPrefix + sub).(bool)
+
+	return allowed
+}
+
+// realtimeClientRef returns the public reference of the provided client
+// (generating a new one if missing).
+func realtimeClientRef(client subscriptions.Client) string {
+	ref, _ := client.Get(realtimeClientRefKey).(string)
+	if ref == "" {
+		ref = security.RandomString(15)
+		client.Set(realtimeClientRefKey, ref)
+	}
+
+	return ref
+}
+
+// realtimePresenceStates returns the client presence states indexed by their channel.
+func realtimePresenceStates(client subscriptions.Client) map[string]map[string]any {
+	states, _ := client.Get(realtimePresenceKey).(map[string]map[string]any)
+
+	return states
+}
+
+// realtimeSetPresenceState replaces the client presence state for the
+// specified channel (nil state removes the channel membership).
+func realtimeSetPresenceState(client subscriptions.Client, channel string, state map[string]any) {
+	old := realtimePresenceStates(client)
+
+	// copy to avoid modifying a shared map
+	states := make(map[string]map[string]any, len(old)+1)
+	for k, v := range old {
+		states[k] = v
+	}
+
+	if state == nil {
+		delete(states, channel)
+	} else {
+		states[channel] = state
+	}
+
+	client.Set(realtimePresenceKey, states)
+}
+
+func realtimeNewPresenceMember(client subscriptions.Client, state map[string]any) *realtimePresenceMember {
+	if state == nil {
+		state = map[string]any{}
+	}
+
+	return &realtimePresenceMember{
+		Ref:    realtimeClientRef(client),
+		AuthId: extractAuthIdFromGetter(client),
+		State:  state,
+	}
+}
@@ -1,8 +1,10 @@
 package apis_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -11,17 +13,22 @@ import (
 	"github.com/hylarucoder/rocketbase/core"
 	"github.com/hylarucoder/rocketbase/daos"
 	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/settings"
 	"github.com/hylarucoder/rocketbase/tests"
 	"github.com/hylarucoder/rocketbase/tools/hook"
 	"github.com/hylarucoder/rocketbase/tools/subscriptions"
+	"github.com/hylarucoder/rocketbase/tools/types"
 	"github.com/labstack/echo/v5"
 	"github.com/pocketbase/dbx"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/websocket"
 )
 
 func (suite *RealtimeTestSuite) TestRealtimeConnect() {
 	t := suite.T()
 
+	var resumeRouter *echo.Echo
+
 	scenarios := []tests.ApiScenario{
 		{
 			Method:         http.MethodGet,
@@ -92,6 +99,167 @@ func (suite *RealtimeTestSuite) TestRealtimeConnect() {
 				}
 			},
 		},
+		{
+			Name:           "non resumable Last-Event-ID",
+			Method:         http.MethodGet,
+			Url:            "/api/realtime",
+			Timeout:        100 * time.Millisecond,
+			RequestHeaders: map[string]string{"Last-Event-ID": "missing:10"},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`event:PB_CONNECT`,
+				`event:PB_RESYNC`,
+				`"subscriptions":[]`,
+			},
+			NotExpectedContent: []string{
+				`"resumed":true`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnRealtimeConnectRequest":    1,
+				"OnRealtimeBeforeMessageSend": 2,
+				"OnRealtimeAfterMessageSend":  2,
+				"OnRealtimeDisconnectRequest": 1,
+			},
+		},
+		{
+			Name:           "resume with Last-Event-ID",
+			Method:         http.MethodGet,
+			Url:            "/api/realtime",
+			Timeout:        100 * time.Millisecond,
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`event:PB_CONNECT`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnRealtimeConnectRequest":    1,
+				"OnRealtimeBeforeMessageSend": 1,
+				"OnRealtimeAfterMessageSend":  1,
+				"OnRealtimeDisconnectRequest": 1,
+			},
+			BeforeTestFunc: func(t *testing.T, app *tests.TestApp, e *echo.Echo) {
+				resumeRouter = e
+
+				app.OnRealtimeConnectRequest().Add(func(e *core.RealtimeConnectEvent) error {
+					if len(e.Client.Subscriptions()) == 0 {
+						e.Client.Subscribe("test1", "test2")
+					}
+					return nil
+				})
+			},
+			AfterTestFunc: func(t *testing.T, app *tests.TestApp, res *http.Response) {
+				clients := app.SubscriptionsBroker().Clients()
+				if len(clients) != 1 {
+					t.Fatalf("Expected the client to be kept for resuming, found %d clients", len(clients))
+				}
+
+				var client subscriptions.Client
+				for _, c := range clients {
+					client = c
+				}
+
+				// messages sent while disconnected
+				client.Send(subscriptions.Message{Name: "test1", Data: []byte(`{"a":1}`)})
+				client.Send(subscriptions.Message{Name: "test2", Data: []byte(`{"b":2}`)})
+				client.Send(subscriptions.Message{Name: "test1", Data: []byte(`{"c":3}`)})
+
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+
+				req := httptest.NewRequest(http.MethodGet, "/api/realtime", nil).WithContext(ctx)
+				req.Header.Set("Last-Event-ID", client.Id()+":1")
+				rec := httptest.NewRecorder()
+				resumeRouter.ServeHTTP(rec, req)
+
+				body := rec.Body.String()
+
+				expected := []string{
+					`"clientId":"` + client.Id() + `","resumed":true`,
+					"id:" + client.Id() + ":2\nevent:test2\ndata:{\"b\":2}",
+					"id:" + client.Id() + ":3\nevent:test1\ndata:{\"c\":3}",
+				}
+				for _, item := range expected {
+					if !strings.Contains(body, item) {
+						t.Errorf("Cannot find %q in the resumed response body\n%v", item, body)
+					}
+				}
+
+				notExpected := []string{`event:PB_RESYNC`, `{"a":1}`}
+				for _, item := range notExpected {
+					if strings.Contains(body, item) {
+						t.Errorf("Didn't expect %q in the resumed response body\n%v", item, body)
+					}
+				}
+			},
+		},
+		{
+			Name:           "resume with Last-Event-ID and different auth",
+			Method:         http.MethodGet,
+			Url:            "/api/realtime",
+			Timeout:        100 * time.Millisecond,
+			RequestHeaders: map[string]string{"Authorization": suite.UserAuthToken},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`event:PB_CONNECT`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnRealtimeConnectRequest":    1,
+				"OnRealtimeBeforeMessageSend": 1,
+				"OnRealtimeAfterMessageSend":  1,
+				"OnRealtimeDisconnectRequest": 1,
+			},
+			BeforeTestFunc: func(t *testing.T, app *tests.TestApp, e *echo.Echo) {
+				resumeRouter = e
+
+				app.OnRealtimeConnectRequest().Add(func(e *core.RealtimeConnectEvent) error {
+					if len(e.Client.Subscriptions()) == 0 {
+						e.Client.Set(apis.ContextAuthRecordKey, e.HttpContext.Get(apis.ContextAuthRecordKey))
+						e.Client.Subscribe("test1")
+					}
+					return nil
+				})
+			},
+			AfterTestFunc: func(t *testing.T, app *tests.TestApp, res *http.Response) {
+				clients := app.SubscriptionsBroker().Clients()
+				if len(clients) != 1 {
+					t.Fatalf("Expected the client to be kept for resuming, found %d clients", len(clients))
+				}
+
+				var client subscriptions.Client
+				for _, c := range clients {
+					client = c
+				}
+
+				resume := func(auth string) string {
+					ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+					defer cancel()
+
+					req := httptest.NewRequest(http.MethodGet, "/api/realtime", nil).WithContext(ctx)
+					req.Header.Set("Last-Event-ID", client.Id()+":0")
+					if auth != "" {
+						req.Header.Set("Authorization", auth)
+					}
+					rec := httptest.NewRecorder()
+					resumeRouter.ServeHTTP(rec, req)
+					return rec.Body.String()
+				}
+
+				// anonymous
+				if body := resume(""); strings.Contains(body, `"resumed":true`) ||
+					!strings.Contains(body, `"subscriptions":[]`) {
+					t.Fatalf("Expected the anonymous request to not resume the client\n%v", body)
+				}
+
+				// different auth
+				if body := resume(suite.AdminAuthToken); strings.Contains(body, `"resumed":true`) {
+					t.Fatalf("Expected the admin request to not resume the client\n%v", body)
+				}
+
+				// the same auth
+				if body := resume(suite.UserAuthToken); !strings.Contains(body, `"clientId":"`+client.Id()+`","resumed":true`) {
+					t.Fatalf("Expected the client to be resumed with the same auth\n%v", body)
+				}
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -455,6 +623,315 @@ func (suite *RealtimeTestSuite) TestRealtimeCustomAuthModelUpdateEvent() {
 	}
 }
 
+func (suite *RealtimeTestSuite) TestRealtimeRecordFilteredSubscriptions() {
+	t := suite.T()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	apis.InitApi(app)
+
+	record, err := app.Dao().FindRecordById("demo2", "3479948460419978246")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		name             string
+		sub              string
+		expectMessage    bool
+		expectedContent  []string
+		notExpectContent []string
+	}{
+		{
+			"without filter",
+			"demo2/*",
+			true,
+			[]string{`"action":"update"`, `"title":`},
+			nil,
+		},
+		{
+			"matching filter option",
+			`demo2/*?options={"filter":"id='` + record.Id + `'"}`,
+			true,
+			[]string{`"id":"` + record.Id + `"`},
+			nil,
+		},
+		{
+			"matching filter query param",
+			`demo2/*?options={"query":{"filter":"id='` + record.Id + `'"}}`,
+			true,
+			[]string{`"id":"` + record.Id + `"`},
+			nil,
+		},
+		{
+			"non-matching filter option",
+			`demo2/*?options={"filter":"id='missing'"}`,
+			false,
+			nil,
+			nil,
+		},
+		{
+			"invalid filter option",
+			`demo2/*?options={"filter":"missing_field=1"}`,
+			false,
+			nil,
+			nil,
+		},
+		{
+			// fails only on evaluation and shouldn't affect the other subscriptions
+			"filter with invalid value",
+			`demo2/*?options={"filter":"created > 'abc'"}`,
+			false,
+			nil,
+			nil,
+		},
+		{
+			"matching filter with fields option",
+			`demo2/*?options={"filter":"id='` + record.Id + `'","fields":"id"}`,
+			true,
+			[]string{`"record":{"id":"` + record.Id + `"}`},
+			[]string{`"title":`},
+		},
+	}
+
+	clients := make([]subscriptions.Client, len(scenarios))
+	for i, s := range scenarios {
+		clients[i] = subscriptions.NewDefaultClient()
+		clients[i].Subscribe(s.sub)
+		app.SubscriptionsBroker().Register(clients[i])
+	}
+
+	e := new(core.ModelEvent)
+	e.Dao = app.Dao()
+	e.Model = record
+	app.OnModelAfterUpdate().Trigger(e)
+
+	for i, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			var msg *subscriptions.Message
+			select {
+			case m := <-clients[i].Channel():
+				msg = &m
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			if !s.expectMessage {
+				if msg != nil {
+					t.Fatalf("Expected no message, got %s", msg.Data)
+				}
+				return
+			}
+
+			if msg == nil {
+				t.Fatal("Expected message, got nil")
+			}
+
+			for _, item := range s.expectedContent {
+				if !strings.Contains(string(msg.Data), item) {
+					t.Errorf("Cannot find %s in %s", item, msg.Data)
+				}
+			}
+
+			for _, item := range s.notExpectContent {
+				if strings.Contains(string(msg.Data), item) {
+					t.Errorf("Didn't expect %s in %s", item, msg.Data)
+				}
+			}
+		})
+	}
+}
+
+func (suite *RealtimeTestSuite) TestRealtimeChannels() {
+	t := suite.T()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	app.Settings().Realtime.Channels = []settings.RealtimeChannelConfig{
+		{Name: "news", SubscribeRule: types.Pointer("")},
+		{Name: "rooms:*", SubscribeRule: types.Pointer(""), PublishRule: types.Pointer(""), Presence: true},
+	}
+
+	e, err := apis.InitApi(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(url string, body string, expectedStatus int) {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != expectedStatus {
+			t.Fatalf("[%s] Expected status %d, got %d (%s)", url, expectedStatus, rec.Code, rec.Body.String())
+		}
+	}
+
+	read := func(client subscriptions.Client, expectedContent ...string) {
+		select {
+		case msg := <-client.Channel():
+			for _, item := range expectedContent {
+				if !strings.Contains(string(msg.Data), item) {
+					t.Fatalf("Cannot find %s in %s", item, msg.Data)
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected message with %v, got none", expectedContent)
+		}
+	}
+
+	expectNoMessage := func(client subscriptions.Client) {
+		select {
+		case msg := <-client.Channel():
+			t.Fatalf("Expected no message, got %s %s", msg.Name, msg.Data)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	clientA := subscriptions.NewDefaultClient()
+	clientB := subscriptions.NewDefaultClient()
+	app.SubscriptionsBroker().Register(clientA)
+	app.SubscriptionsBroker().Register(clientB)
+
+	subs := `["@channels/rooms:1","@presence/rooms:1"]`
+
+	// join
+	request("/api/realtime", `{"clientId":"`+clientA.Id()+`","subscriptions":`+subs+`}`, 204)
+	read(clientA, `"event":"sync"`, `"members":[{`)
+
+	request("/api/realtime", `{"clientId":"`+clientB.Id()+`","subscriptions":`+subs+`}`, 204)
+	read(clientB, `"event":"sync"`, `"members":[{`, `},{`)
+	read(clientA, `"event":"join"`, `"member":{`)
+
+	// publish
+	request("/api/realtime/channels/rooms:1", `{"clientId":"`+clientB.Id()+`","data":{"text":"hi"}}`, 204)
+	read(clientA, `"channel":"rooms:1"`, `"data":{"text":"hi"}`, `"sender":"`)
+	expectNoMessage(clientB)
+
+	request("/api/realtime/channels/missing", `{"data":1}`, 404)
+	request("/api/realtime/channels/news", `{"data":1}`, 403)
+	request("/api/realtime/channels/rooms:2", `{"clientId":"missing","data":1}`, 404)
+
+	// presence state update
+	request("/api/realtime/presence/rooms:1", `{"clientId":"`+clientA.Id()+`","state":{"typing":true}}`, 204)
+	read(clientB, `"event":"update"`, `"state":{"typing":true}`)
+	request("/api/realtime/presence/rooms:2", `{"clientId":"`+clientA.Id()+`","state":{"typing":true}}`, 400)
+
+	// leave
+	request("/api/realtime", `{"clientId":"`+clientB.Id()+`","subscriptions":[]}`, 204)
+	read(clientA, `"event":"leave"`)
+	expectNoMessage(clientA)
+}
+
+func (suite *RealtimeTestSuite) TestRealtimeWebSocket() {
+	t := suite.T()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	e, err := apis.InitApi(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/realtime/ws", "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	send := func(frame string) {
+		if _, err := ws.Write([]byte(frame)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func() map[string]any {
+		ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+		result := map[string]any{}
+		if err := websocket.JSON.Receive(ws, &result); err != nil {
+			t.Fatal(err)
+		}
+
+		return result
+	}
+
+	// connect
+	connectFrame := read()
+	if connectFrame["type"] != "message" || connectFrame["name"] != "PB_CONNECT" {
+		t.Fatalf("Expected PB_CONNECT message frame, got %v", connectFrame)
+	}
+	clientId, _ := connectFrame["data"].(map[string]any)["clientId"].(string)
+	client, err := app.SubscriptionsBroker().ClientById(clientId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ping
+	send(`{"id":"p1","type":"ping"}`)
+	if frame := read(); frame["type"] != "pong" || frame["requestId"] != "p1" {
+		t.Fatalf("Expected pong frame, got %v", frame)
+	}
+
+	// unsupported frame
+	send(`{"id":"u1","type":"unknown"}`)
+	if frame := read(); frame["type"] != "error" || frame["requestId"] != "u1" {
+		t.Fatalf("Expected error frame, got %v", frame)
+	}
+
+	// invalid auth
+	send(`{"id":"a1","type":"auth","token":"invalid"}`)
+	if frame := read(); frame["type"] != "error" || frame["requestId"] != "a1" {
+		t.Fatalf("Expected auth error frame, got %v", frame)
+	}
+
+	// valid auth
+	send(`{"id":"a2","type":"auth","token":"` + suite.UserAuthToken + `"}`)
+	if frame := read(); frame["type"] != "ack" || frame["requestId"] != "a2" {
+		t.Fatalf("Expected auth ack frame, got %v", frame)
+	}
+	if record, _ := client.Get(apis.ContextAuthRecordKey).(*models.Record); record == nil {
+		t.Fatal("Expected the client auth record to be set")
+	}
+
+	// subscribe
+	send(`{"id":"s1","type":"subscribe","subscriptions":["test1","test2"]}`)
+	if frame := read(); frame["type"] != "ack" || frame["requestId"] != "s1" {
+		t.Fatalf("Expected subscribe ack frame, got %v", frame)
+	}
+	if !client.HasSubscription("test1") || !client.HasSubscription("test2") {
+		t.Fatalf("Expected test1 and test2 subscriptions, got %v", client.Subscriptions())
+	}
+
+	// subscription message
+	go client.Send(subscriptions.Message{Name: "test1", Data: []byte(`{"a":1}`)})
+	if frame := read(); frame["type"] != "message" || frame["name"] != "test1" || frame["id"] != float64(1) {
+		t.Fatalf("Expected test1 message frame, got %v", frame)
+	}
+
+	// unsubscribe
+	send(`{"id":"s2","type":"unsubscribe","subscriptions":["test1"]}`)
+	if frame := read(); frame["type"] != "ack" || frame["requestId"] != "s2" {
+		t.Fatalf("Expected unsubscribe ack frame, got %v", frame)
+	}
+	if client.HasSubscription("test1") || !client.HasSubscription("test2") {
+		t.Fatalf("Expected only test2 subscription, got %v", client.Subscriptions())
+	}
+
+	// disconnect
+	ws.Close()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := app.SubscriptionsBroker().ClientById(clientId); err == nil {
+		t.Fatal("Expected the client to be unregistered after disconnect")
+	}
+}
+
 type RealtimeTestSuite struct {
 	suite.Suite
 	App            *tests.TestApp
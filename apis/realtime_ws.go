@@ -0,0 +1,390 @@
+package apis
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/forms"
+	"github.com/hylarucoder/rocketbase/tools/list"
+	"github.com/hylarucoder/rocketbase/tools/subscriptions"
+	"github.com/labstack/echo/v5"
+	"golang.org/x/net/websocket"
+)
+
+// Realtime WebSocket frame types.
+const (
+	realtimeWSTypeAuth        = "auth"
+	realtimeWSTypeSubscribe   = "subscribe"
+	realtimeWSTypeUnsubscribe = "unsubscribe"
+	realtimeWSTypePublish     = "publish"
+	realtimeWSTypePresence    = "presence"
+	realtimeWSTypePing        = "ping"
+	realtimeWSTypePong        = "pong"
+	realtimeWSTypeMessage     = "message"
+	realtimeWSTypeAck         = "ack"
+	realtimeWSTypeError       = "error"
+)
+
+const (
+	realtimeWSPingInterval  = 30 * time.Second
+	realtimeWSWriteTimeout  = 10 * time.Second
+	realtimeWSMaxFrameBytes = 1 << 20
+)
+
+// realtimeWSRequest represents a single client WebSocket frame.
+type realtimeWSRequest struct {
+	// Id is an optional client generated identifier that is
+	// returned with the related ack or error response frame.
+	Id            string         `json:"id"`
+	Type          string         `json:"type"`
+	Token         string         `json:"token"`
+	Subscriptions []string       `json:"subscriptions"`
+	Channel       string         `json:"channel"`
+	Data          any            `json:"data"`
+	State         map[string]any `json:"state"`
+}
+
+// realtimeWSResponse represents a single server WebSocket frame.
+type realtimeWSResponse struct {
+	Type      string          `json:"type"`
+	RequestId string          `json:"requestId,omitempty"`
+	Id        uint64          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Error     *ApiError       `json:"error,omitempty"`
+}
+
+// connectWS handles the realtime WebSocket connection.
+//
+// Unlike the SSE transport, the subscriptions and the auth state are
+// managed with JSON frames sent over the same socket:
+//
+//	{"id": "1", "type": "auth", "token": "..."}
+//	{"id": "2", "type": "subscribe", "subscriptions": ["posts/*"]}
+//	{"id": "3", "type": "unsubscribe", "subscriptions": ["posts/*"]}
+//	{"id": "4", "type": "publish", "channel": "rooms:1", "data": {...}}
+//	{"id": "5", "type": "presence", "channel": "rooms:1", "state": {...}}
+//	{"type": "ping"}
+//
+// The server replies with "ack" or "error" frames, delivers the
+// subscription messages as "message" frames and periodically sends
+// "ping" frames that the client is expected to answer with "pong"
+// (the connection is closed after IdleTimeout without any client frame).
+func (api *realtimeApi) connectWS(c echo.Context) error {
+	// note: the socket is authorized only with the handshake
+	// Authorization header or an explicit auth frame (no cookies),
+	// so similar to the SSE endpoint cross-origin connections are allowed
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			api.serveWS(c, ws)
+		},
+	}
+
+	server.ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
+
+func (api *realtimeApi) serveWS(c echo.Context, ws *websocket.Conn) {
+	ws.MaxPayloadBytes = realtimeWSMaxFrameBytes
+
+	// register new subscription client
+	client := subscriptions.NewDefaultClient()
+	client.Set(ContextAdminKey, c.Get(ContextAdminKey))
+	client.Set(ContextAuthRecordKey, c.Get(ContextAuthRecordKey))
+	api.app.SubscriptionsBroker().Register(client)
+	defer func() {
+		disconnectEvent := &core.RealtimeDisconnectEvent{
+			HttpContext: c,
+			Client:      client,
+		}
+
+		if err := api.app.OnRealtimeDisconnectRequest().Trigger(disconnectEvent); err != nil {
+			api.app.Logger().Debug(
+				"OnRealtimeDisconnectRequest error",
+				slog.String("clientId", client.Id()),
+				slog.String("error", err.Error()),
+			)
+		}
+
+		api.unregisterClient(client)
+	}()
+
+	connectEvent := &core.RealtimeConnectEvent{
+		HttpContext: c,
+		Client:      client,
+		IdleTimeout: 5 * time.Minute,
+	}
+
+	if err := api.app.OnRealtimeConnectRequest().Trigger(connectEvent); err != nil {
+		api.writeWS(ws, &realtimeWSResponse{Type: realtimeWSTypeError, Error: realtimeWSError(err)})
+		ws.Close()
+		return
+	}
+
+	api.app.Logger().Debug("Realtime WebSocket connection established.", slog.String("clientId", client.Id()))
+
+	// signalize established connection (aka. fire "connect" message)
+	connectMsgErr := api.sendWSMessage(c, ws, client, &subscriptions.Message{
+		Name: "PB_CONNECT",
+		Data: []byte(`{"clientId":"` + client.Id() + `"}`),
+	})
+	if connectMsgErr != nil {
+		api.app.Logger().Debug(
+			"Realtime WebSocket connection closed (failed to deliver PB_CONNECT)",
+			slog.String("clientId", client.Id()),
+			slog.String("error", connectMsgErr.Error()),
+		)
+		ws.Close()
+		return
+	}
+
+	// read the client frames until the socket is closed
+	// (the reader must complete before returning because the echo
+	// context is released and reused after the handler completion)
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		api.readWS(c, ws, client, connectEvent.IdleTimeout)
+	}()
+	defer func() {
+		ws.Close()
+		<-readerDone
+	}()
+
+	// ensure that the client has a chance to reply to at least one ping before idling out
+	pingInterval := realtimeWSPingInterval
+	if half := connectEvent.IdleTimeout / 2; half > 0 && half < pingInterval {
+		pingInterval = half
+	}
+	pingTicker := time.NewTicker(pingInterval)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case <-readerDone:
+			api.app.Logger().Debug(
+				"Realtime WebSocket connection closed",
+				slog.String("clientId", client.Id()),
+			)
+			return
+		case <-pingTicker.C:
+			if err := api.writeWS(ws, &realtimeWSResponse{Type: realtimeWSTypePing}); err != nil {
+				return
+			}
+		case msg, ok := <-client.Channel():
+			if !ok {
+				return
+			}
+
+			if err := api.sendWSMessage(c, ws, client, &msg); err != nil {
+				api.app.Logger().Debug(
+					"Realtime WebSocket connection closed (failed to deliver message)",
+					slog.String("clientId", client.Id()),
+					slog.String("error", err.Error()),
+				)
+				return
+			}
+		}
+	}
+}
+
+// readWS reads and handles the client frames until the
+// socket is closed or no frame was received within idleTimeout.
+func (api *realtimeApi) readWS(c echo.Context, ws *websocket.Conn, client subscriptions.Client, idleTimeout time.Duration) {
+	for {
+		ws.SetReadDeadline(time.Now().Add(idleTimeout))
+
+		req := &realtimeWSRequest{}
+		if err := websocket.JSON.Receive(ws, req); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				api.writeWS(ws, &realtimeWSResponse{
+					Type:  realtimeWSTypeError,
+					Error: NewBadRequestError("Invalid frame format.", err),
+				})
+				continue
+			}
+
+			return // closed, timed out or too large frame
+		}
+
+		var err error
+
+		switch req.Type {
+		case realtimeWSTypePing:
+			err = api.writeWS(ws, &realtimeWSResponse{Type: realtimeWSTypePong, RequestId: req.Id})
+			if err != nil {
+				return
+			}
+			continue
+		case realtimeWSTypePong:
+			continue // the read deadline is already extended
+		case realtimeWSTypeAuth:
+			err = api.authorizeWS(c, client, req.Token)
+		case realtimeWSTypeSubscribe:
+			subs := list.ToUniqueStringSlice(append(realtimeClientSubscriptions(client), req.Subscriptions...))
+			err = api.subscribeWS(c, ws, client, req, subs)
+		case realtimeWSTypeUnsubscribe:
+			subs := []string{}
+			if len(req.Subscriptions) > 0 {
+				for _, sub := range realtimeClientSubscriptions(client) {
+					if !list.ExistInSlice(sub, req.Subscriptions) {
+						subs = append(subs, sub)
+					}
+				}
+			}
+			err = api.subscribeWS(c, ws, client, req, subs)
+		case realtimeWSTypePublish:
+			err = api.publishToChannel(c, client, RequestInfo(c), req.Channel, req.Data)
+		case realtimeWSTypePresence:
+			err = api.presenceUpdate(client, req.Channel, req.State)
+		default:
+			err = NewBadRequestError("Unsupported frame type "+req.Type+".", nil)
+		}
+
+		if err != nil {
+			err = api.writeWS(ws, &realtimeWSResponse{
+				Type:      realtimeWSTypeError,
+				RequestId: req.Id,
+				Error:     realtimeWSError(err),
+			})
+		} else if req.Type == realtimeWSTypeAuth || req.Type == realtimeWSTypePublish || req.Type == realtimeWSTypePresence {
+			err = api.writeWS(ws, &realtimeWSResponse{Type: realtimeWSTypeAck, RequestId: req.Id})
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// authorizeWS updates the WebSocket client auth state with the one
+// associated with the provided token.
+//
+// Similar to the SSE transport, the auth state cannot be changed
+// to a different admin or auth record once set.
+func (api *realtimeApi) authorizeWS(c echo.Context, client subscriptions.Client, token string) error {
+	admin, record := findAuthByToken(api.app, token)
+	if admin == nil && record == nil {
+		return NewUnauthorizedError("Missing or invalid auth token.", nil)
+	}
+
+	var newAuthId string
+	if admin != nil {
+		newAuthId = admin.Id
+	} else {
+		newAuthId = record.Id
+	}
+
+	oldAuthId := extractAuthIdFromGetter(client)
+	if oldAuthId != "" && oldAuthId != newAuthId {
+		return NewForbiddenError("The current and the previous request authorization don't match.", nil)
+	}
+
+	c.Set(ContextAdminKey, admin)
+	c.Set(ContextAuthRecordKey, record)
+	client.Set(ContextAdminKey, admin)
+	client.Set(ContextAuthRecordKey, record)
+
+	// reevaluate the custom channels access with the new auth state
+	api.syncChannelSubscriptions(client)
+
+	return nil
+}
+
+// subscribeWS replaces the WebSocket client subscriptions with subs.
+func (api *realtimeApi) subscribeWS(
+	c echo.Context,
+	ws *websocket.Conn,
+	client subscriptions.Client,
+	req *realtimeWSRequest,
+	subs []string,
+) error {
+	form := forms.NewRealtimeSubscribe()
+	form.ClientId = client.Id()
+	form.Subscriptions = subs
+
+	if err := form.Validate(); err != nil {
+		return NewBadRequestError("", err)
+	}
+
+	event := &core.RealtimeSubscribeEvent{
+		HttpContext:   c,
+		Client:        client,
+		Subscriptions: form.Subscriptions,
+	}
+
+	return api.applySubscriptions(event, func(e *core.RealtimeSubscribeEvent) error {
+		return api.writeWS(ws, &realtimeWSResponse{Type: realtimeWSTypeAck, RequestId: req.Id})
+	})
+}
+
+// sendWSMessage writes a single subscription message frame to the WebSocket connection.
+func (api *realtimeApi) sendWSMessage(
+	c echo.Context,
+	ws *websocket.Conn,
+	client subscriptions.Client,
+	msg *subscriptions.Message,
+) error {
+	msgEvent := &core.RealtimeMessageEvent{
+		HttpContext: c,
+		Client:      client,
+		Message:     msg,
+	}
+
+	return api.app.OnRealtimeBeforeMessageSend().Trigger(msgEvent, func(e *core.RealtimeMessageEvent) error {
+		data := json.RawMessage(e.Message.Data)
+		if !json.Valid(data) {
+			// not a json payload, send it as string
+			data, _ = json.Marshal(string(e.Message.Data))
+		}
+
+		err := api.writeWS(ws, &realtimeWSResponse{
+			Type: realtimeWSTypeMessage,
+			Id:   e.Message.Id,
+			Name: e.Message.Name,
+			Data: data,
+		})
+		if err != nil {
+			return err
+		}
+
+		return api.app.OnRealtimeAfterMessageSend().Trigger(e)
+	})
+}
+
+// writeWS writes a single JSON frame to the WebSocket connection.
+//
+// It is safe to be called concurrently.
+func (api *realtimeApi) writeWS(ws *websocket.Conn, frame *realtimeWSResponse) error {
+	ws.SetWriteDeadline(time.Now().Add(realtimeWSWriteTimeout))
+
+	return websocket.JSON.Send(ws, frame)
+}
+
+// realtimeClientSubscriptions returns the raw client subscriptions list.
+func realtimeClientSubscriptions(client subscriptions.Client) []string {
+	subs := client.Subscriptions()
+
+	result := make([]string, 0, len(subs))
+	for sub := range subs {
+		result = append(result, sub)
+	}
+
+	return result
+}
+
+// realtimeWSError normalizes the provided error into an ApiError.
+func realtimeWSError(err error) *ApiError {
+	var apiErr *ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	return NewBadRequestError(err.Error(), nil)
+}
@@ -0,0 +1,243 @@
+package apis
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/resolvers"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/spf13/cast"
+)
+
+const (
+	changesDefaultLimit = 100
+	changesMaxLimit     = 500
+)
+
+// recordChangeItem defines a single changes feed response item.
+type recordChangeItem struct {
+	*models.RecordChange
+
+	// Record is the current state of the changed record
+	// (always nil for the delete entries).
+	Record *models.Record `json:"record,omitempty"`
+}
+
+// changes returns the collection records changes (aka. CDC feed)
+// with seq greater than the "since" query parameter.
+//
+// The create and update entries are returned together with the current
+// record state. If the record is no longer accessible by the collection
+// view rule (or was deleted meanwhile), the entry is returned as a delete
+// (aka. tombstone) so that the client could remove its local copy.
+//
+// The tombstones contain only the record id and they are returned only if
+// the record was accessible by the caller at the time of the change
+// (aka. the view rule is checked against the entry record snapshot).
+// The entries of records that the caller never had access to are skipped.
+//
+// If "since" is older than the oldest retained entry (eg. because of the
+// changes log retention), the endpoint responds with 410 Gone and the
+// client is expected to perform a full resync.
+func (api *recordApi) changes(c echo.Context) error {
+	collection, _ := c.Get(ContextCollectionKey).(*models.Collection)
+	if collection == nil {
+		return NewNotFoundError("", "Missing collection context.")
+	}
+
+	if !api.app.Settings().Changes.Enabled {
+		return NewBadRequestError("The records changes log is not enabled.", nil)
+	}
+
+	requestInfo := RequestInfo(c)
+
+	if requestInfo.Admin == nil && collection.ViewRule == nil {
+		// only admins can access if the rule is nil
+		return NewForbiddenError("Only admins can perform this action.", nil)
+	}
+
+	since := cast.ToInt64(c.QueryParam("since"))
+	if since < 0 {
+		since = 0
+	}
+
+	limit := cast.ToInt(c.QueryParam("limit"))
+	if limit <= 0 {
+		limit = changesDefaultLimit
+	} else if limit > changesMaxLimit {
+		limit = changesMaxLimit
+	}
+
+	oldest, err := api.app.Dao().FindOldestRecordChangeSeq(collection.Id)
+	if err != nil {
+		return NewBadRequestError("Failed to load the collection changes.", err)
+	}
+
+	// the latest collection entry is never pruned so there could be
+	// missing changes only if since is older than the oldest retained one
+	if since > 0 && since < oldest {
+		return NewApiError(
+			http.StatusGone,
+			"The requested changes are no longer available. Please perform a full resync.",
+			nil,
+		)
+	}
+
+	// fetch one extra entry to check whether there are more changes
+	changes, err := api.app.Dao().FindRecordChanges(collection.Id, since, limit+1)
+	if err != nil {
+		return NewBadRequestError("Failed to load the collection changes.", err)
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+
+	cursor := since
+	if len(changes) > 0 {
+		cursor = changes[len(changes)-1].Seq
+	}
+
+	recordIds := make([]string, 0, len(changes))
+	for _, change := range changes {
+		if change.Action != models.RecordChangeActionDelete {
+			recordIds = append(recordIds, change.RecordId)
+		}
+	}
+
+	// the view rule is checked only for the guests and auth records
+	checkRule := requestInfo.Admin == nil && collection.ViewRule != nil && *collection.ViewRule != ""
+
+	ruleFunc := func(q *dbx.SelectQuery) error {
+		if checkRule {
+			resolver := resolvers.NewRecordFieldResolver(api.app.Dao(), collection, requestInfo, true)
+			expr, err := search.FilterData(*collection.ViewRule).BuildExpr(resolver)
+			if err != nil {
+				return err
+			}
+			resolver.UpdateQuery(q)
+			q.AndWhere(expr)
+		}
+		return nil
+	}
+
+	records := []*models.Record{}
+	if len(recordIds) > 0 {
+		records, err = api.app.Dao().FindRecordsByIds(collection.Id, recordIds, ruleFunc)
+		if err != nil {
+			return NewBadRequestError("Failed to load the changed records.", err)
+		}
+
+		if err := EnrichRecords(c, api.app.Dao(), records); err != nil {
+			api.app.Logger().Debug(
+				"Failed to enrich the changed records",
+				slog.String("collectionName", collection.Name),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	recordsMap := make(map[string]*models.Record, len(records))
+	for _, r := range records {
+		recordsMap[r.Id] = r
+	}
+
+	// the seqs of the deleted or no longer accessible entries
+	tombstoneSeqs := make([]any, 0, len(changes))
+	for _, change := range changes {
+		if change.Action == models.RecordChangeActionDelete || recordsMap[change.RecordId] == nil {
+			tombstoneSeqs = append(tombstoneSeqs, change.Seq)
+		}
+	}
+
+	// returns tombstones only for the records that were accessible
+	// by the caller at the time of the change
+	visibleTombstones := map[int64]bool{}
+	if len(tombstoneSeqs) > 0 {
+		if checkRule {
+			visibleTombstones, err = api.findVisibleRecordChanges(collection, tombstoneSeqs, ruleFunc)
+			if err != nil {
+				return NewBadRequestError("Failed to load the collection changes.", err)
+			}
+		} else {
+			for _, seq := range tombstoneSeqs {
+				visibleTombstones[seq.(int64)] = true
+			}
+		}
+	}
+
+	items := make([]recordChangeItem, 0, len(changes))
+	for _, change := range changes {
+		item := recordChangeItem{RecordChange: change}
+
+		if change.Action != models.RecordChangeActionDelete {
+			item.Record = recordsMap[change.RecordId]
+		}
+
+		if item.Record == nil {
+			if !visibleTombstones[change.Seq] {
+				continue // never accessible by the caller
+			}
+
+			tombstone := *change
+			tombstone.Action = models.RecordChangeActionDelete
+			item.RecordChange = &tombstone
+		}
+
+		items = append(items, item)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"cursor":  cursor,
+		"hasMore": hasMore,
+		"items":   items,
+	})
+}
+
+// findVisibleRecordChanges returns the seqs of the specified collection
+// changes whose record snapshot (aka. the record state at the time of the
+// change) satisfies the view rule applied by ruleFunc.
+func (api *recordApi) findVisibleRecordChanges(
+	collection *models.Collection,
+	seqs []any,
+	ruleFunc func(q *dbx.SelectQuery) error,
+) (map[int64]bool, error) {
+	changesTable := (&models.RecordChange{}).TableName()
+
+	// restore each snapshot as a row of the collection table type
+	// so that the rule expression could be evaluated against it
+	query := api.app.Dao().DB().
+		Select("{{"+changesTable+"}}.[[seq]]").
+		Distinct(true).
+		From(
+			changesTable,
+			fmt.Sprintf(
+				"jsonb_populate_record(NULL::{{%s}}, {{%s}}.[[data]]) {{%s}}",
+				collection.Name,
+				changesTable,
+				collection.Name,
+			),
+		).
+		AndWhere(dbx.In(changesTable+".seq", seqs...))
+
+	if err := ruleFunc(query); err != nil {
+		return nil, err
+	}
+
+	visibleSeqs := []int64{}
+	if err := query.Column(&visibleSeqs); err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]bool, len(visibleSeqs))
+	for _, seq := range visibleSeqs {
+		result[seq] = true
+	}
+
+	return result, nil
+}
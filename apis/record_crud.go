@@ -26,6 +26,10 @@ func bindRecordCrudApi(app core.App, rg *echo.Group) {
 	)
 
 	subGroup.GET("/records", api.list, LoadCollectionContext(app))
+	subGroup.GET("/aggregate", api.aggregate, LoadCollectionContext(app))
+	subGroup.GET("/export", api.export, LoadCollectionContext(app))
+	subGroup.GET("/changes", api.changes, LoadCollectionContext(app, models.CollectionTypeBase, models.CollectionTypeAuth))
+	subGroup.POST("/import", api.bulkImport, RequireAdminAuth(), LoadCollectionContext(app, models.CollectionTypeBase, models.CollectionTypeAuth))
 	subGroup.GET("/records/:id", api.view, LoadCollectionContext(app))
 	subGroup.POST("/records", api.create, LoadCollectionContext(app, models.CollectionTypeBase, models.CollectionTypeAuth))
 	subGroup.PATCH("/records/:id", api.update, LoadCollectionContext(app, models.CollectionTypeBase, models.CollectionTypeAuth))
@@ -42,6 +46,8 @@ func (api *recordApi) list(c echo.Context) error {
 		return NewNotFoundError("", "Missing collection context.")
 	}
 
+	dao := withSlowQueryLog(api.app, requestDao(api.app, c), collection, c.QueryParam(search.FilterQueryParam))
+
 	requestInfo := RequestInfo(c)
 
 	// forbid users and guests to query special filter/sort fields
@@ -55,7 +61,7 @@ func (api *recordApi) list(c echo.Context) error {
 	}
 
 	fieldsResolver := resolvers.NewRecordFieldResolver(
-		api.app.Dao(),
+		dao,
 		collection,
 		requestInfo,
 		// hidden fields are searchable only by admins
@@ -63,7 +69,7 @@ func (api *recordApi) list(c echo.Context) error {
 	)
 
 	searchProvider := search.NewProvider(fieldsResolver).
-		Query(api.app.Dao().RecordQuery(collection))
+		Query(dao.RecordQuery(collection))
 
 	if requestInfo.Admin == nil && collection.ListRule != nil {
 		searchProvider.AddFilter(search.FilterData(*collection.ListRule))
@@ -87,7 +93,7 @@ func (api *recordApi) list(c echo.Context) error {
 			return nil
 		}
 
-		if err := EnrichRecords(e.HttpContext, api.app.Dao(), e.Records); err != nil {
+		if err := EnrichRecords(e.HttpContext, dao, e.Records); err != nil {
 			api.app.Logger().Debug("Failed to enrich list records", slog.String("error", err.Error()))
 		}
 
@@ -95,12 +101,57 @@ func (api *recordApi) list(c echo.Context) error {
 	})
 }
 
+func (api *recordApi) aggregate(c echo.Context) error {
+	collection, _ := c.Get(ContextCollectionKey).(*models.Collection)
+	if collection == nil {
+		return NewNotFoundError("", "Missing collection context.")
+	}
+
+	dao := withSlowQueryLog(api.app, requestDao(api.app, c), collection, c.QueryParam(search.FilterQueryParam))
+
+	requestInfo := RequestInfo(c)
+
+	// forbid users and guests to query special filter/sort/groupBy fields
+	if err := checkForAdminOnlyRuleFields(requestInfo); err != nil {
+		return err
+	}
+
+	if requestInfo.Admin == nil && collection.ListRule == nil {
+		// only admins can access if the rule is nil
+		return NewForbiddenError("Only admins can perform this action.", nil)
+	}
+
+	fieldsResolver := resolvers.NewRecordFieldResolver(
+		dao,
+		collection,
+		requestInfo,
+		// hidden fields are searchable only by admins
+		requestInfo.Admin != nil,
+	)
+
+	aggregator := search.NewAggregator(fieldsResolver).
+		Query(dao.RecordQuery(collection))
+
+	if requestInfo.Admin == nil && collection.ListRule != nil {
+		aggregator.AddFilter(search.FilterData(*collection.ListRule))
+	}
+
+	result, err := aggregator.ParseAndExec(c.QueryParams().Encode())
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
 func (api *recordApi) view(c echo.Context) error {
 	collection, _ := c.Get(ContextCollectionKey).(*models.Collection)
 	if collection == nil {
 		return NewNotFoundError("", "Missing collection context.")
 	}
 
+	dao := requestDao(api.app, c)
+
 	recordId := c.PathParam("id")
 	if recordId == "" {
 		return NewNotFoundError("", nil)
@@ -115,7 +166,7 @@ func (api *recordApi) view(c echo.Context) error {
 
 	ruleFunc := func(q *dbx.SelectQuery) error {
 		if requestInfo.Admin == nil && collection.ViewRule != nil && *collection.ViewRule != "" {
-			resolver := resolvers.NewRecordFieldResolver(api.app.Dao(), collection, requestInfo, true)
+			resolver := resolvers.NewRecordFieldResolver(dao, collection, requestInfo, true)
 			expr, err := search.FilterData(*collection.ViewRule).BuildExpr(resolver)
 			if err != nil {
 				return err
@@ -126,7 +177,7 @@ func (api *recordApi) view(c echo.Context) error {
 		return nil
 	}
 
-	record, fetchErr := api.app.Dao().FindRecordById(collection.Id, recordId, ruleFunc)
+	record, fetchErr := dao.FindRecordById(collection.Id, recordId, ruleFunc)
 	if fetchErr != nil || record == nil {
 		return NewNotFoundError("", fetchErr)
 	}
@@ -141,7 +192,7 @@ func (api *recordApi) view(c echo.Context) error {
 			return nil
 		}
 
-		if err := EnrichRecord(e.HttpContext, api.app.Dao(), e.Record); err != nil {
+		if err := EnrichRecord(e.HttpContext, dao, e.Record); err != nil {
 			api.app.Logger().Debug(
 				"Failed to enrich view record",
 				slog.String("id", e.Record.Id),
@@ -160,6 +211,8 @@ func (api *recordApi) create(c echo.Context) error {
 		return NewNotFoundError("", "Missing collection context.")
 	}
 
+	dao := requestDao(api.app, c)
+
 	requestInfo := RequestInfo(c)
 
 	if requestInfo.Admin == nil && collection.CreateRule == nil {
@@ -180,6 +233,7 @@ func (api *recordApi) create(c echo.Context) error {
 		}
 
 		testForm := forms.NewRecordUpsert(api.app, testRecord)
+		testForm.SetDao(dao)
 		testForm.SetFullManageAccess(true)
 		if err := testForm.LoadRequest(c.Request(), ""); err != nil {
 			return NewBadRequestError("Failed to load the submitted data due to invalid formatting.", err)
@@ -195,7 +249,7 @@ func (api *recordApi) create(c echo.Context) error {
 				return nil // no create rule to resolve
 			}
 
-			resolver := resolvers.NewRecordFieldResolver(api.app.Dao(), collection, requestInfo, true)
+			resolver := resolvers.NewRecordFieldResolver(dao, collection, requestInfo, true)
 			expr, err := search.FilterData(*collection.CreateRule).BuildExpr(resolver)
 			if err != nil {
 				return err
@@ -221,6 +275,7 @@ func (api *recordApi) create(c echo.Context) error {
 
 	record := models.NewRecord(collection)
 	form := forms.NewRecordUpsert(api.app, record)
+	form.SetDao(dao)
 	form.SetFullManageAccess(hasFullManageAccess)
 
 	// load request
@@ -244,7 +299,7 @@ func (api *recordApi) create(c echo.Context) error {
 					return NewBadRequestError("Failed to create record.", err)
 				}
 
-				if err := EnrichRecord(e.HttpContext, api.app.Dao(), e.Record); err != nil {
+				if err := EnrichRecord(e.HttpContext, dao, e.Record); err != nil {
 					api.app.Logger().Debug(
 						"Failed to enrich create record",
 						slog.String("id", e.Record.Id),
@@ -271,6 +326,8 @@ func (api *recordApi) update(c echo.Context) error {
 		return NewNotFoundError("", "Missing collection context.")
 	}
 
+	dao := requestDao(api.app, c)
+
 	recordId := c.PathParam("id")
 	if recordId == "" {
 		return NewNotFoundError("", nil)
@@ -286,7 +343,7 @@ func (api *recordApi) update(c echo.Context) error {
 	// eager fetch the record so that the modifier field values are replaced
 	// and available when accessing requestInfo.Data using just the field name
 	if requestInfo.HasModifierDataKeys() {
-		record, err := api.app.Dao().FindRecordById(collection.Id, recordId)
+		record, err := dao.FindRecordById(collection.Id, recordId)
 		if err != nil || record == nil {
 			return NewNotFoundError("", err)
 		}
@@ -295,7 +352,7 @@ func (api *recordApi) update(c echo.Context) error {
 
 	ruleFunc := func(q *dbx.SelectQuery) error {
 		if requestInfo.Admin == nil && collection.UpdateRule != nil && *collection.UpdateRule != "" {
-			resolver := resolvers.NewRecordFieldResolver(api.app.Dao(), collection, requestInfo, true)
+			resolver := resolvers.NewRecordFieldResolver(dao, collection, requestInfo, true)
 			expr, err := search.FilterData(*collection.UpdateRule).BuildExpr(resolver)
 			if err != nil {
 				return err
@@ -307,13 +364,14 @@ func (api *recordApi) update(c echo.Context) error {
 	}
 
 	// fetch record
-	record, fetchErr := api.app.Dao().FindRecordById(collection.Id, recordId, ruleFunc)
+	record, fetchErr := dao.FindRecordById(collection.Id, recordId, ruleFunc)
 	if fetchErr != nil || record == nil {
 		return NewNotFoundError("", fetchErr)
 	}
 
 	form := forms.NewRecordUpsert(api.app, record)
-	form.SetFullManageAccess(requestInfo.Admin != nil || hasAuthManageAccess(api.app.Dao(), record, requestInfo))
+	form.SetDao(dao)
+	form.SetFullManageAccess(requestInfo.Admin != nil || hasAuthManageAccess(dao, record, requestInfo))
 
 	// load request
 	if err := form.LoadRequest(c.Request(), ""); err != nil {
@@ -336,7 +394,7 @@ func (api *recordApi) update(c echo.Context) error {
 					return NewBadRequestError("Failed to update record.", err)
 				}
 
-				if err := EnrichRecord(e.HttpContext, api.app.Dao(), e.Record); err != nil {
+				if err := EnrichRecord(e.HttpContext, dao, e.Record); err != nil {
 					api.app.Logger().Debug(
 						"Failed to enrich update record",
 						slog.String("id", e.Record.Id),
@@ -363,6 +421,8 @@ func (api *recordApi) delete(c echo.Context) error {
 		return NewNotFoundError("", "Missing collection context.")
 	}
 
+	dao := requestDao(api.app, c)
+
 	recordId := c.PathParam("id")
 	if recordId == "" {
 		return NewNotFoundError("", nil)
@@ -377,7 +437,7 @@ func (api *recordApi) delete(c echo.Context) error {
 
 	ruleFunc := func(q *dbx.SelectQuery) error {
 		if requestInfo.Admin == nil && collection.DeleteRule != nil && *collection.DeleteRule != "" {
-			resolver := resolvers.NewRecordFieldResolver(api.app.Dao(), collection, requestInfo, true)
+			resolver := resolvers.NewRecordFieldResolver(dao, collection, requestInfo, true)
 			expr, err := search.FilterData(*collection.DeleteRule).BuildExpr(resolver)
 			if err != nil {
 				return err
@@ -388,7 +448,7 @@ func (api *recordApi) delete(c echo.Context) error {
 		return nil
 	}
 
-	record, fetchErr := api.app.Dao().FindRecordById(collection.Id, recordId, ruleFunc)
+	record, fetchErr := dao.FindRecordById(collection.Id, recordId, ruleFunc)
 	if fetchErr != nil || record == nil {
 		return NewNotFoundError("", fetchErr)
 	}
@@ -400,7 +460,7 @@ func (api *recordApi) delete(c echo.Context) error {
 
 	return api.app.OnRecordBeforeDeleteRequest().Trigger(event, func(e *core.RecordDeleteEvent) error {
 		// delete the record
-		if err := api.app.Dao().DeleteRecord(e.Record); err != nil {
+		if err := dao.DeleteRecord(e.Record); err != nil {
 			return NewBadRequestError("Failed to delete record. Make sure that the record is not part of a required relation reference.", err)
 		}
 
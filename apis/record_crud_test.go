@@ -1,7 +1,9 @@
 package apis_test
 
 import (
+	"bytes"
 	"errors"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
@@ -13,6 +15,7 @@ import (
 	"github.com/hylarucoder/rocketbase/core"
 	"github.com/hylarucoder/rocketbase/models"
 	"github.com/hylarucoder/rocketbase/tests"
+	"github.com/hylarucoder/rocketbase/tools/types"
 	"github.com/labstack/echo/v5"
 	"github.com/stretchr/testify/suite"
 )
@@ -167,6 +170,39 @@ func (suite *RecordCrudTestSuite) TestRecordCrudList() {
 				return suite.App
 			},
 		},
+		{
+			Name:   "cursor pagination (first page)",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo1/records?cursor=&perPage=2&sort=-created",
+			RequestHeaders: map[string]string{
+				"Authorization": suite.AdminAuthToken,
+			},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"perPage":2`,
+				`"totalItems":-1`,
+				`"totalPages":-1`,
+				`"items":[{`,
+				`"nextCursor":"`,
+			},
+			ExpectedEvents: map[string]int{"OnRecordsListRequest": 1},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:   "cursor pagination (invalid cursor)",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo1/records?cursor=invalid&perPage=2&sort=-created",
+			RequestHeaders: map[string]string{
+				"Authorization": suite.AdminAuthToken,
+			},
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
 		{
 			Name:   "invalid filter",
 			Method: http.MethodGet,
@@ -575,6 +611,568 @@ func (suite *RecordCrudTestSuite) TestRecordCrudList() {
 	}
 }
 
+func (suite *RecordCrudTestSuite) TestRecordCrudAggregate() {
+	t := suite.T()
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "missing collection",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/missing/aggregate",
+			ExpectedStatus:  404,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:            "unauthenticated trying to access nil rule collection (aka. need admin auth)",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/demo1/aggregate",
+			ExpectedStatus:  403,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:            "public collection but with admin only groupBy param (aka. @collection, @request, etc.)",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/demo2/aggregate?groupBy=@request.auth.id",
+			ExpectedStatus:  403,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:            "public collection with invalid aggregate function",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/demo2/aggregate?agg=median(title)",
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:            "public collection with invalid group by field",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/demo2/aggregate?groupBy=missing",
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:           "public collection with the default count",
+			Method:         http.MethodGet,
+			Url:            "/api/collections/demo2/aggregate",
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"items":[{"count()":3}]`,
+			},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:   "authorized as admin with filter, group by and multiple aggregates",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo1/aggregate?filter=text~'test'&groupBy=bool&agg=count(),max(text)&sort=-count()",
+			RequestHeaders: map[string]string{
+				"Authorization": suite.AdminAuthToken,
+			},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"items":[{`,
+				`"bool":`,
+				`"count()":`,
+				`"max(text)":`,
+			},
+			NotExpectedContent: []string{
+				// the group values are decoded with their field type
+				`"bool":"`,
+			},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:   "authorized as admin with sum over a multi-valued aggregate field join",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo1/aggregate?agg=sum(number),max(select_many:each)",
+			RequestHeaders: map[string]string{
+				"Authorization": suite.AdminAuthToken,
+			},
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func (suite *RecordCrudTestSuite) TestRecordCrudExport() {
+	t := suite.T()
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "missing collection",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/missing/export",
+			ExpectedStatus:  404,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:            "unauthenticated trying to access nil rule collection (aka. need admin auth)",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/demo1/export",
+			ExpectedStatus:  403,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:            "public collection but with admin only filter param (aka. @collection, @request, etc.)",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/demo2/export?filter=%40collection.demo2.title='test1'",
+			ExpectedStatus:  403,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:            "public collection with invalid format",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/demo2/export?format=xlsx",
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:            "public collection with invalid filter",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/demo2/export?filter=missing=1",
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:           "public collection as csv (default)",
+			Method:         http.MethodGet,
+			Url:            "/api/collections/demo2/export?sort=created",
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				"id,collectionId,collectionName,created,updated,",
+				"3479948460419978246,",
+				"3479948460512252935,",
+				"3479948460562584584,",
+			},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:           "public collection as csv with fields",
+			Method:         http.MethodGet,
+			Url:            "/api/collections/demo2/export?sort=created&fields=id,title",
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				"id,title\n",
+				"3479948460419978246,",
+			},
+			NotExpectedContent: []string{
+				"collectionName",
+				"expand",
+			},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:           "public collection as ndjson with fields",
+			Method:         http.MethodGet,
+			Url:            "/api/collections/demo2/export?format=ndjson&fields=id",
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`{"id":"3479948460419978246"}` + "\n",
+				`{"id":"3479948460512252935"}` + "\n",
+				`{"id":"3479948460562584584"}` + "\n",
+			},
+			NotExpectedContent: []string{
+				`"collectionName"`,
+			},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func (suite *RecordCrudTestSuite) TestRecordCrudChanges() {
+	t := suite.T()
+
+	mockChanges := func(t *testing.T, app *tests.TestApp) {
+		app.Settings().Changes.Enabled = true
+
+		collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		record := models.NewRecord(collection)
+		record.Set("title", "changes_test")
+		if err := app.Dao().SaveRecord(record); err != nil {
+			t.Fatal(err)
+		}
+
+		existing, err := app.Dao().FindRecordById(collection.Id, "3479948460419978246")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := app.Dao().DeleteRecord(existing); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "missing collection",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/missing/changes",
+			ExpectedStatus:  404,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:            "disabled changes log",
+			Method:          http.MethodGet,
+			Url:             "/api/collections/demo2/changes",
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:   "since older than the oldest retained entry",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo2/changes?since=1",
+			BeforeTestFunc: func(t *testing.T, app *tests.TestApp, e *echo.Echo) {
+				mockChanges(t, app)
+				if err := app.Dao().DeleteOldRecordChanges(time.Now().Add(time.Minute)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			ExpectedStatus:  410,
+			ExpectedContent: []string{`"data":{}`},
+		},
+		{
+			Name:   "since newer than the latest entry",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo2/changes?since=999999999",
+			BeforeTestFunc: func(t *testing.T, app *tests.TestApp, e *echo.Echo) {
+				mockChanges(t, app)
+				if err := app.Dao().DeleteOldRecordChanges(time.Now().Add(time.Minute)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"cursor":999999999`,
+				`"items":[]`,
+			},
+		},
+		{
+			Name:   "missing or inaccessible record change as delete",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo2/changes",
+			BeforeTestFunc: func(t *testing.T, app *tests.TestApp, e *echo.Echo) {
+				app.Settings().Changes.Enabled = true
+
+				collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				err = app.Dao().CreateRecordChange(&models.RecordChange{
+					CollectionId: collection.Id,
+					RecordId:     "inaccessible_test",
+					Action:       models.RecordChangeActionUpdate,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"recordId":"inaccessible_test","action":"delete"`,
+			},
+			NotExpectedContent: []string{
+				`"action":"update"`,
+				`"record":`,
+			},
+		},
+		{
+			Name:   "public collection changes",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo2/changes",
+			BeforeTestFunc: func(t *testing.T, app *tests.TestApp, e *echo.Echo) {
+				mockChanges(t, app)
+			},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"hasMore":false`,
+				`"action":"create"`,
+				`"title":"changes_test"`,
+				`"action":"delete"`,
+				`"recordId":"3479948460419978246"`,
+			},
+			NotExpectedContent: []string{
+				`"action":"update"`,
+			},
+		},
+		{
+			Name:   "guest changes filtered by the view rule",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo2/changes",
+			BeforeTestFunc: func(t *testing.T, app *tests.TestApp, e *echo.Echo) {
+				collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+				if err != nil {
+					t.Fatal(err)
+				}
+				collection.ViewRule = types.Pointer("title = 'changes_visible'")
+				if err := app.Dao().SaveCollection(collection); err != nil {
+					t.Fatal(err)
+				}
+
+				// never visible changes (incl. the delete of 3479948460419978246)
+				mockChanges(t, app)
+
+				hidden := models.NewRecord(collection)
+				hidden.Id = "changes_hidden_test"
+				hidden.Set("title", "changes_hidden")
+				if err := app.Dao().SaveRecord(hidden); err != nil {
+					t.Fatal(err)
+				}
+				hidden.Set("title", "changes_hidden_updated")
+				if err := app.Dao().SaveRecord(hidden); err != nil {
+					t.Fatal(err)
+				}
+				if err := app.Dao().DeleteRecord(hidden); err != nil {
+					t.Fatal(err)
+				}
+
+				// visible record
+				visible := models.NewRecord(collection)
+				visible.Id = "changes_visible_test"
+				visible.Set("title", "changes_visible")
+				if err := app.Dao().SaveRecord(visible); err != nil {
+					t.Fatal(err)
+				}
+
+				// previously visible record
+				gone := models.NewRecord(collection)
+				gone.Id = "changes_gone_test"
+				gone.Set("title", "changes_visible")
+				if err := app.Dao().SaveRecord(gone); err != nil {
+					t.Fatal(err)
+				}
+				gone.Set("title", "changes_gone")
+				if err := app.Dao().SaveRecord(gone); err != nil {
+					t.Fatal(err)
+				}
+			},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"recordId":"changes_visible_test","action":"create"`,
+				`"recordId":"changes_gone_test","action":"delete"`,
+			},
+			NotExpectedContent: []string{
+				`changes_hidden_test`,
+				`3479948460419978246`,
+				`"title":"changes_test"`,
+				`"title":"changes_gone"`,
+			},
+		},
+		{
+			Name:   "public collection changes with limit",
+			Method: http.MethodGet,
+			Url:    "/api/collections/demo2/changes?limit=1",
+			BeforeTestFunc: func(t *testing.T, app *tests.TestApp, e *echo.Echo) {
+				mockChanges(t, app)
+			},
+			ExpectedStatus: 200,
+			ExpectedContent: []string{
+				`"hasMore":true`,
+				`"action":"create"`,
+			},
+			NotExpectedContent: []string{
+				`"action":"delete"`,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func (suite *RecordCrudTestSuite) TestRecordCrudImport() {
+	t := suite.T()
+
+	newImportData := func(content string, fields map[string]string) (*bytes.Buffer, string) {
+		body := new(bytes.Buffer)
+		mp := multipart.NewWriter(body)
+		for k, v := range fields {
+			mp.WriteField(k, v)
+		}
+		w, err := mp.CreateFormFile("file", "import.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+		mp.Close()
+		return body, mp.FormDataContentType()
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "unauthenticated",
+			Method:          http.MethodPost,
+			Url:             "/api/collections/demo2/import",
+			ExpectedStatus:  401,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:   "authorized as auth record",
+			Method: http.MethodPost,
+			Url:    "/api/collections/demo2/import",
+			RequestHeaders: map[string]string{
+				"Authorization": suite.UserAuthToken,
+			},
+			ExpectedStatus:  401,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		{
+			Name:   "authorized as admin without uploaded file",
+			Method: http.MethodPost,
+			Url:    "/api/collections/demo2/import",
+			RequestHeaders: map[string]string{
+				"Authorization": suite.AdminAuthToken,
+			},
+			ExpectedStatus:  400,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory: func(t *testing.T) *tests.TestApp {
+				return suite.App
+			},
+		},
+		func() tests.ApiScenario {
+			body, contentType := newImportData("title,active\nimport_api,true\n,false\n", map[string]string{"dryRun": "true"})
+
+			return tests.ApiScenario{
+				Name:   "authorized as admin with dry run csv import",
+				Method: http.MethodPost,
+				Url:    "/api/collections/demo2/import",
+				Body:   body,
+				RequestHeaders: map[string]string{
+					"Authorization": suite.AdminAuthToken,
+					"Content-Type":  contentType,
+				},
+				ExpectedStatus: 200,
+				ExpectedContent: []string{
+					`"dryRun":true`,
+					`"total":2`,
+					`"created":1`,
+					`"failed":1`,
+					`"errors":[{"data":{"title":{"code":"validation_required"`,
+				},
+				TestAppFactory: func(t *testing.T) *tests.TestApp {
+					return suite.App
+				},
+			}
+		}(),
+		func() tests.ApiScenario {
+			body, contentType := newImportData("name,skip\nimport_mapping,x\n", map[string]string{
+				"dryRun":  "true",
+				"mapping": `{"name":"title","skip":"-"}`,
+			})
+
+			return tests.ApiScenario{
+				Name:   "authorized as admin with column mapping",
+				Method: http.MethodPost,
+				Url:    "/api/collections/demo2/import",
+				Body:   body,
+				RequestHeaders: map[string]string{
+					"Authorization": suite.AdminAuthToken,
+					"Content-Type":  contentType,
+				},
+				ExpectedStatus: 200,
+				ExpectedContent: []string{
+					`"dryRun":true`,
+					`"total":1`,
+					`"created":1`,
+					`"failed":0`,
+				},
+				TestAppFactory: func(t *testing.T) *tests.TestApp {
+					return suite.App
+				},
+			}
+		}(),
+		func() tests.ApiScenario {
+			body, contentType := newImportData("name\nimport_mapping\n", map[string]string{
+				"mapping": `{"name":`,
+			})
+
+			return tests.ApiScenario{
+				Name:   "authorized as admin with invalid column mapping",
+				Method: http.MethodPost,
+				Url:    "/api/collections/demo2/import",
+				Body:   body,
+				RequestHeaders: map[string]string{
+					"Authorization": suite.AdminAuthToken,
+					"Content-Type":  contentType,
+				},
+				ExpectedStatus:  400,
+				ExpectedContent: []string{`"data":{}`},
+				TestAppFactory: func(t *testing.T) *tests.TestApp {
+					return suite.App
+				},
+			}
+		}(),
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
 func (suite *RecordCrudTestSuite) TestRecordCrudView() {
 	t := suite.T()
 
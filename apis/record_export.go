@@ -0,0 +1,224 @@
+package apis
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/resolvers"
+	"github.com/hylarucoder/rocketbase/tools/dataset"
+	"github.com/hylarucoder/rocketbase/tools/rest"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/labstack/echo/v5"
+)
+
+const exportFormatQueryParam = "format"
+
+// export streams all records matching the collection list rule
+// and the request filter as CSV or NDJSON file attachment.
+//
+// Every fetched batch is passed through the OnRecordsListRequest hook
+// (with the export request as HttpContext), the same as the list results.
+//
+// The records are fetched in MaxPerPage batches using cursor pagination
+// so that the memory usage remains constant regardless of the collection size.
+func (api *recordApi) export(c echo.Context) error {
+	collection, _ := c.Get(ContextCollectionKey).(*models.Collection)
+	if collection == nil {
+		return NewNotFoundError("", "Missing collection context.")
+	}
+
+	requestInfo := RequestInfo(c)
+
+	// forbid users and guests to query special filter/sort fields
+	if err := checkForAdminOnlyRuleFields(requestInfo); err != nil {
+		return err
+	}
+
+	if requestInfo.Admin == nil && collection.ListRule == nil {
+		// only admins can access if the rule is nil
+		return NewForbiddenError("Only admins can perform this action.", nil)
+	}
+
+	format := dataset.FormatCSV
+	if raw := c.QueryParam(exportFormatQueryParam); raw != "" {
+		format = dataset.Format(raw)
+	}
+	if err := format.Validate(); err != nil {
+		return NewBadRequestError("", err)
+	}
+
+	fieldsResolver := resolvers.NewRecordFieldResolver(
+		api.app.Dao(),
+		collection,
+		requestInfo,
+		// hidden fields are searchable only by admins
+		requestInfo.Admin != nil,
+	)
+
+	searchProvider := search.NewProvider(fieldsResolver).
+		Query(api.app.Dao().RecordQuery(collection)).
+		PerPage(search.MaxPerPage).
+		Cursor("")
+
+	if requestInfo.Admin == nil && collection.ListRule != nil {
+		searchProvider.AddFilter(search.FilterData(*collection.ListRule))
+	}
+
+	if err := searchProvider.Parse(c.QueryParams().Encode()); err != nil {
+		return NewBadRequestError("", err)
+	}
+
+	rawFields := c.QueryParam(fieldsQueryParam)
+
+	// fetch the first batch before committing the response
+	// so that invalid filter/sort errors could be still reported
+	records := []*models.Record{}
+	result, err := searchProvider.Exec(&records)
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
+	filename := fmt.Sprintf("%s_%s.%s", collection.Name, time.Now().UTC().Format("20060102150405"), format.Extension())
+
+	columns := RecordExportColumns(collection, rawFields, c.QueryParam(expandQueryParam) != "")
+
+	res := c.Response()
+
+	var writer dataset.Writer
+
+	for {
+		// each batch goes through the list request hooks so that
+		// the handlers filtering or redacting list results apply also to the export
+		event := new(core.RecordsListEvent)
+		event.HttpContext = c
+		event.Collection = collection
+		event.Records = records
+		event.Result = result
+
+		err := api.app.OnRecordsListRequest().Trigger(event, func(e *core.RecordsListEvent) error {
+			if writer == nil {
+				if e.HttpContext.Response().Committed {
+					return nil
+				}
+
+				res.Header().Set(echo.HeaderContentType, format.ContentType())
+				res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+				res.WriteHeader(http.StatusOK)
+
+				w, err := dataset.NewWriter(res, format, columns...)
+				if err != nil {
+					return err
+				}
+				writer = w
+			}
+
+			if err := EnrichRecords(e.HttpContext, api.app.Dao(), e.Records); err != nil {
+				api.app.Logger().Debug("Failed to enrich export records", slog.String("error", err.Error()))
+			}
+
+			for _, record := range e.Records {
+				picked, err := rest.PickFields(record, rawFields)
+				if err != nil {
+					return err
+				}
+
+				item, ok := picked.(map[string]any)
+				if !ok {
+					return errors.New("failed to normalize the exported record")
+				}
+
+				if err := writer.Write(item); err != nil {
+					return err
+				}
+			}
+
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			res.Flush()
+
+			return nil
+		})
+		if err != nil || writer == nil {
+			// the error is either returned before the response
+			// was committed or aborts the already started stream
+			return err
+		}
+
+		if result.NextCursor == "" {
+			break
+		}
+
+		records = []*models.Record{}
+		result, err = searchProvider.Cursor(result.NextCursor).Exec(&records)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordExportColumns returns the records export columns of the provided
+// collection (system fields, schema fields and optionally the expand).
+//
+// If rawFields is set, only the listed top level fields are returned.
+func RecordExportColumns(collection *models.Collection, rawFields string, withExpand bool) []string {
+	columns := []string{
+		schema.FieldNameId,
+		schema.FieldNameCollectionId,
+		schema.FieldNameCollectionName,
+		schema.FieldNameCreated,
+		schema.FieldNameUpdated,
+	}
+
+	if collection.IsAuth() {
+		columns = append(
+			columns,
+			schema.FieldNameUsername,
+			schema.FieldNameEmail,
+			schema.FieldNameEmailVisibility,
+			schema.FieldNameVerified,
+		)
+	}
+
+	for _, field := range collection.Schema.Fields() {
+		columns = append(columns, field.Name)
+	}
+
+	if withExpand {
+		columns = append(columns, schema.FieldNameExpand)
+	}
+
+	if rawFields == "" {
+		return columns
+	}
+
+	// the top level names of the picked fields
+	// (eg. "expand.rel.title" -> "expand", "description:excerpt(10)" -> "description")
+	picked := map[string]struct{}{}
+	for _, f := range strings.Split(rawFields, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(f), ":")
+		name, _, _ = strings.Cut(name, ".")
+		if name == "*" {
+			return columns
+		}
+		picked[name] = struct{}{}
+	}
+
+	result := make([]string, 0, len(picked))
+	for _, col := range columns {
+		if _, ok := picked[col]; ok {
+			result = append(result, col)
+		}
+	}
+
+	return result
+}
@@ -316,7 +316,12 @@ func hasAuthManageAccess(
 	return findErr == nil
 }
 
-var ruleQueryParams = []string{search.FilterQueryParam, search.SortQueryParam}
+var ruleQueryParams = []string{
+	search.FilterQueryParam,
+	search.SortQueryParam,
+	search.GroupByQueryParam,
+	search.AggregateQueryParam,
+}
 var adminOnlyRuleFields = []string{"@collection.", "@request."}
 
 // @todo consider moving the rules check to the RecordFieldResolver.
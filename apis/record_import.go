@@ -0,0 +1,66 @@
+package apis
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hylarucoder/rocketbase/forms"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tools/rest"
+	"github.com/labstack/echo/v5"
+)
+
+// bulkImport imports (creates or updates) the collection records
+// from the uploaded CSV or NDJSON file and returns a per-row report.
+//
+// The endpoint is available only for admins because the
+// imported records are not checked against the collection API rules.
+func (api *recordApi) bulkImport(c echo.Context) error {
+	collection, _ := c.Get(ContextCollectionKey).(*models.Collection)
+	if collection == nil {
+		return NewNotFoundError("", "Missing collection context.")
+	}
+
+	form := forms.NewRecordsImport(api.app, collection)
+
+	// load request data
+	if err := c.Bind(form); err != nil {
+		return NewBadRequestError("Failed to load the submitted data due to invalid formatting.", err)
+	}
+
+	// the multipart form mapping is submitted as JSON encoded string
+	if raw := c.FormValue("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &form.Mapping); err != nil {
+			return NewBadRequestError("Failed to load the submitted data due to invalid formatting.", err)
+		}
+	}
+
+	files, err := rest.FindUploadedFiles(c.Request(), "file")
+	if err != nil {
+		return NewBadRequestError("Missing or invalid uploaded file.", err)
+	}
+	form.File = files[0]
+
+	result, err := form.Submit()
+	if err != nil {
+		return NewBadRequestError("Failed to import the submitted records.", err)
+	}
+
+	// normalize the row errors to the same format as the regular api validation errors
+	rowErrors := make([]map[string]any, len(result.Errors))
+	for i, rowErr := range result.Errors {
+		rowErrors[i] = map[string]any{
+			"row":  rowErr.Row,
+			"data": safeErrorsData(rowErr.Errors),
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"dryRun":  result.DryRun,
+		"total":   result.Total,
+		"created": result.Created,
+		"updated": result.Updated,
+		"failed":  result.Failed,
+		"errors":  rowErrors,
+	})
+}
@@ -0,0 +1,119 @@
+package apis
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"regexp"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/settings"
+	"github.com/hylarucoder/rocketbase/tools/routine"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+// slowQueryExplainTimeout is the max duration of the slow query EXPLAIN statement.
+const slowQueryExplainTimeout = 5 * time.Second
+
+// planLiteralRegex matches the single quoted string literals
+// (including the escaped quotes) in an execution plan.
+var planLiteralRegex = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// withSlowQueryLog returns a new Dao with the same configuration options
+// as the provided one, but that logs the record queries exceeding
+// the Logs.SlowQueryThreshold app setting (if enabled).
+//
+// The collection and filter are logged as the origin of the slow queries.
+func withSlowQueryLog(app core.App, dao *daos.Dao, collection *models.Collection, filter string) *daos.Dao {
+	config := app.Settings().Logs
+
+	// disabled or no logs retention and sinks
+	if config.SlowQueryThreshold <= 0 || !config.IsEnabled() {
+		return dao
+	}
+
+	dao = dao.Clone()
+	dao.SlowQueryThreshold = time.Duration(config.SlowQueryThreshold) * time.Millisecond
+	dao.SlowQueryFunc = func(q *dbx.Query, duration time.Duration, err error) {
+		sql := q.SQL()
+		params := maps.Clone(q.Params())
+
+		attrs := make([]any, 0, 9)
+		attrs = append(
+			attrs,
+			slog.String("type", "slowQuery"),
+			slog.String("collection", collection.Name),
+			slog.String("filter", filter),
+			slog.String("sql", sql),
+			slog.Any("params", redactQueryParams(params)),
+			slog.Float64("execTime", float64(duration)/float64(time.Millisecond)),
+		)
+
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+
+		// don't block on the explain and logs write
+		routine.FireAndForget(func() {
+			if config.SlowQueryExplain {
+				plan, explainErr := explainQuery(app, sql, params)
+				if explainErr != nil {
+					attrs = append(attrs, slog.String("explainError", explainErr.Error()))
+				} else {
+					attrs = append(attrs, slog.Any("explain", plan))
+				}
+			}
+
+			app.Logger().Warn("Slow query "+collection.Name, attrs...)
+		})
+	}
+
+	return dao
+}
+
+// explainQuery returns the JSON formatted execution plan of the provided query
+// (the query itself is not executed).
+//
+// The plan is planned with the actual params and because of that the
+// string literals in it are masked similar to the logged params.
+func explainQuery(app core.App, sql string, params dbx.Params) (types.JsonRaw, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), slowQueryExplainTimeout)
+	defer cancel()
+
+	var plan types.JsonRaw
+
+	err := app.DB().NewQuery("EXPLAIN (FORMAT JSON) " + sql).
+		Bind(params).
+		WithContext(ctx).
+		Row(&plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return types.JsonRaw(planLiteralRegex.ReplaceAll(plan, []byte("'"+settings.SecretMask+"'"))), nil
+}
+
+// redactQueryParams returns a copy of the provided query params
+// with all non numeric and non boolean values masked since they
+// could contain sensitive data (eg. resolved @request.auth.* fields).
+func redactQueryParams(params dbx.Params) map[string]any {
+	result := make(map[string]any, len(params))
+
+	for k, v := range params {
+		switch v.(type) {
+		case nil, bool,
+			int, int8, int16, int32, int64,
+			uint, uint8, uint16, uint32, uint64,
+			float32, float64:
+			result[k] = v
+		default:
+			result[k] = settings.SecretMask
+		}
+	}
+
+	return result
+}
@@ -250,8 +250,9 @@ type migrationsConnection struct {
 	MigrationsList migrate.MigrationsList
 }
 
-func runMigrations(app core.App) error {
-	connections := []migrationsConnection{
+// appMigrationsConnections returns the app db connections with their registered migrations.
+func appMigrationsConnections(app core.App) []migrationsConnection {
+	return []migrationsConnection{
 		{
 			DB:             app.DB(),
 			MigrationsList: migrations.AppMigrations,
@@ -261,8 +262,10 @@ func runMigrations(app core.App) error {
 			MigrationsList: logs.LogsMigrations,
 		},
 	}
+}
 
-	for _, c := range connections {
+func runMigrations(app core.App) error {
+	for _, c := range appMigrationsConnections(app) {
 		runner, err := migrate.NewRunner(c.DB, c.MigrationsList)
 		if err != nil {
 			return err
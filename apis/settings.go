@@ -6,6 +6,7 @@ import (
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/hylarucoder/rocketbase/core"
 	"github.com/hylarucoder/rocketbase/forms"
+	"github.com/hylarucoder/rocketbase/models"
 	"github.com/hylarucoder/rocketbase/models/settings"
 	"github.com/labstack/echo/v5"
 )
@@ -53,6 +54,13 @@ func (api *settingsApi) set(c echo.Context) error {
 		return NewBadRequestError("An error occurred while loading the submitted data.", err)
 	}
 
+	// snapshot the original state for the audit log
+	// (OldSettings is the app settings instance that is modified in place on refresh)
+	before, err := api.app.Settings().Clone()
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
 	event := new(core.SettingsUpdateEvent)
 	event.HttpContext = c
 	event.OldSettings = api.app.Settings()
@@ -67,6 +75,10 @@ func (api *settingsApi) set(c echo.Context) error {
 					return NewBadRequestError("An error occurred while submitting the form.", err)
 				}
 
+				oldSecrets, _ := settingsAuditSecrets(before)
+				newSecrets, _ := settingsAuditSecrets(e.NewSettings)
+				recordAudit(api.app, c, models.AuditActionUpdate, models.AuditResourceSettings, "", before, e.NewSettings, append(oldSecrets, newSecrets...)...)
+
 				return api.app.OnSettingsAfterUpdateRequest().Trigger(event, func(e *core.SettingsUpdateEvent) error {
 					if e.HttpContext.Response().Committed {
 						return nil
@@ -0,0 +1,83 @@
+package apis
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/tools/tracing"
+	"github.com/labstack/echo/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware starts a new server span for each http request
+// (continuing the trace from the "traceparent" request header if present)
+// and stores it in the request context.
+//
+// It is no-op unless a TracerProvider is registered (usually via the app tracing settings).
+func tracingMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			ctx, span := tracing.Tracer().Start(
+				ctx,
+				req.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.URLPath(req.URL.Path),
+				),
+			)
+			defer span.End()
+
+			if !span.IsRecording() {
+				return next(c)
+			}
+
+			c.SetRequest(req.WithContext(ctx))
+
+			if err := next(c); err != nil {
+				// handle the error here to be able to resolve the final response status
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+
+			if route := c.Path(); route != "" {
+				span.SetName(req.Method + " " + route)
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			return nil
+		}
+	}
+}
+
+// requestDao returns the app Dao bound to the request context
+// so that the executed db queries could be traced as part of the request span.
+//
+// The returned Dao is the same as app.Dao() if the request is not traced.
+func requestDao(app core.App, c echo.Context) *daos.Dao {
+	ctx := c.Request().Context()
+
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return app.Dao()
+	}
+
+	// the request cancellation is not propagated to preserve the existing queries behavior
+	return app.Dao().WithContext(context.WithoutCancel(ctx))
+}
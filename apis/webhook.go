@@ -0,0 +1,148 @@
+package apis
+
+import (
+	"net/http"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/forms"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/labstack/echo/v5"
+)
+
+// bindWebhookApi registers the webhook api endpoints and the corresponding handlers.
+//
+// @todo add hooks once the app hooks api restructuring is finalized
+func bindWebhookApi(app core.App, rg *echo.Group) {
+	api := webhookApi{app: app}
+
+	subGroup := rg.Group("/webhooks", ActivityLogger(app), RequireAdminAuth())
+	subGroup.GET("", api.list)
+	subGroup.POST("", api.create)
+	subGroup.GET("/deliveries", api.deliveriesList)
+	subGroup.POST("/deliveries/:id/redeliver", api.redeliver)
+	subGroup.GET("/:id", api.view)
+	subGroup.PATCH("/:id", api.update)
+	subGroup.DELETE("/:id", api.delete)
+}
+
+type webhookApi struct {
+	app core.App
+}
+
+func (api *webhookApi) list(c echo.Context) error {
+	fieldResolver := search.NewSimpleFieldResolver(
+		"id", "created", "updated", "name", "collectionId", "url", "active",
+	)
+
+	webhooks := []*models.Webhook{}
+
+	result, err := search.NewProvider(fieldResolver).
+		Query(api.app.Dao().WebhookQuery()).
+		ParseAndExec(c.QueryParams().Encode(), &webhooks)
+
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func (api *webhookApi) view(c echo.Context) error {
+	webhook, err := api.app.Dao().FindWebhookById(c.PathParam("id"))
+	if err != nil || webhook == nil {
+		return NewNotFoundError("", err)
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func (api *webhookApi) create(c echo.Context) error {
+	webhook := &models.Webhook{}
+
+	form := forms.NewWebhookUpsert(api.app, webhook)
+
+	// load request
+	if err := c.Bind(form); err != nil {
+		return NewBadRequestError("Failed to load the submitted data due to invalid formatting.", err)
+	}
+
+	if err := form.Submit(); err != nil {
+		return NewBadRequestError("Failed to create webhook.", err)
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func (api *webhookApi) update(c echo.Context) error {
+	webhook, err := api.app.Dao().FindWebhookById(c.PathParam("id"))
+	if err != nil || webhook == nil {
+		return NewNotFoundError("", err)
+	}
+
+	form := forms.NewWebhookUpsert(api.app, webhook)
+
+	// load request
+	if err := c.Bind(form); err != nil {
+		return NewBadRequestError("Failed to load the submitted data due to invalid formatting.", err)
+	}
+
+	if err := form.Submit(); err != nil {
+		return NewBadRequestError("Failed to update webhook.", err)
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+func (api *webhookApi) delete(c echo.Context) error {
+	webhook, err := api.app.Dao().FindWebhookById(c.PathParam("id"))
+	if err != nil || webhook == nil {
+		return NewNotFoundError("", err)
+	}
+
+	if err := api.app.Dao().DeleteWebhook(webhook); err != nil {
+		return NewBadRequestError("Failed to delete webhook.", err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (api *webhookApi) deliveriesList(c echo.Context) error {
+	fieldResolver := search.NewSimpleFieldResolver(
+		"id", "created", "updated", "webhookId", "event", "recordId",
+		"status", "attempts", "nextAttemptAt", "responseStatus",
+	)
+
+	deliveries := []*models.WebhookDelivery{}
+
+	result, err := search.NewProvider(fieldResolver).
+		Query(api.app.Dao().WebhookDeliveryQuery()).
+		ParseAndExec(c.QueryParams().Encode(), &deliveries)
+
+	if err != nil {
+		return NewBadRequestError("", err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// redeliver resets the specified delivery so that
+// it could be picked again by the webhooks worker.
+func (api *webhookApi) redeliver(c echo.Context) error {
+	delivery, err := api.app.Dao().FindWebhookDeliveryById(c.PathParam("id"))
+	if err != nil || delivery == nil {
+		return NewNotFoundError("", err)
+	}
+
+	delivery.Status = models.WebhookDeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = types.NowDateTime()
+	delivery.LastError = ""
+
+	if err := api.app.Dao().SaveWebhookDelivery(delivery); err != nil {
+		return NewBadRequestError("Failed to schedule the webhook redelivery.", err)
+	}
+
+	return c.JSON(http.StatusOK, delivery)
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/fatih/color"
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/forms"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported collections file formats.
+const (
+	collectionsFormatJSON = "json"
+	collectionsFormatYAML = "yaml"
+)
+
+// NewCollectionsCommand creates and returns new command for managing
+// the collections schema as code (export, plan, apply).
+func NewCollectionsCommand(app core.App) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "collections",
+		Short: "Manages the collections schema as code",
+	}
+
+	command.AddCommand(collectionsExportCommand(app))
+	command.AddCommand(collectionsPlanCommand(app))
+	command.AddCommand(collectionsApplyCommand(app))
+
+	return command
+}
+
+func collectionsExportCommand(app core.App) *cobra.Command {
+	var format string
+
+	command := &cobra.Command{
+		Use:          "export",
+		Example:      "collections export collections.yaml",
+		Short:        "Exports the current collections as canonical JSON or YAML file",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("Too many arguments - expected a single optional output file path.")
+			}
+
+			var output string
+			if len(args) == 1 {
+				output = args[0]
+			}
+
+			if format == "" {
+				format = collectionsFileFormat(output)
+			}
+
+			collections := []*models.Collection{}
+			if err := app.Dao().CollectionQuery().OrderBy("name ASC").All(&collections); err != nil {
+				return fmt.Errorf("Failed to load the collections: %v", err)
+			}
+
+			raw, err := marshalCollections(collections, format)
+			if err != nil {
+				return fmt.Errorf("Failed to serialize the collections: %v", err)
+			}
+
+			var out io.Writer = os.Stdout
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("Failed to create the output file: %v", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			if _, err := out.Write(raw); err != nil {
+				return err
+			}
+
+			if output != "" {
+				color.Green("Successfully exported %d collections to %s!", len(collections), output)
+			}
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&format, "format", "", "the file format (json or yaml; default resolved from the file extension)")
+
+	return command
+}
+
+func collectionsPlanCommand(app core.App) *cobra.Command {
+	var deleteMissing bool
+
+	command := &cobra.Command{
+		Use:          "plan",
+		Example:      "collections plan collections.yaml --delete-missing",
+		Short:        "Prints the changes that applying the collections file would make",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if len(args) != 1 || args[0] == "" {
+				return errors.New("Missing collections file path argument.")
+			}
+
+			form, err := loadCollectionsImportForm(app, args[0], deleteMissing)
+			if err != nil {
+				return err
+			}
+
+			plan, err := form.Plan()
+			if err != nil {
+				return fmt.Errorf("Failed to generate the collections plan: %v", err)
+			}
+
+			printCollectionsPlan(command.OutOrStdout(), plan)
+
+			return nil
+		},
+	}
+
+	command.Flags().BoolVar(&deleteMissing, "delete-missing", false, "delete the collections and fields that are not in the file")
+
+	return command
+}
+
+func collectionsApplyCommand(app core.App) *cobra.Command {
+	var deleteMissing bool
+	var yes bool
+
+	command := &cobra.Command{
+		Use:          "apply",
+		Example:      "collections apply collections.yaml --delete-missing",
+		Short:        "Imports the collections file after confirming its plan",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if len(args) != 1 || args[0] == "" {
+				return errors.New("Missing collections file path argument.")
+			}
+
+			form, err := loadCollectionsImportForm(app, args[0], deleteMissing)
+			if err != nil {
+				return err
+			}
+
+			plan, err := form.Plan()
+			if err != nil {
+				return fmt.Errorf("Failed to generate the collections plan: %v", err)
+			}
+
+			printCollectionsPlan(command.OutOrStdout(), plan)
+
+			if !plan.HasChanges() {
+				return nil
+			}
+
+			if !yes {
+				confirm := false
+				prompt := &survey.Confirm{
+					Message: "Do you really want to apply the above changes?",
+				}
+				survey.AskOne(prompt, &confirm)
+				if !confirm {
+					fmt.Fprintln(command.OutOrStdout(), "The command has been cancelled")
+					return nil
+				}
+			}
+
+			if err := form.Submit(); err != nil {
+				return fmt.Errorf("Failed to apply the collections: %v", err)
+			}
+
+			color.Green("Successfully applied the collections changes!")
+
+			return nil
+		},
+	}
+
+	command.Flags().BoolVar(&deleteMissing, "delete-missing", false, "delete the collections and fields that are not in the file")
+	command.Flags().BoolVarP(&yes, "yes", "y", false, "apply the changes without confirmation")
+
+	return command
+}
+
+// loadCollectionsImportForm loads the collections from the JSON or YAML
+// file at path into a new [forms.CollectionsImport] form.
+func loadCollectionsImportForm(app core.App, path string, deleteMissing bool) (*forms.CollectionsImport, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read the collections file: %v", err)
+	}
+
+	collections, err := unmarshalCollections(raw, collectionsFileFormat(path))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse the collections file: %v", err)
+	}
+
+	form := forms.NewCollectionsImport(app)
+	form.Collections = collections
+	form.DeleteMissing = deleteMissing
+
+	return form, nil
+}
+
+func printCollectionsPlan(w io.Writer, plan *forms.CollectionsImportPlan) {
+	if !plan.HasChanges() {
+		fmt.Fprintln(w, "No changes. The collections are up-to-date.")
+		return
+	}
+
+	for _, line := range strings.SplitAfter(plan.String(), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasSuffix(trimmed, "]") && strings.Contains(trimmed, "[destructive"):
+			color.New(color.FgRed).Fprint(w, line)
+		case strings.HasPrefix(trimmed, "+"):
+			color.New(color.FgGreen).Fprint(w, line)
+		case strings.HasPrefix(trimmed, "-"):
+			color.New(color.FgRed).Fprint(w, line)
+		case strings.HasPrefix(trimmed, "~"):
+			color.New(color.FgYellow).Fprint(w, line)
+		default:
+			fmt.Fprint(w, line)
+		}
+	}
+}
+
+func collectionsFileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return collectionsFormatYAML
+	default:
+		return collectionsFormatJSON
+	}
+}
+
+// marshalCollections serializes the collections in their canonical form,
+// aka. sorted by name and without the created and updated timestamps
+// so that the exported file changes only when the schema changes.
+func marshalCollections(collections []*models.Collection, format string) ([]byte, error) {
+	sorted := make([]*models.Collection, len(collections))
+	copy(sorted, collections)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	raw, err := json.Marshal(sorted)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []map[string]any{}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		delete(item, "created")
+		delete(item, "updated")
+	}
+
+	switch format {
+	case collectionsFormatJSON:
+		result, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(result, '\n'), nil
+	case collectionsFormatYAML:
+		return yaml.Marshal(items)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+func unmarshalCollections(raw []byte, format string) ([]*models.Collection, error) {
+	if format == collectionsFormatYAML {
+		var data any
+		if err := yaml.Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+	}
+
+	collections := []*models.Collection{}
+	if err := json.Unmarshal(raw, &collections); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
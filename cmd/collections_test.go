@@ -0,0 +1,83 @@
+package cmd_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/cmd"
+	"github.com/hylarucoder/rocketbase/tests"
+)
+
+func TestCollectionsCommands(t *testing.T) {
+	t.Parallel()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	for _, ext := range []string{"json", "yaml"} {
+		file := filepath.Join(t.TempDir(), "collections."+ext)
+
+		// export
+		command := cmd.NewCollectionsCommand(app)
+		command.SetArgs([]string{"export", file})
+		if err := command.Execute(); err != nil {
+			t.Fatalf("[%s] Export failed: %v", ext, err)
+		}
+
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("[%s] %v", ext, err)
+		}
+		if !strings.Contains(string(raw), "demo1") || strings.Contains(string(raw), "created") {
+			t.Fatalf("[%s] Expected canonical collections export, got\n%s", ext, raw)
+		}
+
+		// plan against the same state
+		var out bytes.Buffer
+		command = cmd.NewCollectionsCommand(app)
+		command.SetOut(&out)
+		command.SetArgs([]string{"plan", file, "--delete-missing"})
+		if err := command.Execute(); err != nil {
+			t.Fatalf("[%s] Plan failed: %v", ext, err)
+		}
+		if !strings.Contains(out.String(), "No changes") {
+			t.Fatalf("[%s] Expected no changes, got\n%s", ext, out.String())
+		}
+
+		// change the db state and apply the file back
+		demo1, err := app.Dao().FindCollectionByNameOrId("demo1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		oldName := demo1.Name
+		demo1.Name = "demo1_renamed"
+		if err := app.Dao().SaveCollection(demo1); err != nil {
+			t.Fatal(err)
+		}
+
+		out.Reset()
+		command = cmd.NewCollectionsCommand(app)
+		command.SetOut(&out)
+		command.SetArgs([]string{"apply", file, "--yes"})
+		if err := command.Execute(); err != nil {
+			t.Fatalf("[%s] Apply failed: %v", ext, err)
+		}
+		if !strings.Contains(out.String(), "~ name: demo1_renamed -> "+oldName) {
+			t.Fatalf("[%s] Expected rename plan, got\n%s", ext, out.String())
+		}
+
+		if _, err := app.Dao().FindCollectionByNameOrId(oldName); err != nil {
+			t.Fatalf("[%s] Expected the collection name to be restored: %v", ext, err)
+		}
+	}
+
+	// missing file
+	command := cmd.NewCollectionsCommand(app)
+	command.SetArgs([]string{"plan", "missing.json"})
+	if err := command.Execute(); err == nil {
+		t.Fatal("Expected missing file error")
+	}
+}
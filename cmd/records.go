@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/hylarucoder/rocketbase/apis"
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/forms"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/resolvers"
+	"github.com/hylarucoder/rocketbase/tools/dataset"
+	"github.com/hylarucoder/rocketbase/tools/filesystem"
+	"github.com/hylarucoder/rocketbase/tools/rest"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/spf13/cobra"
+)
+
+// NewRecordsCommand creates and returns new command for managing
+// collection records in bulk (export, import).
+func NewRecordsCommand(app core.App) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "records",
+		Short: "Manages collection records in bulk",
+	}
+
+	command.AddCommand(recordsExportCommand(app))
+	command.AddCommand(recordsImportCommand(app))
+
+	return command
+}
+
+func recordsExportCommand(app core.App) *cobra.Command {
+	var format string
+	var filter string
+	var sort string
+	var fields string
+	var expand string
+	var output string
+
+	command := &cobra.Command{
+		Use:          "export",
+		Example:      "records export posts --format=ndjson --filter=\"status='active'\" --output=posts.ndjson",
+		Short:        "Exports the records of a single collection as CSV or NDJSON",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if len(args) != 1 || args[0] == "" {
+				return errors.New("Missing collection name or id argument.")
+			}
+
+			if err := dataset.Format(format).Validate(); err != nil {
+				return err
+			}
+
+			collection, err := app.Dao().FindCollectionByNameOrId(args[0])
+			if err != nil {
+				return fmt.Errorf("Collection %s doesn't exist.", args[0])
+			}
+
+			var out io.Writer = os.Stdout
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("Failed to create the output file: %v", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			total, err := exportRecords(app, collection, out, dataset.Format(format), filter, sort, fields, expand)
+			if err != nil {
+				return fmt.Errorf("Failed to export %s records: %v", collection.Name, err)
+			}
+
+			if output != "" {
+				color.Green("Successfully exported %d %s records to %s!", total, collection.Name, output)
+			}
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&format, "format", string(dataset.FormatCSV), "the export format (csv, ndjson or excel)")
+	command.Flags().StringVar(&filter, "filter", "", "optional records filter expression")
+	command.Flags().StringVar(&sort, "sort", "", "optional records sort expression (eg. -created,id)")
+	command.Flags().StringVar(&fields, "fields", "", "optional comma separated list of fields to export")
+	command.Flags().StringVar(&expand, "expand", "", "optional comma separated list of relations to expand")
+	command.Flags().StringVarP(&output, "output", "o", "", "the output file path (default stdout)")
+
+	return command
+}
+
+func recordsImportCommand(app core.App) *cobra.Command {
+	var format string
+	var dryRun bool
+	var upsertBy string
+	var chunkSize int
+	var mapping map[string]string
+
+	command := &cobra.Command{
+		Use:          "import",
+		Example:      "records import posts posts.csv --upsert-by=id --map=\"name=title\"",
+		Short:        "Imports (creates or updates) collection records from a CSV or NDJSON file",
+		SilenceUsage: true,
+		RunE: func(command *cobra.Command, args []string) error {
+			if len(args) != 2 || args[0] == "" || args[1] == "" {
+				return errors.New("Missing collection name or id and file path arguments.")
+			}
+
+			collection, err := app.Dao().FindCollectionByNameOrId(args[0])
+			if err != nil {
+				return fmt.Errorf("Collection %s doesn't exist.", args[0])
+			}
+
+			if collection.IsView() {
+				return errors.New("View collection records cannot be imported.")
+			}
+
+			file, err := filesystem.NewFileFromPath(args[1])
+			if err != nil {
+				return fmt.Errorf("Failed to load the import file: %v", err)
+			}
+
+			form := forms.NewRecordsImport(app, collection)
+			form.SetAllowLocalFiles(true)
+			form.File = file
+			form.Format = format
+			form.DryRun = dryRun
+			form.UpsertBy = upsertBy
+			form.ChunkSize = chunkSize
+			form.Mapping = mapping
+
+			result, err := form.Submit()
+
+			if result != nil {
+				for _, rowErr := range result.Errors {
+					color.Yellow("Row %d: %v", rowErr.Row, rowErr.Errors)
+				}
+
+				if result.Failed > len(result.Errors) {
+					color.Yellow("... and %d more failed rows", result.Failed-len(result.Errors))
+				}
+			}
+
+			if err != nil {
+				return fmt.Errorf("Failed to import %s records: %v", collection.Name, err)
+			}
+
+			summary := fmt.Sprintf(
+				"%d rows processed (%d created, %d updated, %d failed)",
+				result.Total,
+				result.Created,
+				result.Updated,
+				result.Failed,
+			)
+
+			if dryRun {
+				color.Green("Dry run completed: %s!", summary)
+			} else {
+				color.Green("Successfully imported %s records: %s!", collection.Name, summary)
+			}
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&format, "format", "", "the file format (csv, ndjson or excel; default resolved from the file extension)")
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "validate and save the rows without committing the changes")
+	command.Flags().StringVar(&upsertBy, "upsert-by", "", "optional unique field used to update the matching existing records")
+	command.Flags().IntVar(&chunkSize, "chunk-size", forms.DefaultRecordsImportChunkSize, "the number of rows imported within a single transaction")
+	command.Flags().StringToStringVar(&mapping, "map", nil, "optional column=field mapping (use column=- to skip a column)")
+
+	return command
+}
+
+// exportRecords streams all matching collection records into out
+// and returns the total number of exported records.
+func exportRecords(
+	app core.App,
+	collection *models.Collection,
+	out io.Writer,
+	format dataset.Format,
+	filter string,
+	sort string,
+	fields string,
+	expand string,
+) (int, error) {
+	// the CLI has full (aka. admin) access to the records
+	fieldsResolver := resolvers.NewRecordFieldResolver(app.Dao(), collection, nil, true)
+
+	provider := search.NewProvider(fieldsResolver).
+		Query(app.Dao().RecordQuery(collection)).
+		PerPage(search.MaxPerPage).
+		Cursor("")
+
+	if filter != "" {
+		provider.AddFilter(search.FilterData(filter))
+	}
+
+	for _, s := range search.ParseSortFromString(sort) {
+		provider.AddSort(s)
+	}
+
+	var expands []string
+	if expand != "" {
+		expands = strings.Split(expand, ",")
+	}
+
+	writer, err := dataset.NewWriter(out, format, apis.RecordExportColumns(collection, fields, len(expands) > 0)...)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int
+
+	for {
+		records := []*models.Record{}
+
+		result, err := provider.Exec(&records)
+		if err != nil {
+			return total, err
+		}
+
+		if len(expands) > 0 {
+			if errs := app.Dao().ExpandRecords(records, expands, nil); len(errs) > 0 {
+				return total, fmt.Errorf("failed to expand: %v", errs)
+			}
+		}
+
+		for _, record := range records {
+			record.IgnoreEmailVisibility(true)
+
+			picked, err := rest.PickFields(record, fields)
+			if err != nil {
+				return total, err
+			}
+
+			item, ok := picked.(map[string]any)
+			if !ok {
+				return total, errors.New("failed to normalize the exported record")
+			}
+
+			if err := writer.Write(item); err != nil {
+				return total, err
+			}
+
+			total++
+		}
+
+		if err := writer.Flush(); err != nil {
+			return total, err
+		}
+
+		if result.NextCursor == "" {
+			return total, nil
+		}
+
+		provider.Cursor(result.NextCursor)
+	}
+}
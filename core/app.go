@@ -12,6 +12,7 @@ import (
 	"github.com/hylarucoder/rocketbase/tools/filesystem"
 	"github.com/hylarucoder/rocketbase/tools/hook"
 	"github.com/hylarucoder/rocketbase/tools/mailer"
+	"github.com/hylarucoder/rocketbase/tools/metrics"
 	"github.com/hylarucoder/rocketbase/tools/store"
 	"github.com/hylarucoder/rocketbase/tools/subscriptions"
 	"github.com/pocketbase/dbx"
@@ -71,9 +72,18 @@ type App interface {
 	// Store returns the app runtime store.
 	Store() *store.Store[any]
 
+	// Jobs returns the app background jobs queue.
+	Jobs() *Jobs
+
+	// CronStates returns the running states of the app cron schedulers.
+	CronStates() []CronState
+
 	// SubscriptionsBroker returns the app realtime subscriptions broker instance.
 	SubscriptionsBroker() *subscriptions.Broker
 
+	// Metrics returns the app metrics registry.
+	Metrics() *metrics.Registry
+
 	// NewMailClient creates and returns a configured app mail client.
 	NewMailClient() mailer.Mailer
 
@@ -215,6 +225,41 @@ type App interface {
 	// will be triggered and called only if their event data origin matches the tags.
 	OnModelAfterDelete(tags ...string) *hook.TaggedHook[*ModelEvent]
 
+	// OnModelAfterCreateCommit hook is triggered after a model create
+	// is committed to the DB.
+	//
+	// Unlike OnModelAfterCreate, the event is recorded in the jobs queue
+	// (aka. outbox) as part of the same transaction and it is dispatched
+	// by the app jobs workers only if the transaction is committed.
+	// Failed handlers are retried with exponential backoff, meaning that
+	// the handlers could be called more than once for the same event
+	// (use the event EventId to deduplicate the calls if needed).
+	//
+	// If the optional "tags" list (table names and/or the Collection id for Record models)
+	// is specified, then all event handlers registered via the created hook
+	// will be triggered and called only if their event data origin matches the tags.
+	OnModelAfterCreateCommit(tags ...string) *hook.TaggedHook[*ModelCommitEvent]
+
+	// OnModelAfterUpdateCommit hook is triggered after a model update
+	// is committed to the DB.
+	//
+	// See OnModelAfterCreateCommit for details about the delivery guarantees.
+	//
+	// If the optional "tags" list (table names and/or the Collection id for Record models)
+	// is specified, then all event handlers registered via the created hook
+	// will be triggered and called only if their event data origin matches the tags.
+	OnModelAfterUpdateCommit(tags ...string) *hook.TaggedHook[*ModelCommitEvent]
+
+	// OnModelAfterDeleteCommit hook is triggered after a model delete
+	// is committed to the DB.
+	//
+	// See OnModelAfterCreateCommit for details about the delivery guarantees.
+	//
+	// If the optional "tags" list (table names and/or the Collection id for Record models)
+	// is specified, then all event handlers registered via the created hook
+	// will be triggered and called only if their event data origin matches the tags.
+	OnModelAfterDeleteCommit(tags ...string) *hook.TaggedHook[*ModelCommitEvent]
+
 	// ---------------------------------------------------------------
 	// Mailer event hooks
 	// ---------------------------------------------------------------
@@ -311,6 +356,22 @@ type App interface {
 	// subscriptions were successfully changed.
 	OnRealtimeAfterSubscribeRequest() *hook.Hook[*RealtimeSubscribeEvent]
 
+	// OnRealtimeBeforeChannelPublish hook is triggered before broadcasting
+	// a client message to a realtime channel subscribers, allowing you to
+	// further authorize (by returning an error) or modify the message data.
+	OnRealtimeBeforeChannelPublish() *hook.Hook[*RealtimeChannelEvent]
+
+	// OnRealtimeAfterChannelPublish hook is triggered after the client
+	// message was broadcasted to the realtime channel subscribers.
+	OnRealtimeAfterChannelPublish() *hook.Hook[*RealtimeChannelEvent]
+
+	// OnRealtimePresenceChange hook is triggered before broadcasting
+	// a realtime channel presence join, update or leave event.
+	//
+	// Returning an error will prevent the join or the state update
+	// (the leave events cannot be prevented).
+	OnRealtimePresenceChange() *hook.Hook[*RealtimePresenceEvent]
+
 	// ---------------------------------------------------------------
 	// Settings API event hooks
 	// ---------------------------------------------------------------
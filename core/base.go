@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -21,13 +22,15 @@ import (
 	"github.com/hylarucoder/rocketbase/tools/hook"
 	"github.com/hylarucoder/rocketbase/tools/logger"
 	"github.com/hylarucoder/rocketbase/tools/mailer"
+	"github.com/hylarucoder/rocketbase/tools/metrics"
 	"github.com/hylarucoder/rocketbase/tools/routine"
 	"github.com/hylarucoder/rocketbase/tools/security"
 	"github.com/hylarucoder/rocketbase/tools/store"
 	"github.com/hylarucoder/rocketbase/tools/subscriptions"
+	"github.com/hylarucoder/rocketbase/tools/tracing"
 	"github.com/hylarucoder/rocketbase/tools/types"
 	"github.com/pocketbase/dbx"
-	"github.com/spf13/cast"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 const (
@@ -63,6 +66,16 @@ type BaseApp struct {
 	logsDao             *daos.Dao
 	subscriptionsBroker *subscriptions.Broker
 	logger              *slog.Logger
+	jobs                *Jobs
+	cronsMux            sync.RWMutex
+	crons               []*appCron
+	metrics             *metrics.Registry
+	tracingMux          sync.Mutex
+	tracingConfig       string
+	tracerProvider      *sdktrace.TracerProvider
+	logsSinksMux        sync.RWMutex
+	logsSinksConfig     string
+	logsSinks           []logger.Sink
 
 	// app event hooks
 	onBeforeBootstrap *hook.Hook[*BootstrapEvent]
@@ -80,6 +93,11 @@ type BaseApp struct {
 	onModelBeforeDelete *hook.Hook[*ModelEvent]
 	onModelAfterDelete  *hook.Hook[*ModelEvent]
 
+	// dao after commit (aka. outbox) event hooks
+	onModelAfterCreateCommit *hook.Hook[*ModelCommitEvent]
+	onModelAfterUpdateCommit *hook.Hook[*ModelCommitEvent]
+	onModelAfterDeleteCommit *hook.Hook[*ModelCommitEvent]
+
 	// mailer event hooks
 	onMailerBeforeAdminResetPasswordSend  *hook.Hook[*MailerAdminEvent]
 	onMailerAfterAdminResetPasswordSend   *hook.Hook[*MailerAdminEvent]
@@ -97,6 +115,9 @@ type BaseApp struct {
 	onRealtimeAfterMessageSend       *hook.Hook[*RealtimeMessageEvent]
 	onRealtimeBeforeSubscribeRequest *hook.Hook[*RealtimeSubscribeEvent]
 	onRealtimeAfterSubscribeRequest  *hook.Hook[*RealtimeSubscribeEvent]
+	onRealtimeBeforeChannelPublish   *hook.Hook[*RealtimeChannelEvent]
+	onRealtimeAfterChannelPublish    *hook.Hook[*RealtimeChannelEvent]
+	onRealtimePresenceChange         *hook.Hook[*RealtimePresenceEvent]
 
 	// settings api event hooks
 	onSettingsListRequest         *hook.Hook[*SettingsListEvent]
@@ -201,6 +222,7 @@ func NewBaseApp(config BaseAppConfig) *BaseApp {
 		store:               store.New[any](nil),
 		settings:            settings.New(),
 		subscriptionsBroker: subscriptions.NewBroker(),
+		metrics:             metrics.NewRegistry(),
 
 		// app event hooks
 		onBeforeBootstrap: &hook.Hook[*BootstrapEvent]{},
@@ -218,6 +240,11 @@ func NewBaseApp(config BaseAppConfig) *BaseApp {
 		onModelBeforeDelete: &hook.Hook[*ModelEvent]{},
 		onModelAfterDelete:  &hook.Hook[*ModelEvent]{},
 
+		// dao after commit (aka. outbox) event hooks
+		onModelAfterCreateCommit: &hook.Hook[*ModelCommitEvent]{},
+		onModelAfterUpdateCommit: &hook.Hook[*ModelCommitEvent]{},
+		onModelAfterDeleteCommit: &hook.Hook[*ModelCommitEvent]{},
+
 		// mailer event hooks
 		onMailerBeforeAdminResetPasswordSend:  &hook.Hook[*MailerAdminEvent]{},
 		onMailerAfterAdminResetPasswordSend:   &hook.Hook[*MailerAdminEvent]{},
@@ -235,6 +262,9 @@ func NewBaseApp(config BaseAppConfig) *BaseApp {
 		onRealtimeAfterMessageSend:       &hook.Hook[*RealtimeMessageEvent]{},
 		onRealtimeBeforeSubscribeRequest: &hook.Hook[*RealtimeSubscribeEvent]{},
 		onRealtimeAfterSubscribeRequest:  &hook.Hook[*RealtimeSubscribeEvent]{},
+		onRealtimeBeforeChannelPublish:   &hook.Hook[*RealtimeChannelEvent]{},
+		onRealtimeAfterChannelPublish:    &hook.Hook[*RealtimeChannelEvent]{},
+		onRealtimePresenceChange:         &hook.Hook[*RealtimePresenceEvent]{},
 
 		// settings API event hooks
 		onSettingsListRequest:         &hook.Hook[*SettingsListEvent]{},
@@ -312,7 +342,10 @@ func NewBaseApp(config BaseAppConfig) *BaseApp {
 		onCollectionsAfterImportRequest:  &hook.Hook[*CollectionsImportEvent]{},
 	}
 
+	app.jobs = NewJobs(app)
+
 	app.registerDefaultHooks()
+	app.registerDefaultMetrics()
 
 	return app
 }
@@ -370,6 +403,8 @@ func (app *BaseApp) Bootstrap() error {
 	// we don't check for an error because the db migrations may have not been executed yet
 	app.RefreshSettings()
 
+	app.instrumentDBs()
+
 	// cleanup the pb_data temp directory (if any)
 	os.RemoveAll(filepath.Join(app.DataDir(), LocalTempDirName))
 
@@ -485,11 +520,21 @@ func (app *BaseApp) Store() *store.Store[any] {
 	return app.store
 }
 
+// Jobs returns the app background jobs queue.
+func (app *BaseApp) Jobs() *Jobs {
+	return app.jobs
+}
+
 // SubscriptionsBroker returns the app realtime subscriptions broker instance.
 func (app *BaseApp) SubscriptionsBroker() *subscriptions.Broker {
 	return app.subscriptionsBroker
 }
 
+// Metrics returns the app metrics registry.
+func (app *BaseApp) Metrics() *metrics.Registry {
+	return app.metrics
+}
+
 // NewMailClient creates and returns a new SMTP or Sendmail client
 // based on the current app settings.
 func (app *BaseApp) NewMailClient() mailer.Mailer {
@@ -607,6 +652,14 @@ func (app *BaseApp) RefreshSettings() error {
 		}
 	}
 
+	if err := app.reloadTracing(); err != nil {
+		app.Logger().Error("Failed to initialize the tracer provider", slog.String("error", err.Error()))
+	}
+
+	if err := app.reloadLogsSinks(); err != nil {
+		app.Logger().Error("Failed to initialize the logs sinks", slog.String("error", err.Error()))
+	}
+
 	return nil
 }
 
@@ -666,6 +719,18 @@ func (app *BaseApp) OnModelAfterDelete(tags ...string) *hook.TaggedHook[*ModelEv
 	return hook.NewTaggedHook(app.onModelAfterDelete, tags...)
 }
 
+func (app *BaseApp) OnModelAfterCreateCommit(tags ...string) *hook.TaggedHook[*ModelCommitEvent] {
+	return hook.NewTaggedHook(app.onModelAfterCreateCommit, tags...)
+}
+
+func (app *BaseApp) OnModelAfterUpdateCommit(tags ...string) *hook.TaggedHook[*ModelCommitEvent] {
+	return hook.NewTaggedHook(app.onModelAfterUpdateCommit, tags...)
+}
+
+func (app *BaseApp) OnModelAfterDeleteCommit(tags ...string) *hook.TaggedHook[*ModelCommitEvent] {
+	return hook.NewTaggedHook(app.onModelAfterDeleteCommit, tags...)
+}
+
 // -------------------------------------------------------------------
 // Mailer event hooks
 // -------------------------------------------------------------------
@@ -730,6 +795,18 @@ func (app *BaseApp) OnRealtimeAfterSubscribeRequest() *hook.Hook[*RealtimeSubscr
 	return app.onRealtimeAfterSubscribeRequest
 }
 
+func (app *BaseApp) OnRealtimeBeforeChannelPublish() *hook.Hook[*RealtimeChannelEvent] {
+	return app.onRealtimeBeforeChannelPublish
+}
+
+func (app *BaseApp) OnRealtimeAfterChannelPublish() *hook.Hook[*RealtimeChannelEvent] {
+	return app.onRealtimeAfterChannelPublish
+}
+
+func (app *BaseApp) OnRealtimePresenceChange() *hook.Hook[*RealtimePresenceEvent] {
+	return app.onRealtimePresenceChange
+}
+
 // -------------------------------------------------------------------
 // Settings API event hooks
 // -------------------------------------------------------------------
@@ -1084,6 +1161,56 @@ func (app *BaseApp) initDataDB() error {
 	return nil
 }
 
+// instrumentDBs registers the data and logs db statements instrumentation
+// if the app metrics or tracing are enabled.
+//
+// The instrumentation is not installed otherwise because dbx renders
+// the statement sql with the inlined params for every executed query
+// when a log func is set. Because of that enabling the metrics or
+// the tracing takes effect for the db statements after the app restart.
+func (app *BaseApp) instrumentDBs() {
+	if !app.Settings().Metrics.Enabled && !app.Settings().Tracing.Enabled {
+		return
+	}
+
+	app.instrumentDB("data", "concurrent", app.Dao().ConcurrentDB().(*dbx.DB))
+	app.instrumentDB("data", "nonconcurrent", app.Dao().NonconcurrentDB().(*dbx.DB))
+	app.instrumentDB("logs", "concurrent", app.LogsDao().ConcurrentDB().(*dbx.DB))
+	app.instrumentDB("logs", "nonconcurrent", app.LogsDao().NonconcurrentDB().(*dbx.DB))
+}
+
+// instrumentDB registers query and exec log funcs that record the
+// executed db statements duration metrics and trace spans
+// (chaining the existing log funcs).
+func (app *BaseApp) instrumentDB(dbName string, poolName string, db *dbx.DB) {
+	durations := app.metrics.Histogram(
+		MetricDBQueryDuration,
+		"Database statements execution duration in seconds.",
+		nil,
+		"db", "pool", "type",
+	)
+
+	queryLogFunc := db.QueryLogFunc
+	db.QueryLogFunc = func(ctx context.Context, t time.Duration, sql string, rows *sql.Rows, err error) {
+		durations.Observe(t.Seconds(), dbName, poolName, "query")
+		traceDBQuery(ctx, dbName, t, sql, err)
+
+		if queryLogFunc != nil {
+			queryLogFunc(ctx, t, sql, rows, err)
+		}
+	}
+
+	execLogFunc := db.ExecLogFunc
+	db.ExecLogFunc = func(ctx context.Context, t time.Duration, sql string, result sql.Result, err error) {
+		durations.Observe(t.Seconds(), dbName, poolName, "exec")
+		traceDBQuery(ctx, dbName, t, sql, err)
+
+		if execLogFunc != nil {
+			execLogFunc(ctx, t, sql, result, err)
+		}
+	}
+}
+
 func (app *BaseApp) createDaoWithHooks(concurrentDB, nonconcurrentDB dbx.Builder) *daos.Dao {
 	dao := daos.NewMultiDB(concurrentDB, nonconcurrentDB)
 
@@ -1093,11 +1220,30 @@ func (app *BaseApp) createDaoWithHooks(concurrentDB, nonconcurrentDB dbx.Builder
 		e.Model = m
 
 		return app.OnModelBeforeCreate().Trigger(e, func(e *ModelEvent) error {
-			return action()
+			if err := action(); err != nil {
+				return err
+			}
+
+			if err := app.enqueueRecordWebhooks(eventDao, models.WebhookEventCreate, m); err != nil {
+				return err
+			}
+
+			snapshot, err := app.recordChangeSnapshot(eventDao, m)
+			if err != nil {
+				return err
+			}
+
+			if err := app.logRecordChange(eventDao, models.RecordChangeActionCreate, m, snapshot); err != nil {
+				return err
+			}
+
+			return app.enqueueModelCommit(eventDao, modelCommitActionCreate, m)
 		})
 	}
 
 	dao.AfterCreateFunc = func(eventDao *daos.Dao, m models.Model) error {
+		app.notifyModelCommit(modelCommitActionCreate, m)
+
 		e := new(ModelEvent)
 		e.Dao = eventDao
 		e.Model = m
@@ -1111,11 +1257,31 @@ func (app *BaseApp) createDaoWithHooks(concurrentDB, nonconcurrentDB dbx.Builder
 		e.Model = m
 
 		return app.OnModelBeforeUpdate().Trigger(e, func(e *ModelEvent) error {
-			return action()
+			// the record state before the change
+			snapshot, err := app.recordChangeSnapshot(eventDao, m)
+			if err != nil {
+				return err
+			}
+
+			if err := action(); err != nil {
+				return err
+			}
+
+			if err := app.enqueueRecordWebhooks(eventDao, models.WebhookEventUpdate, m); err != nil {
+				return err
+			}
+
+			if err := app.logRecordChange(eventDao, models.RecordChangeActionUpdate, m, snapshot); err != nil {
+				return err
+			}
+
+			return app.enqueueModelCommit(eventDao, modelCommitActionUpdate, m)
 		})
 	}
 
 	dao.AfterUpdateFunc = func(eventDao *daos.Dao, m models.Model) error {
+		app.notifyModelCommit(modelCommitActionUpdate, m)
+
 		e := new(ModelEvent)
 		e.Dao = eventDao
 		e.Model = m
@@ -1129,11 +1295,37 @@ func (app *BaseApp) createDaoWithHooks(concurrentDB, nonconcurrentDB dbx.Builder
 		e.Model = m
 
 		return app.OnModelBeforeDelete().Trigger(e, func(e *ModelEvent) error {
-			return action()
+			// the webhook filters must be checked before the record deletion
+			webhooks, err := app.matchRecordWebhooks(eventDao, models.WebhookEventDelete, m)
+			if err != nil {
+				return err
+			}
+
+			// the record state before the change
+			snapshot, err := app.recordChangeSnapshot(eventDao, m)
+			if err != nil {
+				return err
+			}
+
+			if err := action(); err != nil {
+				return err
+			}
+
+			if err := app.saveWebhookDeliveries(eventDao, models.WebhookEventDelete, m, webhooks); err != nil {
+				return err
+			}
+
+			if err := app.logRecordChange(eventDao, models.RecordChangeActionDelete, m, snapshot); err != nil {
+				return err
+			}
+
+			return app.enqueueModelCommit(eventDao, modelCommitActionDelete, m)
 		})
 	}
 
 	dao.AfterDeleteFunc = func(eventDao *daos.Dao, m models.Model) error {
+		app.notifyModelCommit(modelCommitActionDelete, m)
+
 		e := new(ModelEvent)
 		e.Dao = eventDao
 		e.Model = m
@@ -1141,9 +1333,30 @@ func (app *BaseApp) createDaoWithHooks(concurrentDB, nonconcurrentDB dbx.Builder
 		return app.OnModelAfterDelete().Trigger(e)
 	}
 
+	// commit the model changes together with their outbox entries
+	dao.TransactionalWriteFunc = app.hasModelOutboxWrites
+
+	dao.OnlineSchemaChangesFunc = func() bool {
+		return app.Settings().Schema.OnlineChanges
+	}
+	dao.SchemaBackfillFunc = app.scheduleSchemaBackfill
+	dao.SchemaIndexesBuildFunc = app.scheduleSchemaIndexesBuild
+
 	return dao
 }
 
+// hasModelOutboxWrites reports whether the write hooks of m persist
+// additional outbox entries (eg. webhook deliveries, after commit
+// hooks jobs or changes log entries) that must be committed in the
+// same transaction as the model change.
+func (app *BaseApp) hasModelOutboxWrites(m models.Model) bool {
+	return app.hasRecordWebhooks(m) ||
+		app.isRecordChangeLogged(m) ||
+		app.hasModelCommitHandlers(modelCommitActionCreate, m) ||
+		app.hasModelCommitHandlers(modelCommitActionUpdate, m) ||
+		app.hasModelCommitHandlers(modelCommitActionDelete, m)
+}
+
 func (app *BaseApp) registerDefaultHooks() {
 	deletePrefix := func(prefix string) error {
 		fs, err := app.NewFilesystem()
@@ -1187,6 +1400,14 @@ func (app *BaseApp) registerDefaultHooks() {
 		app.Logger().Error("Failed to init auto backup hooks", slog.String("error", err.Error()))
 	}
 
+	app.initWebhooks()
+	app.initJobs()
+	app.initModelCommitHooks()
+	app.initSchemaBackfills()
+	app.initCollectionPartitions()
+	app.initRecordChanges()
+	app.initTracing()
+
 	registerCachedCollectionsAppHooks(app)
 }
 
@@ -1220,6 +1441,14 @@ func (app *BaseApp) initLogger() error {
 		Level:     app.getLoggerMinLevel(),
 		BatchSize: 200,
 		BeforeAddFunc: func(ctx context.Context, log *logger.Log) bool {
+			// associate the logs created with *Context(ctx, ...) with the active trace (if any)
+			if _, ok := log.Data["traceId"]; !ok {
+				if traceId := tracing.TraceId(ctx); traceId != "" {
+					log.Data["traceId"] = traceId
+					log.Data["spanId"] = tracing.SpanId(ctx)
+				}
+			}
+
 			if app.IsDev() {
 				printLog(log)
 
@@ -1231,13 +1460,22 @@ func (app *BaseApp) initLogger() error {
 
 			ticker.Reset(duration)
 
-			return app.Settings().Logs.MaxDays > 0
+			return app.Settings().Logs.IsEnabled()
 		},
 		WriteFunc: func(ctx context.Context, logs []*logger.Log) error {
+			if err := app.writeLogsSinks(ctx, logs); err != nil {
+				log.Println("Failed to ship logs", err)
+			}
+
 			if !app.IsBootstrapped() || app.Settings().Logs.MaxDays == 0 {
 				return nil
 			}
 
+			// @todo replace with cron so that it doesn't rely on the logs write
+			//
+			// ensure that the logs partitions exist before the write and delete the expired logs
+			app.maintainLogs()
+
 			// write the accumulated logs
 			// (note: based on several local tests there is no significant performance difference between small number of separate write queries vs 1 big INSERT)
 			app.LogsDao().RunInTransaction(func(txDao *daos.Dao) error {
@@ -1261,21 +1499,6 @@ func (app *BaseApp) initLogger() error {
 				return nil
 			})
 
-			// @todo replace with cron so that it doesn't rely on the logs write
-			//
-			// delete old logs
-			// ---
-			now := time.Now()
-			lastLogsDeletedAt := cast.ToTime(app.Store().Get("lastLogsDeletedAt"))
-			if now.Sub(lastLogsDeletedAt).Hours() >= 6 {
-				deleteErr := app.LogsDao().DeleteOldLogs(now.AddDate(0, 0, -1*app.Settings().Logs.MaxDays))
-				if deleteErr == nil {
-					app.Store().Set("lastLogsDeletedAt", now)
-				} else {
-					log.Println("Logs delete failed", deleteErr)
-				}
-			}
-
 			return nil
 		},
 	})
@@ -1299,6 +1522,10 @@ func (app *BaseApp) initLogger() error {
 		// write all remaining logs before ticker.Stop to avoid races with ResetBootstrap user calls
 		handler.WriteAll(context.Background())
 
+		if err := app.closeLogsSinks(); err != nil {
+			log.Println("Failed to close the logs sinks", err)
+		}
+
 		ticker.Stop()
 
 		done <- true
@@ -247,6 +247,10 @@ func (app *BaseApp) initAutobackupHooks() error {
 	c := cron.New()
 	isServe := false
 
+	app.registerCron("backups", c, func() bool {
+		return c.Total() > 0 && app.Settings().Backups.Cron != ""
+	})
+
 	loadJob := func() {
 		c.Stop()
 
@@ -270,7 +274,10 @@ func (app *BaseApp) initAutobackupHooks() error {
 
 			name := app.generateBackupName(autoPrefix)
 
-			if err := app.CreateBackup(context.Background(), name); err != nil {
+			start := time.Now()
+			err := app.CreateBackup(context.Background(), name)
+			ObserveCronJob(app, "@autobackup", time.Since(start), err)
+			if err != nil {
 				app.Logger().Debug(
 					"[Backup cron] Failed to create backup",
 					slog.String("name", name),
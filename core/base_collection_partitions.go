@@ -0,0 +1,90 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tools/cron"
+)
+
+const collectionPartitionsJobId = "@collectionPartitions"
+
+// initCollectionPartitions registers the app serve hooks of the
+// partitioned collections maintenance cron job.
+func (app *BaseApp) initCollectionPartitions() {
+	c := cron.New()
+
+	run := func() {
+		start := time.Now()
+		err := app.maintainCollectionPartitions(start)
+		ObserveCronJob(app, collectionPartitionsJobId, time.Since(start), err)
+		if err != nil {
+			app.Logger().Error(
+				"[Partitions cron] Failed to maintain the collections partitions",
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	c.MustAdd(collectionPartitionsJobId, "@hourly", run)
+
+	var isServe atomic.Bool
+
+	app.registerCron("partitions", c, isServe.Load)
+
+	app.OnBeforeServe().Add(func(e *ServeEvent) error {
+		// catch up with the partitions missed while the app was stopped
+		run()
+
+		c.Start()
+		isServe.Store(true)
+
+		return nil
+	})
+
+	app.OnTerminate().Add(func(e *TerminateEvent) error {
+		isServe.Store(false)
+		c.Stop()
+		return nil
+	})
+}
+
+// maintainCollectionPartitions creates the upcoming record table partitions
+// and drops the expired ones of all partitioned base collections.
+//
+// A failed collection doesn't stop the maintenance of the remaining ones
+// and all errors are returned joined.
+func (app *BaseApp) maintainCollectionPartitions(now time.Time) error {
+	if !app.IsBootstrapped() {
+		return nil
+	}
+
+	collections, err := app.Dao().FindCollectionsByType(models.CollectionTypeBase)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for _, collection := range collections {
+		if collection.BaseOptions().Partition == nil {
+			continue
+		}
+
+		err := app.Dao().CreateCollectionPartitions(collection, now, daos.CollectionPartitionsAhead)
+		if err == nil {
+			err = app.Dao().DeleteExpiredCollectionPartitions(collection, now)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", collection.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
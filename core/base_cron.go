@@ -0,0 +1,53 @@
+package core
+
+import (
+	"github.com/hylarucoder/rocketbase/tools/cron"
+)
+
+// CronState describes the running state of a single app cron scheduler.
+type CronState struct {
+	// Name is the cron scheduler name (eg. "backups").
+	Name string `json:"name"`
+
+	// Enabled reports whether the scheduler is expected to be running
+	// (eg. the backups scheduler is enabled only when serving the app
+	// with a configured autobackups schedule).
+	Enabled bool `json:"enabled"`
+
+	// Running reports whether the scheduler ticker is started.
+	Running bool `json:"running"`
+}
+
+type appCron struct {
+	name    string
+	cron    *cron.Cron
+	enabled func() bool
+}
+
+// registerCron registers an app cron scheduler so that
+// its running state is reported by [BaseApp.CronStates].
+func (app *BaseApp) registerCron(name string, c *cron.Cron, enabled func() bool) {
+	app.cronsMux.Lock()
+	defer app.cronsMux.Unlock()
+
+	app.crons = append(app.crons, &appCron{name: name, cron: c, enabled: enabled})
+}
+
+// CronStates returns the running states of the app cron schedulers
+// (autobackups, collection partitions maintenance, etc.).
+func (app *BaseApp) CronStates() []CronState {
+	app.cronsMux.RLock()
+	defer app.cronsMux.RUnlock()
+
+	result := make([]CronState, len(app.crons))
+
+	for i, c := range app.crons {
+		result[i] = CronState{
+			Name:    c.name,
+			Enabled: c.enabled(),
+			Running: c.cron.HasStarted(),
+		}
+	}
+
+	return result
+}
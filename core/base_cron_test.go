@@ -0,0 +1,38 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/tools/cron"
+)
+
+func TestBaseAppCronStates(t *testing.T) {
+	app := NewBaseApp(BaseAppConfig{DataDir: t.TempDir()})
+
+	// the default schedulers are not expected to run before serve
+	encoded, _ := json.Marshal(app.CronStates())
+	expected := `[{"name":"backups","enabled":false,"running":false},{"name":"partitions","enabled":false,"running":false}]`
+	if string(encoded) != expected {
+		t.Fatalf("Expected the default states\n%s\ngot\n%s", expected, encoded)
+	}
+
+	c := cron.New()
+	c.MustAdd("test", "* * * * *", func() {})
+	app.registerCron("test", c, func() bool { return true })
+
+	states := app.CronStates()
+	last := states[len(states)-1]
+	if last.Name != "test" || !last.Enabled || last.Running {
+		t.Fatalf("Expected enabled but not running test cron, got %v", last)
+	}
+
+	c.Start()
+	defer c.Stop()
+
+	states = app.CronStates()
+	last = states[len(states)-1]
+	if !last.Running {
+		t.Fatalf("Expected running test cron, got %v", last)
+	}
+}
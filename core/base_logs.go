@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/models/settings"
+	"github.com/hylarucoder/rocketbase/tools/logger"
+	"github.com/spf13/cast"
+)
+
+// logsMaintenanceInterval is the min interval between
+// the logs partitions and retention maintenance runs.
+const logsMaintenanceInterval = 6 * time.Hour
+
+// logsPartitionsAhead is the number of daily logs partitions
+// to create in advance (including the current day).
+const logsPartitionsAhead = 3
+
+// logsSinkQueueSize is the max number of logs batches that could be
+// queued per logs shipping sink before the new batches are dropped.
+const logsSinkQueueSize = 100
+
+// reloadLogsSinks (re)initializes the logs shipping sinks
+// based on the current app logs settings.
+//
+// Each sink is wrapped with [logger.NewAsyncSink] so that a slow or
+// unreachable destination doesn't delay the logs persistence.
+//
+// It is no-op if the sinks settings haven't changed since the last call.
+func (app *BaseApp) reloadLogsSinks() error {
+	configs := app.Settings().Logs.Sinks
+
+	rawConfigs, err := json.Marshal(configs)
+	if err != nil {
+		return err
+	}
+
+	app.logsSinksMux.Lock()
+
+	if string(rawConfigs) == app.logsSinksConfig {
+		app.logsSinksMux.Unlock()
+		return nil // no changes
+	}
+
+	sinks := make([]logger.Sink, 0, len(configs))
+
+	for _, config := range configs {
+		sink, err := app.newLogsSink(config)
+		if err != nil {
+			app.logsSinksMux.Unlock()
+			logger.CloseSinks(sinks)
+			return err
+		}
+
+		sinks = append(sinks, logger.NewAsyncSink(
+			logger.NewFilterSink(sink, slog.Level(config.MinLevel), config.SampleRate),
+			logsSinkQueueSize,
+			func(err error) {
+				log.Println("Failed to ship logs", err)
+			},
+		))
+	}
+
+	oldSinks := app.logsSinks
+
+	app.logsSinks = sinks
+	app.logsSinksConfig = string(rawConfigs)
+
+	app.logsSinksMux.Unlock()
+
+	// release the previous sinks resources
+	// (outside of the lock since it waits for their queued batches)
+	if err := logger.CloseSinks(oldSinks); err != nil {
+		log.Println("Failed to close the logs sinks", err)
+	}
+
+	return nil
+}
+
+// closeLogsSinks closes and resets the active logs shipping sinks.
+func (app *BaseApp) closeLogsSinks() error {
+	app.logsSinksMux.Lock()
+	sinks := app.logsSinks
+	app.logsSinks = nil
+	app.logsSinksConfig = ""
+	app.logsSinksMux.Unlock()
+
+	return logger.CloseSinks(sinks)
+}
+
+// writeLogsSinks queues the provided logs batch to all active logs shipping sinks.
+func (app *BaseApp) writeLogsSinks(ctx context.Context, logs []*logger.Log) error {
+	app.logsSinksMux.RLock()
+	sinks := app.logsSinks
+	app.logsSinksMux.RUnlock()
+
+	return logger.WriteSinks(ctx, sinks, logs)
+}
+
+func (app *BaseApp) newLogsSink(config settings.LogsSinkConfig) (logger.Sink, error) {
+	switch config.Type {
+	case settings.LogsSinkFile:
+		return logger.NewFileSink(
+			filepath.Join(app.DataDir(), config.Path),
+			int64(config.MaxSize)<<20,
+			config.MaxBackups,
+		)
+	case settings.LogsSinkSyslog:
+		return logger.NewSyslogSink(config.Network, config.Address, config.Tag)
+	case settings.LogsSinkHttp:
+		return logger.NewHTTPSink(config.Url, config.Headers, config.Labels), nil
+	default:
+		return logger.NewJSONSink(os.Stdout), nil
+	}
+}
+
+// maintainLogs creates the upcoming daily logs partitions and deletes
+// the logs that are older than their configured retention period.
+//
+// It is no-op if it was already successfully executed in the last [logsMaintenanceInterval].
+func (app *BaseApp) maintainLogs() {
+	now := time.Now()
+
+	lastLogsDeletedAt := cast.ToTime(app.Store().Get("lastLogsDeletedAt"))
+	if now.Sub(lastLogsDeletedAt) < logsMaintenanceInterval {
+		return
+	}
+
+	if err := app.LogsDao().CreateLogsPartitions(now, logsPartitionsAhead); err != nil {
+		log.Println("Logs partitions create failed", err)
+	}
+
+	if err := app.LogsDao().DeleteExpiredLogs(app.Settings().Logs, now); err != nil {
+		log.Println("Logs delete failed", err)
+		return
+	}
+
+	app.Store().Set("lastLogsDeletedAt", now)
+}
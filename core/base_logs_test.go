@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/models/settings"
+	"github.com/hylarucoder/rocketbase/tools/logger"
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+func TestBaseAppReloadLogsSinks(t *testing.T) {
+	testDataDir := t.TempDir()
+
+	app := NewBaseApp(BaseAppConfig{DataDir: testDataDir})
+
+	app.Settings().Logs.Sinks = []settings.LogsSinkConfig{
+		{Type: settings.LogsSinkFile, Path: "logs/app.log", MinLevel: int(slog.LevelWarn)},
+	}
+
+	if err := app.reloadLogsSinks(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(app.logsSinks) != 1 {
+		t.Fatalf("Expected 1 sink, got %d", len(app.logsSinks))
+	}
+
+	// no changes
+	sinks := app.logsSinks
+	if err := app.reloadLogsSinks(); err != nil {
+		t.Fatal(err)
+	}
+	if sinks[0] != app.logsSinks[0] {
+		t.Fatal("Expected the sinks to not be reinitialized")
+	}
+
+	logs := []*logger.Log{
+		{Time: time.Now(), Level: slog.LevelInfo, Message: "info", Data: types.JsonMap{}},
+		{Time: time.Now(), Level: slog.LevelError, Message: "error", Data: types.JsonMap{}},
+	}
+	if err := app.writeLogsSinks(context.Background(), logs); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.closeLogsSinks(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(app.logsSinks) != 0 || app.logsSinksConfig != "" {
+		t.Fatal("Expected the sinks to be reset")
+	}
+
+	raw, err := os.ReadFile(filepath.Join(testDataDir, "logs", "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(raw), `"message":"info"`) || !strings.Contains(string(raw), `"message":"error"`) {
+		t.Fatalf("Expected only the error log to be written, got %s", raw)
+	}
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/tools/metrics"
+	"github.com/pocketbase/dbx"
+)
+
+// Builtin app metric names.
+const (
+	MetricDBQueryDuration     = "pb_db_query_duration_seconds"
+	MetricDBOpenConnections   = "pb_db_open_connections"
+	MetricDBInUseConnections  = "pb_db_in_use_connections"
+	MetricDBIdleConnections   = "pb_db_idle_connections"
+	MetricDBMaxOpenConns      = "pb_db_max_open_connections"
+	MetricDBWaitCount         = "pb_db_wait_count_total"
+	MetricDBWaitDuration      = "pb_db_wait_duration_seconds_total"
+	MetricRealtimeClients     = "pb_realtime_clients"
+	MetricRealtimeSubs        = "pb_realtime_subscriptions"
+	MetricCronJobRuns         = "pb_cron_job_runs_total"
+	MetricCronJobDuration     = "pb_cron_job_duration_seconds"
+	MetricHookHandlerDuration = "pb_hook_handler_duration_seconds"
+	MetricHookHandlerErrors   = "pb_hook_handler_errors_total"
+)
+
+// ObserveCronJob records the metrics of a single cron job run.
+//
+// A non-nil err marks the run as failed.
+func ObserveCronJob(app App, jobId string, duration time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+
+	m := app.Metrics()
+
+	m.Counter(MetricCronJobRuns, "Total number of cron job runs.", "job", "status").
+		Inc(jobId, status)
+
+	m.Histogram(MetricCronJobDuration, "Cron job runs duration in seconds.", nil, "job").
+		Observe(duration.Seconds(), jobId)
+}
+
+// ObserveHookHandler records the metrics of a single hook handler execution.
+//
+// A non-nil err (other than a propagation stop) marks the execution as failed.
+func ObserveHookHandler(app App, hookName string, duration time.Duration, err error) {
+	m := app.Metrics()
+
+	m.Histogram(MetricHookHandlerDuration, "Hook handlers execution duration in seconds.", nil, "hook").
+		Observe(duration.Seconds(), hookName)
+
+	if err != nil {
+		m.Counter(MetricHookHandlerErrors, "Total number of failed hook handler executions.", "hook").
+			Inc(hookName)
+	}
+}
+
+// registerDefaultMetrics registers the app db pools and realtime collectors.
+func (app *BaseApp) registerDefaultMetrics() {
+	app.metrics.GaugeFunc(MetricDBOpenConnections, "Number of established db connections (in use + idle).", func() []metrics.Sample {
+		return app.dbStatsSamples(func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	})
+
+	app.metrics.GaugeFunc(MetricDBInUseConnections, "Number of db connections currently in use.", func() []metrics.Sample {
+		return app.dbStatsSamples(func(s sql.DBStats) float64 { return float64(s.InUse) })
+	})
+
+	app.metrics.GaugeFunc(MetricDBIdleConnections, "Number of idle db connections.", func() []metrics.Sample {
+		return app.dbStatsSamples(func(s sql.DBStats) float64 { return float64(s.Idle) })
+	})
+
+	app.metrics.GaugeFunc(MetricDBMaxOpenConns, "Maximum number of open db connections.", func() []metrics.Sample {
+		return app.dbStatsSamples(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	})
+
+	app.metrics.CounterFunc(MetricDBWaitCount, "Total number of waited db connections.", func() []metrics.Sample {
+		return app.dbStatsSamples(func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	})
+
+	app.metrics.CounterFunc(MetricDBWaitDuration, "Total time blocked waiting for a new db connection in seconds.", func() []metrics.Sample {
+		return app.dbStatsSamples(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	})
+
+	app.metrics.GaugeFunc(MetricRealtimeClients, "Number of connected realtime clients.", func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(len(app.subscriptionsBroker.Clients()))}}
+	})
+
+	app.metrics.GaugeFunc(MetricRealtimeSubs, "Number of active realtime subscriptions.", func() []metrics.Sample {
+		var total int
+		for _, client := range app.subscriptionsBroker.Clients() {
+			total += len(client.Subscriptions())
+		}
+
+		return []metrics.Sample{{Value: float64(total)}}
+	})
+}
+
+// dbStatsSamples returns a single sample per app db pool
+// with the value extracted from the pool stats by valueFunc.
+func (app *BaseApp) dbStatsSamples(valueFunc func(s sql.DBStats) float64) []metrics.Sample {
+	pools := []struct {
+		db  string
+		dao *daos.Dao
+	}{
+		{"data", app.dao},
+		{"logs", app.logsDao},
+	}
+
+	samples := make([]metrics.Sample, 0, 4)
+
+	for _, p := range pools {
+		if p.dao == nil {
+			continue // not bootstrapped yet
+		}
+
+		if db, ok := p.dao.ConcurrentDB().(*dbx.DB); ok {
+			samples = append(samples, metrics.Sample{
+				Labels: metrics.Labels{"db": p.db, "pool": "concurrent"},
+				Value:  valueFunc(db.DB().Stats()),
+			})
+		}
+
+		if db, ok := p.dao.NonconcurrentDB().(*dbx.DB); ok {
+			samples = append(samples, metrics.Sample{
+				Labels: metrics.Labels{"db": p.db, "pool": "nonconcurrent"},
+				Value:  valueFunc(db.DB().Stats()),
+			})
+		}
+	}
+
+	return samples
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/tools/hook"
+)
+
+// ModelCommitMaxAttempts is the max number of dispatch attempts of a
+// single committed model change before its outbox job is marked as dead.
+const ModelCommitMaxAttempts = 10
+
+const modelCommitJobName = "@modelCommit"
+
+const (
+	modelCommitActionCreate = "create"
+	modelCommitActionUpdate = "update"
+	modelCommitActionDelete = "delete"
+)
+
+// modelCommitPayload defines the outbox job payload of a single model change.
+type modelCommitPayload struct {
+	Action       string          `json:"action"`
+	Table        string          `json:"table"`
+	CollectionId string          `json:"collectionId,omitempty"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// modelCommitFactories returns the factories of the non-Record
+// models that could be restored from an outbox job payload.
+func modelCommitFactories() map[string]func() models.Model {
+	return map[string]func() models.Model{
+		(&models.Admin{}).TableName():        func() models.Model { return &models.Admin{} },
+		(&models.Collection{}).TableName():   func() models.Model { return &models.Collection{} },
+		(&models.ExternalAuth{}).TableName(): func() models.Model { return &models.ExternalAuth{} },
+		(&models.Webhook{}).TableName():      func() models.Model { return &models.Webhook{} },
+	}
+}
+
+// initModelCommitHooks registers the after commit hooks outbox dispatcher.
+func (app *BaseApp) initModelCommitHooks() {
+	app.jobs.Register(modelCommitJobName, app.dispatchModelCommit)
+}
+
+func (app *BaseApp) modelCommitHook(action string) *hook.Hook[*ModelCommitEvent] {
+	switch action {
+	case modelCommitActionCreate:
+		return app.onModelAfterCreateCommit
+	case modelCommitActionUpdate:
+		return app.onModelAfterUpdateCommit
+	case modelCommitActionDelete:
+		return app.onModelAfterDeleteCommit
+	}
+
+	return nil
+}
+
+// enqueueModelCommit records the model change in the jobs queue (aka. outbox)
+// using the provided, usually transactional, dao so that the change is
+// dispatched to the after commit hook handlers only if it is committed.
+//
+// It is no-op if there are no registered handlers for the action and model.
+func (app *BaseApp) enqueueModelCommit(dao *daos.Dao, action string, m models.Model) error {
+	if !app.hasModelCommitHandlers(action, m) {
+		return nil
+	}
+
+	payload := modelCommitPayload{
+		Action: action,
+		Table:  m.TableName(),
+	}
+
+	var data any
+
+	if record, ok := m.(*models.Record); ok {
+		payload.CollectionId = record.Collection().Id
+
+		// exclude the auth secrets from the persisted snapshot
+		snapshot := record.ColumnValueMap()
+		delete(snapshot, schema.FieldNamePasswordHash)
+		delete(snapshot, schema.FieldNameTokenKey)
+		data = snapshot
+	} else if _, ok := modelCommitFactories()[payload.Table]; ok {
+		data = m
+	} else {
+		return nil // not supported model
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	payload.Data = encoded
+
+	_, err = app.jobs.EnqueueWithDao(dao, modelCommitJobName, payload, JobOptions{
+		MaxAttempts: ModelCommitMaxAttempts,
+	})
+
+	return err
+}
+
+// notifyModelCommit wakes up the jobs workers after a committed
+// model change so that its outbox job could be dispatched immediately.
+//
+// It is called from the after write hooks which are executed after the
+// transaction commit (the writes of models with after commit handlers are
+// always wrapped in a transaction, see hasModelOutboxWrites).
+func (app *BaseApp) notifyModelCommit(action string, m models.Model) {
+	if app.hasModelCommitHandlers(action, m) {
+		app.jobs.Wake()
+	}
+}
+
+// hasModelCommitHandlers checks whether there is at least one after commit
+// hook handler for the action that could be triggered for the provided model
+// (aka. taking into account the handlers tags).
+func (app *BaseApp) hasModelCommitHandlers(action string, m models.Model) bool {
+	h := app.modelCommitHook(action)
+	if h == nil {
+		return false
+	}
+
+	e := BaseModelEvent{Model: m}
+
+	return h.CanTriggerOn(e.Tags())
+}
+
+// dispatchModelCommit restores the model change from the outbox
+// job payload and triggers the related after commit hook.
+func (app *BaseApp) dispatchModelCommit(ctx context.Context, job *models.Job) error {
+	payload := modelCommitPayload{}
+	if err := job.UnmarshalPayload(&payload); err != nil {
+		return err
+	}
+
+	h := app.modelCommitHook(payload.Action)
+	if h == nil {
+		return fmt.Errorf("unsupported model commit action %q", payload.Action)
+	}
+
+	m, err := app.restoreModelCommitModel(payload)
+	if err != nil {
+		return err
+	}
+
+	e := new(ModelCommitEvent)
+	e.Dao = app.Dao()
+	e.Model = m
+	e.EventId = job.Id
+	e.Attempt = job.Attempts
+
+	return h.Trigger(e)
+}
+
+func (app *BaseApp) restoreModelCommitModel(payload modelCommitPayload) (models.Model, error) {
+	if payload.CollectionId != "" {
+		collection, err := app.Dao().FindCollectionByNameOrId(payload.CollectionId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the record collection: %w", err)
+		}
+
+		data := map[string]any{}
+		if err := json.Unmarshal(payload.Data, &data); err != nil {
+			return nil, err
+		}
+
+		record := models.NewRecord(collection)
+		record.Load(data)
+		record.MarkAsNotNew()
+
+		return record, nil
+	}
+
+	factory, ok := modelCommitFactories()[payload.Table]
+	if !ok {
+		return nil, errors.New("unsupported model table " + payload.Table)
+	}
+
+	m := factory()
+	if err := json.Unmarshal(payload.Data, m); err != nil {
+		return nil, err
+	}
+	m.MarkAsNotNew()
+
+	return m, nil
+}
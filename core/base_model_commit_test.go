@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/models"
+)
+
+func TestEnqueueModelCommitWithoutHandlers(t *testing.T) {
+	app := NewBaseApp(BaseAppConfig{})
+
+	// no handlers and no dao - should be no-op
+	for _, action := range []string{modelCommitActionCreate, modelCommitActionUpdate, modelCommitActionDelete} {
+		if err := app.enqueueModelCommit(nil, action, &models.Admin{}); err != nil {
+			t.Fatalf("[%s] Expected nil, got %v", action, err)
+		}
+	}
+}
+
+func TestDispatchModelCommit(t *testing.T) {
+	app := NewBaseApp(BaseAppConfig{})
+
+	var calls []string
+
+	app.OnModelAfterUpdateCommit().Add(func(e *ModelCommitEvent) error {
+		admin, ok := e.Model.(*models.Admin)
+		if !ok {
+			t.Fatalf("Expected *models.Admin model, got %T", e.Model)
+		}
+
+		if admin.IsNew() {
+			t.Fatal("Expected the restored model to be marked as not new")
+		}
+
+		calls = append(calls, "all:"+e.EventId+":"+admin.Email)
+
+		return nil
+	})
+
+	app.OnModelAfterUpdateCommit("_collections").Add(func(e *ModelCommitEvent) error {
+		calls = append(calls, "collections")
+		return nil
+	})
+
+	app.OnModelAfterCreateCommit().Add(func(e *ModelCommitEvent) error {
+		calls = append(calls, "create")
+		return nil
+	})
+
+	admin := &models.Admin{Email: "test@example.com"}
+	admin.Id = "123"
+	data, _ := json.Marshal(admin)
+
+	scenarios := []struct {
+		name          string
+		payload       modelCommitPayload
+		expectError   bool
+		expectedCalls []string
+	}{
+		{
+			"invalid action",
+			modelCommitPayload{Action: "invalid", Table: "_admins", Data: data},
+			true,
+			nil,
+		},
+		{
+			"unsupported table",
+			modelCommitPayload{Action: modelCommitActionUpdate, Table: "_jobs", Data: []byte(`{}`)},
+			true,
+			nil,
+		},
+		{
+			"admin update",
+			modelCommitPayload{Action: modelCommitActionUpdate, Table: "_admins", Data: data},
+			false,
+			[]string{"all:job1:test@example.com"},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			calls = nil
+
+			payload, _ := json.Marshal(s.payload)
+
+			job := &models.Job{Payload: payload, Attempts: 1}
+			job.Id = "job1"
+
+			err := app.dispatchModelCommit(context.Background(), job)
+
+			hasErr := err != nil
+			if hasErr != s.expectError {
+				t.Fatalf("Expected hasErr %v, got %v (%v)", s.expectError, hasErr, err)
+			}
+
+			if len(calls) != len(s.expectedCalls) {
+				t.Fatalf("Expected calls %v, got %v", s.expectedCalls, calls)
+			}
+
+			for i, call := range s.expectedCalls {
+				if calls[i] != call {
+					t.Fatalf("Expected calls %v, got %v", s.expectedCalls, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestHasModelCommitHandlers(t *testing.T) {
+	app := NewBaseApp(BaseAppConfig{})
+
+	admin := &models.Admin{}
+	collection := &models.Collection{}
+
+	if app.hasModelCommitHandlers(modelCommitActionCreate, admin) {
+		t.Fatal("Expected false without registered handlers")
+	}
+
+	app.OnModelAfterCreateCommit(collection.TableName()).Add(func(e *ModelCommitEvent) error {
+		return nil
+	})
+
+	if app.hasModelCommitHandlers(modelCommitActionCreate, admin) {
+		t.Fatal("Expected false for model that doesn't match the handler tags")
+	}
+
+	if !app.hasModelCommitHandlers(modelCommitActionCreate, collection) {
+		t.Fatal("Expected true for model that matches the handler tags")
+	}
+
+	if app.hasModelCommitHandlers(modelCommitActionUpdate, collection) {
+		t.Fatal("Expected false for action without registered handlers")
+	}
+
+	app.OnModelAfterUpdateCommit().Add(func(e *ModelCommitEvent) error {
+		return nil
+	})
+
+	if !app.hasModelCommitHandlers(modelCommitActionUpdate, admin) {
+		t.Fatal("Expected true for untagged handler")
+	}
+}
@@ -0,0 +1,120 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tests"
+	"github.com/pocketbase/dbx"
+)
+
+func TestModelCommitOutboxTransaction(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	app.OnModelAfterCreateCommit("demo2").Add(func(e *core.ModelCommitEvent) error {
+		return nil
+	})
+
+	collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	countOutboxJobs := func() int {
+		var total int
+		app.Dao().JobQuery().
+			Select("count(*)").
+			AndWhere(dbx.HashExp{"name": "@modelCommit"}).
+			Row(&total)
+		return total
+	}
+
+	// rolled back change
+	rollbackErr := errors.New("rollback")
+	err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		record := models.NewRecord(collection)
+		record.Set("title", "outbox_rollback")
+		if err := txDao.SaveRecord(record); err != nil {
+			return err
+		}
+		return rollbackErr
+	})
+	if !errors.Is(err, rollbackErr) {
+		t.Fatalf("Expected rollback error, got %v", err)
+	}
+
+	if total := countOutboxJobs(); total != 0 {
+		t.Fatalf("Expected no outbox jobs after rollback, got %d", total)
+	}
+
+	// committed change
+	err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		record := models.NewRecord(collection)
+		record.Set("title", "outbox_commit")
+		return txDao.SaveRecord(record)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if total := countOutboxJobs(); total != 1 {
+		t.Fatalf("Expected 1 outbox job after commit, got %d", total)
+	}
+}
+
+func TestModelCommitOutboxWithoutTransaction(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	app.OnModelAfterCreateCommit("demo2").Add(func(e *core.ModelCommitEvent) error {
+		return nil
+	})
+
+	collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the outbox job must exist when the after create hooks are triggered
+	var hookJobs int
+	app.OnModelAfterCreate("demo2").Add(func(e *core.ModelEvent) error {
+		app.Dao().JobQuery().
+			Select("count(*)").
+			AndWhere(dbx.HashExp{"name": "@modelCommit"}).
+			Row(&hookJobs)
+		return nil
+	})
+
+	record := models.NewRecord(collection)
+	record.Set("title", "outbox_plain_commit")
+	if err := app.Dao().SaveRecord(record); err != nil {
+		t.Fatal(err)
+	}
+
+	if hookJobs != 1 {
+		t.Fatalf("Expected 1 committed outbox job in the after create hook, got %d", hookJobs)
+	}
+
+	// make every outbox job insert fail
+	_, err = app.Dao().DB().NewQuery(
+		"ALTER TABLE {{_jobs}} ADD CONSTRAINT [[test_fail]] CHECK (false) NOT VALID",
+	).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record = models.NewRecord(collection)
+	record.Set("title", "outbox_plain_rollback")
+	if err := app.Dao().SaveRecord(record); err == nil {
+		t.Fatal("Expected the failing outbox insert to fail the record save")
+	}
+
+	found, _ := app.Dao().FindFirstRecordByData(collection.Id, "title", "outbox_plain_rollback")
+	if found != nil {
+		t.Fatal("Expected the record create to be rolled back")
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+const recordChangesCleanupInterval = 1 * time.Hour
+
+// initRecordChanges registers the records changes log retention app hooks.
+func (app *BaseApp) initRecordChanges() {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	close(done) // nothing to wait initially
+
+	app.OnBeforeServe().Add(func(e *ServeEvent) error {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan struct{})
+
+		go app.runRecordChangesCleanup(ctx, done)
+
+		return nil
+	})
+
+	app.OnTerminate().Add(func(e *TerminateEvent) error {
+		if cancel != nil {
+			cancel()
+		}
+		<-done
+		return nil
+	})
+}
+
+func (app *BaseApp) runRecordChangesCleanup(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(recordChangesCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		config := app.Settings().Changes
+		if !app.IsBootstrapped() || config.MaxDays <= 0 {
+			continue
+		}
+
+		cutoff := time.Now().AddDate(0, 0, -config.MaxDays)
+		if err := app.Dao().DeleteOldRecordChanges(cutoff); err != nil {
+			app.Logger().Debug("Failed to delete old record changes", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// isRecordChangeLogged reports whether the changes of the provided
+// model are appended to the records changes log.
+func (app *BaseApp) isRecordChangeLogged(m models.Model) bool {
+	if !app.Settings().Changes.Enabled {
+		return false
+	}
+
+	record, ok := m.(*models.Record)
+
+	return ok && record.Collection() != nil && !record.Collection().IsView()
+}
+
+// recordChangeSnapshot returns the current db state of the record model
+// that is stored with its changes log entry (see [models.RecordChange.Data]).
+//
+// It is no-op if the model changes are not logged.
+func (app *BaseApp) recordChangeSnapshot(dao *daos.Dao, m models.Model) (types.JsonRaw, error) {
+	if !app.isRecordChangeLogged(m) {
+		return nil, nil
+	}
+
+	record := m.(*models.Record)
+
+	return dao.FindRecordChangeSnapshot(record.Collection(), record.Id)
+}
+
+// logRecordChange appends the model change to the records changes log
+// using the provided, usually transactional, dao.
+//
+// The entry is inserted right before the transaction commit so that
+// the collection changes log lock is held only for the seq assignment
+// (see [daos.Dao.QueueRecordChange]).
+//
+// It is no-op if the model changes are not logged.
+func (app *BaseApp) logRecordChange(dao *daos.Dao, action string, m models.Model, snapshot types.JsonRaw) error {
+	if !app.isRecordChangeLogged(m) {
+		return nil
+	}
+
+	record := m.(*models.Record)
+
+	return dao.QueueRecordChange(&models.RecordChange{
+		CollectionId: record.Collection().Id,
+		RecordId:     record.Id,
+		Action:       action,
+		Data:         snapshot,
+	})
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+)
+
+// SchemaBackfillMaxAttempts is the max number of run attempts of a
+// single field conversion backfill job before it is marked as dead.
+const SchemaBackfillMaxAttempts = 10
+
+const schemaBackfillJobName = "@schemaBackfill"
+
+// SchemaIndexesBuildMaxAttempts is the max number of run attempts of a
+// single online collection indexes build job before it is marked as dead.
+const SchemaIndexesBuildMaxAttempts = 3
+
+const schemaIndexesBuildJobName = "@schemaIndexesBuild"
+
+// schemaBackfillLogInterval is the min interval between two backfill progress log entries.
+const schemaBackfillLogInterval = 10 * time.Second
+
+// initSchemaBackfills registers the online field conversions backfill
+// and indexes build job handlers.
+func (app *BaseApp) initSchemaBackfills() {
+	app.jobs.Register(schemaBackfillJobName, app.runSchemaBackfillJob)
+	app.jobs.Register(schemaIndexesBuildJobName, app.runSchemaIndexesBuildJob)
+}
+
+// scheduleSchemaIndexesBuild enqueues the provided collection indexes build
+// as background job so that a failed index build doesn't fail the already
+// committed collection save, or runs it immediately if the jobs workers
+// are not started (eg. when the collection is saved from a console command).
+func (app *BaseApp) scheduleSchemaIndexesBuild(build *daos.SchemaIndexesBuild) error {
+	if !app.jobs.IsRunning() {
+		return app.Dao().RunSchemaIndexesBuild(build)
+	}
+
+	_, err := app.jobs.Enqueue(schemaIndexesBuildJobName, build, JobOptions{
+		MaxAttempts: SchemaIndexesBuildMaxAttempts,
+	})
+	if err != nil {
+		return err
+	}
+
+	app.jobs.Wake()
+
+	return nil
+}
+
+// runSchemaIndexesBuildJob builds the collection indexes from the job payload.
+func (app *BaseApp) runSchemaIndexesBuildJob(ctx context.Context, job *models.Job) error {
+	build := &daos.SchemaIndexesBuild{}
+	if err := job.UnmarshalPayload(build); err != nil {
+		return err
+	}
+
+	if err := app.Dao().WithContext(ctx).RunSchemaIndexesBuild(build); err != nil {
+		app.Logger().Error(
+			"Schema indexes build failed",
+			slog.String("collectionId", build.CollectionId),
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+
+	return nil
+}
+
+// scheduleSchemaBackfill enqueues the provided field conversion backfill
+// as background job or runs it immediately if the jobs workers are not
+// started (eg. when the collection is saved from a console command).
+func (app *BaseApp) scheduleSchemaBackfill(backfill *daos.SchemaBackfill) error {
+	if !app.jobs.IsRunning() {
+		return app.runSchemaBackfill(context.Background(), backfill, nil)
+	}
+
+	_, err := app.jobs.Enqueue(schemaBackfillJobName, backfill, JobOptions{
+		MaxAttempts: SchemaBackfillMaxAttempts,
+		UniqueKey:   schemaBackfillJobName + ":" + backfill.CollectionId + ":" + backfill.ShadowColumn,
+	})
+	if err != nil {
+		return err
+	}
+
+	app.jobs.Wake()
+
+	return nil
+}
+
+// runSchemaBackfillJob resumes the backfill from its job payload
+// and checkpoints its progress after each batch.
+func (app *BaseApp) runSchemaBackfillJob(ctx context.Context, job *models.Job) error {
+	backfill := &daos.SchemaBackfill{}
+	if err := job.UnmarshalPayload(backfill); err != nil {
+		return err
+	}
+
+	return app.runSchemaBackfill(ctx, backfill, func(backfill *daos.SchemaBackfill) error {
+		payload, err := json.Marshal(backfill)
+		if err != nil {
+			return err
+		}
+
+		job.Payload = payload
+
+		return app.Dao().SaveJobPayload(job)
+	})
+}
+
+func (app *BaseApp) runSchemaBackfill(
+	ctx context.Context,
+	backfill *daos.SchemaBackfill,
+	checkpoint func(backfill *daos.SchemaBackfill) error,
+) error {
+	batchSize := app.Settings().Schema.BackfillBatchSize
+
+	lastLog := time.Now()
+
+	app.Logger().Info("Schema backfill started", schemaBackfillLogAttrs(backfill)...)
+
+	err := app.Dao().RunSchemaBackfill(ctx, backfill, batchSize, func(backfill *daos.SchemaBackfill) error {
+		if checkpoint != nil {
+			if err := checkpoint(backfill); err != nil {
+				return err
+			}
+		}
+
+		if !backfill.Done && time.Since(lastLog) >= schemaBackfillLogInterval {
+			lastLog = time.Now()
+			app.Logger().Info("Schema backfill progress", schemaBackfillLogAttrs(backfill)...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	app.Logger().Info("Schema backfill completed", schemaBackfillLogAttrs(backfill)...)
+
+	return nil
+}
+
+func schemaBackfillLogAttrs(backfill *daos.SchemaBackfill) []any {
+	var progress float64
+	if backfill.Done {
+		progress = 100
+	} else if backfill.Total > 0 {
+		// rows inserted after the conversion are not part of the total
+		progress = math.Min(99, math.Floor(float64(backfill.Processed)/float64(backfill.Total)*100))
+	}
+
+	return []any{
+		slog.String("collectionId", backfill.CollectionId),
+		slog.String("fieldId", backfill.FieldId),
+		slog.Int64("processed", backfill.Processed),
+		slog.Int64("total", backfill.Total),
+		slog.Float64("progress", progress),
+	}
+}
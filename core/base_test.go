@@ -195,6 +195,10 @@ func TestBaseAppGetters(t *testing.T) {
 		t.Fatalf("Expected app.SubscriptionsBroker %v, got %v", app.SubscriptionsBroker(), app.subscriptionsBroker)
 	}
 
+	if app.jobs != app.Jobs() || app.Jobs() == nil {
+		t.Fatalf("Expected app.Jobs %v, got %v", app.Jobs(), app.jobs)
+	}
+
 	if app.onBeforeServe != app.OnBeforeServe() || app.OnBeforeServe() == nil {
 		t.Fatalf("Getter app.OnBeforeServe does not match or nil (%v vs %v)", app.OnBeforeServe(), app.onBeforeServe)
 	}
@@ -0,0 +1,215 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/models/settings"
+	"github.com/hylarucoder/rocketbase/tools/hook"
+	"github.com/hylarucoder/rocketbase/tools/tracing"
+	"github.com/labstack/echo/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// initTracing registers the tracing app hooks.
+func (app *BaseApp) initTracing() {
+	app.OnTerminate().Add(func(e *TerminateEvent) error {
+		app.shutdownTracing()
+		return nil
+	})
+}
+
+// reloadTracing (re)initializes the global OpenTelemetry tracer provider
+// based on the current app tracing settings.
+//
+// It is no-op if the tracing settings haven't changed since the last call.
+func (app *BaseApp) reloadTracing() error {
+	config := app.Settings().Tracing
+	if !config.Enabled {
+		// normalize so that changing any of the other options doesn't trigger a reload
+		config = settings.TracingConfig{}
+	} else if config.ServiceName == "" {
+		config.ServiceName = app.Settings().Meta.AppName
+	}
+
+	rawConfig, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	app.tracingMux.Lock()
+	defer app.tracingMux.Unlock()
+
+	if string(rawConfig) == app.tracingConfig {
+		return nil // no changes
+	}
+
+	// disabled and was never enabled
+	// (leave the global otel providers untouched in case they were configured manually)
+	if !config.Enabled && app.tracerProvider == nil {
+		app.tracingConfig = string(rawConfig)
+		return nil
+	}
+
+	var provider *sdktrace.TracerProvider
+
+	if config.Enabled {
+		provider, err = newTracerProvider(config.Endpoint, config.Headers, config.ServiceName, config.SampleRatio)
+		if err != nil {
+			return err
+		}
+
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+			propagation.TraceContext{},
+			propagation.Baggage{},
+		))
+		hook.SetTriggerObserver(observeHookTrigger)
+	} else {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		hook.SetTriggerObserver(nil)
+	}
+
+	// flush and release the previous provider resources
+	if app.tracerProvider != nil {
+		shutdownTracerProvider(app.tracerProvider)
+	}
+
+	app.tracerProvider = provider
+	app.tracingConfig = string(rawConfig)
+
+	return nil
+}
+
+// shutdownTracing flushes the pending spans and releases the tracer provider resources.
+func (app *BaseApp) shutdownTracing() {
+	app.tracingMux.Lock()
+	defer app.tracingMux.Unlock()
+
+	if app.tracerProvider == nil {
+		return
+	}
+
+	shutdownTracerProvider(app.tracerProvider)
+
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	hook.SetTriggerObserver(nil)
+
+	app.tracerProvider = nil
+	app.tracingConfig = ""
+}
+
+func newTracerProvider(
+	endpoint string,
+	headers map[string]string,
+	serviceName string,
+	sampleRatio float64,
+) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(
+		context.Background(),
+		otlptracehttp.WithEndpointURL(endpoint),
+		otlptracehttp.WithHeaders(headers),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	), nil
+}
+
+func shutdownTracerProvider(provider *sdktrace.TracerProvider) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := provider.Shutdown(ctx); err != nil {
+		slog.Default().Warn("Failed to shutdown the tracer provider", slog.String("error", err.Error()))
+	}
+}
+
+// observeHookTrigger is a [hook.TriggerObserver] that creates a span
+// for the hook events triggered as part of a traced http request.
+func observeHookTrigger(data any) func(err error) {
+	ctx := eventContext(data)
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+
+	eventType := reflect.Indirect(reflect.ValueOf(data)).Type().Name()
+
+	_, span := tracing.StartChild(ctx, "hook "+eventType, attribute.String("hook.event", eventType))
+
+	return func(err error) {
+		tracing.End(span, err)
+	}
+}
+
+// eventContext returns the request context of the provided
+// hook event data (if it has a non-nil HttpContext field).
+func eventContext(data any) context.Context {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	field := v.FieldByName("HttpContext")
+	if !field.IsValid() {
+		return nil
+	}
+
+	c, ok := field.Interface().(echo.Context)
+	if !ok || c == nil || c.Request() == nil {
+		return nil
+	}
+
+	return c.Request().Context()
+}
+
+// traceDBQuery records a span for an already executed db statement
+// (if it was executed as part of a traced operation).
+//
+// Note that the statement text is not recorded because dbx provides
+// it with the inlined param values (eg. password hashes, tokens, etc.).
+func traceDBQuery(ctx context.Context, dbName string, duration time.Duration, sql string, err error) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	operation := "QUERY"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+
+	tracing.Record(
+		ctx,
+		operation+" "+dbName,
+		duration,
+		err,
+		semconv.DBSystemPostgreSQL,
+		semconv.DBNamespace(dbName),
+		semconv.DBOperationName(operation),
+	)
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/tools/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTraceDBQuery(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	// the log funcs sql has the bound params inlined
+	sql := `UPDATE "users" SET "tokenKey"='secret_token_key' WHERE "id"='test'`
+
+	// without active span
+	traceDBQuery(context.Background(), "data", time.Second, sql, nil)
+
+	ctx, root := tracing.Start(context.Background(), "root")
+	traceDBQuery(ctx, "data", time.Second, sql, nil)
+	tracing.End(root, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 ended spans, got %d", len(spans))
+	}
+
+	query := spans[0]
+
+	if query.Name() != "UPDATE data" {
+		t.Fatalf("Expected UPDATE data span, got %q", query.Name())
+	}
+
+	for _, attr := range query.Attributes() {
+		if strings.Contains(attr.Value.Emit(), "secret_token_key") {
+			t.Fatalf("Expected the bound param to not be recorded, found it in %q", attr.Key)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/resolvers"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/hylarucoder/rocketbase/tools/security"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+// Webhook request headers.
+const (
+	WebhookHeaderId        = "X-Webhook-Id"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+
+	// WebhookHeaderSignature is the HMAC-SHA256 signature of the
+	// "{timestamp}.{body}" string using the webhook secret as key
+	// (set only if the webhook has a secret).
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// WebhookMaxAttempts is the max number of delivery attempts
+	// before the delivery is marked as failed.
+	WebhookMaxAttempts = 10
+
+	webhooksWorkerInterval   = 5 * time.Second
+	webhooksWorkerBatchSize  = 50
+	webhooksDeliveryLease    = 2 * time.Minute
+	webhooksRequestTimeout   = 15 * time.Second
+	webhooksBackoffBase      = 10 * time.Second
+	webhooksBackoffMax       = 6 * time.Hour
+	webhooksDeliveriesMaxAge = 7 * 24 * time.Hour
+
+	storeCachedWebhooksKey = "@cachedWebhooks"
+)
+
+// webhookPayload defines the webhook request body.
+type webhookPayload struct {
+	Event      string            `json:"event"`
+	Collection webhookCollection `json:"collection"`
+	Record     map[string]any    `json:"record"`
+	Timestamp  types.DateTime    `json:"timestamp"`
+}
+
+type webhookCollection struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// WebhookSignature returns the signature of the webhook request body
+// generated at the specified unix timestamp.
+//
+// Receivers could use it to verify the [WebhookHeaderSignature] value.
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	return "sha256=" + security.HS256(strconv.FormatInt(timestamp, 10)+"."+string(body), secret)
+}
+
+// webhookBackoff returns the delay before the next delivery attempt
+// (exponential, starting from webhooksBackoffBase and capped to webhooksBackoffMax).
+func webhookBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := float64(webhooksBackoffBase) * math.Pow(2, float64(attempts-1))
+	if delay > float64(webhooksBackoffMax) {
+		return webhooksBackoffMax
+	}
+
+	return time.Duration(delay)
+}
+
+// initWebhooks registers the webhooks cache and delivery worker app hooks.
+func (app *BaseApp) initWebhooks() {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	close(done) // nothing to wait initially
+
+	reloadCache := func(e *ModelEvent) error {
+		app.Store().Remove(storeCachedWebhooksKey)
+		return nil
+	}
+	app.OnModelAfterCreate((&models.Webhook{}).TableName()).Add(reloadCache)
+	app.OnModelAfterUpdate((&models.Webhook{}).TableName()).Add(reloadCache)
+	app.OnModelAfterDelete((&models.Webhook{}).TableName()).Add(reloadCache)
+
+	// enqueue the auth webhooks
+	app.OnRecordAuthRequest().Add(func(e *RecordAuthEvent) error {
+		if err := app.enqueueRecordWebhooks(app.Dao(), models.WebhookEventAuth, e.Record); err != nil {
+			app.Logger().Error("Failed to enqueue auth webhooks", slog.String("error", err.Error()))
+		}
+		return nil
+	})
+
+	// start the delivery worker on app serve
+	app.OnBeforeServe().Add(func(e *ServeEvent) error {
+		app.Store().Remove(storeCachedWebhooksKey)
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan struct{})
+
+		go app.runWebhooksWorker(ctx, done)
+
+		return nil
+	})
+
+	// stop the worker and wait for the in-progress deliveries
+	app.OnTerminate().Add(func(e *TerminateEvent) error {
+		if cancel != nil {
+			cancel()
+		}
+		<-done
+		return nil
+	})
+}
+
+// cachedWebhooks returns the active webhooks grouped by their collection id.
+//
+// The webhooks are loaded with the app (non-transactional) dao so that
+// a failed query doesn't abort the transaction of the current write.
+func (app *BaseApp) cachedWebhooks() map[string][]*models.Webhook {
+	if cached, ok := app.Store().Get(storeCachedWebhooksKey).(map[string][]*models.Webhook); ok {
+		return cached
+	}
+
+	webhooks, err := app.Dao().FindActiveWebhooks()
+	if err != nil {
+		// most likely the migrations are not applied yet
+		return nil
+	}
+
+	result := map[string][]*models.Webhook{}
+	for _, w := range webhooks {
+		result[w.CollectionId] = append(result[w.CollectionId], w)
+	}
+
+	app.Store().Set(storeCachedWebhooksKey, result)
+
+	return result
+}
+
+// hasRecordWebhooks checks whether there is at least one active
+// webhook for the collection of the provided record model.
+func (app *BaseApp) hasRecordWebhooks(m models.Model) bool {
+	record, ok := m.(*models.Record)
+	if !ok || record.Collection() == nil {
+		return false
+	}
+
+	return len(app.cachedWebhooks()[record.Collection().Id]) > 0
+}
+
+// matchRecordWebhooks returns the active webhooks subscribed for
+// the specified record event and whose filter matches the record.
+//
+// The filter is checked against the current record db state
+// using the provided dao (eg. the transaction of the current write).
+func (app *BaseApp) matchRecordWebhooks(dao *daos.Dao, event string, m models.Model) ([]*models.Webhook, error) {
+	record, ok := m.(*models.Record)
+	if !ok || record.Collection() == nil {
+		return nil, nil
+	}
+
+	var result []*models.Webhook
+
+	for _, webhook := range app.cachedWebhooks()[record.Collection().Id] {
+		if !webhook.HasEvent(event) {
+			continue
+		}
+
+		if webhook.Filter != "" {
+			match, err := app.webhookFilterMatch(dao, record, webhook.Filter)
+			if err != nil {
+				app.Logger().Warn(
+					"Failed to check the webhook filter",
+					slog.String("webhookId", webhook.Id),
+					slog.String("error", err.Error()),
+				)
+			}
+			if !match {
+				continue
+			}
+		}
+
+		result = append(result, webhook)
+	}
+
+	return result, nil
+}
+
+// webhookFilterMatch checks whether the record matches the webhook filter.
+//
+// When executed inside a transaction, the filter query is wrapped in a
+// savepoint to prevent a failing filter from aborting the transaction.
+func (app *BaseApp) webhookFilterMatch(dao *daos.Dao, record *models.Record, filter string) (bool, error) {
+	_, inTx := dao.NonconcurrentDB().(*dbx.Tx)
+
+	if inTx {
+		if _, err := dao.NonconcurrentDB().NewQuery("SAVEPOINT webhook_filter").Execute(); err != nil {
+			return false, err
+		}
+	}
+
+	_, err := dao.FindRecordById(record.Collection().Id, record.Id, func(q *dbx.SelectQuery) error {
+		resolver := resolvers.NewRecordFieldResolver(dao, record.Collection(), nil, true)
+
+		expr, err := search.FilterData(filter).BuildExpr(resolver)
+		if err != nil {
+			return err
+		}
+
+		resolver.UpdateQuery(q)
+		q.AndWhere(expr)
+
+		return nil
+	})
+
+	if inTx {
+		savepointQuery := "RELEASE SAVEPOINT webhook_filter"
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			savepointQuery = "ROLLBACK TO SAVEPOINT webhook_filter"
+		}
+		if _, spErr := dao.NonconcurrentDB().NewQuery(savepointQuery).Execute(); spErr != nil {
+			return false, spErr
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// enqueueRecordWebhooks stores a delivery for each matching record webhook.
+func (app *BaseApp) enqueueRecordWebhooks(dao *daos.Dao, event string, m models.Model) error {
+	webhooks, err := app.matchRecordWebhooks(dao, event, m)
+	if err != nil {
+		return err
+	}
+
+	return app.saveWebhookDeliveries(dao, event, m, webhooks)
+}
+
+// saveWebhookDeliveries stores a new pending delivery for each of the
+// provided webhooks using the provided dao (aka. in the same transaction as the change).
+func (app *BaseApp) saveWebhookDeliveries(dao *daos.Dao, event string, m models.Model, webhooks []*models.Webhook) error {
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	record, ok := m.(*models.Record)
+	if !ok {
+		return nil
+	}
+
+	exported := record.CleanCopy()
+	exported.IgnoreEmailVisibility(true)
+
+	payload, err := json.Marshal(webhookPayload{
+		Event: event,
+		Collection: webhookCollection{
+			Id:   record.Collection().Id,
+			Name: record.Collection().Name,
+		},
+		Record:    exported.PublicExport(),
+		Timestamp: types.NowDateTime(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the deliveries are saved without the model hooks to avoid
+	// triggering unnecessary app events for each enqueued delivery
+	deliveriesDao := daos.NewMultiDB(dao.ConcurrentDB(), dao.NonconcurrentDB())
+
+	for _, webhook := range webhooks {
+		delivery := &models.WebhookDelivery{
+			WebhookId:     webhook.Id,
+			Event:         event,
+			RecordId:      record.Id,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryStatusPending,
+			NextAttemptAt: types.NowDateTime(),
+		}
+
+		if err := deliveriesDao.SaveWebhookDelivery(delivery); err != nil {
+			return fmt.Errorf("failed to save webhook %s delivery: %w", webhook.Id, err)
+		}
+	}
+
+	return nil
+}
+
+// runWebhooksWorker periodically claims and sends the pending
+// webhook deliveries until the provided context is canceled.
+func (app *BaseApp) runWebhooksWorker(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(webhooksWorkerInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Time{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !app.IsBootstrapped() {
+			continue
+		}
+
+		for ctx.Err() == nil {
+			deliveries, err := app.Dao().ClaimWebhookDeliveries(webhooksWorkerBatchSize, webhooksDeliveryLease)
+			if err != nil {
+				app.Logger().Debug("Failed to claim webhook deliveries", slog.String("error", err.Error()))
+				break
+			}
+
+			for _, delivery := range deliveries {
+				app.processWebhookDelivery(delivery)
+			}
+
+			if len(deliveries) < webhooksWorkerBatchSize {
+				break
+			}
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			lastCleanup = time.Now()
+			if err := app.Dao().DeleteOldWebhookDeliveries(time.Now().Add(-webhooksDeliveriesMaxAge)); err != nil {
+				app.Logger().Debug("Failed to delete old webhook deliveries", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// processWebhookDelivery sends a single claimed delivery and
+// updates its state according to the response.
+//
+// Note that the request is not bound to the worker context so that the
+// in-progress deliveries could be completed on graceful termination.
+func (app *BaseApp) processWebhookDelivery(delivery *models.WebhookDelivery) {
+	webhook, err := app.Dao().FindWebhookById(delivery.WebhookId)
+	if err != nil {
+		delivery.Status = models.WebhookDeliveryStatusFailed
+		delivery.LastError = "missing webhook"
+	} else {
+		status, sendErr := app.sendWebhookDelivery(webhook, delivery)
+		delivery.ResponseStatus = status
+
+		if sendErr == nil {
+			delivery.Status = models.WebhookDeliveryStatusSuccess
+			delivery.LastError = ""
+		} else {
+			delivery.LastError = sendErr.Error()
+
+			if delivery.Attempts >= WebhookMaxAttempts {
+				delivery.Status = models.WebhookDeliveryStatusFailed
+			} else {
+				delivery.Status = models.WebhookDeliveryStatusPending
+				delivery.NextAttemptAt, _ = types.ParseDateTime(time.Now().Add(webhookBackoff(delivery.Attempts)))
+			}
+		}
+	}
+
+	if err := app.Dao().SaveWebhookDelivery(delivery); err != nil {
+		app.Logger().Error(
+			"Failed to update webhook delivery",
+			slog.String("deliveryId", delivery.Id),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// sendWebhookDelivery sends the delivery payload to the webhook url
+// and returns the response status code.
+//
+// Non 2xx responses are returned as error.
+func (app *BaseApp) sendWebhookDelivery(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhooksRequestTimeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderId, webhook.Id)
+	req.Header.Set(WebhookHeaderDelivery, delivery.Id)
+	req.Header.Set(WebhookHeaderEvent, delivery.Event)
+	req.Header.Set(WebhookHeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookHeaderSignature, WebhookSignature(webhook.Secret, timestamp, body))
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// drain a limited part of the body to allow connection reuse
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected response status code %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		secret    string
+		timestamp int64
+		body      string
+	}{
+		{"", 0, ""},
+		{"secret", 1700000000, `{"event":"create"}`},
+		{"other", 1700000000, `{"event":"create"}`},
+		{"secret", 1700000001, `{"event":"create"}`},
+	}
+
+	seen := map[string]bool{}
+
+	for i, s := range scenarios {
+		result := WebhookSignature(s.secret, s.timestamp, []byte(s.body))
+
+		if len(result) != len("sha256=")+64 || result[:7] != "sha256=" {
+			t.Fatalf("[%d] Invalid signature format %q", i, result)
+		}
+
+		if result != WebhookSignature(s.secret, s.timestamp, []byte(s.body)) {
+			t.Fatalf("[%d] Expected the signature to be deterministic", i)
+		}
+
+		if seen[result] {
+			t.Fatalf("[%d] Expected unique signature, got duplicated %q", i, result)
+		}
+		seen[result] = true
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{-1, webhooksBackoffBase},
+		{0, webhooksBackoffBase},
+		{1, webhooksBackoffBase},
+		{2, 2 * webhooksBackoffBase},
+		{4, 8 * webhooksBackoffBase},
+		{100, webhooksBackoffMax},
+	}
+
+	for _, s := range scenarios {
+		if result := webhookBackoff(s.attempts); result != s.expected {
+			t.Errorf("(%d) Expected %v, got %v", s.attempts, s.expected, result)
+		}
+	}
+}
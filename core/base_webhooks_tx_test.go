@@ -0,0 +1,49 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tests"
+)
+
+func TestWebhookDeliveriesOutboxWithoutTransaction(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	webhook := &models.Webhook{
+		Name:         "test",
+		CollectionId: collection.Id,
+		Events:       []string{models.WebhookEventCreate},
+		Url:          "http://127.0.0.1:0/test",
+		Active:       true,
+	}
+	if err := app.Dao().SaveWebhook(webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	// make every delivery insert fail
+	_, err = app.Dao().DB().NewQuery(
+		"ALTER TABLE {{_webhookDeliveries}} ADD CONSTRAINT [[test_fail]] CHECK (false) NOT VALID",
+	).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := models.NewRecord(collection)
+	record.Set("title", "webhook_outbox_rollback")
+
+	if err := app.Dao().SaveRecord(record); err == nil {
+		t.Fatal("Expected the failing delivery insert to fail the record save")
+	}
+
+	found, _ := app.Dao().FindFirstRecordByData(collection.Id, "title", "webhook_outbox_rollback")
+	if found != nil {
+		t.Fatal("Expected the record create to be rolled back")
+	}
+}
@@ -96,6 +96,24 @@ type ModelEvent struct {
 	Dao *daos.Dao
 }
 
+// ModelCommitEvent defines the after commit model hooks event data.
+//
+// It is dispatched asynchronously from the jobs outbox, aka. after the
+// related transaction commit, and it could be delivered more than once.
+type ModelCommitEvent struct {
+	BaseModelEvent
+
+	// Dao is the default (non-transactional) app Dao.
+	Dao *daos.Dao
+
+	// EventId is the unique identifier of the committed change
+	// (could be used to deduplicate repeated handler calls).
+	EventId string
+
+	// Attempt is the current dispatch attempt (starting from 1).
+	Attempt int
+}
+
 // -------------------------------------------------------------------
 // Mailer events data
 // -------------------------------------------------------------------
@@ -124,6 +142,11 @@ type RealtimeConnectEvent struct {
 	HttpContext echo.Context
 	Client      subscriptions.Client
 	IdleTimeout time.Duration
+
+	// ResumeTimeout is the max time the client subscriptions are kept
+	// after disconnect so that the connection could be resumed with
+	// the Last-Event-ID header (set to 0 to disable).
+	ResumeTimeout time.Duration
 }
 
 type RealtimeDisconnectEvent struct {
@@ -143,6 +166,37 @@ type RealtimeSubscribeEvent struct {
 	Subscriptions []string
 }
 
+// RealtimeChannelEvent defines the custom realtime channel publish hooks event data.
+type RealtimeChannelEvent struct {
+	HttpContext echo.Context
+
+	// Client is the publisher subscription client
+	// (could be nil if the message is published with a plain HTTP request).
+	Client  subscriptions.Client
+	Channel string
+	Data    any
+}
+
+// Realtime presence event actions.
+const (
+	RealtimePresenceJoin   = "join"
+	RealtimePresenceUpdate = "update"
+	RealtimePresenceLeave  = "leave"
+)
+
+// RealtimePresenceEvent defines the custom realtime channel presence change hook event data.
+type RealtimePresenceEvent struct {
+	Client  subscriptions.Client
+	Channel string
+
+	// Action is one of the RealtimePresenceJoin, RealtimePresenceUpdate
+	// or RealtimePresenceLeave constants.
+	Action string
+
+	// State is the client presence state (the last one on leave).
+	State map[string]any
+}
+
 // -------------------------------------------------------------------
 // Settings API events data
 // -------------------------------------------------------------------
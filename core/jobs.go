@@ -0,0 +1,501 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+const (
+	// JobsDefaultMaxAttempts is the default max number of job run
+	// attempts before the job is moved to the dead-letter state.
+	JobsDefaultMaxAttempts = 5
+
+	// JobsDefaultConcurrency is the default number of concurrent job workers.
+	JobsDefaultConcurrency = 4
+
+	// JobsDrainTimeout is the max time to wait for the in-progress
+	// jobs to complete on app termination.
+	JobsDrainTimeout = 30 * time.Second
+
+	// jobsAbortTimeout is the max time to wait for the aborted
+	// job handlers to return after the drain timeout.
+	jobsAbortTimeout = 5 * time.Second
+
+	jobsPollInterval    = 1 * time.Second
+	jobsLease           = 5 * time.Minute
+	jobsLeaseRenewal    = jobsLease / 3
+	jobsBackoffBase     = 10 * time.Second
+	jobsBackoffMax      = 1 * time.Hour
+	jobsCompletedMaxAge = 7 * 24 * time.Hour
+)
+
+// JobHandlerFunc defines a background job handler function.
+//
+// The provided context is canceled if the app is terminating and
+// the job didn't complete within the [JobsDrainTimeout].
+type JobHandlerFunc func(ctx context.Context, job *models.Job) error
+
+// JobOptions defines the optional job enqueue settings.
+type JobOptions struct {
+	// Priority of the job (jobs with higher priority are run first).
+	Priority int
+
+	// RunAt schedules the job to run not earlier than the specified time.
+	RunAt time.Time
+
+	// Delay postpones the job run with the specified duration
+	// (it is ignored if RunAt is set).
+	Delay time.Duration
+
+	// MaxAttempts is the max number of run attempts before the job
+	// is marked as dead (default to [JobsDefaultMaxAttempts]).
+	MaxAttempts int
+
+	// UniqueKey prevents enqueueing the job if there is already
+	// another pending or running job with the same key.
+	UniqueKey string
+}
+
+// Jobs is a Postgres backed background jobs queue.
+//
+// Jobs are persisted in the "_jobs" table and are pulled by a pool of
+// workers (started on app serve) with FOR UPDATE SKIP LOCKED, allowing
+// multiple app instances to process the same queue.
+//
+// Failed jobs are retried with exponential backoff until they reach their
+// max attempts, after which they are left in the dead-letter ("dead") state.
+type Jobs struct {
+	app App
+
+	mux         sync.RWMutex
+	handlers    map[string]JobHandlerFunc
+	concurrency int
+
+	stop   context.CancelFunc
+	abort  context.CancelFunc
+	wg     sync.WaitGroup
+	wakeCh chan struct{}
+}
+
+// NewJobs creates a new Jobs queue instance bound to the provided app.
+func NewJobs(app App) *Jobs {
+	return &Jobs{
+		app:         app,
+		handlers:    map[string]JobHandlerFunc{},
+		concurrency: JobsDefaultConcurrency,
+		wakeCh:      make(chan struct{}, 1),
+	}
+}
+
+// SetConcurrency changes the number of concurrent job workers.
+//
+// The change takes effect on the next workers start.
+func (j *Jobs) SetConcurrency(concurrency int) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	j.concurrency = concurrency
+}
+
+// Register registers a handler for the jobs with the specified name
+// (replacing any previously registered handler with the same name).
+//
+// Only jobs with a registered handler are pulled from the queue by
+// the current app instance workers.
+func (j *Jobs) Register(name string, handler JobHandlerFunc) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	j.handlers[name] = handler
+}
+
+// Remove removes the registered handler for the jobs with the specified name.
+func (j *Jobs) Remove(name string) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	delete(j.handlers, name)
+}
+
+// Names returns the sorted names of all registered job handlers.
+func (j *Jobs) Names() []string {
+	j.mux.RLock()
+	defer j.mux.RUnlock()
+
+	names := make([]string, 0, len(j.handlers))
+	for name := range j.handlers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (j *Jobs) handler(name string) JobHandlerFunc {
+	j.mux.RLock()
+	defer j.mux.RUnlock()
+
+	return j.handlers[name]
+}
+
+// Enqueue adds a new job with the provided name and JSON serializable
+// payload to the jobs queue.
+//
+// If the job has a UniqueKey and another pending or running job with the
+// same key already exists, the existing job is returned instead.
+func (j *Jobs) Enqueue(name string, payload any, options ...JobOptions) (*models.Job, error) {
+	return j.EnqueueWithDao(j.app.Dao(), name, payload, options...)
+}
+
+// EnqueueWithDao is similar to [Jobs.Enqueue] but allows specifying
+// a custom dao (eg. to enqueue the job as part of a transaction).
+func (j *Jobs) EnqueueWithDao(dao *daos.Dao, name string, payload any, options ...JobOptions) (*models.Job, error) {
+	if name == "" {
+		return nil, errors.New("missing job name")
+	}
+
+	var opts JobOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	encodedPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the job payload: %w", err)
+	}
+
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now().Add(opts.Delay)
+	}
+
+	job := &models.Job{
+		Name:        name,
+		Payload:     encodedPayload,
+		Status:      models.JobStatusPending,
+		Priority:    opts.Priority,
+		MaxAttempts: opts.MaxAttempts,
+		UniqueKey:   opts.UniqueKey,
+	}
+
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = JobsDefaultMaxAttempts
+	}
+
+	job.RunAt, err = types.ParseDateTime(runAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dao.EnqueueJob(job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Wake notifies an idle worker to check immediately for due jobs
+// instead of waiting for the next poll interval
+// (eg. after committing a transaction with enqueued jobs).
+func (j *Jobs) Wake() {
+	select {
+	case j.wakeCh <- struct{}{}:
+	default:
+		// a wake up is already pending
+	}
+}
+
+// IsRunning reports whether the jobs workers are started
+// (usually they are started on app serve and stopped on terminate).
+func (j *Jobs) IsRunning() bool {
+	j.mux.RLock()
+	defer j.mux.RUnlock()
+
+	return j.stop != nil
+}
+
+// initJobs registers the jobs workers start and drain app hooks.
+func (app *BaseApp) initJobs() {
+	app.OnBeforeServe().Add(func(e *ServeEvent) error {
+		app.jobs.start()
+		return nil
+	})
+
+	app.OnTerminate().Add(func(e *TerminateEvent) error {
+		app.jobs.drain(JobsDrainTimeout)
+		return nil
+	})
+}
+
+// start starts the job workers (if not already).
+func (j *Jobs) start() {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.stop != nil {
+		return // already started
+	}
+
+	var pollCtx, runCtx context.Context
+	pollCtx, j.stop = context.WithCancel(context.Background())
+	runCtx, j.abort = context.WithCancel(context.Background())
+
+	for i := 0; i < j.concurrency; i++ {
+		j.wg.Add(1)
+		go j.work(pollCtx, runCtx, i == 0)
+	}
+}
+
+// drain stops pulling new jobs and waits up to timeout for the
+// in-progress ones to complete.
+//
+// The handlers of the jobs that didn't complete in time have their
+// context canceled and the jobs are left to be claimed again
+// after their lease expires. drain then waits up to [jobsAbortTimeout]
+// more for the workers to exit so that they don't access the db
+// while the app is being terminated.
+func (j *Jobs) drain(timeout time.Duration) {
+	j.mux.Lock()
+	stop, abort := j.stop, j.abort
+	j.stop, j.abort = nil, nil
+	j.mux.Unlock()
+
+	if stop == nil {
+		return // not started
+	}
+
+	stop()
+
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		abort()
+		return
+	case <-time.After(timeout):
+		j.app.Logger().Warn("Jobs drain timeout exceeded, aborting the in-progress jobs")
+	}
+
+	abort()
+
+	select {
+	case <-done:
+	case <-time.After(jobsAbortTimeout):
+		j.app.Logger().Warn("Jobs abort timeout exceeded, some of the job handlers ignore their context cancellation")
+	}
+}
+
+// work is a single jobs worker loop.
+func (j *Jobs) work(pollCtx, runCtx context.Context, cleanup bool) {
+	defer j.wg.Done()
+
+	lastCleanup := time.Time{}
+
+	for {
+		claimed := false
+
+		if j.app.IsBootstrapped() {
+			claimed = j.runNext(runCtx)
+
+			if cleanup && time.Since(lastCleanup) > time.Hour {
+				lastCleanup = time.Now()
+				if err := j.app.Dao().DeleteOldJobs(time.Now().Add(-jobsCompletedMaxAge)); err != nil {
+					j.app.Logger().Debug("Failed to delete old jobs", slog.String("error", err.Error()))
+				}
+			}
+		}
+
+		if claimed && pollCtx.Err() == nil {
+			continue // check immediately for more due jobs
+		}
+
+		select {
+		case <-pollCtx.Done():
+			return
+		case <-j.wakeCh:
+		case <-time.After(jobsPollInterval):
+		}
+	}
+}
+
+// runNext claims and runs a single due job.
+//
+// Returns false if there was no job to claim.
+func (j *Jobs) runNext(ctx context.Context) bool {
+	jobs, err := j.app.Dao().ClaimJobs(j.Names(), 1, jobsLease)
+	if err != nil {
+		j.app.Logger().Debug("Failed to claim jobs", slog.String("error", err.Error()))
+		return false
+	}
+
+	if len(jobs) == 0 {
+		return false
+	}
+
+	j.process(ctx, jobs[0])
+
+	return true
+}
+
+// process runs the handler of a single claimed job and
+// updates the job state according to the result.
+func (j *Jobs) process(ctx context.Context, job *models.Job) {
+	var runErr error
+
+	handler := j.handler(job.Name)
+
+	switch {
+	case handler == nil:
+		runErr = errors.New("missing job handler")
+	case job.Attempts > job.MaxAttempts:
+		// the job was reclaimed after an expired lease (eg. process crash)
+		runErr = errors.New("max attempts exceeded")
+	default:
+		runErr = j.runClaimedHandler(ctx, handler, job)
+	}
+
+	if runErr != nil && ctx.Err() != nil {
+		// aborted on termination - leave it to be reclaimed after the lease
+		return
+	}
+
+	if errors.Is(runErr, daos.ErrJobClaimLost) {
+		// the job was claimed by another worker that will finalize it
+		j.app.Logger().Warn(
+			"Job claim lost",
+			slog.String("jobId", job.Id),
+			slog.String("name", job.Name),
+			slog.Int("attempts", job.Attempts),
+		)
+		return
+	}
+
+	if runErr == nil {
+		job.Status = models.JobStatusCompleted
+		job.LastError = ""
+	} else {
+		job.LastError = runErr.Error()
+
+		if job.Attempts >= job.MaxAttempts {
+			job.Status = models.JobStatusDead
+		} else {
+			job.Status = models.JobStatusPending
+			job.RunAt, _ = types.ParseDateTime(time.Now().Add(jobBackoff(job.Attempts)))
+		}
+
+		j.app.Logger().Warn(
+			"Job failed",
+			slog.String("jobId", job.Id),
+			slog.String("name", job.Name),
+			slog.Int("attempts", job.Attempts),
+			slog.String("status", job.Status),
+			slog.String("error", job.LastError),
+		)
+	}
+
+	if err := j.app.Dao().FinalizeJob(job); err != nil {
+		j.app.Logger().Error(
+			"Failed to update the job state",
+			slog.String("jobId", job.Id),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// runClaimedHandler runs the job handler while periodically renewing
+// the job lease so that long running jobs are not claimed again.
+//
+// If the job claim is lost (eg. the lease couldn't be renewed in time),
+// the handler context is canceled.
+func (j *Jobs) runClaimedHandler(ctx context.Context, handler JobHandlerFunc, job *models.Job) error {
+	handlerCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// note: the job model is not shared with the renewal goroutine
+	// because the handler is allowed to modify it
+	claim := &models.Job{}
+	claim.Id = job.Id
+	claim.Attempts = job.Attempts
+
+	go func() {
+		ticker := time.NewTicker(jobsLeaseRenewal)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			err := j.app.Dao().RenewJobLease(claim, jobsLease)
+			if errors.Is(err, daos.ErrJobClaimLost) {
+				cancel(err)
+				return
+			}
+			if err != nil {
+				j.app.Logger().Debug(
+					"Failed to renew the job lease",
+					slog.String("jobId", claim.Id),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+	}()
+
+	err := j.runHandler(handlerCtx, handler, job)
+
+	if cause := context.Cause(handlerCtx); errors.Is(cause, daos.ErrJobClaimLost) {
+		return cause
+	}
+
+	return err
+}
+
+func (j *Jobs) runHandler(ctx context.Context, handler JobHandlerFunc, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panic: %v", r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// jobBackoff returns the delay before the next job run attempt
+// (exponential, starting from jobsBackoffBase and capped to jobsBackoffMax).
+func jobBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := float64(jobsBackoffBase) * math.Pow(2, float64(attempts-1))
+	if delay > float64(jobsBackoffMax) {
+		return jobsBackoffMax
+	}
+
+	return time.Duration(delay)
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/models"
+)
+
+func TestJobsRegisterAndRemove(t *testing.T) {
+	t.Parallel()
+
+	jobs := NewJobs(nil)
+
+	handler := func(ctx context.Context, job *models.Job) error { return nil }
+
+	jobs.Register("b", handler)
+	jobs.Register("a", handler)
+	jobs.Register("c", handler)
+	jobs.Register("a", handler) // duplicated
+
+	if names := jobs.Names(); !slices.Equal(names, []string{"a", "b", "c"}) {
+		t.Fatalf("Expected names [a b c], got %v", names)
+	}
+
+	jobs.Remove("b")
+	jobs.Remove("missing")
+
+	if names := jobs.Names(); !slices.Equal(names, []string{"a", "c"}) {
+		t.Fatalf("Expected names [a c], got %v", names)
+	}
+
+	if jobs.handler("a") == nil {
+		t.Fatal("Expected handler a to be registered")
+	}
+
+	if jobs.handler("b") != nil {
+		t.Fatal("Expected handler b to be removed")
+	}
+}
+
+func TestJobsSetConcurrency(t *testing.T) {
+	t.Parallel()
+
+	jobs := NewJobs(nil)
+
+	if jobs.concurrency != JobsDefaultConcurrency {
+		t.Fatalf("Expected default concurrency %d, got %d", JobsDefaultConcurrency, jobs.concurrency)
+	}
+
+	jobs.SetConcurrency(-1)
+	if jobs.concurrency != 1 {
+		t.Fatalf("Expected concurrency 1, got %d", jobs.concurrency)
+	}
+
+	jobs.SetConcurrency(10)
+	if jobs.concurrency != 10 {
+		t.Fatalf("Expected concurrency 10, got %d", jobs.concurrency)
+	}
+}
+
+func TestJobsEnqueueMissingName(t *testing.T) {
+	t.Parallel()
+
+	jobs := NewJobs(nil)
+
+	if _, err := jobs.EnqueueWithDao(nil, "", nil); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+}
+
+func TestJobsDrainNotStarted(t *testing.T) {
+	t.Parallel()
+
+	jobs := NewJobs(nil)
+
+	// should be no-op
+	jobs.drain(time.Second)
+
+	if jobs.IsRunning() {
+		t.Fatal("Expected the jobs workers to not be running")
+	}
+}
+
+func TestJobsRunHandlerPanic(t *testing.T) {
+	t.Parallel()
+
+	jobs := NewJobs(nil)
+
+	expectedErr := errors.New("test")
+
+	err := jobs.runHandler(context.Background(), func(ctx context.Context, job *models.Job) error {
+		return expectedErr
+	}, &models.Job{})
+	if err != expectedErr {
+		t.Fatalf("Expected %v, got %v", expectedErr, err)
+	}
+
+	err = jobs.runHandler(context.Background(), func(ctx context.Context, job *models.Job) error {
+		panic("test")
+	}, &models.Job{})
+	if err == nil {
+		t.Fatal("Expected panic error, got nil")
+	}
+}
+
+func TestJobBackoff(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{0, jobsBackoffBase},
+		{1, jobsBackoffBase},
+		{3, 4 * jobsBackoffBase},
+		{100, jobsBackoffMax},
+	}
+
+	for _, s := range scenarios {
+		if result := jobBackoff(s.attempts); result != s.expected {
+			t.Errorf("(%d) Expected %v, got %v", s.attempts, s.expected, result)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package daos
+
+import (
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/pocketbase/dbx"
+)
+
+// AuditLogQuery returns a new AuditLog select query.
+func (dao *Dao) AuditLogQuery() *dbx.SelectQuery {
+	tableName := (&models.AuditLog{}).TableName()
+
+	return dao.DB().Select("{{" + tableName + "}}.*").From(tableName)
+}
+
+// FindAuditLogById finds a single AuditLog entry by its id.
+func (dao *Dao) FindAuditLogById(id string) (*models.AuditLog, error) {
+	model := &models.AuditLog{}
+
+	err := dao.AuditLogQuery().
+		AndWhere(dbx.HashExp{"id": id}).
+		Limit(1).
+		One(model)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// CreateAuditLog appends a new entry to the audit trail
+// and populates the provided model with the inserted row data.
+//
+// Note that the audit logs are never updated after their creation.
+func (dao *Dao) CreateAuditLog(entry *models.AuditLog) error {
+	diff := entry.Diff
+	if diff == nil {
+		diff = map[string]any{}
+	}
+
+	return dao.NonconcurrentDB().NewQuery(`
+		INSERT INTO {{_auditLogs}} ([[action]], [[resourceType]], [[resourceId]], [[actorId]], [[actorEmail]], [[ip]], [[diff]])
+		VALUES ({:action}, {:resourceType}, {:resourceId}, {:actorId}, {:actorEmail}, {:ip}, {:diff})
+		RETURNING *
+	`).Bind(dbx.Params{
+		"action":       entry.Action,
+		"resourceType": entry.ResourceType,
+		"resourceId":   entry.ResourceId,
+		"actorId":      entry.ActorId,
+		"actorEmail":   entry.ActorEmail,
+		"ip":           entry.Ip,
+		"diff":         diff,
+	}).One(entry)
+}
+
+// EnableAuditLogsAppendOnly creates (if missing) the trigger that
+// rejects any update, delete or truncate of the audit logs table.
+//
+// There is no Dao method to drop the trigger on purpose - it could be
+// removed only with direct database access.
+func (dao *Dao) EnableAuditLogsAppendOnly() error {
+	_, err := dao.NonconcurrentDB().NewQuery(`
+		CREATE OR REPLACE TRIGGER _auditLogs_append_only_row
+			BEFORE UPDATE OR DELETE ON {{_auditLogs}}
+			FOR EACH ROW EXECUTE FUNCTION _audit_logs_append_only();
+
+		CREATE OR REPLACE TRIGGER _auditLogs_append_only_truncate
+			BEFORE TRUNCATE ON {{_auditLogs}}
+			FOR EACH STATEMENT EXECUTE FUNCTION _audit_logs_append_only();
+	`).Execute()
+
+	return err
+}
@@ -4,6 +4,7 @@
 package daos
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -42,9 +43,66 @@ type Dao struct {
 
 	// ModelQueryTimeout is the default max duration of a running ModelQuery().
 	//
-	// This field has no effect if an explicit query context is already specified.
+	// This field has no effect if the query context already has a deadline.
 	ModelQueryTimeout time.Duration
 
+	// SlowQueryThreshold specifies the min execution duration of a
+	// ModelQuery() or RecordQuery() statement to be reported to SlowQueryFunc.
+	//
+	// Zero or negative value disables the slow queries reporting.
+	SlowQueryThreshold time.Duration
+
+	// SlowQueryFunc is an optional function that is invoked for every
+	// ModelQuery() and RecordQuery() statement whose execution exceeded SlowQueryThreshold.
+	SlowQueryFunc func(q *dbx.Query, duration time.Duration, err error)
+
+	// OnlineSchemaChangesFunc is an optional function that reports whether
+	// the collection record table changes should be applied online,
+	// aka. without holding long table locks:
+	//   - the DDL transaction fails fast if it can't acquire a table lock
+	//   - only the new and changed indexes are (re)built with CREATE INDEX CONCURRENTLY
+	//   - the single/multiple field conversions are copied with batched backfills
+	//     to a shadow column that is swapped in after the last batch
+	//     (see [SchemaBackfill] and SchemaBackfillFunc)
+	//
+	// The indexes are built after the collection save commit so a failed
+	// index build doesn't revert the already saved collection changes
+	// (see SchemaIndexesBuildFunc).
+	//
+	// The online changes are never applied within an already started
+	// transaction (eg. collections import or migrations).
+	OnlineSchemaChangesFunc func() bool
+
+	// SchemaBackfillFunc is an optional function that schedules the
+	// online field conversions backfills (eg. as background jobs).
+	//
+	// If not set, the backfills are run as part of the collection save.
+	SchemaBackfillFunc func(backfill *SchemaBackfill) error
+
+	// SchemaIndexesBuildFunc is an optional function that schedules the
+	// online collection indexes builds (eg. as background jobs).
+	//
+	// If not set, the indexes are built as part of the collection save
+	// and their errors are returned after the collection changes commit.
+	SchemaIndexesBuildFunc func(build *SchemaIndexesBuild) error
+
+	// TransactionalWriteFunc is an optional function that reports whether
+	// the Save or Delete of the provided model must be wrapped in a
+	// transaction when the dao is not already in one (eg. because the
+	// write hooks persist outbox entries that must be committed together
+	// with the model change).
+	//
+	// In that case the after write hooks are executed after the commit.
+	TransactionalWriteFunc func(m models.Model) bool
+
+	// ctx is the optional context that is attached to all dao queries
+	// (see WithContext).
+	ctx context.Context
+
+	// txState holds the state of the current RunInTransaction call
+	// (nil if the dao is not in a transaction started by RunInTransaction).
+	txState *transactionState
+
 	// write hooks
 	BeforeCreateFunc func(eventDao *Dao, m models.Model, action func() error) error
 	AfterCreateFunc  func(eventDao *Dao, m models.Model) error
@@ -84,6 +142,37 @@ func (dao *Dao) Clone() *Dao {
 	return &clone
 }
 
+// WithContext returns a new Dao with the same configuration options
+// as the current one, but with ctx attached to all of its db queries.
+//
+// This is usually used to propagate the request trace context
+// to the executed db statements (note that ctx cancellation
+// will also cancel the running queries and transactions).
+func (dao *Dao) WithContext(ctx context.Context) *Dao {
+	new := dao.Clone()
+	new.ctx = ctx
+
+	if db, ok := dao.concurrentDB.(*dbx.DB); ok {
+		new.concurrentDB = db.WithContext(ctx)
+	}
+
+	if db, ok := dao.nonconcurrentDB.(*dbx.DB); ok {
+		new.nonconcurrentDB = db.WithContext(ctx)
+	}
+
+	return new
+}
+
+// Context returns the context attached to the dao with WithContext
+// (fallbacks to [context.Background] if there is none).
+func (dao *Dao) Context() context.Context {
+	if dao.ctx == nil {
+		return context.Background()
+	}
+
+	return dao.ctx
+}
+
 // WithoutHooks returns a new Dao with the same configuration options
 // as the current one, but without create/update/delete hooks.
 func (dao *Dao) WithoutHooks() *Dao {
@@ -95,6 +184,7 @@ func (dao *Dao) WithoutHooks() *Dao {
 	new.AfterUpdateFunc = nil
 	new.BeforeDeleteFunc = nil
 	new.AfterDeleteFunc = nil
+	new.TransactionalWriteFunc = nil
 
 	return new
 }
@@ -108,16 +198,72 @@ func (dao *Dao) ModelQuery(m models.Model) *dbx.SelectQuery {
 		Select("{{" + tableName + "}}.*").
 		From(tableName).
 		WithBuildHook(func(query *dbx.Query) {
-			query.WithExecHook(execLockRetry(dao.ModelQueryTimeout, dao.MaxLockRetries))
+			query.WithExecHook(dao.queryExecHook())
 		})
 }
 
+// queryExecHook returns the exec hook of the ModelQuery() and RecordQuery() statements
+// (the "database is locked" auto retry and the optional slow queries reporting).
+func (dao *Dao) queryExecHook() dbx.ExecHookFunc {
+	retryHook := execLockRetry(dao.ModelQueryTimeout, dao.MaxLockRetries)
+
+	if dao.SlowQueryFunc == nil || dao.SlowQueryThreshold <= 0 {
+		return retryHook
+	}
+
+	threshold, slowQueryFunc := dao.SlowQueryThreshold, dao.SlowQueryFunc
+
+	return func(q *dbx.Query, op func() error) error {
+		return retryHook(q, func() error {
+			start := time.Now()
+
+			err := op()
+
+			if duration := time.Since(start); duration >= threshold {
+				slowQueryFunc(q, duration, err)
+			}
+
+			return err
+		})
+	}
+}
+
 // FindById finds a single db record with the specified id and
 // scans the result into m.
 func (dao *Dao) FindById(m models.Model, id string) error {
 	return dao.ModelQuery(m).Where(dbx.HashExp{"id": id}).Limit(1).One(m)
 }
 
+// BeforeCommit registers fn to be executed within the current
+// transaction right before its commit (after the RunInTransaction
+// callback has returned without error).
+//
+// This is usually used to defer statements that acquire transaction
+// level locks so that the locks are held as short as possible.
+//
+// If the dao wasn't created by [Dao.RunInTransaction], fn is executed immediately.
+func (dao *Dao) BeforeCommit(fn func(txDao *Dao) error) error {
+	if dao.txState == nil {
+		return fn(dao)
+	}
+
+	dao.txState.beforeCommitCalls = append(dao.txState.beforeCommitCalls, fn)
+
+	return nil
+}
+
+// transactionState defines the state shared between the daos of a
+// single RunInTransaction call (incl. the nested ones).
+type transactionState struct {
+	// beforeCommitCalls holds the functions to execute right
+	// before the transaction commit (see BeforeCommit).
+	beforeCommitCalls []func(txDao *Dao) error
+
+	// recordChanges holds the not yet inserted changes log
+	// entries of the transaction (see QueueRecordChange).
+	recordChanges []*models.RecordChange
+}
+
 type afterCallGroup struct {
 	Action   string
 	EventDao *Dao
@@ -135,21 +281,30 @@ func (dao *Dao) RunInTransaction(fn func(txDao *Dao) error) error {
 		// ---
 		// create a new dao with the same hooks to avoid semaphore deadlock when nesting
 		txDao := New(txOrDB)
+		txDao.ctx = dao.ctx
 		txDao.MaxLockRetries = dao.MaxLockRetries
 		txDao.ModelQueryTimeout = dao.ModelQueryTimeout
+		txDao.SlowQueryThreshold = dao.SlowQueryThreshold
+		txDao.SlowQueryFunc = dao.SlowQueryFunc
 		txDao.BeforeCreateFunc = dao.BeforeCreateFunc
 		txDao.BeforeUpdateFunc = dao.BeforeUpdateFunc
 		txDao.BeforeDeleteFunc = dao.BeforeDeleteFunc
 		txDao.AfterCreateFunc = dao.AfterCreateFunc
 		txDao.AfterUpdateFunc = dao.AfterUpdateFunc
 		txDao.AfterDeleteFunc = dao.AfterDeleteFunc
+		txDao.txState = dao.txState
 
 		return fn(txDao)
 	case *dbx.DB:
 		afterCalls := []afterCallGroup{}
+		state := &transactionState{}
 
 		txError := txOrDB.Transactional(func(tx *dbx.Tx) error {
 			txDao := New(tx)
+			txDao.ctx = dao.ctx
+			txDao.SlowQueryThreshold = dao.SlowQueryThreshold
+			txDao.SlowQueryFunc = dao.SlowQueryFunc
+			txDao.txState = state
 
 			if dao.BeforeCreateFunc != nil {
 				txDao.BeforeCreateFunc = func(eventDao *Dao, m models.Model, action func() error) error {
@@ -186,7 +341,18 @@ func (dao *Dao) RunInTransaction(fn func(txDao *Dao) error) error {
 				}
 			}
 
-			return fn(txDao)
+			if err := fn(txDao); err != nil {
+				return err
+			}
+
+			// note: iterate by index since the calls could register new ones
+			for i := 0; i < len(state.beforeCommitCalls); i++ {
+				if err := state.beforeCommitCalls[i](txDao); err != nil {
+					return err
+				}
+			}
+
+			return nil
 		})
 		if txError != nil {
 			return txError
@@ -226,6 +392,12 @@ func (dao *Dao) Delete(m models.Model) error {
 		return errors.New("ID is not set")
 	}
 
+	if dao.isTransactionalWrite(m) {
+		return dao.RunInTransaction(func(txDao *Dao) error {
+			return txDao.Delete(m)
+		})
+	}
+
 	return dao.lockRetry(func(retryDao *Dao) error {
 		action := func() error {
 			if err := retryDao.NonconcurrentDB().Model(m).Delete(); err != nil {
@@ -252,6 +424,12 @@ func (dao *Dao) Delete(m models.Model) error {
 // If m.IsNew() is true, the method will perform a create, otherwise an update.
 // To explicitly mark a model for update you can use m.MarkAsNotNew().
 func (dao *Dao) Save(m models.Model) error {
+	if dao.isTransactionalWrite(m) {
+		return dao.RunInTransaction(func(txDao *Dao) error {
+			return txDao.Save(m)
+		})
+	}
+
 	if m.IsNew() {
 		return dao.lockRetry(func(retryDao *Dao) error {
 			return retryDao.create(m)
@@ -263,6 +441,20 @@ func (dao *Dao) Save(m models.Model) error {
 	})
 }
 
+// isTransactionalWrite reports whether the write of m must be
+// wrapped in a new transaction (see TransactionalWriteFunc).
+func (dao *Dao) isTransactionalWrite(m models.Model) bool {
+	if dao.TransactionalWriteFunc == nil {
+		return false
+	}
+
+	if _, ok := dao.NonconcurrentDB().(*dbx.DB); !ok {
+		return false // already in a transaction
+	}
+
+	return dao.TransactionalWriteFunc(m)
+}
+
 func (dao *Dao) update(m models.Model) error {
 	if !m.HasId() {
 		return errors.New("ID is not set")
@@ -365,6 +557,7 @@ func (dao *Dao) lockRetry(op func(retryDao *Dao) error) error {
 			retryDao.AfterCreateFunc = dao.AfterCreateFunc
 			retryDao.AfterUpdateFunc = dao.AfterUpdateFunc
 			retryDao.AfterDeleteFunc = dao.AfterDeleteFunc
+			retryDao.txState = dao.txState
 		}
 
 		return op(retryDao)
@@ -16,12 +16,19 @@ var defaultRetryIntervals = []int{100, 250, 350, 500, 700, 1000}
 
 func execLockRetry(timeout time.Duration, maxRetries int) dbx.ExecHookFunc {
 	return func(q *dbx.Query, op func() error) error {
-		if q.Context() == nil {
-			cancelCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		if _, hasDeadline := queryDeadline(q); !hasDeadline {
+			originalCtx := q.Context()
+
+			parentCtx := originalCtx
+			if parentCtx == nil {
+				parentCtx = context.Background()
+			}
+
+			cancelCtx, cancel := context.WithTimeout(parentCtx, timeout)
 			defer func() {
 				cancel()
 				//nolint:staticcheck
-				q.WithContext(nil) // reset
+				q.WithContext(originalCtx) // reset
 			}()
 			q.WithContext(cancelCtx)
 		}
@@ -37,6 +44,15 @@ func execLockRetry(timeout time.Duration, maxRetries int) dbx.ExecHookFunc {
 	}
 }
 
+// queryDeadline returns the deadline of the query context (if any).
+func queryDeadline(q *dbx.Query) (time.Time, bool) {
+	if q.Context() == nil {
+		return time.Time{}, false
+	}
+
+	return q.Context().Deadline()
+}
+
 func baseLockRetry(op func(attempt int) error, maxRetries int) error {
 	attempt := 1
 
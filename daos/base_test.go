@@ -2,6 +2,7 @@ package daos_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/hylarucoder/rocketbase/daos"
 	"github.com/hylarucoder/rocketbase/models"
 	"github.com/hylarucoder/rocketbase/tests"
+	"github.com/pocketbase/dbx"
 )
 
 func TestNew(t *testing.T) {
@@ -165,6 +167,9 @@ func TestDaoWithoutHooks(t *testing.T) {
 		hookCalls["AfterCreateFunc"]++
 		return nil
 	}
+	dao.TransactionalWriteFunc = func(m models.Model) bool {
+		return true
+	}
 
 	new := dao.WithoutHooks()
 
@@ -199,6 +204,10 @@ func TestDaoWithoutHooks(t *testing.T) {
 	if new.AfterCreateFunc != nil {
 		t.Fatal("Expected AfterCreateFunc to be nil")
 	}
+
+	if new.TransactionalWriteFunc != nil {
+		t.Fatal("Expected TransactionalWriteFunc to be nil")
+	}
 }
 
 func TestDaoModelQuery(t *testing.T) {
@@ -251,6 +260,52 @@ func TestDaoModelQueryCancellation(t *testing.T) {
 	}
 }
 
+func TestDaoSlowQueryFunc(t *testing.T) {
+	testApp, _ := tests.NewTestApp()
+	defer testApp.Cleanup()
+
+	dao := daos.New(testApp.DB())
+
+	var calls []string
+	dao.SlowQueryFunc = func(q *dbx.Query, duration time.Duration, err error) {
+		calls = append(calls, q.SQL())
+	}
+
+	m := &models.Admin{}
+
+	// disabled threshold
+	if err := dao.ModelQuery(m).One(m); err != nil {
+		t.Fatalf("Failed to execute control query: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("Expected no slow query calls with disabled threshold, got %v", calls)
+	}
+
+	// threshold above the query execution time
+	dao.SlowQueryThreshold = time.Hour
+	if err := dao.ModelQuery(m).One(m); err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("Expected no slow query calls, got %v", calls)
+	}
+
+	// threshold below the query execution time
+	dao.SlowQueryThreshold = time.Nanosecond
+	if err := dao.ModelQuery(m).One(m); err != nil {
+		t.Fatalf("Failed to execute model query: %v", err)
+	}
+	if _, err := dao.FindRecordsByFilter("demo1", "id != ''", "", 1, 0); err != nil {
+		t.Fatalf("Failed to execute record query: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 slow query calls, got %v", calls)
+	}
+	if !strings.Contains(calls[0], "_admins") || !strings.Contains(calls[1], "demo1") {
+		t.Fatalf("Expected the slow _admins and demo1 queries to be reported, got %v", calls)
+	}
+}
+
 func TestDaoFindById(t *testing.T) {
 	testApp, _ := tests.NewTestApp()
 	defer testApp.Cleanup()
@@ -331,6 +386,139 @@ func TestDaoRunInTransaction(t *testing.T) {
 	}
 }
 
+func TestDaoBeforeCommit(t *testing.T) {
+	testApp, _ := tests.NewTestApp()
+	defer testApp.Cleanup()
+
+	// outside of a transaction
+	calls := ""
+	err := testApp.Dao().BeforeCommit(func(txDao *daos.Dao) error {
+		calls += "a"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != "a" {
+		t.Fatalf("Expected the callback to be executed immediately, got calls %q", calls)
+	}
+
+	// successful nested transaction
+	calls = ""
+	err = testApp.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		txDao.BeforeCommit(func(txDao *daos.Dao) error {
+			calls += "b"
+			return txDao.BeforeCommit(func(txDao *daos.Dao) error {
+				calls += "d"
+				return nil
+			})
+		})
+
+		err := txDao.RunInTransaction(func(tx2Dao *daos.Dao) error {
+			return tx2Dao.BeforeCommit(func(txDao *daos.Dao) error {
+				calls += "c"
+				return nil
+			})
+		})
+
+		calls += "a"
+
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != "abcd" {
+		t.Fatalf("Expected calls %q, got %q", "abcd", calls)
+	}
+
+	// failing callback
+	err = testApp.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		admin, _ := txDao.FindAdminByEmail("test@example.com")
+
+		if err := txDao.DeleteAdmin(admin); err != nil {
+			t.Fatal(err)
+		}
+
+		return txDao.BeforeCommit(func(txDao *daos.Dao) error {
+			return errors.New("test error")
+		})
+	})
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	// admin should still exist
+	admin, _ := testApp.Dao().FindAdminByEmail("test@example.com")
+	if admin == nil {
+		t.Fatal("Expected admin test@example.com to not be deleted")
+	}
+}
+
+func TestDaoTransactionalWrite(t *testing.T) {
+	testApp, _ := tests.NewTestApp()
+	defer testApp.Cleanup()
+
+	calls := ""
+
+	isTxAdmin := func(m models.Model) bool {
+		admin, ok := m.(*models.Admin)
+		return ok && admin.Email == "test_tx_write@example.com"
+	}
+
+	dao := daos.NewMultiDB(testApp.Dao().ConcurrentDB(), testApp.Dao().NonconcurrentDB())
+	dao.TransactionalWriteFunc = isTxAdmin
+	dao.BeforeCreateFunc = func(eventDao *daos.Dao, m models.Model, action func() error) error {
+		if _, ok := eventDao.NonconcurrentDB().(*dbx.Tx); ok {
+			calls += "t"
+		}
+
+		if err := action(); err != nil {
+			return err
+		}
+
+		if !isTxAdmin(m) {
+			return nil
+		}
+
+		// simulate a failing outbox write
+		return eventDao.BeforeCommit(func(txDao *daos.Dao) error {
+			calls += "c"
+			return errors.New("test error")
+		})
+	}
+	dao.AfterCreateFunc = func(eventDao *daos.Dao, m models.Model) error {
+		calls += "a"
+		return nil
+	}
+
+	// wrapped in a transaction
+	admin := &models.Admin{}
+	admin.Email = "test_tx_write@example.com"
+	admin.SetPassword("1234567890")
+	if err := dao.Save(admin); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if calls != "tc" {
+		t.Fatalf("Expected calls %q, got %q", "tc", calls)
+	}
+	if found, _ := testApp.Dao().FindAdminByEmail(admin.Email); found != nil {
+		t.Fatal("Expected the admin create to be rolled back")
+	}
+
+	// not wrapped
+	calls = ""
+	other := &models.Admin{}
+	other.Email = "test_no_tx_write@example.com"
+	other.SetPassword("1234567890")
+	if err := dao.Save(other); err != nil {
+		t.Fatal(err)
+	}
+	if calls != "a" {
+		t.Fatalf("Expected calls %q, got %q", "a", calls)
+	}
+}
+
 func TestDaoSaveCreate(t *testing.T) {
 	testApp, _ := tests.NewTestApp()
 	defer testApp.Cleanup()
@@ -164,6 +164,10 @@ func (dao *Dao) DeleteCollection(collection *models.Collection) error {
 //
 // If collection.IsNew() is true, the method will perform a create, otherwise an update.
 // To explicitly mark a collection for update you can use collection.MarkAsNotNew().
+//
+// With enabled online schema changes, the fields with changed single/multiple
+// values state keep their old definition until their backfill completion
+// (see [SchemaBackfill]).
 func (dao *Dao) SaveCollection(collection *models.Collection) error {
 	var oldCollection *models.Collection
 
@@ -177,6 +181,10 @@ func (dao *Dao) SaveCollection(collection *models.Collection) error {
 		}
 	}
 
+	online := oldCollection != nil && dao.isOnlineSchemaChange()
+
+	var backfills []*SchemaBackfill
+
 	txErr := dao.RunInTransaction(func(txDao *Dao) error {
 		// set default collection type
 		if collection.Type == "" {
@@ -189,13 +197,26 @@ func (dao *Dao) SaveCollection(collection *models.Collection) error {
 				return err
 			}
 		default:
+			if online {
+				// keep the old definition of the converted fields until their backfill completion
+				var err error
+				backfills, err = txDao.deferSingleVsMultipleFieldChanges(collection, oldCollection)
+				if err != nil {
+					return err
+				}
+			}
+
 			// persist the collection model
 			if err := txDao.Save(collection); err != nil {
 				return err
 			}
 
 			// sync the changes with the related records table
-			if err := txDao.SyncRecordTableSchema(collection, oldCollection); err != nil {
+			if err := txDao.syncRecordTableSchema(collection, oldCollection, online); err != nil {
+				return err
+			}
+
+			if err := txDao.createSchemaBackfillShadows(collection, backfills); err != nil {
 				return err
 			}
 		}
@@ -211,6 +232,10 @@ func (dao *Dao) SaveCollection(collection *models.Collection) error {
 	// (ignoring view errors to allow users to update the query from the UI)
 	dao.resaveViewsWithChangedSchema(collection.Id)
 
+	if online && !collection.IsView() {
+		return dao.completeOnlineSchemaChanges(collection, oldCollection, backfills)
+	}
+
 	return nil
 }
 
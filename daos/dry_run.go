@@ -0,0 +1,58 @@
+package daos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/pocketbase/dbx"
+)
+
+var errDryRunRollback = errors.New("dry run rollback")
+
+// DryRun executes fn in a transaction that is always rolled back
+// and returns the SQL statements executed by fn (with the params inlined).
+//
+// Only the statements executed through the provided txDao are captured
+// and rolled back, so fn must not perform any other side effects
+// (eg. writes through a different Dao instance).
+//
+// On fn failure the already captured statements are returned
+// together with the fn error.
+func (dao *Dao) DryRun(fn func(txDao *Dao) error) ([]string, error) {
+	db, ok := dao.NonconcurrentDB().(*dbx.DB)
+	if !ok {
+		return nil, errors.New("dry run is not supported within an already started transaction")
+	}
+
+	statements := []string{}
+
+	captureDB := db.Clone()
+	execLogFunc := db.ExecLogFunc
+	captureDB.ExecLogFunc = func(ctx context.Context, t time.Duration, sql string, result sql.Result, err error) {
+		statements = append(statements, sql)
+
+		if execLogFunc != nil {
+			execLogFunc(ctx, t, sql, result, err)
+		}
+	}
+
+	dryDao := dao.Clone()
+	dryDao.concurrentDB = captureDB
+	dryDao.nonconcurrentDB = captureDB
+
+	err := dryDao.RunInTransaction(func(txDao *Dao) error {
+		if err := fn(txDao); err != nil {
+			return err
+		}
+
+		return errDryRunRollback
+	})
+
+	if err != nil && !errors.Is(err, errDryRunRollback) {
+		return statements, err
+	}
+
+	return statements, nil
+}
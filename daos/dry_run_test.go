@@ -0,0 +1,76 @@
+package daos_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/tests"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	statements, err := app.Dao().DryRun(func(txDao *daos.Dao) error {
+		collection, err := txDao.FindCollectionByNameOrId("demo1")
+		if err != nil {
+			return err
+		}
+
+		collection.Schema.AddField(&schema.SchemaField{
+			Name: "dry_run_field",
+			Type: schema.FieldTypeText,
+		})
+
+		return txDao.SaveCollection(collection)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := strings.Join(statements, ";\n")
+	if !strings.Contains(script, "ALTER TABLE") || !strings.Contains(script, "dry_run_field") {
+		t.Fatalf("Expected the ALTER TABLE statement to be captured, got\n%s", script)
+	}
+
+	// the changes must be rolled back
+	columns, err := app.Dao().TableColumns("demo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range columns {
+		if c == "dry_run_field" {
+			t.Fatal("Expected the dry run changes to be rolled back")
+		}
+	}
+
+	collection, err := app.Dao().FindCollectionByNameOrId("demo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if collection.Schema.GetFieldByName("dry_run_field") != nil {
+		t.Fatal("Expected the dry run collection changes to be rolled back")
+	}
+
+	// fn error
+	fnErr := errors.New("test")
+	_, err = app.Dao().DryRun(func(txDao *daos.Dao) error {
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("Expected fn error, got %v", err)
+	}
+
+	// nested transaction
+	app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		if _, err := txDao.DryRun(func(*daos.Dao) error { return nil }); err == nil {
+			t.Fatal("Expected nested transaction error")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,229 @@
+package daos
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+// JobQuery returns a new Job select query.
+func (dao *Dao) JobQuery() *dbx.SelectQuery {
+	return dao.ModelQuery(&models.Job{})
+}
+
+// FindJobById finds the job with the provided id.
+func (dao *Dao) FindJobById(id string) (*models.Job, error) {
+	model := &models.Job{}
+
+	err := dao.JobQuery().
+		AndWhere(dbx.HashExp{"id": id}).
+		Limit(1).
+		One(model)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// SaveJob upserts the provided Job model.
+func (dao *Dao) SaveJob(job *models.Job) error {
+	return dao.Save(job)
+}
+
+// DeleteJob deletes the provided Job model.
+func (dao *Dao) DeleteJob(job *models.Job) error {
+	return dao.Delete(job)
+}
+
+// EnqueueJob inserts the provided new job in the jobs queue.
+//
+// If the job has a UniqueKey and there is already another pending or
+// running job with the same key, no new job is inserted and the
+// provided model is populated with the existing job data instead.
+func (dao *Dao) EnqueueJob(job *models.Job) error {
+	if !job.IsNew() {
+		return errors.New("the job is already enqueued")
+	}
+
+	if job.Status == "" {
+		job.Status = models.JobStatusPending
+	}
+
+	if job.RunAt.IsZero() {
+		job.RunAt = types.NowDateTime()
+	}
+
+	if len(job.Payload) == 0 {
+		job.Payload = types.JsonRaw("{}")
+	}
+
+	err := dao.NonconcurrentDB().NewQuery(`
+		INSERT INTO {{_jobs}} ([[name]], [[payload]], [[status]], [[priority]], [[maxAttempts]], [[uniqueKey]], [[runAt]])
+		VALUES ({:name}, {:payload}, {:status}, {:priority}, {:maxAttempts}, {:uniqueKey}, {:runAt})
+		ON CONFLICT ([[uniqueKey]]) WHERE [[uniqueKey]] != '' AND [[status]] IN ('pending', 'running') DO NOTHING
+		RETURNING *
+	`).Bind(dbx.Params{
+		"name":        job.Name,
+		"payload":     job.Payload,
+		"status":      job.Status,
+		"priority":    job.Priority,
+		"maxAttempts": job.MaxAttempts,
+		"uniqueKey":   job.UniqueKey,
+		"runAt":       job.RunAt,
+	}).One(job)
+
+	if errors.Is(err, sql.ErrNoRows) && job.UniqueKey != "" {
+		// load the existing active job
+		return dao.JobQuery().
+			AndWhere(dbx.HashExp{"uniqueKey": job.UniqueKey}).
+			AndWhere(dbx.In("status", models.JobStatusPending, models.JobStatusRunning)).
+			Limit(1).
+			One(job)
+	}
+
+	return err
+}
+
+// ClaimJobs locks and returns up to limit due jobs with one of the
+// specified names ordered by their priority (highest first) and run time.
+//
+// The claimed jobs are marked as running, their attempts counter is
+// incremented and their RunAt is postponed with the lease duration
+// so that they could be claimed again if the current worker doesn't
+// finalize them in time (eg. because of a process crash).
+// A long running job lease could be extended with [Dao.RenewJobLease].
+//
+// The rows are selected with FOR UPDATE SKIP LOCKED allowing
+// multiple concurrent workers to claim different jobs.
+func (dao *Dao) ClaimJobs(names []string, limit int, lease time.Duration) ([]*models.Job, error) {
+	jobs := []*models.Job{}
+
+	if len(names) == 0 {
+		return jobs, nil
+	}
+
+	leaseUntil, err := types.ParseDateTime(time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+
+	params := dbx.Params{
+		"leaseUntil": leaseUntil,
+		"pending":    models.JobStatusPending,
+		"running":    models.JobStatusRunning,
+		"limit":      limit,
+	}
+
+	placeholders := make([]string, len(names))
+	for i, name := range names {
+		key := fmt.Sprintf("name%d", i)
+		params[key] = name
+		placeholders[i] = "{:" + key + "}"
+	}
+
+	err = dao.NonconcurrentDB().NewQuery(`
+		UPDATE {{_jobs}}
+		SET [[status]] = {:running}, [[attempts]] = [[attempts]] + 1, [[runAt]] = {:leaseUntil}, [[updated]] = NOW()
+		WHERE [[id]] IN (
+			SELECT [[id]] FROM {{_jobs}}
+			WHERE [[status]] IN ({:pending}, {:running}) AND [[runAt]] <= NOW() AND [[name]] IN (` + strings.Join(placeholders, ",") + `)
+			ORDER BY [[priority]] DESC, [[runAt]] ASC
+			LIMIT {:limit}
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`).Bind(params).All(&jobs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// ErrJobClaimLost is returned when a running job update is attempted
+// by a worker whose claim is no longer valid (eg. the job lease expired
+// and the job was claimed again by another worker).
+var ErrJobClaimLost = errors.New("the job claim is no longer valid")
+
+// RenewJobLease postpones the RunAt of the provided running job
+// with the lease duration so that it is not claimed by another worker.
+//
+// Returns [ErrJobClaimLost] if the job claim is no longer valid.
+func (dao *Dao) RenewJobLease(job *models.Job, lease time.Duration) error {
+	leaseUntil, err := types.ParseDateTime(time.Now().Add(lease))
+	if err != nil {
+		return err
+	}
+
+	return dao.updateClaimedJob(job, dbx.Params{"runAt": leaseUntil})
+}
+
+// SaveJobPayload persists the payload of the provided running job
+// (eg. to checkpoint the progress of a long running job).
+//
+// Returns [ErrJobClaimLost] if the job claim is no longer valid.
+func (dao *Dao) SaveJobPayload(job *models.Job) error {
+	return dao.updateClaimedJob(job, dbx.Params{"payload": job.Payload})
+}
+
+// FinalizeJob persists the status, error and run time of the provided
+// running job after its handler execution.
+//
+// Returns [ErrJobClaimLost] if the job claim is no longer valid
+// in which case the job state is left untouched.
+func (dao *Dao) FinalizeJob(job *models.Job) error {
+	return dao.updateClaimedJob(job, dbx.Params{
+		"status":    job.Status,
+		"lastError": job.LastError,
+		"runAt":     job.RunAt,
+		"payload":   job.Payload,
+	})
+}
+
+// updateClaimedJob updates the specified job columns only if the job
+// is still running with the same claim, aka. the same attempts counter
+// (it is incremented on every claim).
+func (dao *Dao) updateClaimedJob(job *models.Job, cols dbx.Params) error {
+	cols["updated"] = types.NowDateTime()
+
+	result, err := dao.NonconcurrentDB().Update(job.TableName(), cols, dbx.HashExp{
+		"id":       job.Id,
+		"status":   models.JobStatusRunning,
+		"attempts": job.Attempts,
+	}).Execute()
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrJobClaimLost
+	}
+
+	return nil
+}
+
+// DeleteOldJobs deletes all completed jobs finalized before the specified date.
+func (dao *Dao) DeleteOldJobs(date time.Time) error {
+	formattedDate := date.UTC().Format(types.DefaultDateLayout)
+
+	_, err := dao.NonconcurrentDB().Delete((&models.Job{}).TableName(), dbx.And(
+		dbx.NewExp("[[updated]] <= {:date}", dbx.Params{"date": formattedDate}),
+		dbx.HashExp{"status": models.JobStatusCompleted},
+	)).Execute()
+
+	return err
+}
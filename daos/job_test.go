@@ -0,0 +1,182 @@
+package daos_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tests"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+func TestEnqueueJobUniqueKey(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	job1 := &models.Job{Name: "test", UniqueKey: "abc", MaxAttempts: 1}
+	if err := app.Dao().EnqueueJob(job1); err != nil {
+		t.Fatal(err)
+	}
+
+	if job1.Id == "" || job1.IsNew() {
+		t.Fatalf("Expected the job to be persisted, got %v", job1)
+	}
+
+	if job1.Status != models.JobStatusPending {
+		t.Fatalf("Expected status %q, got %q", models.JobStatusPending, job1.Status)
+	}
+
+	// duplicated unique key
+	job2 := &models.Job{Name: "test", UniqueKey: "abc", MaxAttempts: 1}
+	if err := app.Dao().EnqueueJob(job2); err != nil {
+		t.Fatal(err)
+	}
+
+	if job2.Id != job1.Id {
+		t.Fatalf("Expected the existing job %q to be returned, got %q", job1.Id, job2.Id)
+	}
+
+	// finalized jobs don't block the unique key
+	job1.Status = models.JobStatusCompleted
+	if err := app.Dao().SaveJob(job1); err != nil {
+		t.Fatal(err)
+	}
+
+	job3 := &models.Job{Name: "test", UniqueKey: "abc", MaxAttempts: 1}
+	if err := app.Dao().EnqueueJob(job3); err != nil {
+		t.Fatal(err)
+	}
+
+	if job3.Id == job1.Id {
+		t.Fatal("Expected a new job to be created")
+	}
+
+	// already enqueued
+	if err := app.Dao().EnqueueJob(job3); err == nil {
+		t.Fatal("Expected error for already enqueued job")
+	}
+}
+
+func TestClaimJobs(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	future, _ := types.ParseDateTime(time.Now().Add(time.Hour))
+
+	jobs := []*models.Job{
+		{Name: "a", Priority: 0},
+		{Name: "a", Priority: 10},
+		{Name: "a", Priority: 20, RunAt: future}, // not due
+		{Name: "b", Priority: 30},                // not requested
+	}
+	for _, job := range jobs {
+		job.MaxAttempts = 3
+		if err := app.Dao().EnqueueJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	claimed, err := app.Dao().ClaimJobs([]string{"a"}, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(claimed) != 2 {
+		t.Fatalf("Expected 2 claimed jobs, got %d", len(claimed))
+	}
+
+	for _, job := range claimed {
+		if job.Id != jobs[0].Id && job.Id != jobs[1].Id {
+			t.Fatalf("Unexpected claimed job %v", job)
+		}
+
+		if job.Status != models.JobStatusRunning || job.Attempts != 1 {
+			t.Fatalf("Expected running job with 1 attempt, got %q, %d", job.Status, job.Attempts)
+		}
+	}
+
+	// the leased jobs shouldn't be claimed again
+	claimed, err = app.Dao().ClaimJobs([]string{"a"}, 10, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(claimed) != 0 {
+		t.Fatalf("Expected no claimed jobs, got %d", len(claimed))
+	}
+
+	// no names
+	claimed, err = app.Dao().ClaimJobs(nil, 10, time.Minute)
+	if err != nil || len(claimed) != 0 {
+		t.Fatalf("Expected no claimed jobs and no error, got %d (%v)", len(claimed), err)
+	}
+}
+
+func TestJobClaimUpdates(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	job := &models.Job{Name: "a", MaxAttempts: 3}
+	if err := app.Dao().EnqueueJob(job); err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := app.Dao().ClaimJobs([]string{"a"}, 1, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Expected 1 claimed job, got %d (%v)", len(claimed), err)
+	}
+	first := claimed[0]
+
+	if err := app.Dao().RenewJobLease(first, time.Minute); err != nil {
+		t.Fatalf("Expected the lease to be renewed, got %v", err)
+	}
+
+	first.Payload = types.JsonRaw(`{"progress":1}`)
+	if err := app.Dao().SaveJobPayload(first); err != nil {
+		t.Fatalf("Expected the payload to be saved, got %v", err)
+	}
+
+	// simulate lease expiration and claim the job again
+	_, err = app.Dao().DB().NewQuery("UPDATE {{_jobs}} SET [[runAt]] = NOW() - INTERVAL '1 second' WHERE [[id]] = {:id}").
+		Bind(dbx.Params{"id": first.Id}).
+		Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err = app.Dao().ClaimJobs([]string{"a"}, 1, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("Expected 1 reclaimed job, got %d (%v)", len(claimed), err)
+	}
+	second := claimed[0]
+
+	if !strings.Contains(string(second.Payload), `"progress"`) {
+		t.Fatalf("Expected the checkpointed payload, got %s", second.Payload)
+	}
+
+	// the stale claim updates must be rejected
+	first.Status = models.JobStatusCompleted
+	if err := app.Dao().FinalizeJob(first); !errors.Is(err, daos.ErrJobClaimLost) {
+		t.Fatalf("Expected ErrJobClaimLost, got %v", err)
+	}
+	if err := app.Dao().RenewJobLease(first, time.Minute); !errors.Is(err, daos.ErrJobClaimLost) {
+		t.Fatalf("Expected ErrJobClaimLost, got %v", err)
+	}
+
+	second.Status = models.JobStatusCompleted
+	if err := app.Dao().FinalizeJob(second); err != nil {
+		t.Fatalf("Expected the job to be finalized, got %v", err)
+	}
+
+	stored, err := app.Dao().FindJobById(job.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.JobStatusCompleted || stored.Attempts != 2 {
+		t.Fatalf("Expected completed job with 2 attempts, got %q, %d", stored.Status, stored.Attempts)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/settings"
 	"github.com/hylarucoder/rocketbase/tools/types"
 	"github.com/pocketbase/dbx"
 )
@@ -51,16 +52,133 @@ func (dao *Dao) LogsStats(expr dbx.Expression) ([]*LogsStatsItem, error) {
 	return result, err
 }
 
-// DeleteOldLogs delete all requests that are created before createdBefore.
+// logsPartitionPrefix is the name prefix of the daily _logs partitions
+// (the full name format is "_logs_pYYYYMMDD").
+const logsPartitionPrefix = "_logs_p"
+
+// LogsPartition represents a single daily partition of the _logs table.
+type LogsPartition = RangePartition
+
+// FindLogsPartitions returns the daily partitions of the _logs table
+// sorted by their start date (the default partition is not included).
+func (dao *Dao) FindLogsPartitions() ([]*LogsPartition, error) {
+	return dao.findRangePartitions(
+		(&models.Log{}).TableName(),
+		logsPartitionPrefix,
+		func(start time.Time) time.Time {
+			return start.AddDate(0, 0, 1)
+		},
+	)
+}
+
+// CreateLogsPartitions creates (if missing) the daily _logs partitions
+// for the specified number of days starting from the day of the from date (UTC).
+//
+// Days that already have logs in the default partition are skipped
+// (see [Dao.createRangePartition]).
+func (dao *Dao) CreateLogsPartitions(from time.Time, days int) error {
+	start := from.UTC().Truncate(24 * time.Hour)
+
+	for i := 0; i < days; i++ {
+		dayStart := start.AddDate(0, 0, i)
+
+		err := dao.createRangePartition(
+			(&models.Log{}).TableName(),
+			"_logs_default",
+			logsPartitionPrefix,
+			dayStart,
+			dayStart.AddDate(0, 0, 1),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteOldLogs delete all logs that are created before createdBefore.
+//
+// The daily partitions that are entirely before createdBefore are dropped
+// and the remaining matching logs are deleted with a regular DELETE statement.
 func (dao *Dao) DeleteOldLogs(createdBefore time.Time) error {
+	partitions, err := dao.FindLogsPartitions()
+	if err != nil {
+		return err
+	}
+
+	if err := dao.dropRangePartitions(partitions, createdBefore); err != nil {
+		return err
+	}
+
 	formattedDate := createdBefore.UTC().Format(types.DefaultDateLayout)
 	expr := dbx.NewExp("[[created]] <= {:date}", dbx.Params{"date": formattedDate})
 
-	_, err := dao.NonconcurrentDB().Delete((&models.Log{}).TableName(), expr).Execute()
+	_, err = dao.NonconcurrentDB().Delete((&models.Log{}).TableName(), expr).Execute()
 
 	return err
 }
 
+// DeleteExpiredLogs deletes the logs that are older than the
+// retention period of their level (see [settings.LogsConfig.LevelMaxDays]).
+//
+// The per level retention rules are applied only if config.MaxDays > 0,
+// otherwise all logs are deleted.
+func (dao *Dao) DeleteExpiredLogs(config settings.LogsConfig, now time.Time) error {
+	maxDays := config.MaxDays
+	if maxDays > 0 {
+		for _, r := range config.Retention {
+			maxDays = max(maxDays, r.MaxDays)
+		}
+	}
+
+	// drop all partitions and logs outside of the longest retention period
+	if err := dao.DeleteOldLogs(now.AddDate(0, 0, -maxDays)); err != nil {
+		return err
+	}
+
+	if maxDays == 0 {
+		return nil
+	}
+
+	table := (&models.Log{}).TableName()
+
+	levels := make([]any, 0, len(config.Retention))
+
+	for _, r := range config.Retention {
+		levels = append(levels, r.Level)
+
+		if r.MaxDays >= maxDays {
+			continue
+		}
+
+		_, err := dao.NonconcurrentDB().Delete(table, dbx.And(
+			dbx.HashExp{"level": r.Level},
+			dbx.NewExp("[[created]] <= {:date}", dbx.Params{
+				"date": now.AddDate(0, 0, -r.MaxDays).UTC().Format(types.DefaultDateLayout),
+			}),
+		)).Execute()
+		if err != nil {
+			return err
+		}
+	}
+
+	// the levels without explicit retention rule
+	if config.MaxDays < maxDays {
+		_, err := dao.NonconcurrentDB().Delete(table, dbx.And(
+			dbx.NotIn("level", levels...),
+			dbx.NewExp("[[created]] <= {:date}", dbx.Params{
+				"date": now.AddDate(0, 0, -config.MaxDays).UTC().Format(types.DefaultDateLayout),
+			}),
+		)).Execute()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // SaveLog upserts the provided Log model.
 func (dao *Dao) SaveLog(log *models.Log) error {
 	return dao.Save(log)
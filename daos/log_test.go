@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/settings"
 	"github.com/hylarucoder/rocketbase/tests"
 	"github.com/hylarucoder/rocketbase/tools/types"
 	"github.com/pocketbase/dbx"
@@ -115,6 +116,89 @@ func (suite *LogTestSuite) TestDeleteOldLogs() {
 	}
 }
 
+func (suite *LogTestSuite) TestCreateAndFindLogsPartitions() {
+	t := suite.T()
+	app := suite.App
+
+	tests.MockLogsData(app)
+
+	// 2022-05-01 has rows in the default partition and should be skipped
+	from := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	if err := app.LogsDao().CreateLogsPartitions(from, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	// should be no-op
+	if err := app.LogsDao().CreateLogsPartitions(from, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	partitions, err := app.LogsDao().FindLogsPartitions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]bool{}
+	for _, p := range partitions {
+		names[p.Name] = true
+
+		if p.End.Sub(p.Start) != 24*time.Hour {
+			t.Fatalf("Expected %s to be a daily partition, got %v - %v", p.Name, p.Start, p.End)
+		}
+	}
+
+	expected := map[string]bool{
+		"_logs_p20220501": false,
+		"_logs_p20220502": false,
+		"_logs_p20220503": true,
+		"_logs_p20220504": true,
+		"_logs_default":   false,
+	}
+	for name, exists := range expected {
+		if names[name] != exists {
+			t.Errorf("Expected partition %s exists to be %v", name, exists)
+		}
+	}
+}
+
+func (suite *LogTestSuite) TestDeleteExpiredLogs() {
+	t := suite.T()
+	app := suite.App
+
+	now := time.Date(2022, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	scenarios := []struct {
+		name          string
+		config        settings.LogsConfig
+		expectedTotal int
+	}{
+		{"disabled", settings.LogsConfig{}, 0},
+		{"max days", settings.LogsConfig{MaxDays: 8}, 1},
+		{"longer level retention", settings.LogsConfig{MaxDays: 1, Retention: []settings.LogsRetentionRule{{Level: 8, MaxDays: 30}}}, 1},
+		{"shorter level retention", settings.LogsConfig{MaxDays: 30, Retention: []settings.LogsRetentionRule{{Level: 8, MaxDays: 1}}}, 1},
+		{"longer retention for all", settings.LogsConfig{MaxDays: 30, Retention: []settings.LogsRetentionRule{{Level: 8, MaxDays: 60}}}, 2},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			tests.MockLogsData(app)
+
+			if err := app.LogsDao().DeleteExpiredLogs(s.config, now); err != nil {
+				t.Fatal(err)
+			}
+
+			var total int
+			if err := app.LogsDao().LogQuery().Select("count(*)").Row(&total); err != nil {
+				t.Fatal(err)
+			}
+
+			if total != s.expectedTotal {
+				t.Fatalf("Expected %d remaining logs, got %d", s.expectedTotal, total)
+			}
+		})
+	}
+}
+
 func (suite *LogTestSuite) TestSaveLog() {
 	t := suite.T()
 
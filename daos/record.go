@@ -64,7 +64,7 @@ func (dao *Dao) RecordQuery(collectionModelOrIdentifier any) *dbx.SelectQuery {
 	}
 
 	return query.WithBuildHook(func(q *dbx.Query) {
-		q.WithExecHook(execLockRetry(dao.ModelQueryTimeout, dao.MaxLockRetries)).
+		q.WithExecHook(dao.queryExecHook()).
 			WithOneHook(func(q *dbx.Query, a any, op func(b any) error) error {
 				switch v := a.(type) {
 				case *models.Record:
@@ -0,0 +1,64 @@
+package daos
+
+import (
+	"github.com/hylarucoder/rocketbase/resolvers"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/pocketbase/dbx"
+)
+
+// AggregateRecords returns the grouped aggregate values of the
+// collection records matching the provided (optional) filter.
+//
+// groupBy is a comma separated list of record fields (including relation paths)
+// and aggregates is a comma separated list of count(), sum(field),
+// avg(field), min(field) and max(field) calls.
+//
+// NB! Use the last params argument to bind untrusted user variables!
+//
+// Example:
+//
+//	dao.AggregateRecords(
+//		"orders",
+//		"status,author.name",
+//		"count(),sum(total)",
+//		"created >= {:date}",
+//		dbx.Params{"date": "2024-01-01 00:00:00"},
+//	)
+func (dao *Dao) AggregateRecords(
+	collectionNameOrId string,
+	groupBy string,
+	aggregates string,
+	filter string,
+	params ...dbx.Params,
+) ([]map[string]any, error) {
+	collection, err := dao.FindCollectionByNameOrId(collectionNameOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := resolvers.NewRecordFieldResolver(
+		dao,
+		collection, // the base collection
+		nil,        // no request data
+		true,       // allow aggregating hidden/protected fields like "email"
+	)
+
+	aggregateFields, err := search.ParseAggregatesFromString(aggregates)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregator := search.NewAggregator(resolver).
+		Query(dao.RecordQuery(collection)).
+		GroupBy(search.ParseGroupByFromString(groupBy)).
+		Aggregates(aggregateFields).
+		AddFilter(search.FilterData(filter)).
+		FilterParams(params...)
+
+	result, err := aggregator.Exec()
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Items, nil
+}
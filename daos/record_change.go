@@ -0,0 +1,165 @@
+package daos
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+// RecordChangeQuery returns a new RecordChange select query.
+func (dao *Dao) RecordChangeQuery() *dbx.SelectQuery {
+	tableName := (&models.RecordChange{}).TableName()
+
+	return dao.DB().Select("{{" + tableName + "}}.*").From(tableName)
+}
+
+// CreateRecordChange appends a new entry to the records changes log
+// and populates the provided model with the inserted row data.
+//
+// The insert acquires a transaction level advisory lock per collection
+// so that the sequence numbers of the same collection changes are
+// assigned in their commit order (aka. a client that has already
+// synced up to a certain seq will not miss a later committed lower seq).
+//
+// Note that when used inside a transaction the lock is held until the
+// transaction completion, so the record write operations defer the insert
+// with [Dao.QueueRecordChange] and only their commits are serialized
+// (the rest of the concurrent transactions are not affected).
+func (dao *Dao) CreateRecordChange(change *models.RecordChange) error {
+	return dao.NonconcurrentDB().NewQuery(`
+		INSERT INTO {{_recordChanges}} ([[collectionId]], [[recordId]], [[action]], [[data]])
+		SELECT {:collectionId}, {:recordId}, {:action}, {:data}
+		FROM (SELECT pg_advisory_xact_lock(hashtext({:lockKey}))) AS [[lock]]
+		RETURNING *
+	`).Bind(dbx.Params{
+		"collectionId": change.CollectionId,
+		"recordId":     change.RecordId,
+		"action":       change.Action,
+		"data":         change.Data,
+		"lockKey":      change.TableName() + "_" + change.CollectionId,
+	}).One(change)
+}
+
+// QueueRecordChange schedules the provided entry to be appended to the
+// records changes log right before the current transaction commit.
+//
+// All queued entries of the transaction are inserted together, ordered
+// by their collection id, so that the concurrent transactions acquire the
+// collections changes log locks in the same order and don't deadlock.
+//
+// If the dao wasn't created by [Dao.RunInTransaction], the entry is inserted immediately.
+func (dao *Dao) QueueRecordChange(change *models.RecordChange) error {
+	if dao.txState == nil {
+		return dao.CreateRecordChange(change)
+	}
+
+	if len(dao.txState.recordChanges) == 0 {
+		if err := dao.BeforeCommit(insertQueuedRecordChanges); err != nil {
+			return err
+		}
+	}
+
+	dao.txState.recordChanges = append(dao.txState.recordChanges, change)
+
+	return nil
+}
+
+func insertQueuedRecordChanges(txDao *Dao) error {
+	changes := txDao.txState.recordChanges
+	txDao.txState.recordChanges = nil
+
+	// note: stable to preserve the write order of the same collection changes
+	slices.SortStableFunc(changes, func(a, b *models.RecordChange) int {
+		return strings.Compare(a.CollectionId, b.CollectionId)
+	})
+
+	for _, change := range changes {
+		if err := txDao.CreateRecordChange(change); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindRecordChangeSnapshot returns the current db row state of the
+// specified record (without the auth secrets) as a changes log entry snapshot.
+//
+// Returns nil if the record doesn't exist.
+func (dao *Dao) FindRecordChangeSnapshot(collection *models.Collection, recordId string) (types.JsonRaw, error) {
+	var snapshot types.JsonRaw
+
+	err := dao.NonconcurrentDB().NewQuery(fmt.Sprintf(
+		"SELECT to_jsonb([[t]]) - '%s' - '%s' FROM {{%s}} AS [[t]] WHERE [[t.id]] = {:id}",
+		schema.FieldNamePasswordHash,
+		schema.FieldNameTokenKey,
+		collection.Name,
+	)).Bind(dbx.Params{"id": recordId}).Row(&snapshot)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return snapshot, err
+}
+
+// FindRecordChanges returns up to limit collection changes
+// with seq greater than the provided one, ordered by their seq.
+func (dao *Dao) FindRecordChanges(collectionId string, since int64, limit int) ([]*models.RecordChange, error) {
+	changes := []*models.RecordChange{}
+
+	err := dao.RecordChangeQuery().
+		AndWhere(dbx.HashExp{"collectionId": collectionId}).
+		AndWhere(dbx.NewExp("[[seq]] > {:since}", dbx.Params{"since": since})).
+		OrderBy("seq ASC").
+		Limit(int64(limit)).
+		All(&changes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// FindOldestRecordChangeSeq returns the seq of the oldest retained
+// collection changes log entry or 0 if the collection has no entries.
+func (dao *Dao) FindOldestRecordChangeSeq(collectionId string) (int64, error) {
+	var seq int64
+
+	err := dao.RecordChangeQuery().
+		Select("COALESCE(MIN([[seq]]), 0)").
+		AndWhere(dbx.HashExp{"collectionId": collectionId}).
+		Row(&seq)
+
+	return seq, err
+}
+
+// DeleteOldRecordChanges deletes all changes log entries created before the specified date.
+//
+// The latest entry of each collection is always kept so that a client
+// that has already synced up to it can continue without a full resync
+// (see [Dao.FindOldestRecordChangeSeq]).
+func (dao *Dao) DeleteOldRecordChanges(date time.Time) error {
+	formattedDate := date.UTC().Format(types.DefaultDateLayout)
+
+	tableName := (&models.RecordChange{}).TableName()
+
+	_, err := dao.NonconcurrentDB().Delete(tableName, dbx.And(
+		dbx.NewExp("[[created]] <= {:date}", dbx.Params{"date": formattedDate}),
+		dbx.NewExp(fmt.Sprintf(
+			"[[seq]] NOT IN (SELECT MAX([[seq]]) FROM {{%s}} GROUP BY [[collectionId]])",
+			tableName,
+		)),
+	)).Execute()
+
+	return err
+}
@@ -0,0 +1,163 @@
+package daos_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tests"
+)
+
+func TestRecordChanges(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := []string{
+		models.RecordChangeActionCreate,
+		models.RecordChangeActionUpdate,
+		models.RecordChangeActionDelete,
+	}
+
+	var lastSeq int64
+	for _, action := range actions {
+		change := &models.RecordChange{
+			CollectionId: collection.Id,
+			RecordId:     "test",
+			Action:       action,
+		}
+
+		if err := app.Dao().CreateRecordChange(change); err != nil {
+			t.Fatal(err)
+		}
+
+		if change.Seq <= lastSeq {
+			t.Fatalf("Expected seq greater than %d, got %d", lastSeq, change.Seq)
+		}
+		lastSeq = change.Seq
+	}
+
+	all, err := app.Dao().FindRecordChanges(collection.Id, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 changes, got %d", len(all))
+	}
+
+	since, err := app.Dao().FindRecordChanges(collection.Id, all[0].Seq, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(since) != 1 || since[0].Seq != all[1].Seq {
+		t.Fatalf("Expected only change %d, got %v", all[1].Seq, since)
+	}
+
+	oldest, err := app.Dao().FindOldestRecordChangeSeq(collection.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldest != all[0].Seq {
+		t.Fatalf("Expected oldest seq %d, got %d", all[0].Seq, oldest)
+	}
+
+	missing, err := app.Dao().FindOldestRecordChangeSeq("missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != 0 {
+		t.Fatalf("Expected 0 oldest seq for collection without changes, got %d", missing)
+	}
+
+	if err := app.Dao().DeleteOldRecordChanges(time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the latest collection entry is always retained
+	oldest, err = app.Dao().FindOldestRecordChangeSeq(collection.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oldest != all[2].Seq {
+		t.Fatalf("Expected oldest seq after the cleanup %d, got %d", all[2].Seq, oldest)
+	}
+}
+
+func TestQueueRecordChange(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	demo2, err := app.Dao().FindCollectionByNameOrId("demo2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	demo3, err := app.Dao().FindCollectionByNameOrId("demo3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := demo2, demo3
+	if first.Id > second.Id {
+		first, second = second, first
+	}
+
+	err = app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		queue := []*models.RecordChange{
+			{CollectionId: second.Id, RecordId: "a", Action: models.RecordChangeActionCreate},
+			{CollectionId: first.Id, RecordId: "b", Action: models.RecordChangeActionCreate},
+			{CollectionId: second.Id, RecordId: "c", Action: models.RecordChangeActionCreate},
+		}
+
+		for _, change := range queue {
+			if err := txDao.QueueRecordChange(change); err != nil {
+				return err
+			}
+		}
+
+		// the changes must be inserted right before the commit
+		for _, c := range []*models.Collection{first, second} {
+			changes, err := txDao.FindRecordChanges(c.Id, 0, 10)
+			if err != nil {
+				return err
+			}
+			if len(changes) != 0 {
+				t.Fatalf("Expected no inserted changes before the commit, got %d", len(changes))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firstChanges, err := app.Dao().FindRecordChanges(first.Id, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondChanges, err := app.Dao().FindRecordChanges(second.Id, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(firstChanges) != 1 || len(secondChanges) != 2 {
+		t.Fatalf("Expected 1 and 2 changes, got %d and %d", len(firstChanges), len(secondChanges))
+	}
+
+	// inserted in collection id order
+	if firstChanges[0].Seq > secondChanges[0].Seq {
+		t.Fatalf("Expected the %s changes to be inserted first", first.Name)
+	}
+
+	// preserved write order of the same collection changes
+	if secondChanges[0].RecordId != "a" || secondChanges[1].RecordId != "c" {
+		t.Fatalf("Expected records order [a, c], got [%s, %s]", secondChanges[0].RecordId, secondChanges[1].RecordId)
+	}
+}
@@ -13,8 +13,10 @@ import (
 	//"github.com/hylarucoder/rocketbase/tools/inflector"
 	"github.com/hylarucoder/rocketbase/tools/list"
 	"github.com/hylarucoder/rocketbase/tools/security"
+	"github.com/hylarucoder/rocketbase/tools/tracing"
 	"github.com/hylarucoder/rocketbase/tools/types"
 	"github.com/pocketbase/dbx"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // MaxExpandDepth specifies the max allowed nested expand depth path.
@@ -44,6 +46,17 @@ func (dao *Dao) ExpandRecord(record *models.Record, expands []string, optFetchFu
 func (dao *Dao) ExpandRecords(records []*models.Record, expands []string, optFetchFunc ExpandFetchFunc) map[string]error {
 	normalized := normalizeExpands(expands)
 
+	ctx, span := tracing.StartChild(
+		dao.Context(),
+		"ExpandRecords",
+		attribute.StringSlice("expand", normalized),
+		attribute.Int("records", len(records)),
+	)
+	if span.IsRecording() {
+		// nest the expand queries under the expand span
+		dao = dao.WithContext(ctx)
+	}
+
 	failed := map[string]error{}
 
 	for _, expand := range normalized {
@@ -52,6 +65,12 @@ func (dao *Dao) ExpandRecords(records []*models.Record, expands []string, optFet
 		}
 	}
 
+	if len(failed) > 0 {
+		tracing.End(span, fmt.Errorf("failed to expand: %v", failed))
+	} else {
+		tracing.End(span, nil)
+	}
+
 	return failed
 }
 
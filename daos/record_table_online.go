@@ -0,0 +1,640 @@
+package daos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/tools/dbutils"
+	"github.com/hylarucoder/rocketbase/tools/list"
+	"github.com/pocketbase/dbx"
+)
+
+// DefaultSchemaBackfillBatchSize is the default number of rows
+// converted by a single [Dao.SchemaBackfillBatch] call.
+const DefaultSchemaBackfillBatchSize = 1000
+
+// onlineSchemaLockTimeout is the max time that the online schema changes
+// transaction waits to acquire a table lock before failing.
+const onlineSchemaLockTimeout = "5s"
+
+// SchemaBackfill defines a pending online field values conversion.
+//
+// The converted values are copied in batches to a shadow column with
+// the new field definition, which is kept in sync with the concurrent
+// record writes by a table trigger. Until the backfill completes, the
+// collection keeps the old field definition and the original column
+// remains the one that is read and written.
+//
+// After the last batch the original column is dropped, the shadow
+// column is renamed to the field name and the new field definition
+// is saved in a single short transaction.
+type SchemaBackfill struct {
+	CollectionId string `json:"collectionId"`
+	FieldId      string `json:"fieldId"`
+	ShadowColumn string `json:"shadowColumn"`
+	ToMultiple   bool   `json:"toMultiple"`
+
+	// Field is the new field definition that is saved after the backfill completion.
+	Field *schema.SchemaField `json:"field"`
+
+	// progress state
+	LastId    string `json:"lastId"`
+	Processed int64  `json:"processed"`
+	Total     int64  `json:"total"`
+	Done      bool   `json:"done"`
+}
+
+// SchemaIndexesBuild defines a pending online (concurrent) build
+// of the collection indexes after the collection save commit.
+type SchemaIndexesBuild struct {
+	CollectionId string `json:"collectionId"`
+
+	// OldIndexes are the collection indexes before the save
+	// (aka. the indexes that are expected to exist in the db).
+	OldIndexes []string `json:"oldIndexes"`
+}
+
+// RunSchemaIndexesBuild drops the removed and (re)builds the new or changed
+// indexes of the current collection state with DROP/CREATE INDEX CONCURRENTLY
+// (see [Dao.OnlineSchemaChangesFunc]).
+//
+// It is no-op if the collection was deleted meanwhile.
+func (dao *Dao) RunSchemaIndexesBuild(build *SchemaIndexesBuild) error {
+	collection, err := dao.FindCollectionByNameOrId(build.CollectionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	oldCollection := *collection
+	oldCollection.Indexes = build.OldIndexes
+
+	return dao.syncCollectionIndexesConcurrently(collection, &oldCollection)
+}
+
+// RunSchemaBackfill runs the remaining batches of the provided backfill
+// until its completion or ctx cancellation.
+//
+// The optional progress function is called after each batch
+// (eg. to checkpoint the backfill state).
+func (dao *Dao) RunSchemaBackfill(
+	ctx context.Context,
+	backfill *SchemaBackfill,
+	batchSize int,
+	progress func(backfill *SchemaBackfill) error,
+) error {
+	for !backfill.Done {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := dao.SchemaBackfillBatch(backfill, batchSize); err != nil {
+			return err
+		}
+
+		if progress != nil {
+			if err := progress(backfill); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SchemaBackfillBatch converts the next batch of the backfill rows
+// (ordered by their id) and updates the backfill progress state.
+//
+// When there are no more rows, the shadow column is swapped with the
+// original one and the backfill is marked as done.
+// If the field was deleted or converted meanwhile, the shadow column
+// is dropped and the backfill is marked as done without a swap.
+func (dao *Dao) SchemaBackfillBatch(backfill *SchemaBackfill, batchSize int) error {
+	if backfill.Done {
+		return nil
+	}
+
+	if batchSize <= 0 {
+		batchSize = DefaultSchemaBackfillBatchSize
+	}
+
+	collection, err := dao.FindCollectionByNameOrId(backfill.CollectionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// the collection and its table (incl. the trigger) were deleted
+			_, err := dao.DB().NewQuery(fmt.Sprintf(
+				"DROP FUNCTION IF EXISTS [[%s]]()",
+				schemaBackfillTriggerName(backfill.CollectionId, backfill.FieldId),
+			)).Execute()
+			if err != nil {
+				return err
+			}
+
+			backfill.Done = true
+			return nil
+		}
+		return err
+	}
+
+	columns, err := dao.TableColumns(collection.Name)
+	if err != nil {
+		return err
+	}
+
+	if !list.ExistInSlice(backfill.ShadowColumn, columns) {
+		// already completed or aborted
+		backfill.Done = true
+		return nil
+	}
+
+	field := collection.Schema.GetFieldById(backfill.FieldId)
+	if field == nil || isMultipleField(field) == backfill.ToMultiple {
+		// the field was deleted or converted by other means so there is nothing to swap
+		if err := dao.dropSchemaBackfillShadow(collection, backfill.FieldId); err != nil {
+			return err
+		}
+
+		backfill.Done = true
+		return nil
+	}
+
+	if backfill.Total == 0 {
+		err := dao.DB().Select("count(*)").From(collection.Name).Row(&backfill.Total)
+		if err != nil {
+			return err
+		}
+	}
+
+	var batchTotal int64
+	var batchLastId string
+
+	err = dao.NonconcurrentDB().NewQuery(fmt.Sprintf(
+		`WITH batch AS (
+			SELECT [[id]] FROM {{%[1]s}}
+			WHERE [[id]] > {:lastId}
+			ORDER BY [[id]]
+			LIMIT {:limit}
+		), updated AS (
+			UPDATE {{%[1]s}} SET [[%[2]s]] = %[3]s
+			FROM batch
+			WHERE {{%[1]s}}.[[id]] = batch.[[id]]
+		)
+		SELECT count(*), COALESCE(max([[id]]), '') FROM batch`,
+		collection.Name,
+		backfill.ShadowColumn,
+		schemaBackfillCopyExpr(collection.Name+"."+field.Name, backfill.ToMultiple),
+	)).Bind(dbx.Params{
+		"lastId": backfill.LastId,
+		"limit":  batchSize,
+	}).Row(&batchTotal, &batchLastId)
+	if err != nil {
+		return err
+	}
+
+	if batchTotal == 0 {
+		return dao.swapSchemaBackfill(backfill)
+	}
+
+	backfill.LastId = batchLastId
+	backfill.Processed += batchTotal
+
+	return nil
+}
+
+// swapSchemaBackfill replaces the original field column with the
+// backfilled shadow one and saves the new field definition.
+//
+// The indexes that were dropped together with the original column
+// are rebuilt after the swap commit.
+func (dao *Dao) swapSchemaBackfill(backfill *SchemaBackfill) error {
+	var collection *models.Collection
+
+	err := dao.RunInTransaction(func(txDao *Dao) error {
+		// fail fast instead of blocking all table queries
+		_, err := txDao.DB().NewQuery("SET LOCAL lock_timeout = '" + onlineSchemaLockTimeout + "'").Execute()
+		if err != nil {
+			return err
+		}
+
+		collection, err = txDao.FindCollectionByNameOrId(backfill.CollectionId)
+		if err != nil {
+			return err
+		}
+
+		field := collection.Schema.GetFieldById(backfill.FieldId)
+		if field == nil || isMultipleField(field) == backfill.ToMultiple {
+			// changed after the last batch
+			collection = nil
+			return nil
+		}
+
+		if err := txDao.dropSchemaBackfillTrigger(collection, backfill.FieldId); err != nil {
+			return err
+		}
+
+		if _, err := txDao.DB().DropColumn(collection.Name, field.Name).Execute(); err != nil {
+			return err
+		}
+
+		if _, err := txDao.DB().RenameColumn(collection.Name, backfill.ShadowColumn, field.Name).Execute(); err != nil {
+			return err
+		}
+
+		newField := *backfill.Field
+		newField.Name = field.Name
+		collection.Schema.AddField(&newField)
+
+		return txDao.Save(collection)
+	})
+	if err != nil {
+		return err
+	}
+
+	backfill.Done = true
+
+	if collection == nil {
+		return nil // the field was changed meanwhile and will be cleaned up by the next batch
+	}
+
+	dao.resaveViewsWithChangedSchema(collection.Id)
+
+	// rebuild the indexes that were dropped together with the original column
+	return dao.syncCollectionIndexesConcurrently(collection, collection)
+}
+
+// -------------------------------------------------------------------
+
+// isOnlineSchemaChange reports whether the record table changes
+// should be applied online with the current dao.
+//
+// The online changes require autocommit statements and therefore
+// they are never applied within an already started transaction.
+func (dao *Dao) isOnlineSchemaChange() bool {
+	if dao.OnlineSchemaChangesFunc == nil || !dao.OnlineSchemaChangesFunc() {
+		return false
+	}
+
+	_, ok := dao.NonconcurrentDB().(*dbx.DB)
+
+	return ok
+}
+
+// completeOnlineSchemaChanges applies the collection record table changes
+// that must run outside of a transaction, aka. the concurrent indexes
+// builds and the field conversions backfills scheduling.
+//
+// The indexes errors (if any) are returned after the backfills are scheduled.
+func (dao *Dao) completeOnlineSchemaChanges(
+	newCollection *models.Collection,
+	oldCollection *models.Collection,
+	backfills []*SchemaBackfill,
+) error {
+	var indexesErr error
+	if dao.SchemaIndexesBuildFunc != nil {
+		indexesErr = dao.SchemaIndexesBuildFunc(&SchemaIndexesBuild{
+			CollectionId: newCollection.Id,
+			OldIndexes:   oldCollection.Indexes,
+		})
+	} else {
+		indexesErr = dao.syncCollectionIndexesConcurrently(newCollection, oldCollection)
+	}
+
+	for _, backfill := range backfills {
+		var err error
+
+		if dao.SchemaBackfillFunc != nil {
+			err = dao.SchemaBackfillFunc(backfill)
+		} else {
+			err = dao.RunSchemaBackfill(dao.Context(), backfill, 0, nil)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to run the %s field backfill - %w", backfill.FieldId, err)
+		}
+	}
+
+	return indexesErr
+}
+
+// deferSingleVsMultipleFieldChanges reverts the newCollection fields with
+// changed single/multiple values state to their old definition (preserving
+// their new name) and returns the pending backfills of the new definitions.
+//
+// The fields with already pending backfill are left unchanged
+// (see checkPendingSchemaBackfills).
+func (dao *Dao) deferSingleVsMultipleFieldChanges(newCollection, oldCollection *models.Collection) ([]*SchemaBackfill, error) {
+	backfills := []*SchemaBackfill{}
+
+	if newCollection.IsView() {
+		return backfills, nil
+	}
+
+	columns, err := dao.TableColumns(oldCollection.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, newField := range newCollection.Schema.Fields() {
+		oldField := oldCollection.Schema.GetFieldById(newField.Id)
+		if oldField == nil {
+			continue // new field
+		}
+
+		isNewMultiple := isMultipleField(newField)
+		if isMultipleField(oldField) == isNewMultiple {
+			continue // no change
+		}
+
+		shadowColumn := schemaBackfillShadowColumn(newField.Id)
+		if list.ExistInSlice(shadowColumn, columns) {
+			continue // already pending
+		}
+
+		deferredField := *oldField
+		deferredField.Name = newField.Name
+		newCollection.Schema.AddField(&deferredField)
+
+		backfills = append(backfills, &SchemaBackfill{
+			CollectionId: newCollection.Id,
+			FieldId:      newField.Id,
+			ShadowColumn: shadowColumn,
+			ToMultiple:   isNewMultiple,
+			Field:        newField,
+		})
+	}
+
+	return backfills, nil
+}
+
+// createSchemaBackfillShadows adds the shadow column of each of the
+// provided backfills together with a trigger that keeps it in sync
+// with the original column on every record insert and update.
+//
+// None of the operations rewrites the table (the shadow column is
+// added with a constant default value).
+func (dao *Dao) createSchemaBackfillShadows(collection *models.Collection, backfills []*SchemaBackfill) error {
+	for _, backfill := range backfills {
+		field := collection.Schema.GetFieldById(backfill.FieldId)
+		if field == nil {
+			return fmt.Errorf("missing backfill field %s", backfill.FieldId)
+		}
+
+		_, err := dao.DB().AddColumn(collection.Name, backfill.ShadowColumn, backfill.Field.ColDefinition()).Execute()
+		if err != nil {
+			return err
+		}
+
+		triggerName := schemaBackfillTriggerName(collection.Id, backfill.FieldId)
+
+		_, err = dao.DB().NewQuery(fmt.Sprintf(
+			`CREATE OR REPLACE FUNCTION [[%[1]s]]() RETURNS trigger AS $$
+			BEGIN
+				NEW.[[%[2]s]] := %[3]s;
+				RETURN NEW;
+			END
+			$$ LANGUAGE plpgsql;
+
+			CREATE TRIGGER [[%[1]s]] BEFORE INSERT OR UPDATE ON {{%[4]s}}
+			FOR EACH ROW EXECUTE FUNCTION [[%[1]s]]();`,
+			triggerName,
+			backfill.ShadowColumn,
+			schemaBackfillCopyExpr("new."+field.Name, backfill.ToMultiple),
+			collection.Name,
+		)).Execute()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkPendingSchemaBackfills checks the fields with pending backfill
+// (aka. existing shadow column) and returns a validation error if any
+// of them was changed.
+//
+// The shadow column of a deleted field is dropped.
+func (dao *Dao) checkPendingSchemaBackfills(newCollection, oldCollection *models.Collection) error {
+	columns, err := dao.TableColumns(oldCollection.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, oldField := range oldCollection.Schema.Fields() {
+		if !list.ExistInSlice(schemaBackfillShadowColumn(oldField.Id), columns) {
+			continue
+		}
+
+		newField := newCollection.Schema.GetFieldById(oldField.Id)
+		if newField == nil {
+			// deleted
+			if err := dao.dropSchemaBackfillShadow(oldCollection, oldField.Id); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if newField.String() != oldField.String() {
+			return validation.Errors{"schema": validation.NewError(
+				"validation_pending_field_conversion",
+				fmt.Sprintf("The field %q has a pending online conversion. Please wait for it to complete before changing the field.", oldField.Name),
+			)}
+		}
+	}
+
+	return nil
+}
+
+// dropSchemaBackfillShadow drops the backfill trigger and shadow column of the specified field.
+func (dao *Dao) dropSchemaBackfillShadow(collection *models.Collection, fieldId string) error {
+	if err := dao.dropSchemaBackfillTrigger(collection, fieldId); err != nil {
+		return err
+	}
+
+	_, err := dao.DB().NewQuery(fmt.Sprintf(
+		"ALTER TABLE {{%s}} DROP COLUMN IF EXISTS [[%s]]",
+		collection.Name,
+		schemaBackfillShadowColumn(fieldId),
+	)).Execute()
+
+	return err
+}
+
+// dropSchemaBackfillTrigger drops the backfill trigger (and its function) of the specified field.
+func (dao *Dao) dropSchemaBackfillTrigger(collection *models.Collection, fieldId string) error {
+	triggerName := schemaBackfillTriggerName(collection.Id, fieldId)
+
+	_, err := dao.DB().NewQuery(fmt.Sprintf(
+		"DROP TRIGGER IF EXISTS [[%[1]s]] ON {{%[2]s}}; DROP FUNCTION IF EXISTS [[%[1]s]]();",
+		triggerName,
+		collection.Name,
+	)).Execute()
+
+	return err
+}
+
+// schemaBackfillShadowColumn returns the shadow column name of the specified field backfill.
+func schemaBackfillShadowColumn(fieldId string) string {
+	return "_backfill_" + fieldId
+}
+
+// schemaBackfillTriggerName returns the trigger and trigger function
+// name of the specified field backfill.
+func schemaBackfillTriggerName(collectionId, fieldId string) string {
+	return "_backfill_" + collectionId + "_" + fieldId
+}
+
+// schemaBackfillCopyExpr returns the SQL expression that converts the
+// provided source column value to the single or multiple values format.
+func schemaBackfillCopyExpr(column string, toMultiple bool) string {
+	if toMultiple {
+		return singleToMultipleValueExpr(column)
+	}
+
+	return multipleToSingleValueExpr(column)
+}
+
+// syncCollectionIndexesConcurrently drops the removed and creates the new
+// or changed collection indexes with DROP/CREATE INDEX CONCURRENTLY,
+// leaving the unchanged ones untouched.
+//
+// A failed index build is cleaned up and retried on the next collection save.
+//
+// Postgres doesn't support concurrent index operations on partitioned tables
+// so the indexes of a partitioned record table are built with a regular
+// (table locking) CREATE INDEX after the transaction commit.
+func (dao *Dao) syncCollectionIndexesConcurrently(newCollection, oldCollection *models.Collection) error {
+	if newCollection.IsView() {
+		return nil // views don't have indexes
+	}
+
+	concurrently := collectionPartitionOptions(newCollection) == nil
+
+	validIndexes, err := dao.validTableIndexNames(newCollection.Name)
+	if err != nil {
+		return err
+	}
+
+	// normalized old index definitions
+	// (the indexes are preserved on table rename)
+	oldIndexes := map[string]string{}
+	for _, raw := range oldCollection.Indexes {
+		parsed := dbutils.ParseIndex(raw)
+		if !parsed.IsValid() {
+			continue
+		}
+
+		parsed.TableName = newCollection.Name
+		oldIndexes[strings.ToLower(parsed.IndexName)] = parsed.Build()
+	}
+
+	newIndexes := make([]dbutils.Index, 0, len(newCollection.Indexes))
+	for _, raw := range newCollection.Indexes {
+		parsed := dbutils.ParseIndex(raw)
+		parsed.TableName = newCollection.Name
+		newIndexes = append(newIndexes, parsed)
+	}
+
+	// drop the removed indexes
+	for name := range oldIndexes {
+		if !hasIndexName(newIndexes, name) {
+			if err := dao.dropIndex(name, concurrently); err != nil {
+				return err
+			}
+		}
+	}
+
+	errs := validation.Errors{}
+	for i, parsed := range newIndexes {
+		if !parsed.IsValid() {
+			continue // already validated before the commit
+		}
+
+		name := strings.ToLower(parsed.IndexName)
+		sql := parsed.Build()
+
+		if oldIndexes[name] == sql && validIndexes[name] {
+			continue // unchanged
+		}
+
+		// drop the changed or previously failed (aka. invalid) index
+		if err := dao.dropIndex(name, concurrently); err != nil {
+			return err
+		}
+
+		if concurrently {
+			// note: Build always starts with "CREATE [UNIQUE ]INDEX "
+			sql = strings.Replace(sql, "INDEX ", "INDEX CONCURRENTLY ", 1)
+		}
+
+		if _, err := dao.DB().NewQuery(sql).Execute(); err != nil {
+			// a failed concurrent build leaves an invalid index behind
+			dao.dropIndex(name, concurrently)
+
+			errs[strconv.Itoa(i)] = validation.NewError(
+				"validation_invalid_index_expression",
+				fmt.Sprintf("Failed to create index %s - %v.", parsed.IndexName, err.Error()),
+			)
+		}
+	}
+
+	if len(errs) > 0 {
+		return validation.Errors{"indexes": errs}
+	}
+
+	return nil
+}
+
+func (dao *Dao) dropIndex(name string, concurrently bool) error {
+	sql := "DROP INDEX IF EXISTS [[%s]]"
+	if concurrently {
+		sql = "DROP INDEX CONCURRENTLY IF EXISTS [[%s]]"
+	}
+
+	_, err := dao.DB().NewQuery(fmt.Sprintf(sql, name)).Execute()
+
+	return err
+}
+
+// validTableIndexNames returns the lowercased names of the valid
+// (aka. fully built) indexes of the specified table.
+func (dao *Dao) validTableIndexNames(tableName string) (map[string]bool, error) {
+	names := []string{}
+
+	err := dao.DB().NewQuery(`
+		SELECT lower(i.relname)
+		FROM pg_index idx
+		JOIN pg_class i ON i.oid = idx.indexrelid
+		JOIN pg_class t ON t.oid = idx.indrelid
+		WHERE t.relname = {:tableName} AND idx.indisvalid
+	`).Bind(dbx.Params{"tableName": tableName}).Column(&names)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]bool, len(names))
+	for _, name := range names {
+		result[name] = true
+	}
+
+	return result, nil
+}
+
+func hasIndexName(indexes []dbutils.Index, name string) bool {
+	for _, idx := range indexes {
+		if strings.EqualFold(idx.IndexName, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,341 @@
+package daos_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/tests"
+	"github.com/hylarucoder/rocketbase/tools/list"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+func TestSaveCollectionOnlineSchemaChanges(t *testing.T) {
+	t.Parallel()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	backfills := []*daos.SchemaBackfill{}
+
+	dao := app.Dao().Clone()
+	dao.OnlineSchemaChangesFunc = func() bool { return true }
+	dao.SchemaBackfillFunc = func(backfill *daos.SchemaBackfill) error {
+		backfills = append(backfills, backfill)
+		return nil
+	}
+
+	collection, err := dao.FindCollectionByNameOrId("demo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collection.Schema.GetFieldByName("select_one").Options.(*schema.SelectOptions).MaxSelect = 2
+	collection.Schema.GetFieldByName("rel_many").Options.(*schema.RelationOptions).MaxSelect = types.Pointer(1)
+	collection.Indexes = append(collection.Indexes, "CREATE INDEX idx_online_test ON demo1 (text)")
+
+	if err := dao.SaveCollection(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	// the new index must be created
+	indexes, err := dao.TableIndexes(collection.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := indexes["idx_online_test"]; !ok {
+		t.Fatalf("Expected idx_online_test to be created, got %v", indexes)
+	}
+
+	if len(backfills) != 2 {
+		t.Fatalf("Expected 2 scheduled backfills, got %d", len(backfills))
+	}
+
+	columns, err := dao.TableColumns(collection.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range backfills {
+		if !list.ExistInSlice(b.ShadowColumn, columns) {
+			t.Fatalf("Expected shadow column %q to exist before the backfill", b.ShadowColumn)
+		}
+	}
+
+	// the converted fields keep their old definition until the backfill completion
+	saved, err := dao.FindCollectionByNameOrId(collection.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts := saved.Schema.GetFieldByName("select_one").Options.(*schema.SelectOptions); opts.MaxSelect != 1 {
+		t.Fatalf("Expected select_one to be still single before the backfill, got %d", opts.MaxSelect)
+	}
+
+	type fieldsExpectation struct {
+		SelectOne string `db:"select_one"`
+		RelMany   string `db:"rel_many"`
+	}
+
+	loadFields := func() *fieldsExpectation {
+		result := new(fieldsExpectation)
+
+		err := dao.DB().Select("select_one", "rel_many").
+			From(collection.Name).
+			Where(dbx.HashExp{"id": "3479947686587667460"}).
+			One(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return result
+	}
+
+	// the original values remain readable during the backfill
+	if v := loadFields(); v.SelectOne != "optionB" {
+		t.Fatalf("Expected the original select_one value before the backfill, got %v", v)
+	}
+
+	// the writes during the backfill are synced with the shadow column
+	_, err = dao.DB().Update(
+		collection.Name,
+		dbx.Params{"select_one": "optionA"},
+		dbx.HashExp{"id": "3479947686587667460"},
+	).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range backfills {
+		batches := 0
+		err := dao.RunSchemaBackfill(context.Background(), b, 1, func(b *daos.SchemaBackfill) error {
+			batches++
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !b.Done || b.Processed == 0 || b.Processed != b.Total {
+			t.Fatalf("Unexpected backfill state %v", b)
+		}
+
+		if int64(batches) != b.Total+1 {
+			t.Fatalf("Expected %d batches, got %d", b.Total+1, batches)
+		}
+	}
+
+	if v := loadFields(); v.SelectOne != `["optionA"]` || v.RelMany != "oap640cot4yru2s" {
+		t.Fatalf("Expected converted values after the backfill, got %v", v)
+	}
+
+	// the shadow columns must be swapped
+	columns, err = dao.TableColumns(collection.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range backfills {
+		if list.ExistInSlice(b.ShadowColumn, columns) {
+			t.Fatalf("Expected shadow column %q to be swapped", b.ShadowColumn)
+		}
+	}
+
+	// the new field definitions must be saved
+	collection, err = dao.FindCollectionByNameOrId(collection.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts := collection.Schema.GetFieldByName("select_one").Options.(*schema.SelectOptions); opts.MaxSelect != 2 {
+		t.Fatalf("Expected select_one to be multiple after the backfill, got %d", opts.MaxSelect)
+	}
+	if opts := collection.Schema.GetFieldByName("rel_many").Options.(*schema.RelationOptions); opts.MaxSelect == nil || *opts.MaxSelect != 1 {
+		t.Fatalf("Expected rel_many to be single after the backfill, got %v", opts.MaxSelect)
+	}
+
+	// removed index
+	collection.Indexes = collection.Indexes[:len(collection.Indexes)-1]
+	if err := dao.SaveCollection(collection); err != nil {
+		t.Fatal(err)
+	}
+	indexes, err = dao.TableIndexes(collection.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := indexes["idx_online_test"]; ok {
+		t.Fatal("Expected idx_online_test to be dropped")
+	}
+}
+
+func TestSaveCollectionOnlineSchemaChangesInTransaction(t *testing.T) {
+	t.Parallel()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	dao := app.Dao().Clone()
+	dao.OnlineSchemaChangesFunc = func() bool { return true }
+	dao.SchemaBackfillFunc = func(backfill *daos.SchemaBackfill) error {
+		t.Fatal("Didn't expect backfills within a transaction")
+		return nil
+	}
+
+	err := dao.RunInTransaction(func(txDao *daos.Dao) error {
+		txDao.OnlineSchemaChangesFunc = dao.OnlineSchemaChangesFunc
+		txDao.SchemaBackfillFunc = dao.SchemaBackfillFunc
+
+		collection, err := txDao.FindCollectionByNameOrId("demo1")
+		if err != nil {
+			return err
+		}
+
+		collection.Schema.GetFieldByName("select_one").Options.(*schema.SelectOptions).MaxSelect = 2
+
+		return txDao.SaveCollection(collection)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// converted in place
+	var selectOne string
+	err = app.Dao().DB().Select("select_one").
+		From("demo1").
+		Where(dbx.HashExp{"id": "3479947686587667460"}).
+		Row(&selectOne)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selectOne != `["optionB"]` {
+		t.Fatalf("Expected the value to be converted in place, got %q", selectOne)
+	}
+}
+
+func TestSchemaBackfillBatchMissingCollection(t *testing.T) {
+	t.Parallel()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	backfill := &daos.SchemaBackfill{CollectionId: "missing", FieldId: "missing", ShadowColumn: "_backfill_missing"}
+
+	if err := app.Dao().SchemaBackfillBatch(backfill, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if !backfill.Done {
+		t.Fatal("Expected the backfill of a missing collection to be marked as done")
+	}
+}
+
+func TestSaveCollectionPendingSchemaBackfill(t *testing.T) {
+	t.Parallel()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	dao := app.Dao().Clone()
+	dao.OnlineSchemaChangesFunc = func() bool { return true }
+	dao.SchemaBackfillFunc = func(backfill *daos.SchemaBackfill) error {
+		return nil // leave it pending
+	}
+
+	collection, err := dao.FindCollectionByNameOrId("demo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collection.Schema.GetFieldByName("select_one").Options.(*schema.SelectOptions).MaxSelect = 2
+	if err := dao.SaveCollection(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	// changing a field with pending conversion
+	collection, err = dao.FindCollectionByNameOrId("demo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection.Schema.GetFieldByName("select_one").Name = "select_one_renamed"
+	if err := dao.SaveCollection(collection); err == nil {
+		t.Fatal("Expected the pending field change to fail")
+	}
+
+	// deleting a field with pending conversion
+	collection, err = dao.FindCollectionByNameOrId("demo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := collection.Schema.GetFieldByName("select_one")
+	collection.Schema.RemoveField(field.Id)
+	if err := dao.SaveCollection(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	columns, err := dao.TableColumns(collection.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.ExistInSlice("_backfill_"+field.Id, columns) {
+		t.Fatal("Expected the shadow column of the deleted field to be dropped")
+	}
+}
+
+func TestSaveCollectionScheduledSchemaIndexesBuild(t *testing.T) {
+	t.Parallel()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	builds := []*daos.SchemaIndexesBuild{}
+
+	dao := app.Dao().Clone()
+	dao.OnlineSchemaChangesFunc = func() bool { return true }
+	dao.SchemaIndexesBuildFunc = func(build *daos.SchemaIndexesBuild) error {
+		builds = append(builds, build)
+		return nil
+	}
+
+	collection, err := dao.FindCollectionByNameOrId("demo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldTotal := len(collection.Indexes)
+
+	collection.Indexes = append(collection.Indexes, "CREATE INDEX idx_online_build_test ON demo1 (text)")
+
+	if err := dao.SaveCollection(collection); err != nil {
+		t.Fatalf("Expected the collection save to succeed, got %v", err)
+	}
+
+	if len(builds) != 1 || builds[0].CollectionId != collection.Id || len(builds[0].OldIndexes) != oldTotal {
+		t.Fatalf("Unexpected scheduled builds %v", builds)
+	}
+
+	saved, err := dao.FindCollectionByNameOrId(collection.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Indexes) != oldTotal+1 {
+		t.Fatalf("Expected the new index definition to be saved, got %v", saved.Indexes)
+	}
+
+	// not built until the scheduled build run
+	indexes, err := dao.TableIndexes(collection.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := indexes["idx_online_build_test"]; ok {
+		t.Fatal("Expected idx_online_build_test to not be created before the build run")
+	}
+
+	if err := dao.RunSchemaIndexesBuild(builds[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	indexes, err = dao.TableIndexes(collection.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := indexes["idx_online_build_test"]; !ok {
+		t.Fatalf("Expected idx_online_build_test to be created, got %v", indexes)
+	}
+}
@@ -0,0 +1,221 @@
+package daos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+// CollectionPartitionsAhead is the number of the record table partitions
+// that are kept created in advance (including the current one).
+const CollectionPartitionsAhead = 3
+
+// collectionPartitionInfix separates the record table name
+// and the partition start date in the partition table name.
+const collectionPartitionInfix = "_p"
+
+// collectionDefaultPartitionSuffix is the name suffix
+// of the record table default partition.
+const collectionDefaultPartitionSuffix = "_default"
+
+// MaxPartitionedCollectionNameLength is the max length of a partitioned
+// collection name so that its partitions names don't exceed
+// the Postgres identifier length limit (63 bytes) and get truncated.
+const MaxPartitionedCollectionNameLength = 63 - len(collectionPartitionInfix) - len(rangePartitionDateLayout)
+
+// CollectionPartition represents a single range partition of a record table.
+type CollectionPartition = RangePartition
+
+// collectionPartitionOptions returns the partition options
+// of the provided collection or nil if its record table is not partitioned.
+func collectionPartitionOptions(collection *models.Collection) *models.CollectionPartitionOptions {
+	if !collection.IsBase() {
+		return nil
+	}
+
+	return collection.BaseOptions().Partition
+}
+
+// FindCollectionPartitions returns the range partitions of the collection
+// record table sorted by their start date (the default partition is not included).
+//
+// Returns an empty slice if the collection record table is not partitioned.
+func (dao *Dao) FindCollectionPartitions(collection *models.Collection) ([]*CollectionPartition, error) {
+	options := collectionPartitionOptions(collection)
+	if options == nil {
+		return []*CollectionPartition{}, nil
+	}
+
+	return dao.findRangePartitions(
+		collection.Name,
+		collection.Name+collectionPartitionInfix,
+		func(start time.Time) time.Time {
+			return options.AddIntervals(start, 1)
+		},
+	)
+}
+
+// CreateCollectionPartitions creates (if missing) the specified number
+// of collection record table partitions starting from the one containing the from date.
+//
+// Intervals that already have records in the default partition are skipped
+// (see [Dao.createRangePartition]).
+//
+// It is no-op if the collection record table is not partitioned.
+func (dao *Dao) CreateCollectionPartitions(collection *models.Collection, from time.Time, count int) error {
+	options := collectionPartitionOptions(collection)
+	if options == nil {
+		return nil
+	}
+
+	existing, err := dao.FindCollectionPartitions(collection)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		start := options.AddIntervals(from, i)
+
+		if hasCollectionPartition(existing, start) {
+			continue
+		}
+
+		err := dao.createRangePartition(
+			collection.Name,
+			collectionDefaultPartitionName(collection),
+			collection.Name+collectionPartitionInfix,
+			start,
+			options.AddIntervals(start, 1),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredCollectionPartitions drops the collection record table
+// partitions that are outside of the collection partition retention period
+// and deletes the expired records from the default partition.
+//
+// Note that the records are removed directly from the database, aka.
+// without triggering the record delete hooks and the related files cleanup.
+//
+// It is no-op if the collection record table is not partitioned
+// or its partition retention is not set.
+func (dao *Dao) DeleteExpiredCollectionPartitions(collection *models.Collection, now time.Time) error {
+	options := collectionPartitionOptions(collection)
+	if options == nil || options.Retention <= 0 {
+		return nil
+	}
+
+	cutoff := options.AddIntervals(now, -options.Retention)
+
+	partitions, err := dao.FindCollectionPartitions(collection)
+	if err != nil {
+		return err
+	}
+
+	if err := dao.dropRangePartitions(partitions, cutoff); err != nil {
+		return err
+	}
+
+	_, err = dao.NonconcurrentDB().Delete(
+		collectionDefaultPartitionName(collection),
+		dbx.NewExp("[[created]] < {:date}", dbx.Params{"date": cutoff.Format(types.DefaultDateLayout)}),
+	).Execute()
+
+	return err
+}
+
+// createPartitionedRecordTable creates the range partitioned by "created"
+// collection record table together with its default and initial partitions.
+//
+// Postgres requires the partition key to be part of the primary key
+// so the id column uniqueness is guaranteed only by its generator.
+func (dao *Dao) createPartitionedRecordTable(collection *models.Collection, cols map[string]string) error {
+	cols[schema.FieldNameId] = "VARCHAR(32) DEFAULT generate_snowflake() NOT NULL"
+
+	_, err := dao.DB().CreateTable(collection.Name, cols, "PARTITION BY RANGE ([[created]])").Execute()
+	if err != nil {
+		return err
+	}
+
+	_, err = dao.DB().NewQuery(fmt.Sprintf(
+		"ALTER TABLE {{%s}} ADD PRIMARY KEY ([[id]], [[created]])",
+		collection.Name,
+	)).Execute()
+	if err != nil {
+		return err
+	}
+
+	_, err = dao.DB().NewQuery(fmt.Sprintf(
+		"CREATE TABLE {{%s}} PARTITION OF {{%s}} DEFAULT",
+		collectionDefaultPartitionName(collection),
+		collection.Name,
+	)).Execute()
+	if err != nil {
+		return err
+	}
+
+	return dao.CreateCollectionPartitions(collection, time.Now(), CollectionPartitionsAhead)
+}
+
+// renameCollectionPartitions renames the record table partitions
+// after the collection rename so that they share its name prefix.
+func (dao *Dao) renameCollectionPartitions(oldName string, newCollection *models.Collection) error {
+	names, err := dao.partitionNames(newCollection.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		suffix, ok := strings.CutPrefix(name, oldName)
+		if !ok {
+			continue
+		}
+
+		_, err := dao.DB().RenameTable("{{"+name+"}}", "{{"+newCollection.Name+suffix+"}}").Execute()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRecordTablePartitionChange returns an error if the
+// record table partitioning of the two collections differs.
+func checkRecordTablePartitionChange(newCollection, oldCollection *models.Collection) error {
+	newOptions := collectionPartitionOptions(newCollection)
+	oldOptions := collectionPartitionOptions(oldCollection)
+
+	if (newOptions == nil) != (oldOptions == nil) ||
+		(newOptions != nil && newOptions.Interval != oldOptions.Interval) {
+		return errors.New("the record table partitioning cannot be changed after the collection create")
+	}
+
+	return nil
+}
+
+// collectionDefaultPartitionName returns the name of the record table default partition.
+func collectionDefaultPartitionName(collection *models.Collection) string {
+	return collection.Name + collectionDefaultPartitionSuffix
+}
+
+func hasCollectionPartition(partitions []*CollectionPartition, start time.Time) bool {
+	for _, p := range partitions {
+		if p.Start.Equal(start) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+package daos_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/tests"
+	"github.com/hylarucoder/rocketbase/tools/list"
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+func TestPartitionedCollection(t *testing.T) {
+	t.Parallel()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	collection := &models.Collection{
+		Name: "partition_test",
+		Type: models.CollectionTypeBase,
+		Schema: schema.NewSchema(
+			&schema.SchemaField{Name: "title", Type: schema.FieldTypeText},
+		),
+		Options: types.JsonMap{
+			"partition": map[string]any{"interval": models.PartitionIntervalMonth, "retention": 1},
+		},
+	}
+
+	if err := app.Dao().SaveCollection(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	partitions, err := app.Dao().FindCollectionPartitions(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 3 {
+		t.Fatalf("Expected 3 initial partitions, got %d", len(partitions))
+	}
+
+	// regular records CRUD
+	record := models.NewRecord(collection)
+	record.Set("title", "test")
+	if err := app.Dao().SaveRecord(record); err != nil {
+		t.Fatal(err)
+	}
+
+	// records outside of the existing partitions are stored in the default one
+	old := models.NewRecord(collection)
+	old.Set("title", "old")
+	old.Set("created", time.Now().AddDate(0, -6, 0))
+	if err := app.Dao().SaveRecord(old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := app.Dao().FindRecordById(collection.Name, record.Id); err != nil {
+		t.Fatalf("Expected the record to be found, got %v", err)
+	}
+
+	// schema changes are propagated to all partitions
+	collection.Schema.AddField(&schema.SchemaField{Name: "count", Type: schema.FieldTypeNumber})
+	collection.Indexes = types.JsonArray[string]{"CREATE INDEX idx_partition_test ON partition_test (title)"}
+	if err := app.Dao().SaveCollection(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range partitions {
+		columns, err := app.Dao().TableColumns(p.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !list.ExistInSlice("count", columns) {
+			t.Fatalf("Expected partition %s to have the count column, got %v", p.Name, columns)
+		}
+	}
+
+	// the partitioning cannot be changed
+	collection.Options = types.JsonMap{}
+	if err := app.Dao().SaveCollection(collection); err == nil {
+		t.Fatal("Expected the partitioning change to fail")
+	}
+	collection.Options = types.JsonMap{
+		"partition": map[string]any{"interval": models.PartitionIntervalMonth, "retention": 1},
+	}
+
+	// retention
+	future := time.Now().AddDate(0, 2, 0)
+	if err := app.Dao().CreateCollectionPartitions(collection, future, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Dao().DeleteExpiredCollectionPartitions(collection, future); err != nil {
+		t.Fatal(err)
+	}
+
+	partitions, err = app.Dao().FindCollectionPartitions(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range partitions {
+		if p.End.Before(future.AddDate(0, -1, 0)) {
+			t.Fatalf("Expected partition %s to be dropped", p.Name)
+		}
+	}
+
+	if _, err := app.Dao().FindRecordById(collection.Name, record.Id); err == nil {
+		t.Fatal("Expected the record of the dropped partition to be deleted")
+	}
+	if _, err := app.Dao().FindRecordById(collection.Name, old.Id); err == nil {
+		t.Fatal("Expected the expired default partition record to be deleted")
+	}
+
+	// rename
+	collection.Name = "partition_test_renamed"
+	if err := app.Dao().SaveCollection(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	renamed, err := app.Dao().FindCollectionPartitions(collection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(renamed) != len(partitions) {
+		t.Fatalf("Expected %d renamed partitions, got %d", len(partitions), len(renamed))
+	}
+}
@@ -10,14 +10,45 @@ import (
 	"github.com/hylarucoder/rocketbase/models/schema"
 	"github.com/hylarucoder/rocketbase/tools/dbutils"
 	"github.com/hylarucoder/rocketbase/tools/security"
-	"github.com/pocketbase/dbx"
 )
 
 // SyncRecordTableSchema compares the two provided collections
 // and applies the necessary related record table changes.
 //
 // If `oldCollection` is null, then only `newCollection` is used to create the record table.
+//
+// If the online schema changes are enabled and the dao is not in a transaction,
+// the changes are applied in a lock-friendly way (see [Dao.OnlineSchemaChangesFunc]).
+//
+// The changes of a partitioned record table are applied on its parent table
+// and Postgres propagates them to all existing and future partitions.
 func (dao *Dao) SyncRecordTableSchema(newCollection *models.Collection, oldCollection *models.Collection) error {
+	online := oldCollection != nil && dao.isOnlineSchemaChange()
+
+	if err := dao.syncRecordTableSchema(newCollection, oldCollection, online); err != nil {
+		return err
+	}
+
+	if online {
+		// note: built directly since the collection models may not be persisted
+		return dao.syncCollectionIndexesConcurrently(newCollection, oldCollection)
+	}
+
+	return nil
+}
+
+// syncRecordTableSchema applies the record table changes in a single transaction.
+//
+// In online mode the indexes are only validated and the caller is expected
+// to create them after the transaction commit (see completeOnlineSchemaChanges).
+//
+// The field conversions are always applied in place. The online ones are
+// expected to be already deferred by the caller (see deferSingleVsMultipleFieldChanges).
+func (dao *Dao) syncRecordTableSchema(
+	newCollection *models.Collection,
+	oldCollection *models.Collection,
+	online bool,
+) error {
 	return dao.RunInTransaction(func(txDao *Dao) error {
 		// create
 		// -----------------------------------------------------------
@@ -56,7 +87,11 @@ func (dao *Dao) SyncRecordTableSchema(newCollection *models.Collection, oldColle
 			}
 
 			// create table
-			if _, err := txDao.DB().CreateTable(tableName, cols).Execute(); err != nil {
+			if collectionPartitionOptions(newCollection) != nil {
+				if err := txDao.createPartitionedRecordTable(newCollection, cols); err != nil {
+					return err
+				}
+			} else if _, err := txDao.DB().CreateTable(tableName, cols).Execute(); err != nil {
 				return err
 			}
 
@@ -89,17 +124,40 @@ func (dao *Dao) SyncRecordTableSchema(newCollection *models.Collection, oldColle
 		deletedFieldNames := []string{}
 		renamedFieldNames := map[string]string{}
 
-		// drop old indexes (if any)
-		if err := txDao.dropCollectionIndex(oldCollection); err != nil {
+		if err := checkRecordTablePartitionChange(newCollection, oldCollection); err != nil {
 			return err
 		}
 
+		if err := txDao.checkPendingSchemaBackfills(newCollection, oldCollection); err != nil {
+			return err
+		}
+
+		if online {
+			// fail fast instead of blocking all table queries
+			// while waiting behind a long running one for the lock
+			_, err := txDao.DB().NewQuery("SET LOCAL lock_timeout = '" + onlineSchemaLockTimeout + "'").Execute()
+			if err != nil {
+				return err
+			}
+		} else {
+			// drop old indexes (if any)
+			if err := txDao.dropCollectionIndex(oldCollection); err != nil {
+				return err
+			}
+		}
+
 		// check for renamed table
 		if !strings.EqualFold(oldTableName, newTableName) {
 			_, err := txDao.DB().RenameTable("{{"+oldTableName+"}}", "{{"+newTableName+"}}").Execute()
 			if err != nil {
 				return err
 			}
+
+			if collectionPartitionOptions(newCollection) != nil {
+				if err := txDao.renameCollectionPartitions(oldTableName, newCollection); err != nil {
+					return err
+				}
+			}
 		}
 
 		// check for deleted columns
@@ -161,6 +219,11 @@ func (dao *Dao) SyncRecordTableSchema(newCollection *models.Collection, oldColle
 			return err
 		}
 
+		if online {
+			// the indexes are created concurrently after the transaction commit
+			return validateCollectionIndexes(newCollection)
+		}
+
 		return txDao.createCollectionIndexes(newCollection)
 	})
 }
@@ -177,17 +240,8 @@ func (dao *Dao) normalizeSingleVsMultipleFieldChanges(newCollection, oldCollecti
 		for _, newField := range newCollection.Schema.Fields() {
 			// allow to continue even if there is no old field for the cases
 			// when a new field is added and there are already inserted data
-			var isOldMultiple bool
-			if oldField := oldCollection.Schema.GetFieldById(newField.Id); oldField != nil {
-				if opt, ok := oldField.Options.(schema.MultiValuer); ok {
-					isOldMultiple = opt.IsMultiple()
-				}
-			}
-
-			var isNewMultiple bool
-			if opt, ok := newField.Options.(schema.MultiValuer); ok {
-				isNewMultiple = opt.IsMultiple()
-			}
+			isOldMultiple := isMultipleField(oldCollection.Schema.GetFieldById(newField.Id))
+			isNewMultiple := isMultipleField(newField)
 
 			if isOldMultiple == isNewMultiple {
 				continue // no change
@@ -208,70 +262,22 @@ func (dao *Dao) normalizeSingleVsMultipleFieldChanges(newCollection, oldCollecti
 				return err
 			}
 
-			var copyQuery *dbx.Query
-
+			var copyExpr string
 			if !isOldMultiple && isNewMultiple {
-				// single -> multiple (convert to array)
-				copyQuery = txDao.DB().NewQuery(fmt.Sprintf(
-					`UPDATE {{%s}} SET [[%s]] = (
-        CASE
-            WHEN CAST([[%s]] AS text) = ''
-            THEN '[]'::json
-            ELSE (
-                CASE
-                    WHEN json_typeof([[%s]]::json) = 'array'
-                    THEN [[%s]]::json
-                    ELSE json_build_array(
-                        CASE
-                            WHEN json_typeof([[%s]]::json) = 'string'
-                            THEN [[%s]]::json
-                            ELSE to_json([[%s]])
-                        END
-                    )
-                END
-            )
-        END
-    )`,
-					newCollection.Name,
-					tempName,
-					originalName,
-					originalName,
-					originalName,
-					originalName,
-					originalName,
-					originalName,
-				))
+				copyExpr = singleToMultipleValueExpr(originalName)
 			} else {
-				// multiple -> single (keep only the last element)
-				//
 				// note: for file fields the actual file objects are not
 				// deleted allowing additional custom handling via migration
-				copyQuery = txDao.DB().NewQuery(fmt.Sprintf(
-					`UPDATE {{%s}} SET [[%s]] = (
-        CASE
-            WHEN COALESCE([[%s]]::text, '[]') = '[]'
-            THEN ''
-            ELSE (
-                CASE
-                    WHEN json_typeof([[%s]]::json) = 'array'
-                    THEN COALESCE(
-                        TRIM(BOTH '"' FROM (([[%s]]::json) ->> -1)::text),
-                        ''
-                    )
-                    ELSE TRIM(BOTH '"' FROM ([[%s]]::json)::text)
-                END
-            )
-        END
-    )`,
-					newCollection.Name,
-					tempName,
-					originalName,
-					originalName,
-					originalName,
-					originalName,
-				))
+				copyExpr = multipleToSingleValueExpr(originalName)
 			}
 
+			copyQuery := txDao.DB().NewQuery(fmt.Sprintf(
+				"UPDATE {{%s}} SET [[%s]] = %s",
+				newCollection.Name,
+				tempName,
+				copyExpr,
+			))
+
 			// copy the normalized values
 			if _, err := copyQuery.Execute(); err != nil {
 				return err
@@ -293,6 +299,63 @@ func (dao *Dao) normalizeSingleVsMultipleFieldChanges(newCollection, oldCollecti
 	})
 }
 
+// isMultipleField reports whether the provided field stores multiple values
+// (nil field is considered single value).
+func isMultipleField(field *schema.SchemaField) bool {
+	if field == nil {
+		return false
+	}
+
+	opt, ok := field.Options.(schema.MultiValuer)
+
+	return ok && opt.IsMultiple()
+}
+
+// singleToMultipleValueExpr returns an SQL expression that
+// converts the single value column to a json array.
+func singleToMultipleValueExpr(column string) string {
+	return strings.ReplaceAll(`(
+        CASE
+            WHEN CAST([[{col}]] AS text) = ''
+            THEN '[]'::json
+            ELSE (
+                CASE
+                    WHEN json_typeof([[{col}]]::json) = 'array'
+                    THEN [[{col}]]::json
+                    ELSE json_build_array(
+                        CASE
+                            WHEN json_typeof([[{col}]]::json) = 'string'
+                            THEN [[{col}]]::json
+                            ELSE to_json([[{col}]])
+                        END
+                    )
+                END
+            )
+        END
+    )`, "{col}", column)
+}
+
+// multipleToSingleValueExpr returns an SQL expression that
+// converts the multiple values column to its last element.
+func multipleToSingleValueExpr(column string) string {
+	return strings.ReplaceAll(`(
+        CASE
+            WHEN COALESCE([[{col}]]::text, '[]') = '[]'
+            THEN ''
+            ELSE (
+                CASE
+                    WHEN json_typeof([[{col}]]::json) = 'array'
+                    THEN COALESCE(
+                        TRIM(BOTH '"' FROM (([[{col}]]::json) ->> -1)::text),
+                        ''
+                    )
+                    ELSE TRIM(BOTH '"' FROM ([[{col}]]::json)::text)
+                END
+            )
+        END
+    )`, "{col}", column)
+}
+
 func (dao *Dao) dropCollectionIndex(collection *models.Collection) error {
 	if collection.IsView() {
 		return nil // views don't have indexes
@@ -362,3 +425,30 @@ func (dao *Dao) createCollectionIndexes(collection *models.Collection) error {
 		return nil
 	})
 }
+
+// validateCollectionIndexes checks only the collection indexes expressions
+// without creating them.
+func validateCollectionIndexes(collection *models.Collection) error {
+	if collection.IsView() {
+		return nil // views don't have indexes
+	}
+
+	errs := validation.Errors{}
+	for i, idx := range collection.Indexes {
+		parsed := dbutils.ParseIndex(idx)
+		parsed.TableName = collection.Name
+
+		if !parsed.IsValid() {
+			errs[strconv.Itoa(i)] = validation.NewError(
+				"validation_invalid_index_expression",
+				"Invalid CREATE INDEX expression.",
+			)
+		}
+	}
+
+	if len(errs) > 0 {
+		return validation.Errors{"indexes": errs}
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package daos_test
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"regexp"
 	"strings"
@@ -659,6 +660,99 @@ func (suite *RecordTestSuite) TestFindFirstRecordByFilter() {
 	}
 }
 
+func (suite *RecordTestSuite) TestAggregateRecords() {
+	t := suite.T()
+	app := suite.App
+
+	scenarios := []struct {
+		name               string
+		collectionIdOrName string
+		groupBy            string
+		aggregates         string
+		filter             string
+		params             []dbx.Params
+		expectError        bool
+		expectedItems      string
+	}{
+		{
+			"missing collection",
+			"missing",
+			"",
+			"count()",
+			"",
+			nil,
+			true,
+			"",
+		},
+		{
+			"invalid aggregate",
+			"demo2",
+			"",
+			"median(title)",
+			"",
+			nil,
+			true,
+			"",
+		},
+		{
+			"invalid group by field",
+			"demo2",
+			"missing",
+			"count()",
+			"",
+			nil,
+			true,
+			"",
+		},
+		{
+			"default count without filter",
+			"demo2",
+			"",
+			"",
+			"",
+			nil,
+			false,
+			`[{"count()":3}]`,
+		},
+		{
+			"count with filter and params",
+			"demo2",
+			"",
+			"count()",
+			"title = {:title}",
+			[]dbx.Params{{"title": "test1"}},
+			false,
+			`[{"count()":1}]`,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			items, err := app.Dao().AggregateRecords(
+				s.collectionIdOrName,
+				s.groupBy,
+				s.aggregates,
+				s.filter,
+				s.params...,
+			)
+
+			hasErr := err != nil
+			if hasErr != s.expectError {
+				t.Fatalf("Expected hasErr to be %v, got %v (%v)", s.expectError, hasErr, err)
+			}
+
+			if hasErr {
+				return
+			}
+
+			encoded, _ := json.Marshal(items)
+			if string(encoded) != s.expectedItems {
+				t.Fatalf("Expected items %s, got %s", s.expectedItems, encoded)
+			}
+		})
+	}
+}
+
 func (suite *RecordTestSuite) TestCanAccessRecord() {
 	t := suite.T()
 	app := suite.App
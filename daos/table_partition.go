@@ -0,0 +1,140 @@
+package daos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+// rangePartitionDateLayout is the start date format used
+// in the range partitions table names (eg. "_logs_p20240131").
+const rangePartitionDateLayout = "20060102"
+
+// RangePartition represents a single "created" column range partition
+// of a partitioned table (eg. the _logs or a collection record table).
+type RangePartition struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// partitionNames returns the names of all partitions
+// (including the default one) of the specified partitioned table.
+func (dao *Dao) partitionNames(table string) ([]string, error) {
+	names := []string{}
+
+	err := dao.DB().NewQuery(`
+		SELECT child.relname
+		FROM pg_inherits
+		JOIN pg_class parent ON pg_inherits.inhparent = parent.oid
+		JOIN pg_class child ON pg_inherits.inhrelid = child.oid
+		WHERE parent.relname = {:table}
+		ORDER BY child.relname
+	`).Bind(dbx.Params{"table": table}).Column(&names)
+
+	return names, err
+}
+
+// findRangePartitions returns the range partitions of the specified table,
+// aka. the ones named with the prefix followed by their start date,
+// sorted by their start date (the default partition is not included).
+//
+// The end date of each partition is resolved with the end func.
+func (dao *Dao) findRangePartitions(
+	table string,
+	prefix string,
+	end func(start time.Time) time.Time,
+) ([]*RangePartition, error) {
+	names, err := dao.partitionNames(table)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*RangePartition, 0, len(names))
+
+	for _, name := range names {
+		date, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+
+		start, err := time.Parse(rangePartitionDateLayout, date)
+		if err != nil {
+			continue // not a range partition
+		}
+
+		result = append(result, &RangePartition{
+			Name:  name,
+			Start: start,
+			End:   end(start),
+		})
+	}
+
+	return result, nil
+}
+
+// createRangePartition creates (if missing) the specified table range
+// partition for the [start, end) interval named with the prefix
+// followed by the start date.
+//
+// The partition is not created if the default partition already has
+// rows for the interval because Postgres doesn't allow creating
+// a partition that overlaps with the default partition rows.
+func (dao *Dao) createRangePartition(
+	table string,
+	defaultPartition string,
+	prefix string,
+	start time.Time,
+	end time.Time,
+) error {
+	params := dbx.Params{
+		"start": start.Format(types.DefaultDateLayout),
+		"end":   end.Format(types.DefaultDateLayout),
+	}
+
+	var hasDefaultRows bool
+
+	err := dao.DB().NewQuery(fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM {{%s}}
+			WHERE [[created]] >= {:start} AND [[created]] < {:end}
+		)
+	`, defaultPartition)).Bind(params).Row(&hasDefaultRows)
+	if err != nil {
+		return err
+	}
+
+	if hasDefaultRows {
+		return nil
+	}
+
+	// note: the range values are inlined because DDL statements don't support bind parameters
+	_, err = dao.NonconcurrentDB().NewQuery(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS {{%s}} PARTITION OF {{%s}} FOR VALUES FROM ('%s') TO ('%s')",
+		prefix+start.Format(rangePartitionDateLayout),
+		table,
+		params["start"],
+		params["end"],
+	)).Execute()
+
+	return err
+}
+
+// dropRangePartitions drops the provided range partitions
+// (sorted by their start date) that end before or at the specified date.
+func (dao *Dao) dropRangePartitions(partitions []*RangePartition, before time.Time) error {
+	for _, p := range partitions {
+		if p.End.After(before) {
+			break // sorted by date
+		}
+
+		if _, err := dao.NonconcurrentDB().DropTable(p.Name).Execute(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package daos
+
+import (
+	"time"
+
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+// WebhookQuery returns a new Webhook select query.
+func (dao *Dao) WebhookQuery() *dbx.SelectQuery {
+	return dao.ModelQuery(&models.Webhook{})
+}
+
+// FindWebhookById finds the webhook with the provided id.
+func (dao *Dao) FindWebhookById(id string) (*models.Webhook, error) {
+	model := &models.Webhook{}
+
+	err := dao.WebhookQuery().
+		AndWhere(dbx.HashExp{"id": id}).
+		Limit(1).
+		One(model)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// FindActiveWebhooks returns all active webhooks.
+func (dao *Dao) FindActiveWebhooks() ([]*models.Webhook, error) {
+	webhooks := []*models.Webhook{}
+
+	err := dao.WebhookQuery().
+		AndWhere(dbx.HashExp{"active": true}).
+		OrderBy("created ASC").
+		All(&webhooks)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// SaveWebhook upserts the provided Webhook model.
+func (dao *Dao) SaveWebhook(webhook *models.Webhook) error {
+	return dao.Save(webhook)
+}
+
+// DeleteWebhook deletes the provided Webhook model
+// (its deliveries are deleted on cascade).
+func (dao *Dao) DeleteWebhook(webhook *models.Webhook) error {
+	return dao.Delete(webhook)
+}
+
+// WebhookDeliveryQuery returns a new WebhookDelivery select query.
+func (dao *Dao) WebhookDeliveryQuery() *dbx.SelectQuery {
+	return dao.ModelQuery(&models.WebhookDelivery{})
+}
+
+// FindWebhookDeliveryById finds the webhook delivery with the provided id.
+func (dao *Dao) FindWebhookDeliveryById(id string) (*models.WebhookDelivery, error) {
+	model := &models.WebhookDelivery{}
+
+	err := dao.WebhookDeliveryQuery().
+		AndWhere(dbx.HashExp{"id": id}).
+		Limit(1).
+		One(model)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// SaveWebhookDelivery upserts the provided WebhookDelivery model.
+func (dao *Dao) SaveWebhookDelivery(delivery *models.WebhookDelivery) error {
+	return dao.Save(delivery)
+}
+
+// ClaimWebhookDeliveries locks and returns up to limit pending webhook
+// deliveries that are due for an attempt.
+//
+// The claimed deliveries attempts counter is incremented and their next
+// attempt is postponed with the lease duration so that they could be
+// picked again if the current worker doesn't finalize them in time.
+//
+// The rows are selected with FOR UPDATE SKIP LOCKED allowing
+// multiple concurrent workers to claim different deliveries.
+func (dao *Dao) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	deliveries := []*models.WebhookDelivery{}
+
+	leaseUntil, err := types.ParseDateTime(time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+
+	err = dao.NonconcurrentDB().NewQuery(`
+		UPDATE {{_webhookDeliveries}}
+		SET [[attempts]] = [[attempts]] + 1, [[nextAttemptAt]] = {:leaseUntil}, [[updated]] = NOW()
+		WHERE [[id]] IN (
+			SELECT [[id]] FROM {{_webhookDeliveries}}
+			WHERE [[status]] = {:status} AND [[nextAttemptAt]] <= NOW()
+			ORDER BY [[nextAttemptAt]] ASC
+			LIMIT {:limit}
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`).Bind(dbx.Params{
+		"leaseUntil": leaseUntil,
+		"status":     models.WebhookDeliveryStatusPending,
+		"limit":      limit,
+	}).All(&deliveries)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// DeleteOldWebhookDeliveries deletes all finalized (success
+// or failed) webhook deliveries created before the specified date.
+func (dao *Dao) DeleteOldWebhookDeliveries(date time.Time) error {
+	formattedDate := date.UTC().Format(types.DefaultDateLayout)
+
+	_, err := dao.NonconcurrentDB().Delete((&models.WebhookDelivery{}).TableName(), dbx.And(
+		dbx.NewExp("[[created]] <= {:date}", dbx.Params{"date": formattedDate}),
+		dbx.In("status", models.WebhookDeliveryStatusSuccess, models.WebhookDeliveryStatusFailed),
+	)).Execute()
+
+	return err
+}
@@ -18,10 +18,15 @@ import (
 	"github.com/hylarucoder/rocketbase/tools/list"
 	"github.com/hylarucoder/rocketbase/tools/search"
 	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
 )
 
 var collectionNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_]*$`)
 
+// collectionPartitionNameRegex matches the names of the partitioned
+// record table partitions (eg. "posts_default", "posts_p20240131").
+var collectionPartitionNameRegex = regexp.MustCompile(`(?i)^(.+)_(?:default|p[0-9]{8})$`)
+
 // CollectionUpsert is a [models.Collection] upsert (create/update) form.
 type CollectionUpsert struct {
 	app        core.App
@@ -133,6 +138,7 @@ func (form *CollectionUpsert) Validate() error {
 			validation.By(form.ensureNoSystemNameChange),
 			validation.By(form.checkUniqueName),
 			validation.By(form.checkForVia),
+			validation.By(form.checkPartitionNames),
 		),
 		// validates using the type's own validation rules + some collection's specifics
 		validation.Field(
@@ -194,6 +200,71 @@ func (form *CollectionUpsert) checkUniqueName(value any) error {
 	return nil
 }
 
+// checkPartitionNames ensures that the collection name doesn't clash
+// with the record table partitions names of a partitioned collection.
+func (form *CollectionUpsert) checkPartitionNames(value any) error {
+	v, _ := value.(string)
+	if v == "" {
+		return nil
+	}
+
+	if form.isPartitioned() {
+		// longer names are truncated by Postgres and their partitions could clash
+		if len(v) > daos.MaxPartitionedCollectionNameLength {
+			return validation.NewError(
+				"validation_collection_partitioned_name_length",
+				fmt.Sprintf("The name of a partitioned collection must be no more than %d characters.", daos.MaxPartitionedCollectionNameLength),
+			)
+		}
+
+		// note: the name is already validated against collectionNameRegex
+		lowerName := strings.ToLower(v)
+
+		var total int
+
+		err := form.dao.CollectionQuery().
+			Select("count(*)").
+			AndWhere(dbx.NewExp("LOWER([[name]]) = {:default} OR LOWER([[name]]) ~ {:pattern}", dbx.Params{
+				"default": lowerName + "_default",
+				"pattern": "^" + lowerName + "_p[0-9]{8}$",
+			})).
+			AndWhere(dbx.Not(dbx.HashExp{"id": form.collection.Id})).
+			Row(&total)
+		if err != nil || total > 0 {
+			return validation.NewError(
+				"validation_collection_partition_name_exists",
+				"The partitions names of the collection clash with an existing collection name.",
+			)
+		}
+	}
+
+	if match := collectionPartitionNameRegex.FindStringSubmatch(v); len(match) > 1 {
+		base, err := form.dao.FindCollectionByNameOrId(match[1])
+		if err == nil && base.Id != form.collection.Id && base.IsBase() && base.BaseOptions().Partition != nil {
+			return validation.NewError(
+				"validation_collection_partition_name_exists",
+				"The name clashes with the partitions names of an existing partitioned collection.",
+			)
+		}
+	}
+
+	return nil
+}
+
+// isPartitioned checks whether the form is for a partitioned base collection.
+func (form *CollectionUpsert) isPartitioned() bool {
+	if form.Type != models.CollectionTypeBase {
+		return false
+	}
+
+	options := models.CollectionBaseOptions{}
+	if err := decodeOptions(form.Options, &options); err != nil {
+		return false
+	}
+
+	return options.Partition != nil
+}
+
 func (form *CollectionUpsert) ensureNoSystemNameChange(value any) error {
 	v, _ := value.(string)
 
@@ -433,6 +504,28 @@ func (form *CollectionUpsert) checkOptions(value any) error {
 	v, _ := value.(types.JsonMap)
 
 	switch form.Type {
+	case models.CollectionTypeBase:
+		options := models.CollectionBaseOptions{}
+		if err := decodeOptions(v, &options); err != nil {
+			return err
+		}
+
+		// check the generic validations
+		if err := options.Validate(); err != nil {
+			return err
+		}
+
+		// the record table cannot be (re)partitioned after its creation
+		if !form.collection.IsNew() {
+			oldPartition := form.collection.BaseOptions().Partition
+			if (options.Partition == nil) != (oldPartition == nil) ||
+				(options.Partition != nil && options.Partition.Interval != oldPartition.Interval) {
+				return validation.Errors{"partition": validation.NewError(
+					"validation_collection_partition_change",
+					"The collection partitioning cannot be changed.",
+				)}
+			}
+		}
 	case models.CollectionTypeAuth:
 		options := models.CollectionAuthOptions{}
 		if err := decodeOptions(v, &options); err != nil {
@@ -12,6 +12,7 @@ import (
 	"github.com/hylarucoder/rocketbase/tests"
 	"github.com/hylarucoder/rocketbase/tools/dbutils"
 	"github.com/hylarucoder/rocketbase/tools/security"
+	"github.com/hylarucoder/rocketbase/tools/types"
 	"github.com/spf13/cast"
 	"github.com/stretchr/testify/suite"
 )
@@ -195,6 +196,18 @@ func (suite *CollectionUpsertTestSuite) TestCollectionUpsertValidateAndSubmit()
 			}`,
 			[]string{"options"},
 		},
+		{
+			"create failure - too long partitioned collection name",
+			"",
+			`{
+				"name": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"schema": [
+					{"name":"test","type":"text"}
+				],
+				"options": { "partition": { "interval": "day" } }
+			}`,
+			[]string{"name"},
+		},
 		{
 			"create success",
 			"",
@@ -808,6 +821,79 @@ func (suite *CollectionUpsertTestSuite) TestCollectionUpsertWithCustomId() {
 	}
 }
 
+func (suite *CollectionUpsertTestSuite) TestCollectionUpsertPartitionNames() {
+	t := suite.T()
+	app := suite.App
+
+	partitioned := &models.Collection{}
+	form := forms.NewCollectionUpsert(app, partitioned)
+	form.Name = "partitioned_test"
+	form.Schema.AddField(&schema.SchemaField{Name: "title", Type: schema.FieldTypeText})
+	form.Options = types.JsonMap{"partition": map[string]any{"interval": "day"}}
+	if err := form.Submit(); err != nil {
+		t.Fatalf("Failed to create the partitioned collection: %v", err)
+	}
+
+	plain := &models.Collection{}
+	form = forms.NewCollectionUpsert(app, plain)
+	form.Name = "plain_test_p20240101"
+	form.Schema.AddField(&schema.SchemaField{Name: "title", Type: schema.FieldTypeText})
+	if err := form.Submit(); err != nil {
+		t.Fatalf("Failed to create the plain collection: %v", err)
+	}
+
+	scenarios := []struct {
+		name        string
+		jsonData    string
+		expectError bool
+	}{
+		{
+			"default partition name of a partitioned collection",
+			`{"name":"partitioned_test_default"}`,
+			true,
+		},
+		{
+			"range partition name of a partitioned collection (case insensitive)",
+			`{"name":"Partitioned_Test_p20240101"}`,
+			true,
+		},
+		{
+			"partition like name of a non partitioned collection",
+			`{"name":"demo1_default"}`,
+			false,
+		},
+		{
+			"partitioned collection clashing with an existing collection",
+			`{"name":"plain_test","options":{"partition":{"interval":"day"}}}`,
+			true,
+		},
+		{
+			"non partitioned collection with an existing partition like name prefix",
+			`{"name":"plain_test"}`,
+			false,
+		},
+	}
+
+	for _, s := range scenarios {
+		form := forms.NewCollectionUpsert(app, &models.Collection{})
+		form.Schema.AddField(&schema.SchemaField{Name: "title", Type: schema.FieldTypeText})
+
+		// load data
+		loadErr := json.Unmarshal([]byte(s.jsonData), form)
+		if loadErr != nil {
+			t.Errorf("[%s] Failed to load form data: %v", s.name, loadErr)
+			continue
+		}
+
+		errs, _ := form.Validate().(validation.Errors)
+		_, hasErr := errs["name"]
+
+		if hasErr != s.expectError {
+			t.Errorf("[%s] Expected hasErr to be %v, got %v (%v)", s.name, s.expectError, hasErr, errs)
+		}
+	}
+}
+
 type CollectionUpsertTestSuite struct {
 	suite.Suite
 	App *tests.TestApp
@@ -0,0 +1,494 @@
+package forms
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+// Collections import plan change actions.
+const (
+	PlanActionCreate = "create"
+	PlanActionUpdate = "update"
+	PlanActionDelete = "delete"
+)
+
+// CollectionsImportPlan describes the changes that a [CollectionsImport]
+// submit would apply to the existing collections.
+type CollectionsImportPlan struct {
+	Changes []*CollectionPlanChange `json:"changes"`
+}
+
+// CollectionPlanChange describes a single collection change.
+type CollectionPlanChange struct {
+	Action      string                `json:"action"`
+	Id          string                `json:"id"`
+	Name        string                `json:"name"`
+	Type        string                `json:"type"`
+	Destructive bool                  `json:"destructive"`
+	Details     []*CollectionPlanItem `json:"details"`
+}
+
+// CollectionPlanItem describes a single collection property change
+// (eg. a rule, field, index or option).
+type CollectionPlanItem struct {
+	Action      string `json:"action"`
+	Target      string `json:"target"`
+	Old         string `json:"old,omitempty"`
+	New         string `json:"new,omitempty"`
+	Destructive bool   `json:"destructive"`
+}
+
+// HasChanges reports whether the plan has at least one change.
+func (p *CollectionsImportPlan) HasChanges() bool {
+	return len(p.Changes) > 0
+}
+
+// Count returns the number of the create, update and delete collection changes
+// and the total number of destructive changes.
+func (p *CollectionsImportPlan) Count() (created, updated, deleted, destructive int) {
+	for _, c := range p.Changes {
+		switch c.Action {
+		case PlanActionCreate:
+			created++
+		case PlanActionUpdate:
+			updated++
+		case PlanActionDelete:
+			deleted++
+		}
+
+		if c.Destructive {
+			destructive++
+		}
+
+		for _, item := range c.Details {
+			if item.Destructive {
+				destructive++
+			}
+		}
+	}
+
+	return
+}
+
+// String returns a human-readable representation of the plan
+// (the lines are prefixed with "+" for additions, "-" for removals and "~" for modifications).
+func (p *CollectionsImportPlan) String() string {
+	var sb strings.Builder
+
+	for _, c := range p.Changes {
+		sb.WriteString(fmt.Sprintf("%s collection %q (%s, %s)", planSymbol(c.Action), c.Name, c.Type, c.Id))
+		if c.Destructive {
+			sb.WriteString(" [destructive: drops the collection table and all its records]")
+		}
+		sb.WriteString("\n")
+
+		for _, item := range c.Details {
+			sb.WriteString("    ")
+			sb.WriteString(planSymbol(item.Action))
+			sb.WriteString(" ")
+			sb.WriteString(item.Target)
+
+			switch item.Action {
+			case PlanActionUpdate:
+				sb.WriteString(": " + item.Old + " -> " + item.New)
+			case PlanActionCreate:
+				if item.New != "" {
+					sb.WriteString(": " + item.New)
+				}
+			case PlanActionDelete:
+				if item.Old != "" {
+					sb.WriteString(": " + item.Old)
+				}
+			}
+
+			if item.Destructive {
+				sb.WriteString(" [destructive]")
+			}
+
+			sb.WriteString("\n")
+		}
+	}
+
+	created, updated, deleted, destructive := p.Count()
+	sb.WriteString(fmt.Sprintf(
+		"Plan: %d to create, %d to update, %d to delete (%d destructive changes).\n",
+		created, updated, deleted, destructive,
+	))
+
+	return sb.String()
+}
+
+func planSymbol(action string) string {
+	switch action {
+	case PlanActionCreate:
+		return "+"
+	case PlanActionDelete:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// Plan compares the form collections with the existing ones and
+// returns the changes that would be applied on [CollectionsImport.Submit]
+// (without modifying the form or the database).
+//
+// Similar to the submit, the collections are matched by their id and
+// the existing collections and fields that are not in the form list
+// are considered for deletion only if [CollectionsImport.DeleteMissing] is set.
+func (form *CollectionsImport) Plan() (*CollectionsImportPlan, error) {
+	existingCollections := []*models.Collection{}
+	if err := form.dao.CollectionQuery().OrderBy("name ASC").All(&existingCollections); err != nil {
+		return nil, err
+	}
+
+	mappedExisting := make(map[string]*models.Collection, len(existingCollections))
+	for _, existing := range existingCollections {
+		mappedExisting[existing.Id] = existing
+	}
+
+	plan := &CollectionsImportPlan{}
+	imported := make(map[string]struct{}, len(form.Collections))
+
+	for _, c := range form.Collections {
+		collectionType := c.Type
+		if collectionType == "" {
+			collectionType = models.CollectionTypeBase
+		}
+
+		existing := mappedExisting[c.Id]
+		if c.Id == "" || existing == nil {
+			change := &CollectionPlanChange{
+				Action: PlanActionCreate,
+				Id:     c.Id,
+				Name:   c.Name,
+				Type:   collectionType,
+			}
+			if change.Id == "" {
+				change.Id = "new"
+			}
+			for _, f := range c.Schema.Fields() {
+				change.Details = append(change.Details, &CollectionPlanItem{
+					Action: PlanActionCreate,
+					Target: fmt.Sprintf("field %q", f.Name),
+					New:    f.Type,
+				})
+			}
+			for _, idx := range c.Indexes {
+				change.Details = append(change.Details, &CollectionPlanItem{
+					Action: PlanActionCreate,
+					Target: "index",
+					New:    idx,
+				})
+			}
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+
+		imported[existing.Id] = struct{}{}
+
+		details, err := diffCollections(existing, c, collectionType, form.DeleteMissing)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(details) > 0 {
+			plan.Changes = append(plan.Changes, &CollectionPlanChange{
+				Action:  PlanActionUpdate,
+				Id:      existing.Id,
+				Name:    c.Name,
+				Type:    collectionType,
+				Details: details,
+			})
+		}
+	}
+
+	if form.DeleteMissing {
+		for _, existing := range existingCollections {
+			if _, ok := imported[existing.Id]; ok {
+				continue
+			}
+
+			plan.Changes = append(plan.Changes, &CollectionPlanChange{
+				Action:      PlanActionDelete,
+				Id:          existing.Id,
+				Name:        existing.Name,
+				Type:        existing.Type,
+				Destructive: true,
+			})
+		}
+	}
+
+	return plan, nil
+}
+
+func diffCollections(old, new *models.Collection, newType string, deleteMissing bool) ([]*CollectionPlanItem, error) {
+	details := []*CollectionPlanItem{}
+
+	if old.Name != new.Name {
+		details = append(details, &CollectionPlanItem{
+			Action: PlanActionUpdate,
+			Target: "name",
+			Old:    old.Name,
+			New:    new.Name,
+		})
+	}
+
+	if old.Type != newType {
+		details = append(details, &CollectionPlanItem{
+			Action:      PlanActionUpdate,
+			Target:      "type",
+			Old:         old.Type,
+			New:         newType,
+			Destructive: true,
+		})
+	}
+
+	if old.System != new.System {
+		details = append(details, &CollectionPlanItem{
+			Action: PlanActionUpdate,
+			Target: "system",
+			Old:    fmt.Sprint(old.System),
+			New:    fmt.Sprint(new.System),
+		})
+	}
+
+	rules := []struct {
+		name string
+		old  *string
+		new  *string
+	}{
+		{"listRule", old.ListRule, new.ListRule},
+		{"viewRule", old.ViewRule, new.ViewRule},
+		{"createRule", old.CreateRule, new.CreateRule},
+		{"updateRule", old.UpdateRule, new.UpdateRule},
+		{"deleteRule", old.DeleteRule, new.DeleteRule},
+	}
+	for _, r := range rules {
+		oldRule, newRule := formatPlanRule(r.old), formatPlanRule(r.new)
+		if oldRule != newRule {
+			details = append(details, &CollectionPlanItem{
+				Action: PlanActionUpdate,
+				Target: r.name,
+				Old:    oldRule,
+				New:    newRule,
+			})
+		}
+	}
+
+	// the view collections schema is resolved from their query
+	if newType != models.CollectionTypeView {
+		fieldsDetails, err := diffSchemas(&old.Schema, &new.Schema, deleteMissing)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, fieldsDetails...)
+	}
+
+	for _, idx := range old.Indexes {
+		if !slices.Contains(new.Indexes, idx) {
+			details = append(details, &CollectionPlanItem{
+				Action: PlanActionDelete,
+				Target: "index",
+				Old:    idx,
+			})
+		}
+	}
+	for _, idx := range new.Indexes {
+		if !slices.Contains(old.Indexes, idx) {
+			details = append(details, &CollectionPlanItem{
+				Action: PlanActionCreate,
+				Target: "index",
+				New:    idx,
+			})
+		}
+	}
+
+	optionsDetails, err := diffPlanValues("options", normalizedPlanOptions(old, old.Type), normalizedPlanOptions(new, newType))
+	if err != nil {
+		return nil, err
+	}
+	details = append(details, optionsDetails...)
+
+	return details, nil
+}
+
+func diffSchemas(old, new *schema.Schema, deleteMissing bool) ([]*CollectionPlanItem, error) {
+	details := []*CollectionPlanItem{}
+
+	for _, newField := range new.Fields() {
+		oldField := old.GetFieldById(newField.Id)
+		if newField.Id == "" || oldField == nil {
+			details = append(details, &CollectionPlanItem{
+				Action: PlanActionCreate,
+				Target: fmt.Sprintf("field %q", newField.Name),
+				New:    newField.Type,
+			})
+			continue
+		}
+
+		target := fmt.Sprintf("field %q", oldField.Name)
+
+		if oldField.Name != newField.Name {
+			details = append(details, &CollectionPlanItem{
+				Action: PlanActionUpdate,
+				Target: target + " name",
+				Old:    oldField.Name,
+				New:    newField.Name,
+			})
+		}
+
+		if oldField.Type != newField.Type {
+			details = append(details, &CollectionPlanItem{
+				Action:      PlanActionUpdate,
+				Target:      target + " type",
+				Old:         oldField.Type,
+				New:         newField.Type,
+				Destructive: true,
+			})
+		}
+
+		if oldField.Required != newField.Required {
+			details = append(details, &CollectionPlanItem{
+				Action: PlanActionUpdate,
+				Target: target + " required",
+				Old:    fmt.Sprint(oldField.Required),
+				New:    fmt.Sprint(newField.Required),
+			})
+		}
+
+		if oldField.Presentable != newField.Presentable {
+			details = append(details, &CollectionPlanItem{
+				Action: PlanActionUpdate,
+				Target: target + " presentable",
+				Old:    fmt.Sprint(oldField.Presentable),
+				New:    fmt.Sprint(newField.Presentable),
+			})
+		}
+
+		optionsDetails, err := diffPlanValues(target+" options", oldField.Options, newField.Options)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, optionsDetails...)
+	}
+
+	// the missing fields are preserved on import unless deleteMissing is set
+	if deleteMissing {
+		for _, oldField := range old.Fields() {
+			if new.GetFieldById(oldField.Id) != nil {
+				continue
+			}
+
+			details = append(details, &CollectionPlanItem{
+				Action:      PlanActionDelete,
+				Target:      fmt.Sprintf("field %q", oldField.Name),
+				Old:         oldField.Type,
+				Destructive: true,
+			})
+		}
+	}
+
+	return details, nil
+}
+
+// diffPlanValues compares the top level keys of the JSON
+// serialized old and new values (eg. collection or field options).
+func diffPlanValues(target string, old, new any) ([]*CollectionPlanItem, error) {
+	oldMap, err := toPlanMap(old)
+	if err != nil {
+		return nil, err
+	}
+
+	newMap, err := toPlanMap(new)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+	for k := range oldMap {
+		keys = append(keys, k)
+	}
+	for k := range newMap {
+		if _, ok := oldMap[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	details := []*CollectionPlanItem{}
+
+	for _, k := range keys {
+		oldVal, newVal := oldMap[k], newMap[k]
+		if oldVal == newVal {
+			continue
+		}
+
+		if oldVal == "" {
+			oldVal = "null"
+		}
+		if newVal == "" {
+			newVal = "null"
+		}
+
+		details = append(details, &CollectionPlanItem{
+			Action: PlanActionUpdate,
+			Target: target + "." + k,
+			Old:    oldVal,
+			New:    newVal,
+		})
+	}
+
+	return details, nil
+}
+
+// toPlanMap returns the JSON serialized top level values of the provided object.
+func toPlanMap(v any) (map[string]string, error) {
+	result := map[string]string{}
+
+	if v == nil {
+		return result, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		// not an object (eg. null)
+		return result, nil
+	}
+
+	for k, v := range obj {
+		result[k] = string(v)
+	}
+
+	return result, nil
+}
+
+// normalizedPlanOptions returns the collection options normalized
+// based on the provided collection type (without modifying the collection).
+func normalizedPlanOptions(c *models.Collection, collectionType string) types.JsonMap {
+	clone := *c
+	clone.Type = collectionType
+	clone.NormalizeOptions()
+
+	return clone.Options
+}
+
+func formatPlanRule(rule *string) string {
+	if rule == nil {
+		return "null"
+	}
+
+	return fmt.Sprintf("%q", *rule)
+}
@@ -3,6 +3,7 @@ package forms_test
 import (
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/hylarucoder/rocketbase/forms"
@@ -507,6 +508,110 @@ func (suite *CollectionsImportTestSuite) TestCollectionsImportSubmitInterceptors
 	}
 }
 
+func (suite *CollectionsImportTestSuite) TestCollectionsImportPlan() {
+	t := suite.T()
+	app := suite.App
+
+	existing := []*models.Collection{}
+	if err := app.Dao().CollectionQuery().OrderBy("name ASC").All(&existing); err != nil {
+		t.Fatal(err)
+	}
+
+	// no changes
+	form := forms.NewCollectionsImport(app)
+	form.Collections = existing
+	plan, err := form.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.HasChanges() {
+		t.Fatalf("Expected no changes, got\n%s", plan)
+	}
+
+	// modify a single collection and add a new one
+	var demo1 *models.Collection
+	for _, c := range existing {
+		if c.Name == "demo1" {
+			demo1 = c
+		}
+	}
+	if demo1 == nil {
+		t.Fatal("Missing demo1 collection")
+	}
+
+	newRule := "@request.auth.id != ''"
+	demo1.ListRule = &newRule
+	removedField := demo1.Schema.Fields()[0]
+	demo1.Schema.RemoveField(removedField.Id)
+
+	newCollection := &models.Collection{Name: "plan_new"}
+
+	scenarios := []struct {
+		deleteMissing        bool
+		expectedCounts       [4]int
+		expectedContains     []string
+		expectedNotContained []string
+	}{
+		{
+			false,
+			[4]int{1, 1, 0, 0},
+			[]string{
+				`+ collection "plan_new" (base, new)`,
+				`~ collection "demo1"`,
+				`-> "@request.auth.id != ''"`,
+			},
+			[]string{"[destructive"},
+		},
+		{
+			true,
+			[4]int{1, 1, len(existing) - 1, len(existing)},
+			[]string{
+				`- field "` + removedField.Name + `"`,
+				`- collection "demo2"`,
+				"[destructive",
+			},
+			nil,
+		},
+	}
+
+	for i, s := range scenarios {
+		form := forms.NewCollectionsImport(app)
+		form.Collections = []*models.Collection{demo1, newCollection}
+		form.DeleteMissing = s.deleteMissing
+
+		plan, err := form.Plan()
+		if err != nil {
+			t.Fatalf("[%d] %v", i, err)
+		}
+
+		created, updated, deleted, destructive := plan.Count()
+		if counts := [4]int{created, updated, deleted, destructive}; counts != s.expectedCounts {
+			t.Fatalf("[%d] Expected counts %v, got %v", i, s.expectedCounts, counts)
+		}
+
+		str := plan.String()
+		for _, v := range s.expectedContains {
+			if !strings.Contains(str, v) {
+				t.Fatalf("[%d] Expected %q in\n%s", i, v, str)
+			}
+		}
+		for _, v := range s.expectedNotContained {
+			if strings.Contains(str, v) {
+				t.Fatalf("[%d] Didn't expect %q in\n%s", i, v, str)
+			}
+		}
+	}
+
+	// the plan shouldn't modify the db
+	var total int
+	if err := app.Dao().CollectionQuery().Select("count(*)").Row(&total); err != nil {
+		t.Fatal(err)
+	}
+	if total != len(existing) {
+		t.Fatalf("Expected %d collections, got %d", len(existing), total)
+	}
+}
+
 type CollectionsImportTestSuite struct {
 	suite.Suite
 	App *tests.TestApp
@@ -800,7 +800,13 @@ func (form *RecordUpsert) Submit(interceptors ...InterceptorFunc[*models.Record]
 		// ---
 
 		// persist the record model
-		if err := dao.SaveRecord(form.record); err != nil {
+		//
+		// note: wrapped in a transaction so that the record change and its
+		// related writes in the model hooks (eg. webhook deliveries) are atomic
+		err := dao.RunInTransaction(func(txDao *daos.Dao) error {
+			return txDao.SaveRecord(form.record)
+		})
+		if err != nil {
 			return form.prepareError(err)
 		}
 
@@ -0,0 +1,561 @@
+package forms
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/models/schema"
+	"github.com/hylarucoder/rocketbase/tools/dataset"
+	"github.com/hylarucoder/rocketbase/tools/dbutils"
+	"github.com/hylarucoder/rocketbase/tools/filesystem"
+	"github.com/hylarucoder/rocketbase/tools/list"
+	"github.com/spf13/cast"
+)
+
+const (
+	// DefaultRecordsImportChunkSize specifies the default number
+	// of rows that are imported within a single transaction.
+	DefaultRecordsImportChunkSize = 100
+
+	// MaxRecordsImportChunkSize specifies the max allowed number
+	// of rows that are imported within a single transaction.
+	MaxRecordsImportChunkSize = 1000
+
+	// MaxRecordsImportReportErrors specifies the max number of
+	// row errors that are included in the import report
+	// (the remaining failures are only counted).
+	MaxRecordsImportReportErrors = 1000
+)
+
+const (
+	recordsImportActionCreate = "create"
+	recordsImportActionUpdate = "update"
+)
+
+var errRecordsImportDryRun = errors.New("records import dry run")
+
+// RecordsImportRowError defines a single failed import row.
+type RecordsImportRowError struct {
+	// Row is the 1-based data row number (the CSV header is not counted).
+	Row int `json:"row"`
+
+	Errors validation.Errors `json:"errors"`
+}
+
+// RecordsImportResult defines the records import report.
+type RecordsImportResult struct {
+	DryRun  bool                     `json:"dryRun"`
+	Total   int                      `json:"total"`
+	Created int                      `json:"created"`
+	Updated int                      `json:"updated"`
+	Failed  int                      `json:"failed"`
+	Errors  []*RecordsImportRowError `json:"errors"`
+}
+
+// RecordsImport is a form model to bulk import (create or update)
+// collection records from a CSV or NDJSON file.
+//
+// Each row is validated and saved with the [RecordUpsert] form.
+type RecordsImport struct {
+	app             core.App
+	dao             *daos.Dao
+	collection      *models.Collection
+	allowLocalFiles bool
+
+	File *filesystem.File `json:"file"`
+
+	// Format is the file format (csv, ndjson or excel).
+	// If not set, it is resolved from the file extension.
+	Format string `form:"format" json:"format"`
+
+	// DryRun validates and saves the rows without committing the changes.
+	DryRun bool `form:"dryRun" json:"dryRun"`
+
+	// UpsertBy is an optional unique field used to match and
+	// update the existing records instead of creating new ones.
+	UpsertBy string `form:"upsertBy" json:"upsertBy"`
+
+	// ChunkSize is the number of rows imported within a single transaction.
+	ChunkSize int `form:"chunkSize" json:"chunkSize"`
+
+	// Mapping is an optional "source column => record field" map.
+	// Columns mapped to empty string or "-" are skipped.
+	//
+	// Note that the form data binder doesn't support maps and
+	// it is expected to be loaded manually from a JSON encoded value.
+	Mapping map[string]string `form:"-" json:"mapping"`
+}
+
+// NewRecordsImport creates a new [RecordsImport] form for the
+// provided collection initialized with the [core.App] instance.
+//
+// If you want to submit the form as part of a transaction,
+// you can change the default Dao via [SetDao()].
+func NewRecordsImport(app core.App, collection *models.Collection) *RecordsImport {
+	return &RecordsImport{
+		app:        app,
+		dao:        app.Dao(),
+		collection: collection,
+		ChunkSize:  DefaultRecordsImportChunkSize,
+	}
+}
+
+// SetDao replaces the default form Dao instance with the provided one.
+func (form *RecordsImport) SetDao(dao *daos.Dao) {
+	form.dao = dao
+}
+
+// SetAllowLocalFiles enables/disables loading file field values
+// from local filesystem paths (by default only http(s) urls are allowed).
+//
+// Usually it should be enabled only when used from the console.
+func (form *RecordsImport) SetAllowLocalFiles(allow bool) {
+	form.allowLocalFiles = allow
+}
+
+// Validate makes the form validatable by implementing [validation.Validatable] interface.
+func (form *RecordsImport) Validate() error {
+	formats := make([]any, 0, len(dataset.Formats()))
+	for _, f := range dataset.Formats() {
+		formats = append(formats, string(f))
+	}
+
+	return validation.ValidateStruct(form,
+		validation.Field(&form.File, validation.Required),
+		validation.Field(&form.Format, validation.In(formats...)),
+		validation.Field(&form.UpsertBy, validation.By(form.checkUpsertBy)),
+		validation.Field(&form.ChunkSize, validation.Min(0), validation.Max(MaxRecordsImportChunkSize)),
+		validation.Field(&form.Mapping, validation.By(form.checkMapping)),
+	)
+}
+
+func (form *RecordsImport) checkUpsertBy(value any) error {
+	v, _ := value.(string)
+	if v == "" || v == schema.FieldNameId {
+		return nil
+	}
+
+	if form.collection.IsAuth() && (v == schema.FieldNameUsername || v == schema.FieldNameEmail) {
+		return nil
+	}
+
+	if form.collection.Schema.GetFieldByName(v) != nil &&
+		dbutils.HasSingleColumnUniqueIndex(v, form.collection.Indexes) {
+		return nil
+	}
+
+	return validation.NewError("validation_invalid_upsert_field", "The upsert field must be a unique collection field.")
+}
+
+func (form *RecordsImport) checkMapping(value any) error {
+	v, _ := value.(map[string]string)
+
+	for column, field := range v {
+		if field == "" || field == "-" || form.isImportableField(field) {
+			continue
+		}
+
+		return validation.NewError(
+			"validation_invalid_mapping_field",
+			fmt.Sprintf("Column %q is mapped to unknown field %q.", column, field),
+		)
+	}
+
+	return nil
+}
+
+func (form *RecordsImport) isImportableField(name string) bool {
+	if name == schema.FieldNameId || form.collection.Schema.GetFieldByName(name) != nil {
+		return true
+	}
+
+	if form.collection.IsAuth() {
+		return list.ExistInSlice(name, []string{
+			schema.FieldNameUsername,
+			schema.FieldNameEmail,
+			schema.FieldNameEmailVisibility,
+			schema.FieldNameVerified,
+			"password",
+			"passwordConfirm",
+		})
+	}
+
+	return false
+}
+
+func (form *RecordsImport) resolveFormat() dataset.Format {
+	if form.Format != "" {
+		return dataset.Format(form.Format)
+	}
+
+	switch strings.ToLower(filepath.Ext(form.File.OriginalName)) {
+	case ".ndjson", ".jsonl":
+		return dataset.FormatNDJSON
+	}
+
+	return dataset.FormatCSV
+}
+
+// Submit validates the form and imports the file rows in chunks.
+//
+// Every chunk is imported in its own transaction and the failed rows
+// are skipped and reported in the returned result.
+// If [form.DryRun] is set, all chunk transactions are rolled back.
+//
+// A non-nil error is returned only on form validation or source read/db
+// failure (in which case the previously imported chunks remain committed).
+func (form *RecordsImport) Submit() (*RecordsImportResult, error) {
+	if err := form.Validate(); err != nil {
+		return nil, err
+	}
+
+	chunkSize := form.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultRecordsImportChunkSize
+	}
+
+	src, err := form.File.Reader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	reader, err := dataset.NewReader(src, form.resolveFormat())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RecordsImportResult{
+		DryRun: form.DryRun,
+		Errors: []*RecordsImportRowError{},
+	}
+
+	for {
+		chunk, readErr := readRecordsImportChunk(reader, chunkSize)
+		if readErr != nil {
+			return result, fmt.Errorf("failed to read row %d: %w", result.Total+len(chunk)+1, readErr)
+		}
+
+		if len(chunk) == 0 {
+			return result, nil
+		}
+
+		chunkResult := &RecordsImportResult{}
+
+		// the imported rows upsert forms with their uploaded and replaced files
+		var imported []*RecordUpsert
+
+		txErr := form.dao.RunInTransaction(func(txDao *daos.Dao) error {
+			for i, item := range chunk {
+				row := result.Total + i + 1
+
+				upsertForm, action, rowErrs, err := form.importRowWithSavepoint(txDao, item)
+				if err != nil {
+					return err
+				}
+
+				if len(rowErrs) > 0 {
+					chunkResult.Failed++
+					chunkResult.Errors = append(chunkResult.Errors, &RecordsImportRowError{Row: row, Errors: rowErrs})
+					continue
+				}
+
+				if upsertForm != nil {
+					imported = append(imported, upsertForm)
+				}
+
+				if action == recordsImportActionCreate {
+					chunkResult.Created++
+				} else {
+					chunkResult.Updated++
+				}
+			}
+
+			if form.DryRun {
+				return errRecordsImportDryRun
+			}
+
+			return nil
+		})
+		if txErr != nil && !errors.Is(txErr, errRecordsImportDryRun) {
+			form.cleanupChunkFiles(imported)
+			return result, txErr
+		}
+
+		form.deleteChunkReplacedFiles(imported)
+
+		result.Total += len(chunk)
+		result.Created += chunkResult.Created
+		result.Updated += chunkResult.Updated
+		result.Failed += chunkResult.Failed
+		for _, rowErr := range chunkResult.Errors {
+			if len(result.Errors) >= MaxRecordsImportReportErrors {
+				break
+			}
+			result.Errors = append(result.Errors, rowErr)
+		}
+	}
+}
+
+// readRecordsImportChunk reads up to size items from the provided reader.
+func readRecordsImportChunk(reader dataset.Reader, size int) ([]map[string]any, error) {
+	chunk := make([]map[string]any, 0, size)
+
+	for len(chunk) < size {
+		item, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return chunk, err
+		}
+
+		chunk = append(chunk, item)
+	}
+
+	return chunk, nil
+}
+
+// importRowWithSavepoint imports a single row within a savepoint so that
+// a failed row db statement doesn't abort the entire chunk transaction.
+//
+// The row failure is returned as validation.Errors and the error
+// result is reserved only for the savepoint statements failures.
+func (form *RecordsImport) importRowWithSavepoint(txDao *daos.Dao, item map[string]any) (*RecordUpsert, string, validation.Errors, error) {
+	if _, err := txDao.DB().NewQuery("SAVEPOINT records_import_row").Execute(); err != nil {
+		return nil, "", nil, err
+	}
+
+	upsertForm, action, importErr := form.importRow(txDao, item)
+	if importErr != nil {
+		if _, err := txDao.DB().NewQuery("ROLLBACK TO SAVEPOINT records_import_row").Execute(); err != nil {
+			return nil, "", nil, err
+		}
+
+		rowErrs, ok := importErr.(validation.Errors)
+		if !ok {
+			rowErrs = validation.Errors{"row": validation.NewError("validation_import_row_failure", importErr.Error())}
+		}
+
+		return nil, "", rowErrs, nil
+	}
+
+	if _, err := txDao.DB().NewQuery("RELEASE SAVEPOINT records_import_row").Execute(); err != nil {
+		form.cleanupChunkFiles([]*RecordUpsert{upsertForm})
+		return nil, "", nil, err
+	}
+
+	return upsertForm, action, nil, nil
+}
+
+// importRow maps, validates and saves a single import row.
+//
+// Returns the row upsert form (nil on dry run) so that its replaced
+// files could be deleted only after the chunk transaction commit.
+//
+// note: the record is saved directly with the chunk txDao instead of
+// RecordUpsert.Submit because the Submit nested transaction is only
+// a savepoint here and the replaced files would be deleted before the
+// chunk commit (or even if the chunk is later rolled back).
+func (form *RecordsImport) importRow(txDao *daos.Dao, item map[string]any) (*RecordUpsert, string, error) {
+	data := make(map[string]any, len(item))
+	for column, value := range item {
+		field := column
+		if mapped, ok := form.Mapping[column]; ok {
+			field = mapped
+		}
+
+		if field == "" || field == "-" {
+			continue
+		}
+
+		data[field] = value
+	}
+
+	record, err := form.findUpsertRecord(txDao, data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	action := recordsImportActionUpdate
+	if record == nil {
+		action = recordsImportActionCreate
+		record = models.NewRecord(form.collection)
+	}
+
+	newFiles, err := form.extractFiles(record, data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	upsertForm := NewRecordUpsert(form.app, record)
+	upsertForm.SetDao(txDao)
+	upsertForm.SetFullManageAccess(true)
+
+	if err := upsertForm.LoadData(data); err != nil {
+		return nil, "", err
+	}
+
+	for key, files := range newFiles {
+		if err := upsertForm.AddFiles(key, files...); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := upsertForm.ValidateAndFill(); err != nil {
+		return nil, "", err
+	}
+
+	if err := txDao.SaveRecord(record); err != nil {
+		return nil, "", upsertForm.prepareError(err)
+	}
+
+	if form.DryRun {
+		return nil, action, nil
+	}
+
+	// upload the new files after the save so that the final record id
+	// (eg. changed by a before hook) is used for the files path
+	//
+	// (on failure the row is rolled back and the partially uploaded
+	// files are removed by processFilesToUpload)
+	if err := upsertForm.processFilesToUpload(); err != nil {
+		return nil, "", err
+	}
+
+	return upsertForm, action, nil
+}
+
+// cleanupChunkFiles removes the uploaded files of the provided
+// rolled back chunk rows.
+func (form *RecordsImport) cleanupChunkFiles(imported []*RecordUpsert) {
+	for _, upsertForm := range imported {
+		var uploaded []string
+		for _, files := range upsertForm.filesToUpload {
+			for _, file := range files {
+				uploaded = append(uploaded, file.Name)
+			}
+		}
+
+		if _, err := upsertForm.deleteFilesByNamesList(uploaded); err != nil {
+			form.app.Logger().Debug(
+				"Failed to cleanup the rolled back import files",
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// deleteChunkReplacedFiles deletes the old (replaced or removed)
+// files of the provided committed chunk rows.
+//
+// Similar to RecordUpsert.Submit the delete errors are only logged.
+func (form *RecordsImport) deleteChunkReplacedFiles(imported []*RecordUpsert) {
+	for _, upsertForm := range imported {
+		if err := upsertForm.processFilesToDelete(); err != nil {
+			form.app.Logger().Debug(
+				"Failed to delete old files",
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
+
+// findUpsertRecord returns the existing record matching the form.UpsertBy
+// row value or nil if there is no such record.
+func (form *RecordsImport) findUpsertRecord(txDao *daos.Dao, data map[string]any) (*models.Record, error) {
+	if form.UpsertBy == "" {
+		return nil, nil
+	}
+
+	value := cast.ToString(data[form.UpsertBy])
+	if value == "" {
+		return nil, nil
+	}
+
+	var record *models.Record
+	var err error
+
+	if form.UpsertBy == schema.FieldNameId {
+		record, err = txDao.FindRecordById(form.collection.Id, value)
+	} else {
+		record, err = txDao.FindFirstRecordByData(form.collection.Id, form.UpsertBy, value)
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// extractFiles replaces the data file field values with the names of
+// the already existing record files and returns the new files to upload
+// loaded from the remaining urls (or local paths, if allowed).
+func (form *RecordsImport) extractFiles(record *models.Record, data map[string]any) (map[string][]*filesystem.File, error) {
+	result := map[string][]*filesystem.File{}
+
+	for _, field := range form.collection.Schema.Fields() {
+		if field.Type != schema.FieldTypeFile {
+			continue
+		}
+
+		raw, ok := data[field.Name]
+		if !ok {
+			continue
+		}
+
+		existing := record.GetStringSlice(field.Name)
+		kept := []string{}
+
+		for _, source := range list.ToUniqueStringSlice(raw) {
+			if list.ExistInSlice(source, existing) {
+				kept = append(kept, source)
+				continue
+			}
+
+			file, err := form.loadFile(source)
+			if err != nil {
+				return nil, validation.Errors{field.Name: validation.NewError(
+					"validation_invalid_file_source",
+					fmt.Sprintf("Failed to load file %q: %v.", source, err),
+				)}
+			}
+
+			result[field.Name] = append(result[field.Name], file)
+		}
+
+		data[field.Name] = kept
+	}
+
+	return result, nil
+}
+
+func (form *RecordsImport) loadFile(source string) (*filesystem.File, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		return filesystem.NewFileFromUrl(ctx, source)
+	}
+
+	if !form.allowLocalFiles {
+		return nil, errors.New("only http(s) urls are allowed")
+	}
+
+	return filesystem.NewFileFromPath(source)
+}
@@ -0,0 +1,289 @@
+package forms_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/forms"
+	"github.com/hylarucoder/rocketbase/tests"
+	"github.com/hylarucoder/rocketbase/tools/filesystem"
+	"github.com/hylarucoder/rocketbase/tools/list"
+	"github.com/stretchr/testify/suite"
+)
+
+func (suite *RecordsImportTestSuite) TestRecordsImportValidate() {
+	t := suite.T()
+	app := suite.App
+
+	collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := filesystem.NewFileFromBytes([]byte("title\ntest"), "test.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		name           string
+		file           *filesystem.File
+		format         string
+		upsertBy       string
+		chunkSize      int
+		mapping        map[string]string
+		expectedErrors []string
+	}{
+		{
+			"empty",
+			nil,
+			"",
+			"",
+			0,
+			nil,
+			[]string{"file"},
+		},
+		{
+			"invalid fields",
+			file,
+			"xlsx",
+			"active",
+			forms.MaxRecordsImportChunkSize + 1,
+			map[string]string{"a": "title", "b": "missing"},
+			[]string{"format", "upsertBy", "chunkSize", "mapping"},
+		},
+		{
+			"valid fields",
+			file,
+			"ndjson",
+			"id",
+			10,
+			map[string]string{"a": "title", "b": "-", "c": ""},
+			[]string{},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			form := forms.NewRecordsImport(app, collection)
+			form.File = s.file
+			form.Format = s.format
+			form.UpsertBy = s.upsertBy
+			form.ChunkSize = s.chunkSize
+			form.Mapping = s.mapping
+
+			result := form.Validate()
+
+			// parse errors
+			errs := map[string]any{}
+			if result != nil {
+				raw, _ := json.Marshal(result)
+				if err := json.Unmarshal(raw, &errs); err != nil {
+					t.Fatalf("Failed to parse errors %v", result)
+				}
+			}
+
+			if len(errs) != len(s.expectedErrors) {
+				t.Fatalf("Expected error keys %v, got %v", s.expectedErrors, errs)
+			}
+			for _, k := range s.expectedErrors {
+				if _, ok := errs[k]; !ok {
+					t.Fatalf("Missing expected error key %q in %v", k, errs)
+				}
+			}
+		})
+	}
+}
+
+func (suite *RecordsImportTestSuite) TestRecordsImportSubmit() {
+	t := suite.T()
+
+	scenarios := []struct {
+		name             string
+		content          string
+		filename         string
+		dryRun           bool
+		upsertBy         string
+		chunkSize        int
+		mapping          map[string]string
+		expectError      bool
+		expectedResult   string
+		expectedTitles   []string
+		unexpectedTitles []string
+	}{
+		{
+			name:           "csv with valid and invalid rows",
+			content:        "name,active,extra\nimport1,true,x\n,false,y\nimport2,0,z\n",
+			filename:       "test.csv",
+			chunkSize:      2,
+			mapping:        map[string]string{"name": "title", "extra": "-"},
+			expectedResult: `{"dryRun":false,"total":3,"created":2,"updated":0,"failed":1,"errors":[{"row":2,"errors":{"title":"cannot be blank"}}]}`,
+			expectedTitles: []string{"import1", "import2"},
+		},
+		{
+			name:             "dry run",
+			content:          "{\"title\":\"import_dry\"}\n",
+			filename:         "test.ndjson",
+			dryRun:           true,
+			expectedResult:   `{"dryRun":true,"total":1,"created":1,"updated":0,"failed":0,"errors":[]}`,
+			unexpectedTitles: []string{"import_dry"},
+		},
+		{
+			name:           "upsert by id",
+			content:        "id,title\n3479948460419978246,import_updated\n",
+			filename:       "test.csv",
+			upsertBy:       "id",
+			expectedResult: `{"dryRun":false,"total":1,"created":0,"updated":1,"failed":0,"errors":[]}`,
+			expectedTitles: []string{"import_updated"},
+		},
+		{
+			name:        "malformed csv row",
+			content:     "title,active\nimport_malformed\n",
+			filename:    "test.csv",
+			expectError: true,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app, _ := tests.NewTestApp()
+			defer app.Cleanup()
+
+			collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := filesystem.NewFileFromBytes([]byte(s.content), s.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			form := forms.NewRecordsImport(app, collection)
+			form.File = file
+			form.DryRun = s.dryRun
+			form.UpsertBy = s.upsertBy
+			form.Mapping = s.mapping
+			if s.chunkSize > 0 {
+				form.ChunkSize = s.chunkSize
+			}
+
+			result, err := form.Submit()
+
+			hasErr := err != nil
+			if hasErr != s.expectError {
+				t.Fatalf("Expected hasErr %v, got %v (%v)", s.expectError, hasErr, err)
+			}
+
+			if hasErr {
+				return
+			}
+
+			encoded, _ := json.Marshal(result)
+			if string(encoded) != s.expectedResult {
+				t.Fatalf("Expected result \n%s, \ngot \n%s", s.expectedResult, encoded)
+			}
+
+			titles := []string{}
+			records, _ := app.Dao().FindRecordsByFilter(collection.Id, "id != ''", "", 0, 0)
+			for _, r := range records {
+				titles = append(titles, r.GetString("title"))
+			}
+
+			for _, title := range s.expectedTitles {
+				if !list.ExistInSlice(title, titles) {
+					t.Fatalf("Missing expected record with title %q in %v", title, titles)
+				}
+			}
+
+			for _, title := range s.unexpectedTitles {
+				if list.ExistInSlice(title, titles) {
+					t.Fatalf("Didn't expect record with title %q", title)
+				}
+			}
+		})
+	}
+}
+
+func (suite *RecordsImportTestSuite) TestRecordsImportSubmitReplacedFiles() {
+	t := suite.T()
+
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	recordBefore, err := app.Dao().FindRecordById("demo1", "3479947686461838339")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldFile := recordBefore.GetString("file_one")
+	if oldFile == "" || !hasRecordFile(app, recordBefore, oldFile) {
+		t.Fatalf("Expected the record to have an existing file_one file, got %q", oldFile)
+	}
+
+	localFile := filepath.Join(t.TempDir(), "import_new.txt")
+	if err := os.WriteFile(localFile, []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	content := fmt.Sprintf(`{"id":%q,"file_one":%q}`, recordBefore.Id, localFile)
+	file, err := filesystem.NewFileFromBytes([]byte(content), "test.ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := forms.NewRecordsImport(app, recordBefore.Collection())
+	form.SetAllowLocalFiles(true)
+	form.File = file
+	form.UpsertBy = "id"
+
+	result, err := form.Submit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Updated != 1 {
+		encoded, _ := json.Marshal(result)
+		t.Fatalf("Expected 1 updated record, got %s", encoded)
+	}
+
+	recordAfter, err := app.Dao().FindRecordById("demo1", recordBefore.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newFile := recordAfter.GetString("file_one")
+	if newFile == "" || newFile == oldFile {
+		t.Fatalf("Expected file_one to be replaced, got %q", newFile)
+	}
+
+	if !hasRecordFile(app, recordAfter, newFile) {
+		t.Fatalf("Expected the new file %q to be uploaded", newFile)
+	}
+
+	// the replaced file is deleted after the chunk commit
+	if hasRecordFile(app, recordAfter, oldFile) {
+		t.Fatalf("Expected the replaced file %q to be deleted", oldFile)
+	}
+}
+
+type RecordsImportTestSuite struct {
+	suite.Suite
+	App *tests.TestApp
+}
+
+func (suite *RecordsImportTestSuite) SetupSuite() {
+	app, _ := tests.NewTestApp()
+	suite.App = app
+}
+
+func (suite *RecordsImportTestSuite) TearDownSuite() {
+	suite.App.Cleanup()
+}
+
+func TestRecordsImportTestSuite(t *testing.T) {
+	suite.Run(t, new(RecordsImportTestSuite))
+}
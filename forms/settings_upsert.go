@@ -4,11 +4,11 @@ import (
 	"os"
 	"time"
 
+	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/hylarucoder/rocketbase/core"
 	"github.com/hylarucoder/rocketbase/daos"
 	"github.com/hylarucoder/rocketbase/models"
 	"github.com/hylarucoder/rocketbase/models/settings"
-	"github.com/hylarucoder/rocketbase/tools/types"
 	"github.com/pocketbase/dbx"
 )
 
@@ -44,7 +44,21 @@ func (form *SettingsUpsert) SetDao(dao *daos.Dao) {
 
 // Validate makes the form validatable by implementing [validation.Validatable] interface.
 func (form *SettingsUpsert) Validate() error {
-	return form.Settings.Validate()
+	if err := form.Settings.Validate(); err != nil {
+		return err
+	}
+
+	// the audit logs append-only protection is irreversible
+	if form.app.Settings().Audit.AppendOnly && !form.Settings.Audit.AppendOnly {
+		return validation.Errors{"audit": validation.Errors{
+			"appendOnly": validation.NewError(
+				"validation_audit_append_only_locked",
+				"The audit logs append-only protection can't be disabled.",
+			),
+		}}
+	}
+
+	return nil
 }
 
 // Submit validates the form and upserts the loaded settings.
@@ -61,6 +75,14 @@ func (form *SettingsUpsert) Submit(interceptors ...InterceptorFunc[*settings.Set
 	return runInterceptors(form.Settings, func(s *settings.Settings) error {
 		form.Settings = s
 
+		// protect the audit logs before persisting the setting so that
+		// it is never stored as enabled without the trigger in place
+		if s.Audit.AppendOnly {
+			if err := form.dao.EnableAuditLogsAppendOnly(); err != nil {
+				return err
+			}
+		}
+
 		// persists settings change
 		encryptionKey := os.Getenv(form.app.EncryptionEnv())
 		if err := form.dao.SaveSettings(form.Settings, encryptionKey); err != nil {
@@ -73,9 +95,8 @@ func (form *SettingsUpsert) Submit(interceptors ...InterceptorFunc[*settings.Set
 		}
 
 		// try to clear old logs not matching the new settings
-		createdBefore := time.Now().AddDate(0, 0, -1*form.Settings.Logs.MaxDays).UTC().Format(types.DefaultDateLayout)
-		expr := dbx.NewExp("[[created]] <= {:date} OR [[level]] < {:level}", dbx.Params{
-			"date":  createdBefore,
+		form.app.LogsDao().DeleteExpiredLogs(form.Settings.Logs, time.Now())
+		expr := dbx.NewExp("[[level]] < {:level}", dbx.Params{
 			"level": form.Settings.Logs.MinLevel,
 		})
 		form.app.LogsDao().NonconcurrentDB().Delete((&models.Log{}).TableName(), expr).Execute()
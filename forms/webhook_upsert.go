@@ -0,0 +1,172 @@
+package forms
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/hylarucoder/rocketbase/core"
+	"github.com/hylarucoder/rocketbase/daos"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/resolvers"
+	"github.com/hylarucoder/rocketbase/tools/list"
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+// WebhookUpsert is a [models.Webhook] upsert (create/update) form.
+type WebhookUpsert struct {
+	app     core.App
+	dao     *daos.Dao
+	webhook *models.Webhook
+
+	Name         string                  `form:"name" json:"name"`
+	CollectionId string                  `form:"collectionId" json:"collectionId"`
+	Events       types.JsonArray[string] `form:"events" json:"events"`
+	Filter       string                  `form:"filter" json:"filter"`
+	Url          string                  `form:"url" json:"url"`
+	Secret       string                  `form:"secret" json:"secret"`
+	Active       bool                    `form:"active" json:"active"`
+}
+
+// NewWebhookUpsert creates a new [WebhookUpsert] form with initializer
+// config created from the provided [core.App] and [models.Webhook] instances
+// (for create you could pass a pointer to an empty Webhook - `&models.Webhook{}`).
+//
+// If you want to submit the form as part of a transaction,
+// you can change the default Dao via [SetDao()].
+func NewWebhookUpsert(app core.App, webhook *models.Webhook) *WebhookUpsert {
+	form := &WebhookUpsert{
+		app:     app,
+		dao:     app.Dao(),
+		webhook: webhook,
+	}
+
+	// load defaults
+	form.Name = webhook.Name
+	form.CollectionId = webhook.CollectionId
+	form.Events = webhook.Events
+	form.Filter = webhook.Filter
+	form.Url = webhook.Url
+	form.Secret = webhook.Secret
+	form.Active = webhook.Active || webhook.IsNew()
+
+	return form
+}
+
+// SetDao replaces the default form Dao instance with the provided one.
+func (form *WebhookUpsert) SetDao(dao *daos.Dao) {
+	form.dao = dao
+}
+
+// Validate makes the form validatable by implementing [validation.Validatable] interface.
+func (form *WebhookUpsert) Validate() error {
+	collection, _ := form.dao.FindCollectionByNameOrId(form.CollectionId)
+
+	return validation.ValidateStruct(form,
+		validation.Field(
+			&form.Name,
+			validation.Required,
+			validation.Length(1, 255),
+		),
+		validation.Field(
+			&form.CollectionId,
+			validation.Required,
+			validation.By(form.checkCollection(collection)),
+		),
+		validation.Field(
+			&form.Events,
+			validation.Required,
+			validation.By(form.checkEvents(collection)),
+		),
+		validation.Field(
+			&form.Filter,
+			validation.By(form.checkFilter(collection)),
+		),
+		validation.Field(
+			&form.Url,
+			validation.Required,
+			validation.Length(1, 2000),
+			is.URL,
+		),
+		validation.Field(
+			&form.Secret,
+			validation.Length(0, 255),
+		),
+	)
+}
+
+func (form *WebhookUpsert) checkCollection(collection *models.Collection) validation.RuleFunc {
+	return func(value any) error {
+		if collection == nil {
+			return validation.NewError("validation_missing_collection", "The collection doesn't exist.")
+		}
+
+		if collection.IsView() {
+			return validation.NewError("validation_view_collection", "Webhooks are not supported for view collections.")
+		}
+
+		return nil
+	}
+}
+
+func (form *WebhookUpsert) checkEvents(collection *models.Collection) validation.RuleFunc {
+	return func(value any) error {
+		v, _ := value.(types.JsonArray[string])
+
+		for _, event := range v {
+			if !list.ExistInSlice(event, models.WebhookEvents()) {
+				return validation.NewError("validation_invalid_event", "Invalid webhook event "+event+".")
+			}
+
+			if event == models.WebhookEventAuth && collection != nil && !collection.IsAuth() {
+				return validation.NewError("validation_invalid_auth_event", "The auth event is supported only for auth collections.")
+			}
+		}
+
+		return nil
+	}
+}
+
+func (form *WebhookUpsert) checkFilter(collection *models.Collection) validation.RuleFunc {
+	return func(value any) error {
+		v, _ := value.(string)
+		if v == "" || collection == nil {
+			return nil // nothing to check
+		}
+
+		r := resolvers.NewRecordFieldResolver(form.dao, collection, nil, true)
+
+		if _, err := search.FilterData(v).BuildExpr(r); err != nil {
+			return validation.NewError("validation_invalid_filter", "Invalid filter expression. Raw error: "+err.Error())
+		}
+
+		return nil
+	}
+}
+
+// Submit validates the form and upserts the form webhook model.
+//
+// You can optionally provide a list of InterceptorFunc to further
+// modify the form behavior before persisting it.
+func (form *WebhookUpsert) Submit(interceptors ...InterceptorFunc[*models.Webhook]) error {
+	if err := form.Validate(); err != nil {
+		return err
+	}
+
+	// normalize the collection identifier in case a name was submitted
+	collection, err := form.dao.FindCollectionByNameOrId(form.CollectionId)
+	if err != nil {
+		return err
+	}
+
+	form.webhook.Name = form.Name
+	form.webhook.CollectionId = collection.Id
+	form.webhook.Events = list.ToUniqueStringSlice(form.Events)
+	form.webhook.Filter = form.Filter
+	form.webhook.Url = form.Url
+	form.webhook.Secret = form.Secret
+	form.webhook.Active = form.Active
+
+	return runInterceptors(form.webhook, func(webhook *models.Webhook) error {
+		return form.dao.SaveWebhook(webhook)
+	}, interceptors...)
+}
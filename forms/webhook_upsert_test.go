@@ -0,0 +1,99 @@
+package forms_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/forms"
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tests"
+)
+
+func TestWebhookUpsertValidateAndSubmit(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		name           string
+		jsonData       string
+		expectedErrors []string
+	}{
+		{
+			"empty data",
+			`{}`,
+			[]string{"name", "collectionId", "events", "url"},
+		},
+		{
+			"invalid data",
+			`{"name":"test","collectionId":"missing","events":["invalid"],"url":"invalid","filter":"missing = 1"}`,
+			[]string{"collectionId", "events", "url"},
+		},
+		{
+			"auth event for non-auth collection + invalid filter",
+			`{"name":"test","collectionId":"demo2","events":["create","auth"],"url":"https://example.com","filter":"missing = 1"}`,
+			[]string{"events", "filter"},
+		},
+		{
+			"valid data",
+			`{"name":"test","collectionId":"demo2","events":["create","create","delete"],"url":"https://example.com","filter":"active = true","secret":"abc"}`,
+			[]string{},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			app, _ := tests.NewTestApp()
+			defer app.Cleanup()
+
+			webhook := &models.Webhook{}
+			form := forms.NewWebhookUpsert(app, webhook)
+
+			// load data
+			loadErr := json.Unmarshal([]byte(s.jsonData), form)
+			if loadErr != nil {
+				t.Fatalf("Failed to load form data: %v", loadErr)
+			}
+
+			result := form.Submit()
+
+			// parse errors
+			errs := map[string]any{}
+			if result != nil {
+				raw, _ := json.Marshal(result)
+				if err := json.Unmarshal(raw, &errs); err != nil {
+					t.Fatalf("Failed to parse errors %v", result)
+				}
+			}
+
+			if len(errs) != len(s.expectedErrors) {
+				t.Fatalf("Expected error keys %v, got %v", s.expectedErrors, errs)
+			}
+			for _, k := range s.expectedErrors {
+				if _, ok := errs[k]; !ok {
+					t.Fatalf("Missing expected error key %q in %v", k, errs)
+				}
+			}
+
+			if len(s.expectedErrors) > 0 {
+				return
+			}
+
+			saved, err := app.Dao().FindWebhookById(webhook.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			collection, _ := app.Dao().FindCollectionByNameOrId("demo2")
+			if saved.CollectionId != collection.Id {
+				t.Fatalf("Expected collectionId %q, got %q", collection.Id, saved.CollectionId)
+			}
+
+			if !saved.Active {
+				t.Fatal("Expected the webhook to be active by default")
+			}
+
+			if len(saved.Events) != 2 {
+				t.Fatalf("Expected 2 unique events, got %v", saved.Events)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+)
+
+// creates the webhooks and webhook deliveries (aka. outbox) tables
+func init() {
+	AppMigrations.Register(func(db dbx.Builder) error {
+		_, err := db.NewQuery(`
+			CREATE TABLE {{_webhooks}} (
+				[[id]]           VARCHAR(32) PRIMARY KEY DEFAULT generate_snowflake() NOT NULL,
+				[[name]]         TEXT DEFAULT '' NOT NULL,
+				[[collectionId]] TEXT NOT NULL,
+				[[events]]       JSON DEFAULT '[]' NOT NULL,
+				[[filter]]       TEXT DEFAULT '' NOT NULL,
+				[[url]]          TEXT NOT NULL,
+				[[secret]]       TEXT DEFAULT '' NOT NULL,
+				[[active]]       BOOLEAN DEFAULT TRUE NOT NULL,
+				[[created]]      TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				[[updated]]      TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				---
+				FOREIGN KEY ([[collectionId]]) REFERENCES {{_collections}} ([[id]]) ON UPDATE CASCADE ON DELETE CASCADE
+			);
+
+			CREATE INDEX _webhooks_collectionId_idx on {{_webhooks}} ([[collectionId]]);
+
+			CREATE TABLE {{_webhookDeliveries}} (
+				[[id]]             VARCHAR(32) PRIMARY KEY DEFAULT generate_snowflake() NOT NULL,
+				[[webhookId]]      VARCHAR(32) NOT NULL,
+				[[event]]          TEXT NOT NULL,
+				[[recordId]]       TEXT DEFAULT '' NOT NULL,
+				[[payload]]        JSON DEFAULT '{}' NOT NULL,
+				[[status]]         TEXT DEFAULT 'pending' NOT NULL,
+				[[attempts]]       INTEGER DEFAULT 0 NOT NULL,
+				[[nextAttemptAt]]  TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				[[responseStatus]] INTEGER DEFAULT 0 NOT NULL,
+				[[lastError]]      TEXT DEFAULT '' NOT NULL,
+				[[created]]        TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				[[updated]]        TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				---
+				FOREIGN KEY ([[webhookId]]) REFERENCES {{_webhooks}} ([[id]]) ON UPDATE CASCADE ON DELETE CASCADE
+			);
+
+			CREATE INDEX _webhookDeliveries_webhookId_idx on {{_webhookDeliveries}} ([[webhookId]]);
+			CREATE INDEX _webhookDeliveries_status_nextAttemptAt_idx on {{_webhookDeliveries}} ([[status]], [[nextAttemptAt]]);
+			CREATE INDEX _webhookDeliveries_created_idx on {{_webhookDeliveries}} ([[created]]);
+		`).Execute()
+
+		return err
+	}, func(db dbx.Builder) error {
+		tables := []string{
+			"_webhookDeliveries",
+			"_webhooks",
+		}
+
+		for _, name := range tables {
+			if _, err := db.DropTable(name).Execute(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+)
+
+// creates the background jobs queue table
+func init() {
+	AppMigrations.Register(func(db dbx.Builder) error {
+		_, err := db.NewQuery(`
+			CREATE TABLE {{_jobs}} (
+				[[id]]          VARCHAR(32) PRIMARY KEY DEFAULT generate_snowflake() NOT NULL,
+				[[name]]        TEXT NOT NULL,
+				[[payload]]     JSON DEFAULT '{}' NOT NULL,
+				[[status]]      TEXT DEFAULT 'pending' NOT NULL,
+				[[priority]]    INTEGER DEFAULT 0 NOT NULL,
+				[[attempts]]    INTEGER DEFAULT 0 NOT NULL,
+				[[maxAttempts]] INTEGER DEFAULT 5 NOT NULL,
+				[[uniqueKey]]   TEXT DEFAULT '' NOT NULL,
+				[[runAt]]       TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				[[lastError]]   TEXT DEFAULT '' NOT NULL,
+				[[created]]     TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				[[updated]]     TIMESTAMPTZ DEFAULT NOW() NOT NULL
+			);
+
+			CREATE INDEX _jobs_status_priority_runAt_idx on {{_jobs}} ([[status]], [[priority]] DESC, [[runAt]]);
+			CREATE INDEX _jobs_name_idx on {{_jobs}} ([[name]]);
+			CREATE INDEX _jobs_updated_idx on {{_jobs}} ([[updated]]);
+			CREATE UNIQUE INDEX _jobs_uniqueKey_idx on {{_jobs}} ([[uniqueKey]])
+				WHERE [[uniqueKey]] != '' AND [[status]] IN ('pending', 'running');
+		`).Execute()
+
+		return err
+	}, func(db dbx.Builder) error {
+		_, err := db.DropTable("_jobs").Execute()
+		return err
+	})
+}
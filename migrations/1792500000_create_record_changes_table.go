@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+)
+
+// creates the records changes log (aka. CDC feed) table
+func init() {
+	AppMigrations.Register(func(db dbx.Builder) error {
+		_, err := db.NewQuery(`
+			CREATE TABLE {{_recordChanges}} (
+				[[seq]]          BIGSERIAL PRIMARY KEY NOT NULL,
+				[[collectionId]] VARCHAR(32) NOT NULL,
+				[[recordId]]     TEXT NOT NULL,
+				[[action]]       TEXT NOT NULL,
+				[[data]]         JSONB DEFAULT NULL,
+				[[created]]      TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				---
+				FOREIGN KEY ([[collectionId]]) REFERENCES {{_collections}} ([[id]]) ON UPDATE CASCADE ON DELETE CASCADE
+			);
+
+			CREATE INDEX _recordChanges_collectionId_seq_idx on {{_recordChanges}} ([[collectionId]], [[seq]]);
+			CREATE INDEX _recordChanges_created_idx on {{_recordChanges}} ([[created]]);
+		`).Execute()
+
+		return err
+	}, func(db dbx.Builder) error {
+		_, err := db.DropTable("_recordChanges").Execute()
+		return err
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+)
+
+// creates the admin audit trail table and the append-only trigger function
+// (the trigger itself is created only when the audit append-only setting is enabled)
+func init() {
+	AppMigrations.Register(func(db dbx.Builder) error {
+		_, err := db.NewQuery(`
+			CREATE TABLE {{_auditLogs}} (
+				[[id]]           VARCHAR(32) PRIMARY KEY DEFAULT generate_snowflake() NOT NULL,
+				[[action]]       TEXT NOT NULL,
+				[[resourceType]] TEXT NOT NULL,
+				[[resourceId]]   TEXT DEFAULT '' NOT NULL,
+				[[actorId]]      TEXT DEFAULT '' NOT NULL,
+				[[actorEmail]]   TEXT DEFAULT '' NOT NULL,
+				[[ip]]           TEXT DEFAULT '' NOT NULL,
+				[[diff]]         JSON DEFAULT '{}' NOT NULL,
+				[[created]]      TIMESTAMPTZ DEFAULT NOW() NOT NULL
+			);
+
+			CREATE INDEX _auditLogs_resource_idx on {{_auditLogs}} ([[resourceType]], [[resourceId]]);
+			CREATE INDEX _auditLogs_actorId_idx on {{_auditLogs}} ([[actorId]]);
+			CREATE INDEX _auditLogs_created_idx on {{_auditLogs}} ([[created]]);
+
+			CREATE OR REPLACE FUNCTION _audit_logs_append_only() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'the audit logs are append-only';
+			END;
+			$$ LANGUAGE plpgsql;
+		`).Execute()
+
+		return err
+	}, func(db dbx.Builder) error {
+		if _, err := db.DropTable("_auditLogs").Execute(); err != nil {
+			return err
+		}
+
+		_, err := db.NewQuery("DROP FUNCTION IF EXISTS _audit_logs_append_only()").Execute()
+
+		return err
+	})
+}
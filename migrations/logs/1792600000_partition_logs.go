@@ -0,0 +1,89 @@
+package logs
+
+import (
+	"github.com/pocketbase/dbx"
+)
+
+// Converts the _logs table to a range partitioned by day table
+// so that the old logs could be deleted by simply dropping their partitions.
+//
+// Rows that don't match any of the daily partitions are stored in the _logs_default partition.
+func init() {
+	LogsMigrations.Register(func(db dbx.Builder) error {
+		_, err := db.NewQuery(`
+			ALTER TABLE {{_logs}} RENAME TO {{_logs_old}};
+
+			DROP INDEX IF EXISTS _logs_level_idx;
+			DROP INDEX IF EXISTS _logs_message_idx;
+			DROP INDEX IF EXISTS _logs_created_hour_idx;
+
+			CREATE TABLE {{_logs}} (
+				[[id]]      VARCHAR(32) DEFAULT generate_snowflake() NOT NULL,
+				[[level]]   INTEGER DEFAULT 0 NOT NULL,
+				[[message]] TEXT DEFAULT '' NOT NULL,
+				[[data]]    JSON DEFAULT '{}' NOT NULL,
+				[[created]] TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				[[updated]] TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				PRIMARY KEY ([[id]], [[created]])
+			) PARTITION BY RANGE ([[created]]);
+
+			CREATE TABLE {{_logs_default}} PARTITION OF {{_logs}} DEFAULT;
+
+			DO $$
+			DECLARE
+				day date;
+			BEGIN
+				FOR day IN
+					SELECT DISTINCT ([[created]] AT TIME ZONE 'UTC')::date FROM {{_logs_old}}
+					UNION
+					SELECT generate_series(0, 2) + (NOW() AT TIME ZONE 'UTC')::date
+				LOOP
+					EXECUTE format(
+						'CREATE TABLE IF NOT EXISTS %I PARTITION OF _logs FOR VALUES FROM (%L) TO (%L)',
+						'_logs_p' || to_char(day, 'YYYYMMDD'),
+						day::text || ' 00:00:00Z',
+						(day + 1)::text || ' 00:00:00Z'
+					);
+				END LOOP;
+			END $$;
+
+			INSERT INTO {{_logs}} SELECT * FROM {{_logs_old}};
+
+			DROP TABLE {{_logs_old}};
+
+			CREATE INDEX _logs_level_idx on {{_logs}} ([[level]]);
+			CREATE INDEX _logs_message_idx on {{_logs}} ([[message]]);
+			CREATE INDEX _logs_created_hour_idx on {{_logs}} (immutable_date_trunc('hour', [[created]]));
+		`).Execute()
+
+		return err
+	}, func(db dbx.Builder) error {
+		_, err := db.NewQuery(`
+			ALTER TABLE {{_logs}} RENAME TO {{_logs_old}};
+
+			DROP INDEX IF EXISTS _logs_level_idx;
+			DROP INDEX IF EXISTS _logs_message_idx;
+			DROP INDEX IF EXISTS _logs_created_hour_idx;
+
+			CREATE TABLE {{_logs}} (
+				[[id]]      VARCHAR(32) PRIMARY KEY DEFAULT generate_snowflake() NOT NULL,
+				[[level]]   INTEGER DEFAULT 0 NOT NULL,
+				[[message]] TEXT DEFAULT '' NOT NULL,
+				[[data]]    JSON DEFAULT '{}' NOT NULL,
+				[[created]] TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+				[[updated]] TIMESTAMPTZ DEFAULT NOW() NOT NULL
+			);
+
+			INSERT INTO {{_logs}} SELECT * FROM {{_logs_old}};
+
+			-- drops also all partitions
+			DROP TABLE {{_logs_old}};
+
+			CREATE INDEX _logs_level_idx on {{_logs}} ([[level]]);
+			CREATE INDEX _logs_message_idx on {{_logs}} ([[message]]);
+			CREATE INDEX _logs_created_hour_idx on {{_logs}} (immutable_date_trunc('hour', [[created]]));
+		`).Execute()
+
+		return err
+	})
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+// Audit log actions.
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionImport  = "import"
+	AuditActionRestore = "restore"
+)
+
+// Audit log resource types.
+const (
+	AuditResourceCollection = "collection"
+	AuditResourceSettings   = "settings"
+	AuditResourceAdmin      = "admin"
+	AuditResourceBackup     = "backup"
+)
+
+// AuditLog defines a single admin audit trail entry.
+//
+// Diff contains the changed resource fields as flattened
+// dot-notation paths mapped to their old and new values
+// (eg. {"listRule": {"old": "", "new": "@request.auth.id != ”"}}).
+type AuditLog struct {
+	Id           string         `db:"id" json:"id"`
+	Action       string         `db:"action" json:"action"`
+	ResourceType string         `db:"resourceType" json:"resourceType"`
+	ResourceId   string         `db:"resourceId" json:"resourceId"`
+	ActorId      string         `db:"actorId" json:"actorId"`
+	ActorEmail   string         `db:"actorEmail" json:"actorEmail"`
+	Ip           string         `db:"ip" json:"ip"`
+	Diff         types.JsonMap  `db:"diff" json:"diff"`
+	Created      types.DateTime `db:"created" json:"created"`
+}
+
+func (m *AuditLog) TableName() string {
+	return "_auditLogs"
+}
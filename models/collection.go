@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
@@ -20,6 +21,13 @@ const (
 	CollectionTypeView = "view"
 )
 
+// Collection record table partition intervals.
+const (
+	PartitionIntervalDay   = "day"
+	PartitionIntervalWeek  = "week"
+	PartitionIntervalMonth = "month"
+)
+
 type Collection struct {
 	BaseModel
 
@@ -162,11 +170,71 @@ func (m *Collection) SetOptions(typedOptions any) error {
 
 // CollectionBaseOptions defines the "base" Collection.Options fields.
 type CollectionBaseOptions struct {
+	// Partition enables the range partitioning of the record table
+	// by its "created" column.
+	//
+	// It can be set only on collection create and its interval cannot be changed.
+	Partition *CollectionPartitionOptions `form:"partition" json:"partition,omitempty"`
 }
 
 // Validate implements [validation.Validatable] interface.
 func (o CollectionBaseOptions) Validate() error {
-	return nil
+	return validation.ValidateStruct(&o,
+		validation.Field(&o.Partition),
+	)
+}
+
+// CollectionPartitionOptions defines the record table partitioning options.
+type CollectionPartitionOptions struct {
+	// Interval is the "created" time range of a single partition
+	// (day, week or month; the ranges are in UTC and the weeks start on Monday).
+	Interval string `form:"interval" json:"interval"`
+
+	// Retention is the number of the past partitions to keep
+	// in addition to the current one (0 disables the retention).
+	Retention int `form:"retention" json:"retention"`
+}
+
+// Validate implements [validation.Validatable] interface.
+func (o CollectionPartitionOptions) Validate() error {
+	return validation.ValidateStruct(&o,
+		validation.Field(
+			&o.Interval,
+			validation.Required,
+			validation.In(PartitionIntervalDay, PartitionIntervalWeek, PartitionIntervalMonth),
+		),
+		validation.Field(&o.Retention, validation.Min(0)),
+	)
+}
+
+// IntervalStart returns the start of the partition interval that contains t.
+func (o CollectionPartitionOptions) IntervalStart(t time.Time) time.Time {
+	t = t.UTC()
+
+	switch o.Interval {
+	case PartitionIntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case PartitionIntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// AddIntervals returns the start of the partition interval
+// that is n intervals after (or before for negative n) the one containing t.
+func (o CollectionPartitionOptions) AddIntervals(t time.Time, n int) time.Time {
+	start := o.IntervalStart(t)
+
+	switch o.Interval {
+	case PartitionIntervalMonth:
+		return start.AddDate(0, n, 0)
+	case PartitionIntervalWeek:
+		return start.AddDate(0, 0, 7*n)
+	default:
+		return start.AddDate(0, 0, n)
+	}
 }
 
 // -------------------------------------------------------------------
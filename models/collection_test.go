@@ -3,6 +3,7 @@ package models_test
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/hylarucoder/rocketbase/models"
@@ -146,6 +147,14 @@ func TestCollectionBaseOptions(t *testing.T) {
 			models.Collection{Type: models.CollectionTypeBase, Options: types.JsonMap{"test": 123}},
 			"{}",
 		},
+		{
+			"base type with partition",
+			models.Collection{Type: models.CollectionTypeBase, Options: types.JsonMap{
+				"test":      123,
+				"partition": map[string]any{"interval": "week", "retention": 4},
+			}},
+			`{"partition":{"interval":"week","retention":4}}`,
+		},
 	}
 
 	for _, s := range scenarios {
@@ -381,9 +390,100 @@ func TestSetOptions(t *testing.T) {
 func TestCollectionBaseOptionsValidate(t *testing.T) {
 	t.Parallel()
 
-	opt := models.CollectionBaseOptions{}
-	if err := opt.Validate(); err != nil {
-		t.Fatal(err)
+	scenarios := []struct {
+		name           string
+		options        models.CollectionBaseOptions
+		expectedErrors []string
+	}{
+		{
+			"empty",
+			models.CollectionBaseOptions{},
+			nil,
+		},
+		{
+			"empty partition interval",
+			models.CollectionBaseOptions{Partition: &models.CollectionPartitionOptions{}},
+			[]string{"partition"},
+		},
+		{
+			"invalid partition interval",
+			models.CollectionBaseOptions{Partition: &models.CollectionPartitionOptions{Interval: "year"}},
+			[]string{"partition"},
+		},
+		{
+			"negative partition retention",
+			models.CollectionBaseOptions{Partition: &models.CollectionPartitionOptions{
+				Interval:  models.PartitionIntervalDay,
+				Retention: -1,
+			}},
+			[]string{"partition"},
+		},
+		{
+			"valid partition",
+			models.CollectionBaseOptions{Partition: &models.CollectionPartitionOptions{
+				Interval:  models.PartitionIntervalMonth,
+				Retention: 12,
+			}},
+			nil,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			result := s.options.Validate()
+
+			// parse errors
+			errs, ok := result.(validation.Errors)
+			if !ok && result != nil {
+				t.Fatalf("Failed to parse errors %v", result)
+			}
+
+			if len(errs) != len(s.expectedErrors) {
+				t.Fatalf("Expected error keys %v, got errors \n%v", s.expectedErrors, result)
+			}
+
+			for key := range errs {
+				if !list.ExistInSlice(key, s.expectedErrors) {
+					t.Fatalf("Unexpected error key %q in \n%v", key, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectionPartitionOptionsIntervals(t *testing.T) {
+	t.Parallel()
+
+	// Wednesday
+	date := time.Date(2024, 3, 13, 15, 4, 5, 0, time.UTC)
+
+	scenarios := []struct {
+		interval      string
+		expectedStart string
+		expectedNext  string
+		expectedPrev  string
+	}{
+		{models.PartitionIntervalDay, "2024-03-13", "2024-03-14", "2024-03-11"},
+		{models.PartitionIntervalWeek, "2024-03-11", "2024-03-18", "2024-02-26"},
+		{models.PartitionIntervalMonth, "2024-03-01", "2024-04-01", "2024-01-01"},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.interval, func(t *testing.T) {
+			options := models.CollectionPartitionOptions{Interval: s.interval}
+
+			if v := options.IntervalStart(date).Format(time.DateOnly); v != s.expectedStart {
+				t.Fatalf("Expected start %s, got %s", s.expectedStart, v)
+			}
+
+			if v := options.AddIntervals(date, 1).Format(time.DateOnly); v != s.expectedNext {
+				t.Fatalf("Expected next %s, got %s", s.expectedNext, v)
+			}
+
+			if v := options.AddIntervals(date, -2).Format(time.DateOnly); v != s.expectedPrev {
+				t.Fatalf("Expected prev %s, got %s", s.expectedPrev, v)
+			}
+		})
 	}
 }
 
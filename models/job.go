@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+var _ Model = (*Job)(nil)
+
+// Job statuses.
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusDead      = "dead"
+)
+
+// Job defines a single background job queue entry.
+//
+// While the job is running its RunAt field holds the worker lease
+// expiration time after which the job could be claimed again.
+type Job struct {
+	BaseModel
+
+	Name        string         `db:"name" json:"name"`
+	Payload     types.JsonRaw  `db:"payload" json:"payload"`
+	Status      string         `db:"status" json:"status"`
+	Priority    int            `db:"priority" json:"priority"`
+	Attempts    int            `db:"attempts" json:"attempts"`
+	MaxAttempts int            `db:"maxAttempts" json:"maxAttempts"`
+	UniqueKey   string         `db:"uniqueKey" json:"uniqueKey"`
+	RunAt       types.DateTime `db:"runAt" json:"runAt"`
+	LastError   string         `db:"lastError" json:"lastError"`
+}
+
+func (m *Job) TableName() string {
+	return "_jobs"
+}
+
+// UnmarshalPayload unmarshals the job JSON payload into result.
+func (m *Job) UnmarshalPayload(result any) error {
+	if len(m.Payload) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(m.Payload, result)
+}
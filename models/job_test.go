@@ -0,0 +1,51 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/models"
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+func TestJobTableName(t *testing.T) {
+	t.Parallel()
+
+	m := models.Job{}
+	if m.TableName() != "_jobs" {
+		t.Fatalf("Unexpected table name, got %q", m.TableName())
+	}
+}
+
+func TestJobUnmarshalPayload(t *testing.T) {
+	t.Parallel()
+
+	scenarios := []struct {
+		payload     types.JsonRaw
+		expectError bool
+		expected    string
+	}{
+		{nil, false, ""},
+		{types.JsonRaw(`{"name":"test"}`), false, "test"},
+		{types.JsonRaw(`{"name":`), true, ""},
+	}
+
+	for i, s := range scenarios {
+		m := models.Job{Payload: s.payload}
+
+		result := struct {
+			Name string `json:"name"`
+		}{}
+
+		err := m.UnmarshalPayload(&result)
+
+		hasErr := err != nil
+		if hasErr != s.expectError {
+			t.Errorf("[%d] Expected hasErr %v, got %v (%v)", i, s.expectError, hasErr, err)
+			continue
+		}
+
+		if result.Name != s.expected {
+			t.Errorf("[%d] Expected name %q, got %q", i, s.expected, result.Name)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+// Record change actions.
+const (
+	RecordChangeActionCreate = "create"
+	RecordChangeActionUpdate = "update"
+	RecordChangeActionDelete = "delete"
+)
+
+// RecordChange defines a single records changes log (aka. CDC feed) entry.
+//
+// Seq is a monotonically increasing sequence number that could be used
+// as a cursor to fetch the collection changes since the last sync.
+type RecordChange struct {
+	Seq          int64          `db:"seq" json:"seq"`
+	CollectionId string         `db:"collectionId" json:"collectionId"`
+	RecordId     string         `db:"recordId" json:"recordId"`
+	Action       string         `db:"action" json:"action"`
+	Created      types.DateTime `db:"created" json:"created"`
+
+	// Data is the db row snapshot of the record before the change
+	// (or after it for the create entries) without the auth secrets.
+	//
+	// It is used to check whether the record was accessible by the
+	// changes feed caller and it is never exported.
+	Data types.JsonRaw `db:"data" json:"-"`
+}
+
+func (m *RecordChange) TableName() string {
+	return "_recordChanges"
+}
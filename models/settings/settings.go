@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 
+	"github.com/ganigeorgiev/fexpr"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/go-ozzo/ozzo-validation/v4/is"
 	"github.com/hylarucoder/rocketbase/tools/auth"
@@ -24,11 +28,17 @@ const SecretMask string = "******"
 type Settings struct {
 	mux sync.RWMutex
 
-	Meta    MetaConfig    `form:"meta" json:"meta"`
-	Logs    LogsConfig    `form:"logs" json:"logs"`
-	Smtp    SmtpConfig    `form:"smtp" json:"smtp"`
-	S3      S3Config      `form:"s3" json:"s3"`
-	Backups BackupsConfig `form:"backups" json:"backups"`
+	Meta     MetaConfig     `form:"meta" json:"meta"`
+	Logs     LogsConfig     `form:"logs" json:"logs"`
+	Changes  ChangesConfig  `form:"changes" json:"changes"`
+	Realtime RealtimeConfig `form:"realtime" json:"realtime"`
+	Metrics  MetricsConfig  `form:"metrics" json:"metrics"`
+	Tracing  TracingConfig  `form:"tracing" json:"tracing"`
+	Audit    AuditConfig    `form:"audit" json:"audit"`
+	Schema   SchemaConfig   `form:"schema" json:"schema"`
+	Smtp     SmtpConfig     `form:"smtp" json:"smtp"`
+	S3       S3Config       `form:"s3" json:"s3"`
+	Backups  BackupsConfig  `form:"backups" json:"backups"`
 
 	AdminAuthToken           TokenConfig `form:"adminAuthToken" json:"adminAuthToken"`
 	AdminPasswordResetToken  TokenConfig `form:"adminPasswordResetToken" json:"adminPasswordResetToken"`
@@ -86,6 +96,15 @@ func New() *Settings {
 			MaxDays: 5,
 			LogIp:   true,
 		},
+		Changes: ChangesConfig{
+			MaxDays: 30,
+		},
+		Schema: SchemaConfig{
+			BackfillBatchSize: 1000,
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
 		Smtp: SmtpConfig{
 			Enabled:  false,
 			Host:     "smtp.example.com",
@@ -215,6 +234,11 @@ func (s *Settings) Validate() error {
 	return validation.ValidateStruct(s,
 		validation.Field(&s.Meta),
 		validation.Field(&s.Logs),
+		validation.Field(&s.Changes),
+		validation.Field(&s.Realtime),
+		validation.Field(&s.Metrics),
+		validation.Field(&s.Tracing),
+		validation.Field(&s.Schema),
 		validation.Field(&s.AdminAuthToken),
 		validation.Field(&s.AdminPasswordResetToken),
 		validation.Field(&s.AdminFileToken),
@@ -288,6 +312,7 @@ func (s *Settings) RedactClone() (*Settings, error) {
 		&clone.Smtp.Password,
 		&clone.S3.Secret,
 		&clone.Backups.S3.Secret,
+		&clone.Metrics.Token,
 		&clone.AdminAuthToken.Secret,
 		&clone.AdminPasswordResetToken.Secret,
 		&clone.AdminFileToken.Secret,
@@ -330,6 +355,19 @@ func (s *Settings) RedactClone() (*Settings, error) {
 		}
 	}
 
+	// the tracing and logs sinks headers usually contain the collectors auth keys
+	headers := []map[string]string{clone.Tracing.Headers}
+	for _, sink := range clone.Logs.Sinks {
+		headers = append(headers, sink.Headers)
+	}
+	for _, h := range headers {
+		for k, v := range h {
+			if v != "" {
+				h[k] = SecretMask
+			}
+		}
+	}
+
 	return clone, nil
 }
 
@@ -617,12 +655,378 @@ type LogsConfig struct {
 	MaxDays  int  `form:"maxDays" json:"maxDays"`
 	MinLevel int  `form:"minLevel" json:"minLevel"`
 	LogIp    bool `form:"logIp" json:"logIp"`
+
+	// SlowQueryThreshold is the min execution time (in ms) of a records
+	// list query to be logged as slow query (0 disables the slow queries logging).
+	SlowQueryThreshold int `form:"slowQueryThreshold" json:"slowQueryThreshold"`
+
+	// SlowQueryExplain enables capturing the EXPLAIN output of the logged slow queries.
+	SlowQueryExplain bool `form:"slowQueryExplain" json:"slowQueryExplain"`
+
+	// Retention overrides the MaxDays retention of the logs with specific level.
+	Retention []LogsRetentionRule `form:"retention" json:"retention"`
+
+	// Sinks are additional log shipping destinations.
+	//
+	// The logs are shipped to the sinks even if their db persistence is disabled (aka. MaxDays = 0).
+	// Each sink has its own bounded queue and the logs batches are
+	// dropped while the sink destination can't keep up.
+	Sinks []LogsSinkConfig `form:"sinks" json:"sinks"`
 }
 
 // Validate makes LogsConfig validatable by implementing [validation.Validatable] interface.
 func (c LogsConfig) Validate() error {
 	return validation.ValidateStruct(&c,
 		validation.Field(&c.MaxDays, validation.Min(0)),
+		validation.Field(&c.SlowQueryThreshold, validation.Min(0)),
+		validation.Field(&c.Retention, validation.By(checkUniqueRetentionLevels)),
+		validation.Field(&c.Sinks),
+	)
+}
+
+// IsEnabled reports whether the logs are persisted in the logs db
+// or shipped to at least one sink.
+func (c LogsConfig) IsEnabled() bool {
+	return c.MaxDays > 0 || len(c.Sinks) > 0
+}
+
+// LevelMaxDays returns the retention days of the logs with the specified level
+// (fallbacks to MaxDays if there is no explicit retention rule for the level).
+func (c LogsConfig) LevelMaxDays(level int) int {
+	for _, r := range c.Retention {
+		if r.Level == level {
+			return r.MaxDays
+		}
+	}
+
+	return c.MaxDays
+}
+
+func checkUniqueRetentionLevels(value any) error {
+	rules, _ := value.([]LogsRetentionRule)
+
+	levels := make(map[int]struct{}, len(rules))
+
+	for _, r := range rules {
+		if _, ok := levels[r.Level]; ok {
+			return validation.NewError("validation_duplicated_retention_level", fmt.Sprintf("Duplicated retention rule for level %d.", r.Level))
+		}
+		levels[r.Level] = struct{}{}
+	}
+
+	return nil
+}
+
+// LogsRetentionRule defines the retention of the logs with specific level.
+type LogsRetentionRule struct {
+	Level   int `form:"level" json:"level"`
+	MaxDays int `form:"maxDays" json:"maxDays"`
+}
+
+// Validate makes LogsRetentionRule validatable by implementing [validation.Validatable] interface.
+func (r LogsRetentionRule) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.MaxDays, validation.Required, validation.Min(1)),
+	)
+}
+
+// Supported logs sink types.
+const (
+	LogsSinkStdout = "stdout"
+	LogsSinkFile   = "file"
+	LogsSinkSyslog = "syslog"
+	LogsSinkHttp   = "http"
+)
+
+// LogsSinkConfig defines a single log shipping destination.
+type LogsSinkConfig struct {
+	// Type is the sink type (stdout, file, syslog or http).
+	Type string `form:"type" json:"type"`
+
+	// MinLevel is the min level of the logs to ship.
+	MinLevel int `form:"minLevel" json:"minLevel"`
+
+	// SampleRate is the fraction of the logs to ship in the (0-1] range
+	// (0 means that all logs are shipped).
+	SampleRate float64 `form:"sampleRate" json:"sampleRate"`
+
+	// Path is the JSON lines file path relative to the app data dir (file sink only).
+	Path string `form:"path" json:"path"`
+
+	// MaxSize is the max size in MB of the logs file before rotating it (file sink only).
+	MaxSize int `form:"maxSize" json:"maxSize"`
+
+	// MaxBackups is the number of the rotated logs files to keep (file sink only).
+	MaxBackups int `form:"maxBackups" json:"maxBackups"`
+
+	// Network and Address are the optional remote syslog server
+	// connection details (syslog sink only, empty for the local syslog server).
+	Network string `form:"network" json:"network"`
+	Address string `form:"address" json:"address"`
+
+	// Tag is the syslog messages tag (syslog sink only).
+	Tag string `form:"tag" json:"tag"`
+
+	// Url is the Loki compatible push endpoint url (http sink only).
+	Url string `form:"url" json:"url"`
+
+	// Headers are optional extra headers that will be sent
+	// with each push request (http sink only).
+	Headers map[string]string `form:"headers" json:"headers"`
+
+	// Labels are optional extra labels that will be attached
+	// to the pushed log streams (http sink only).
+	Labels map[string]string `form:"labels" json:"labels"`
+}
+
+// Validate makes LogsSinkConfig validatable by implementing [validation.Validatable] interface.
+func (c LogsSinkConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(
+			&c.Type,
+			validation.Required,
+			validation.In(LogsSinkStdout, LogsSinkFile, LogsSinkSyslog, LogsSinkHttp),
+		),
+		validation.Field(&c.SampleRate, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(
+			&c.Path,
+			validation.When(c.Type == LogsSinkFile, validation.Required, validation.By(checkRelativePath)),
+		),
+		validation.Field(&c.MaxSize, validation.Min(0)),
+		validation.Field(&c.MaxBackups, validation.Min(0)),
+		validation.Field(&c.Network, validation.In("tcp", "udp")),
+		validation.Field(&c.Address, validation.When(c.Network != "", validation.Required)),
+		validation.Field(&c.Url, validation.When(c.Type == LogsSinkHttp, validation.Required), is.URL),
+	)
+}
+
+func checkRelativePath(value any) error {
+	v, _ := value.(string)
+
+	if filepath.IsAbs(v) || strings.HasPrefix(v, "/") || slices.Contains(strings.Split(filepath.ToSlash(v), "/"), "..") {
+		return validation.NewError("validation_invalid_relative_path", "Must be a path relative to the app data dir.")
+	}
+
+	return nil
+}
+
+// -------------------------------------------------------------------
+
+// AuditConfig defines the admin audit trail settings.
+type AuditConfig struct {
+	// AppendOnly enables the database trigger that rejects
+	// any update or delete of the audit logs entries.
+	//
+	// Once enabled it can't be disabled through the settings API.
+	AppendOnly bool `form:"appendOnly" json:"appendOnly"`
+}
+
+// -------------------------------------------------------------------
+
+// SchemaConfig defines the collections schema changes settings.
+type SchemaConfig struct {
+	// OnlineChanges enables the lock-friendly collection record table changes
+	// (concurrent indexes builds and batched field conversions backfills).
+	OnlineChanges bool `form:"onlineChanges" json:"onlineChanges"`
+
+	// BackfillBatchSize is the number of rows converted by a single
+	// field conversion backfill batch (0 fallbacks to the default one).
+	BackfillBatchSize int `form:"backfillBatchSize" json:"backfillBatchSize"`
+}
+
+// Validate makes SchemaConfig validatable by implementing [validation.Validatable] interface.
+func (c SchemaConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.BackfillBatchSize, validation.Min(0), validation.Max(100000)),
+	)
+}
+
+// -------------------------------------------------------------------
+
+// ChangesConfig defines the records changes log (aka. CDC feed) settings.
+type ChangesConfig struct {
+	// Enabled enables the records changes log.
+	//
+	// Note that to keep the log sequence in commit order, the commits
+	// of the concurrent record writes to the same collection are
+	// serialized while enabled.
+	Enabled bool `form:"enabled" json:"enabled"`
+
+	// MaxDays is the number of days to retain the changes log entries
+	// (including the delete tombstones).
+	MaxDays int `form:"maxDays" json:"maxDays"`
+}
+
+// Validate makes ChangesConfig validatable by implementing [validation.Validatable] interface.
+func (c ChangesConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.MaxDays, validation.When(c.Enabled, validation.Required), validation.Min(0)),
+	)
+}
+
+// -------------------------------------------------------------------
+
+var realtimeChannelNameRegex = regexp.MustCompile(`^([\w\-\.\:]+\*?|\*)$`)
+
+// RealtimeConfig defines the realtime broadcast channels settings.
+type RealtimeConfig struct {
+	Channels []RealtimeChannelConfig `form:"channels" json:"channels"`
+}
+
+// Validate makes RealtimeConfig validatable by implementing [validation.Validatable] interface.
+func (c RealtimeConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Channels, validation.By(checkUniqueChannelNames)),
+	)
+}
+
+// FindChannel returns the config of the channel matching the provided name
+// (exact names have precedence over the longest matching wildcard pattern).
+//
+// Returns nil if there is no matching channel.
+func (c RealtimeConfig) FindChannel(name string) *RealtimeChannelConfig {
+	var found *RealtimeChannelConfig
+
+	for i, channel := range c.Channels {
+		if channel.Name == name {
+			return &c.Channels[i]
+		}
+
+		prefix, isPattern := strings.CutSuffix(channel.Name, "*")
+		if !isPattern || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		if found == nil || len(found.Name) < len(channel.Name) {
+			found = &c.Channels[i]
+		}
+	}
+
+	return found
+}
+
+func checkUniqueChannelNames(value any) error {
+	v, _ := value.([]RealtimeChannelConfig)
+
+	names := make(map[string]struct{}, len(v))
+	for _, channel := range v {
+		if _, ok := names[channel.Name]; ok {
+			return validation.NewError("validation_duplicated_channel", "Duplicated channel "+channel.Name+".")
+		}
+		names[channel.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+// RealtimeChannelConfig defines a single realtime broadcast channel settings.
+//
+// The channel rules are regular filter expressions evaluated against the
+// client auth record, so they could reference any of its fields or the
+// @request.auth.* and @collection.* identifiers.
+// The channel name is available as @request.data.channel and for
+// published messages the message payload as @request.data.data.
+//
+// Similar to the collection API rules, nil rules are admins only,
+// empty rules are public and non-empty rules always require an auth record.
+type RealtimeChannelConfig struct {
+	// Name is the channel name or a channel names prefix pattern ending with "*" (eg. "rooms:*").
+	Name string `form:"name" json:"name"`
+
+	// SubscribeRule is the rule that a client must satisfy to receive
+	// the channel messages and to join its presence.
+	SubscribeRule *string `form:"subscribeRule" json:"subscribeRule"`
+
+	// PublishRule is the rule that a client must satisfy to publish
+	// messages to the channel.
+	PublishRule *string `form:"publishRule" json:"publishRule"`
+
+	// Presence enables the channel presence (aka. online members) tracking.
+	Presence bool `form:"presence" json:"presence"`
+}
+
+// Validate makes RealtimeChannelConfig validatable by implementing [validation.Validatable] interface.
+func (c RealtimeChannelConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(
+			&c.Name,
+			validation.Required,
+			validation.Length(1, 255),
+			validation.Match(realtimeChannelNameRegex),
+		),
+		validation.Field(&c.SubscribeRule, validation.By(checkRuleSyntax)),
+		validation.Field(&c.PublishRule, validation.By(checkRuleSyntax)),
+	)
+}
+
+func checkRuleSyntax(value any) error {
+	v, _ := value.(*string)
+	if v == nil || *v == "" {
+		return nil // nothing to check
+	}
+
+	if _, err := fexpr.Parse(*v); err != nil {
+		return validation.NewError("validation_invalid_rule", "Invalid rule. Raw error: "+err.Error())
+	}
+
+	return nil
+}
+
+// -------------------------------------------------------------------
+
+// MetricsConfig defines the Prometheus metrics endpoint settings.
+type MetricsConfig struct {
+	// Enabled enables the metrics collection and endpoint.
+	//
+	// Note that the db statements metrics are collected only if
+	// the metrics were enabled on the app start.
+	Enabled bool `form:"enabled" json:"enabled"`
+
+	// Token is an optional static bearer token that allows non-admin
+	// clients (eg. a Prometheus scraper) to access the metrics endpoint.
+	Token string `form:"token" json:"token"`
+}
+
+// Validate makes MetricsConfig validatable by implementing [validation.Validatable] interface.
+func (c MetricsConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Token, validation.Length(30, 255)),
+	)
+}
+
+// -------------------------------------------------------------------
+
+// TracingConfig defines the OpenTelemetry tracing settings.
+type TracingConfig struct {
+	// Enabled enables the traces export.
+	//
+	// Note that the db statements are traced only if
+	// the tracing was enabled on the app start.
+	Enabled bool `form:"enabled" json:"enabled"`
+
+	// Endpoint is the OTLP/HTTP traces collector url
+	// (eg. "http://localhost:4318/v1/traces").
+	Endpoint string `form:"endpoint" json:"endpoint"`
+
+	// Headers are optional extra headers that will be sent with
+	// each export request (eg. the collector authorization key).
+	Headers map[string]string `form:"headers" json:"headers"`
+
+	// ServiceName is the exported service name (fallbacks to Meta.AppName).
+	ServiceName string `form:"serviceName" json:"serviceName"`
+
+	// SampleRatio is the ratio of the sampled root traces in the [0-1] range.
+	//
+	// Traces started by an incoming "traceparent" header follow the parent sampling decision.
+	SampleRatio float64 `form:"sampleRatio" json:"sampleRatio"`
+}
+
+// Validate makes TracingConfig validatable by implementing [validation.Validatable] interface.
+func (c TracingConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Endpoint, validation.When(c.Enabled, validation.Required), is.URL),
+		validation.Field(&c.ServiceName, validation.Length(0, 255)),
+		validation.Field(&c.SampleRatio, validation.Min(0.0), validation.Max(1.0)),
 	)
 }
 
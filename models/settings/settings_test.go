@@ -20,6 +20,12 @@ func TestSettingsValidate(t *testing.T) {
 	// set invalid settings data
 	s.Meta.AppName = ""
 	s.Logs.MaxDays = -10
+	s.Changes.MaxDays = -10
+	s.Realtime.Channels = []settings.RealtimeChannelConfig{{Name: ""}}
+	s.Metrics.Token = "short"
+	s.Tracing.Enabled = true
+	s.Tracing.Endpoint = ""
+	s.Schema.BackfillBatchSize = -10
 	s.Smtp.Enabled = true
 	s.Smtp.Host = ""
 	s.S3.Enabled = true
@@ -88,6 +94,11 @@ func TestSettingsValidate(t *testing.T) {
 	expectations := []string{
 		`"meta":{`,
 		`"logs":{`,
+		`"changes":{`,
+		`"realtime":{`,
+		`"metrics":{`,
+		`"tracing":{`,
+		`"schema":{`,
 		`"smtp":{`,
 		`"s3":{`,
 		`"adminAuthToken":{`,
@@ -259,6 +270,9 @@ func TestSettingsRedactClone(t *testing.T) {
 	s1.Smtp.Password = testSecret
 	s1.S3.Secret = testSecret
 	s1.Backups.S3.Secret = testSecret
+	s1.Metrics.Token = testSecret
+	s1.Tracing.Headers = map[string]string{"Authorization": testSecret}
+	s1.Logs.Sinks = []settings.LogsSinkConfig{{Type: settings.LogsSinkHttp, Headers: map[string]string{"Authorization": testSecret}}}
 	s1.AdminAuthToken.Secret = testSecret
 	s1.AdminPasswordResetToken.Secret = testSecret
 	s1.AdminFileToken.Secret = testSecret
@@ -865,6 +879,86 @@ func TestEmailTemplateResolve(t *testing.T) {
 	}
 }
 
+func TestChangesConfigValidate(t *testing.T) {
+	scenarios := []struct {
+		config      settings.ChangesConfig
+		expectError bool
+	}{
+		// zero values
+		{
+			settings.ChangesConfig{},
+			false,
+		},
+		// enabled with missing max days
+		{
+			settings.ChangesConfig{Enabled: true},
+			true,
+		},
+		// invalid max days
+		{
+			settings.ChangesConfig{MaxDays: -10},
+			true,
+		},
+		// valid data
+		{
+			settings.ChangesConfig{Enabled: true, MaxDays: 1},
+			false,
+		},
+	}
+
+	for i, scenario := range scenarios {
+		result := scenario.config.Validate()
+
+		if result != nil && !scenario.expectError {
+			t.Errorf("(%d) Didn't expect error, got %v", i, result)
+		}
+
+		if result == nil && scenario.expectError {
+			t.Errorf("(%d) Expected error, got nil", i)
+		}
+	}
+}
+
+func TestSchemaConfigValidate(t *testing.T) {
+	scenarios := []struct {
+		config      settings.SchemaConfig
+		expectError bool
+	}{
+		// zero values
+		{
+			settings.SchemaConfig{},
+			false,
+		},
+		// negative batch size
+		{
+			settings.SchemaConfig{OnlineChanges: true, BackfillBatchSize: -1},
+			true,
+		},
+		// too large batch size
+		{
+			settings.SchemaConfig{OnlineChanges: true, BackfillBatchSize: 100001},
+			true,
+		},
+		// valid data
+		{
+			settings.SchemaConfig{OnlineChanges: true, BackfillBatchSize: 500},
+			false,
+		},
+	}
+
+	for i, scenario := range scenarios {
+		result := scenario.config.Validate()
+
+		if result != nil && !scenario.expectError {
+			t.Errorf("(%d) Didn't expect error, got %v", i, result)
+		}
+
+		if result == nil && scenario.expectError {
+			t.Errorf("(%d) Expected error, got nil", i)
+		}
+	}
+}
+
 func TestLogsConfigValidate(t *testing.T) {
 	scenarios := []struct {
 		config      settings.LogsConfig
@@ -880,11 +974,366 @@ func TestLogsConfigValidate(t *testing.T) {
 			settings.LogsConfig{MaxDays: -10},
 			true,
 		},
+		{
+			settings.LogsConfig{MaxDays: 1, SlowQueryThreshold: -1},
+			true,
+		},
+		{
+			settings.LogsConfig{MaxDays: 1, Retention: []settings.LogsRetentionRule{{Level: 8, MaxDays: 0}}},
+			true,
+		},
+		{
+			settings.LogsConfig{MaxDays: 1, Retention: []settings.LogsRetentionRule{{Level: 8, MaxDays: 1}, {Level: 8, MaxDays: 2}}},
+			true,
+		},
+		{
+			settings.LogsConfig{MaxDays: 1, Sinks: []settings.LogsSinkConfig{{Type: "missing"}}},
+			true,
+		},
 		// valid data
 		{
 			settings.LogsConfig{MaxDays: 1},
 			false,
 		},
+		{
+			settings.LogsConfig{MaxDays: 1, SlowQueryThreshold: 500, SlowQueryExplain: true},
+			false,
+		},
+		{
+			settings.LogsConfig{
+				MaxDays:   1,
+				Retention: []settings.LogsRetentionRule{{Level: 0, MaxDays: 1}, {Level: 8, MaxDays: 30}},
+				Sinks:     []settings.LogsSinkConfig{{Type: settings.LogsSinkStdout}},
+			},
+			false,
+		},
+	}
+
+	for i, scenario := range scenarios {
+		result := scenario.config.Validate()
+
+		if result != nil && !scenario.expectError {
+			t.Errorf("(%d) Didn't expect error, got %v", i, result)
+		}
+
+		if result == nil && scenario.expectError {
+			t.Errorf("(%d) Expected error, got nil", i)
+		}
+	}
+}
+
+func TestLogsConfigIsEnabled(t *testing.T) {
+	scenarios := []struct {
+		config   settings.LogsConfig
+		expected bool
+	}{
+		{settings.LogsConfig{}, false},
+		{settings.LogsConfig{MaxDays: 1}, true},
+		{settings.LogsConfig{Sinks: []settings.LogsSinkConfig{{Type: settings.LogsSinkStdout}}}, true},
+	}
+
+	for i, s := range scenarios {
+		if v := s.config.IsEnabled(); v != s.expected {
+			t.Errorf("(%d) Expected %v, got %v", i, s.expected, v)
+		}
+	}
+}
+
+func TestLogsConfigLevelMaxDays(t *testing.T) {
+	config := settings.LogsConfig{
+		MaxDays: 5,
+		Retention: []settings.LogsRetentionRule{
+			{Level: -4, MaxDays: 1},
+			{Level: 8, MaxDays: 30},
+		},
+	}
+
+	scenarios := map[int]int{-4: 1, 0: 5, 4: 5, 8: 30}
+
+	for level, expected := range scenarios {
+		if v := config.LevelMaxDays(level); v != expected {
+			t.Errorf("(%d) Expected %d max days, got %d", level, expected, v)
+		}
+	}
+}
+
+func TestLogsRetentionRuleValidate(t *testing.T) {
+	scenarios := []struct {
+		rule        settings.LogsRetentionRule
+		expectError bool
+	}{
+		{settings.LogsRetentionRule{}, true},
+		{settings.LogsRetentionRule{Level: 8, MaxDays: -1}, true},
+		{settings.LogsRetentionRule{Level: 8, MaxDays: 1}, false},
+	}
+
+	for i, s := range scenarios {
+		result := s.rule.Validate()
+
+		if result != nil && !s.expectError {
+			t.Errorf("(%d) Didn't expect error, got %v", i, result)
+		}
+
+		if result == nil && s.expectError {
+			t.Errorf("(%d) Expected error, got nil", i)
+		}
+	}
+}
+
+func TestLogsSinkConfigValidate(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		config      settings.LogsSinkConfig
+		expectError bool
+	}{
+		{"zero values", settings.LogsSinkConfig{}, true},
+		{"unknown type", settings.LogsSinkConfig{Type: "missing"}, true},
+		{"stdout", settings.LogsSinkConfig{Type: settings.LogsSinkStdout, MinLevel: 4}, false},
+		{"invalid sample rate", settings.LogsSinkConfig{Type: settings.LogsSinkStdout, SampleRate: 1.1}, true},
+		{"file without path", settings.LogsSinkConfig{Type: settings.LogsSinkFile}, true},
+		{"file with absolute path", settings.LogsSinkConfig{Type: settings.LogsSinkFile, Path: "/var/log/app.log"}, true},
+		{"file with parent path", settings.LogsSinkConfig{Type: settings.LogsSinkFile, Path: "logs/../../app.log"}, true},
+		{"file with negative max size", settings.LogsSinkConfig{Type: settings.LogsSinkFile, Path: "app.log", MaxSize: -1}, true},
+		{"file", settings.LogsSinkConfig{Type: settings.LogsSinkFile, Path: "logs/app.log", MaxSize: 10, MaxBackups: 3}, false},
+		{"syslog local", settings.LogsSinkConfig{Type: settings.LogsSinkSyslog}, false},
+		{"syslog invalid network", settings.LogsSinkConfig{Type: settings.LogsSinkSyslog, Network: "unix"}, true},
+		{"syslog network without address", settings.LogsSinkConfig{Type: settings.LogsSinkSyslog, Network: "udp"}, true},
+		{"syslog remote", settings.LogsSinkConfig{Type: settings.LogsSinkSyslog, Network: "udp", Address: "localhost:514"}, false},
+		{"http without url", settings.LogsSinkConfig{Type: settings.LogsSinkHttp}, true},
+		{"http with invalid url", settings.LogsSinkConfig{Type: settings.LogsSinkHttp, Url: "invalid"}, true},
+		{"http", settings.LogsSinkConfig{Type: settings.LogsSinkHttp, Url: "http://localhost:3100/loki/api/v1/push", SampleRate: 0.5}, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			result := s.config.Validate()
+
+			if result != nil && !s.expectError {
+				t.Fatalf("Didn't expect error, got %v", result)
+			}
+
+			if result == nil && s.expectError {
+				t.Fatal("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestRealtimeConfigValidate(t *testing.T) {
+	scenarios := []struct {
+		config      settings.RealtimeConfig
+		expectError bool
+	}{
+		// zero values
+		{
+			settings.RealtimeConfig{},
+			false,
+		},
+		// invalid channel
+		{
+			settings.RealtimeConfig{
+				Channels: []settings.RealtimeChannelConfig{{Name: "test"}, {Name: ""}},
+			},
+			true,
+		},
+		// duplicated channel names
+		{
+			settings.RealtimeConfig{
+				Channels: []settings.RealtimeChannelConfig{{Name: "test"}, {Name: "test"}},
+			},
+			true,
+		},
+		// valid data
+		{
+			settings.RealtimeConfig{
+				Channels: []settings.RealtimeChannelConfig{{Name: "test"}, {Name: "test*"}},
+			},
+			false,
+		},
+	}
+
+	for i, scenario := range scenarios {
+		result := scenario.config.Validate()
+
+		if result != nil && !scenario.expectError {
+			t.Errorf("(%d) Didn't expect error, got %v", i, result)
+		}
+
+		if result == nil && scenario.expectError {
+			t.Errorf("(%d) Expected error, got nil", i)
+		}
+	}
+}
+
+func TestMetricsConfigValidate(t *testing.T) {
+	scenarios := []struct {
+		config      settings.MetricsConfig
+		expectError bool
+	}{
+		// zero values
+		{
+			settings.MetricsConfig{},
+			false,
+		},
+		// enabled without token
+		{
+			settings.MetricsConfig{Enabled: true},
+			false,
+		},
+		// too short token
+		{
+			settings.MetricsConfig{Enabled: true, Token: "test"},
+			true,
+		},
+		// valid data
+		{
+			settings.MetricsConfig{Enabled: true, Token: strings.Repeat("a", 30)},
+			false,
+		},
+	}
+
+	for i, scenario := range scenarios {
+		result := scenario.config.Validate()
+
+		if result != nil && !scenario.expectError {
+			t.Errorf("(%d) Didn't expect error, got %v", i, result)
+		}
+
+		if result == nil && scenario.expectError {
+			t.Errorf("(%d) Expected error, got nil", i)
+		}
+	}
+}
+
+func TestTracingConfigValidate(t *testing.T) {
+	scenarios := []struct {
+		config      settings.TracingConfig
+		expectError bool
+	}{
+		// zero values
+		{
+			settings.TracingConfig{},
+			false,
+		},
+		// enabled with missing endpoint
+		{
+			settings.TracingConfig{Enabled: true},
+			true,
+		},
+		// invalid endpoint
+		{
+			settings.TracingConfig{Endpoint: "invalid"},
+			true,
+		},
+		// invalid sample ratio
+		{
+			settings.TracingConfig{SampleRatio: 1.5},
+			true,
+		},
+		// valid data
+		{
+			settings.TracingConfig{Enabled: true, Endpoint: "http://localhost:4318/v1/traces", SampleRatio: 0.5},
+			false,
+		},
+	}
+
+	for i, scenario := range scenarios {
+		result := scenario.config.Validate()
+
+		if result != nil && !scenario.expectError {
+			t.Errorf("(%d) Didn't expect error, got %v", i, result)
+		}
+
+		if result == nil && scenario.expectError {
+			t.Errorf("(%d) Expected error, got nil", i)
+		}
+	}
+}
+
+func TestRealtimeConfigFindChannel(t *testing.T) {
+	config := settings.RealtimeConfig{
+		Channels: []settings.RealtimeChannelConfig{
+			{Name: "*"},
+			{Name: "rooms:*"},
+			{Name: "rooms:1"},
+			{Name: "rooms:1*"},
+			{Name: "news"},
+		},
+	}
+
+	scenarios := []struct {
+		name     string
+		expected string
+	}{
+		{"", "*"},
+		{"missing", "*"},
+		{"news", "news"},
+		{"news2", "*"},
+		{"rooms:", "rooms:*"},
+		{"rooms:2", "rooms:*"},
+		{"rooms:1", "rooms:1"},
+		{"rooms:12", "rooms:1*"},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			result := config.FindChannel(s.name)
+			if result == nil {
+				t.Fatalf("Expected channel %q, got nil", s.expected)
+			}
+
+			if result.Name != s.expected {
+				t.Fatalf("Expected channel %q, got %q", s.expected, result.Name)
+			}
+		})
+	}
+
+	// without wildcard fallback
+	if result := (settings.RealtimeConfig{Channels: []settings.RealtimeChannelConfig{{Name: "news"}}}).FindChannel("news2"); result != nil {
+		t.Fatalf("Expected nil, got %v", result)
+	}
+}
+
+func TestRealtimeChannelConfigValidate(t *testing.T) {
+	scenarios := []struct {
+		config      settings.RealtimeChannelConfig
+		expectError bool
+	}{
+		// zero values
+		{
+			settings.RealtimeChannelConfig{},
+			true,
+		},
+		// invalid name
+		{
+			settings.RealtimeChannelConfig{Name: "rooms/1"},
+			true,
+		},
+		// invalid wildcard position
+		{
+			settings.RealtimeChannelConfig{Name: "rooms*:1"},
+			true,
+		},
+		// invalid subscribe rule
+		{
+			settings.RealtimeChannelConfig{Name: "rooms:1", SubscribeRule: types.Pointer("id =")},
+			true,
+		},
+		// invalid publish rule
+		{
+			settings.RealtimeChannelConfig{Name: "rooms:1", PublishRule: types.Pointer("id =")},
+			true,
+		},
+		// valid data
+		{
+			settings.RealtimeChannelConfig{
+				Name:          "rooms:*",
+				SubscribeRule: types.Pointer(""),
+				PublishRule:   types.Pointer("@request.auth.id != ''"),
+				Presence:      true,
+			},
+			false,
+		},
 	}
 
 	for i, scenario := range scenarios {
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"slices"
+
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+var _ Model = (*Webhook)(nil)
+
+// Supported webhook events.
+const (
+	WebhookEventCreate = "create"
+	WebhookEventUpdate = "update"
+	WebhookEventDelete = "delete"
+	WebhookEventAuth   = "auth"
+)
+
+// WebhookEvents returns all supported webhook events.
+func WebhookEvents() []string {
+	return []string{
+		WebhookEventCreate,
+		WebhookEventUpdate,
+		WebhookEventDelete,
+		WebhookEventAuth,
+	}
+}
+
+type Webhook struct {
+	BaseModel
+
+	Name         string                  `db:"name" json:"name"`
+	CollectionId string                  `db:"collectionId" json:"collectionId"`
+	Events       types.JsonArray[string] `db:"events" json:"events"`
+	Filter       string                  `db:"filter" json:"filter"`
+	Url          string                  `db:"url" json:"url"`
+	Secret       string                  `db:"secret" json:"-"` // write-only (see forms.WebhookUpsert)
+	Active       bool                    `db:"active" json:"active"`
+}
+
+func (m *Webhook) TableName() string {
+	return "_webhooks"
+}
+
+// HasEvent checks whether the webhook is subscribed for the specified event.
+func (m *Webhook) HasEvent(event string) bool {
+	return slices.Contains(m.Events, event)
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+var _ Model = (*WebhookDelivery)(nil)
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryStatusPending = "pending"
+	WebhookDeliveryStatusSuccess = "success"
+	WebhookDeliveryStatusFailed  = "failed"
+)
+
+// WebhookDelivery defines a single webhook delivery (aka. outbox) entry.
+type WebhookDelivery struct {
+	BaseModel
+
+	WebhookId      string         `db:"webhookId" json:"webhookId"`
+	Event          string         `db:"event" json:"event"`
+	RecordId       string         `db:"recordId" json:"recordId"`
+	Payload        types.JsonRaw  `db:"payload" json:"payload"`
+	Status         string         `db:"status" json:"status"`
+	Attempts       int            `db:"attempts" json:"attempts"`
+	NextAttemptAt  types.DateTime `db:"nextAttemptAt" json:"nextAttemptAt"`
+	ResponseStatus int            `db:"responseStatus" json:"responseStatus"`
+	LastError      string         `db:"lastError" json:"lastError"`
+}
+
+func (m *WebhookDelivery) TableName() string {
+	return "_webhookDeliveries"
+}
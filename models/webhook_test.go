@@ -0,0 +1,48 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/models"
+)
+
+func TestWebhookTableName(t *testing.T) {
+	t.Parallel()
+
+	m := models.Webhook{}
+	if m.TableName() != "_webhooks" {
+		t.Fatalf("Unexpected table name, got %q", m.TableName())
+	}
+}
+
+func TestWebhookHasEvent(t *testing.T) {
+	t.Parallel()
+
+	m := models.Webhook{Events: []string{models.WebhookEventCreate, models.WebhookEventDelete}}
+
+	scenarios := []struct {
+		event    string
+		expected bool
+	}{
+		{"", false},
+		{models.WebhookEventCreate, true},
+		{models.WebhookEventUpdate, false},
+		{models.WebhookEventDelete, true},
+		{models.WebhookEventAuth, false},
+	}
+
+	for _, s := range scenarios {
+		if result := m.HasEvent(s.event); result != s.expected {
+			t.Errorf("(%q) Expected %v, got %v", s.event, s.expected, result)
+		}
+	}
+}
+
+func TestWebhookDeliveryTableName(t *testing.T) {
+	t.Parallel()
+
+	m := models.WebhookDelivery{}
+	if m.TableName() != "_webhookDeliveries" {
+		t.Fatalf("Unexpected table name, got %q", m.TableName())
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,7 +39,9 @@ import (
 	"github.com/hylarucoder/rocketbase/tools/subscriptions"
 	"github.com/hylarucoder/rocketbase/tools/types"
 	"github.com/labstack/echo/v5"
+	"github.com/labstack/echo/v5/middleware"
 	"github.com/pocketbase/dbx"
+	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
 )
 
@@ -59,7 +64,9 @@ func hooksBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
 
 		// register the hook to the loader
 		loader.Set(jsName, func(callback string, tags ...string) {
-			pr := goja.MustCompile("", "{("+callback+").apply(undefined, __args)}", true)
+			pr := callerImports(loader, executors).compile("(" + callback + ").apply(undefined, __args)")
+
+			source := callerSource(loader)
 
 			tagsAsValues := make([]reflect.Value, len(tags))
 			for i, tag := range tags {
@@ -77,7 +84,9 @@ func hooksBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
 					handlerArgs[i] = arg.Interface()
 				}
 
-				err := executors.run(func(executor *goja.Runtime) error {
+				start := time.Now()
+
+				err := executors.run(hookContext(handlerArgs), source, func(executor *goja.Runtime) error {
 					executor.Set("__args", handlerArgs)
 					res, err := executor.RunProgram(pr)
 					executor.Set("__args", goja.Undefined())
@@ -97,6 +106,12 @@ func hooksBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
 					return err
 				})
 
+				handlerErr := err
+				if errors.Is(handlerErr, hook.StopPropagation) {
+					handlerErr = nil // not a failure
+				}
+				core.ObserveHookHandler(app, jsName, time.Since(start), handlerErr)
+
 				return []reflect.Value{reflect.ValueOf(&err).Elem()}
 			})
 
@@ -112,14 +127,20 @@ func cronBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
 	var wasServeTriggered bool
 
 	loader.Set("cronAdd", func(jobId, cronExpr, handler string) {
-		pr := goja.MustCompile("", "{("+handler+").apply(undefined)}", true)
+		pr := callerImports(loader, executors).compile("(" + handler + ").apply(undefined)")
+
+		source := callerSource(loader)
 
 		err := scheduler.Add(jobId, cronExpr, func() {
-			err := executors.run(func(executor *goja.Runtime) error {
+			start := time.Now()
+
+			err := executors.runBackground(context.Background(), source, func(executor *goja.Runtime) error {
 				_, err := executor.RunProgram(pr)
 				return err
 			})
 
+			core.ObserveCronJob(app, jobId, time.Since(start), err)
+
 			if err != nil {
 				app.Logger().Error(
 					"[cronAdd] failed to execute cron job",
@@ -159,14 +180,46 @@ func cronBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
 	})
 }
 
+func jobsBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
+	loader.Set("jobsRegister", func(name string, handler string) {
+		pr := callerImports(loader, executors).compile("(" + handler + ").apply(undefined, __args)")
+
+		source := callerSource(loader)
+
+		app.Jobs().Register(name, func(ctx context.Context, job *models.Job) error {
+			return executors.runBackground(ctx, source, func(executor *goja.Runtime) error {
+				executor.Set("__args", []any{job})
+				res, err := executor.RunProgram(pr)
+				executor.Set("__args", goja.Undefined())
+
+				// check for returned error
+				if res != nil {
+					if v, ok := res.Export().(error); ok {
+						return v
+					}
+				}
+
+				return err
+			})
+		})
+	})
+
+	loader.Set("jobsRemove", func(name string) {
+		app.Jobs().Remove(name)
+	})
+}
+
 func routerBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
 	loader.Set("routerAdd", func(method string, path string, handler goja.Value, middlewares ...goja.Value) {
-		wrappedMiddlewares, err := wrapMiddlewares(executors, middlewares...)
+		source := callerSource(loader)
+		imports := callerImports(loader, executors)
+
+		wrappedMiddlewares, err := wrapMiddlewares(executors, source, imports, middlewares...)
 		if err != nil {
 			panic("[routerAdd] failed to wrap middlewares: " + err.Error())
 		}
 
-		wrappedHandler, err := wrapHandler(executors, handler)
+		wrappedHandler, err := wrapHandler(executors, source, imports, handler)
 		if err != nil {
 			panic("[routerAdd] failed to wrap handler: " + err.Error())
 		}
@@ -179,7 +232,7 @@ func routerBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
 	})
 
 	loader.Set("routerUse", func(middlewares ...goja.Value) {
-		wrappedMiddlewares, err := wrapMiddlewares(executors, middlewares...)
+		wrappedMiddlewares, err := wrapMiddlewares(executors, callerSource(loader), callerImports(loader, executors), middlewares...)
 		if err != nil {
 			panic("[routerUse] failed to wrap middlewares: " + err.Error())
 		}
@@ -191,7 +244,7 @@ func routerBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
 	})
 
 	loader.Set("routerPre", func(middlewares ...goja.Value) {
-		wrappedMiddlewares, err := wrapMiddlewares(executors, middlewares...)
+		wrappedMiddlewares, err := wrapMiddlewares(executors, callerSource(loader), callerImports(loader, executors), middlewares...)
 		if err != nil {
 			panic("[routerPre] failed to wrap middlewares: " + err.Error())
 		}
@@ -203,7 +256,7 @@ func routerBinds(app core.App, loader *goja.Runtime, executors *vmsPool) {
 	})
 }
 
-func wrapHandler(executors *vmsPool, handler goja.Value) (echo.HandlerFunc, error) {
+func wrapHandler(executors *vmsPool, source string, imports handlerImports, handler goja.Value) (echo.HandlerFunc, error) {
 	if handler == nil {
 		return nil, errors.New("handler must be non-nil")
 	}
@@ -213,10 +266,10 @@ func wrapHandler(executors *vmsPool, handler goja.Value) (echo.HandlerFunc, erro
 		// "native" handler - no need to wrap
 		return h, nil
 	case func(goja.FunctionCall) goja.Value, string:
-		pr := goja.MustCompile("", "{("+handler.String()+").apply(undefined, __args)}", true)
+		pr := imports.compile("(" + handler.String() + ").apply(undefined, __args)")
 
 		wrappedHandler := func(c echo.Context) error {
-			return executors.run(func(executor *goja.Runtime) error {
+			return executors.run(c.Request().Context(), source, func(executor *goja.Runtime) error {
 				executor.Set("__args", []any{c})
 				res, err := executor.RunProgram(pr)
 				executor.Set("__args", goja.Undefined())
@@ -238,7 +291,7 @@ func wrapHandler(executors *vmsPool, handler goja.Value) (echo.HandlerFunc, erro
 	}
 }
 
-func wrapMiddlewares(executors *vmsPool, rawMiddlewares ...goja.Value) ([]echo.MiddlewareFunc, error) {
+func wrapMiddlewares(executors *vmsPool, source string, imports handlerImports, rawMiddlewares ...goja.Value) ([]echo.MiddlewareFunc, error) {
 	wrappedMiddlewares := make([]echo.MiddlewareFunc, len(rawMiddlewares))
 
 	for i, m := range rawMiddlewares {
@@ -251,11 +304,11 @@ func wrapMiddlewares(executors *vmsPool, rawMiddlewares ...goja.Value) ([]echo.M
 			// "native" middleware - no need to wrap
 			wrappedMiddlewares[i] = v
 		case func(goja.FunctionCall) goja.Value, string:
-			pr := goja.MustCompile("", "{(("+m.String()+").apply(undefined, __args)).apply(undefined, __args2)}", true)
+			pr := imports.compile("((" + m.String() + ").apply(undefined, __args)).apply(undefined, __args2)")
 
 			wrappedMiddlewares[i] = func(next echo.HandlerFunc) echo.HandlerFunc {
 				return func(c echo.Context) error {
-					return executors.run(func(executor *goja.Runtime) error {
+					return executors.run(c.Request().Context(), source, func(executor *goja.Runtime) error {
 						executor.Set("__args", []any{next})
 						executor.Set("__args2", []any{c})
 						res, err := executor.RunProgram(pr)
@@ -281,6 +334,82 @@ func wrapMiddlewares(executors *vmsPool, rawMiddlewares ...goja.Value) ([]echo.M
 	return wrappedMiddlewares, nil
 }
 
+// callerSource returns the "file:line" position of the JS code
+// that is currently calling the loader Go function
+// (or empty string if the position cannot be determined).
+func callerSource(loader *goja.Runtime) string {
+	for _, frame := range loader.CaptureCallStack(0, nil) {
+		pos := frame.Position()
+		if pos.Filename == "" {
+			continue // native or anonymous
+		}
+
+		return pos.Filename + ":" + strconv.Itoa(pos.Line)
+	}
+
+	return ""
+}
+
+// handlerImports describes the import declarations of the hook file
+// from which a handler is registered.
+type handlerImports struct {
+	path         string
+	declarations string
+}
+
+// callerImports returns the import declarations of the hook file
+// that is currently executed by the loader (if any).
+func callerImports(loader *goja.Runtime, executors *vmsPool) handlerImports {
+	for _, frame := range loader.CaptureCallStack(0, nil) {
+		if frame.Position().Filename == "" {
+			continue // native or anonymous
+		}
+
+		path := frame.SrcName()
+
+		return handlerImports{path: path, declarations: executors.importsOf(path)}
+	}
+
+	return handlerImports{}
+}
+
+// compile compiles the provided handler call expression into a program
+// that could be executed on the pool vms.
+//
+// The hook file import declarations (if any) are prepended to the call
+// so that the serialized handler could access the file top-level imports.
+// In this case the program is also named after the hook file so that the
+// relative require() paths are resolved from its directory.
+func (h handlerImports) compile(call string) *goja.Program {
+	if h.declarations == "" {
+		return goja.MustCompile("", "{"+call+"}", true)
+	}
+
+	return goja.MustCompile(h.path, "{"+h.declarations+"\n"+call+"}", true)
+}
+
+// hookContext returns the request context of the first hook event
+// argument with HttpContext (fallbacks to [context.Background]).
+func hookContext(args []any) context.Context {
+	for _, arg := range args {
+		v := reflect.Indirect(reflect.ValueOf(arg))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+
+		field := v.FieldByName("HttpContext")
+		if !field.IsValid() {
+			continue
+		}
+
+		if c, ok := field.Interface().(echo.Context); ok && c != nil && c.Request() != nil {
+			return c.Request().Context()
+		}
+	}
+
+	return context.Background()
+}
+
 func baseBinds(vm *goja.Runtime) {
 	vm.SetFieldNameMapper(FieldMapper{})
 
@@ -640,6 +769,34 @@ func formsBinds(vm *goja.Runtime) {
 	registerFactoryAsConstructor(vm, "TestS3FilesystemForm", forms.NewTestS3Filesystem)
 }
 
+func jobsQueueBinds(app core.App, vm *goja.Runtime) {
+	obj := vm.NewObject()
+	vm.Set("$jobs", obj)
+
+	// enqueue adds a new job to the app jobs queue.
+	//
+	// The options object accepts the same fields as [core.JobOptions]
+	// with the exception of "delay" which is in milliseconds.
+	obj.Set("enqueue", func(name string, payload any, options map[string]any) (*models.Job, error) {
+		opts := core.JobOptions{
+			Priority:    cast.ToInt(options["priority"]),
+			Delay:       time.Duration(cast.ToInt64(options["delay"])) * time.Millisecond,
+			MaxAttempts: cast.ToInt(options["maxAttempts"]),
+			UniqueKey:   cast.ToString(options["uniqueKey"]),
+		}
+
+		if raw, ok := options["runAt"]; ok && raw != nil {
+			runAt, err := types.ParseDateTime(raw)
+			if err != nil {
+				return nil, err
+			}
+			opts.RunAt = runAt.Time()
+		}
+
+		return app.Jobs().Enqueue(name, payload, opts)
+	})
+}
+
 func apisBinds(vm *goja.Runtime) {
 	obj := vm.NewObject()
 	vm.Set("$apis", obj)
@@ -673,7 +830,34 @@ func apisBinds(vm *goja.Runtime) {
 	registerFactoryAsConstructor(vm, "UnauthorizedError", apis.NewUnauthorizedError)
 }
 
-func httpClientBinds(vm *goja.Runtime) {
+const (
+	defaultHttpMaxResponseSize int64 = 10 << 20
+	defaultHttpTimeout               = 120 * time.Second
+)
+
+func httpClientBinds(vm *goja.Runtime, config Config) {
+	maxResponseSize := config.HttpMaxResponseSize
+	if maxResponseSize <= 0 {
+		maxResponseSize = defaultHttpMaxResponseSize
+	}
+
+	maxTimeout := config.HttpTimeout
+	if maxTimeout <= 0 {
+		maxTimeout = defaultHttpTimeout
+	}
+
+	allowedHosts := config.HttpAllowedHosts
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+
+			return checkHttpAllowedHost(req.URL, allowedHosts)
+		},
+	}
+
 	obj := vm.NewObject()
 	vm.Set("$http", obj)
 
@@ -702,7 +886,7 @@ func httpClientBinds(vm *goja.Runtime) {
 		Headers map[string]string
 		Method  string
 		Url     string
-		Timeout int // seconds (default to 120)
+		Timeout int // seconds (default to and max Config.HttpTimeout)
 	}
 
 	obj.Set("send", func(params map[string]any) (*sendResult, error) {
@@ -734,11 +918,12 @@ func httpClientBinds(vm *goja.Runtime) {
 			config.Timeout = cast.ToInt(v)
 		}
 
-		if config.Timeout <= 0 {
-			config.Timeout = 120
+		timeout := time.Duration(config.Timeout) * time.Second
+		if timeout <= 0 || timeout > maxTimeout {
+			timeout = maxTimeout
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Timeout)*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
 		var reqBody io.Reader
@@ -771,6 +956,10 @@ func httpClientBinds(vm *goja.Runtime) {
 			return nil, err
 		}
 
+		if err := checkHttpAllowedHost(req.URL, allowedHosts); err != nil {
+			return nil, err
+		}
+
 		for k, v := range config.Headers {
 			req.Header.Add(k, v)
 		}
@@ -788,13 +977,20 @@ func httpClientBinds(vm *goja.Runtime) {
 			req.Header.Set("content-type", "application/json")
 		}
 
-		res, err := http.DefaultClient.Do(req)
+		res, err := client.Do(req)
 		if err != nil {
 			return nil, err
 		}
 		defer res.Body.Close()
 
-		bodyRaw, _ := io.ReadAll(res.Body)
+		// read up to 1 extra byte to detect oversized responses
+		bodyRaw, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(bodyRaw)) > maxResponseSize {
+			return nil, fmt.Errorf("the response body exceeds the max allowed size of %d bytes", maxResponseSize)
+		}
 
 		result := &sendResult{
 			StatusCode: res.StatusCode,
@@ -827,6 +1023,36 @@ func httpClientBinds(vm *goja.Runtime) {
 	})
 }
 
+// checkHttpAllowedHost checks whether the url is an http(s) address
+// with host matching one of the allowedHosts exact names or "*.example.com" patterns.
+//
+// Empty allowedHosts means that all hosts are allowed.
+func checkHttpAllowedHost(u *url.URL, allowedHosts []string) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+
+	if len(allowedHosts) == 0 {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+
+		if host == allowed {
+			return nil
+		}
+
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("host %q is not in the allowed hosts list", host)
+}
+
 // -------------------------------------------------------------------
 
 // registerFactoryAsConstructor registers the factory function as native JS constructor.
@@ -6,6 +6,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -47,7 +48,7 @@ func TestBaseBindsCount(t *testing.T) {
 	vm := goja.New()
 	baseBinds(vm)
 
-	testBindsCount(vm, "this", 17, t)
+	testBindsCount(vm, "this", 18, t)
 }
 
 func TestBaseBindsSleep(t *testing.T) {
@@ -1122,7 +1123,7 @@ func TestHttpClientBindsCount(t *testing.T) {
 	defer app.Cleanup()
 
 	vm := goja.New()
-	httpClientBinds(vm)
+	httpClientBinds(vm, Config{})
 
 	testBindsCount(vm, "$http", 1, t)
 }
@@ -1171,7 +1172,7 @@ func TestHttpClientBindsSend(t *testing.T) {
 
 	vm := goja.New()
 	baseBinds(vm)
-	httpClientBinds(vm)
+	httpClientBinds(vm, Config{})
 	vm.Set("testUrl", server.URL)
 
 	_, err := vm.RunString(`
@@ -1265,6 +1266,147 @@ func TestHttpClientBindsSend(t *testing.T) {
 	}
 }
 
+func TestHttpClientBindsSendRestrictions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("redirect") != "" {
+			http.Redirect(res, req, req.URL.Query().Get("redirect"), http.StatusFound)
+			return
+		}
+
+		if req.URL.Query().Get("sleep") != "" {
+			time.Sleep(1 * time.Second)
+		}
+
+		res.Write([]byte(`{"size":"0123456789"}`))
+	}))
+	defer server.Close()
+
+	serverUrl, _ := url.Parse(server.URL)
+
+	scenarios := []struct {
+		name        string
+		config      Config
+		url         string
+		expectError bool
+	}{
+		{
+			"no restrictions",
+			Config{},
+			server.URL,
+			false,
+		},
+		{
+			"non http scheme",
+			Config{},
+			"file:///etc/passwd",
+			true,
+		},
+		{
+			"allowed host",
+			Config{HttpAllowedHosts: []string{"example.com", serverUrl.Hostname()}},
+			server.URL,
+			false,
+		},
+		{
+			"not allowed host",
+			Config{HttpAllowedHosts: []string{"example.com", "*.127.0.0.1"}},
+			server.URL,
+			true,
+		},
+		{
+			"redirect to not allowed host",
+			Config{HttpAllowedHosts: []string{serverUrl.Hostname()}},
+			server.URL + "?redirect=" + url.QueryEscape("http://example.com"),
+			true,
+		},
+		{
+			"response within the max size",
+			Config{HttpMaxResponseSize: 21},
+			server.URL,
+			false,
+		},
+		{
+			"response exceeding the max size",
+			Config{HttpMaxResponseSize: 20},
+			server.URL,
+			true,
+		},
+		{
+			"max timeout",
+			Config{HttpTimeout: 500 * time.Millisecond},
+			server.URL + "?sleep=1",
+			true,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			vm := goja.New()
+			httpClientBinds(vm, s.config)
+			vm.Set("testUrl", s.url)
+
+			// the explicit timeout should be capped to the config one
+			_, err := vm.RunString(`$http.send({url: testUrl, timeout: 10})`)
+
+			hasErr := err != nil
+			if hasErr != s.expectError {
+				t.Fatalf("Expected hasErr %v, got %v (%v)", s.expectError, hasErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckHttpAllowedHost(t *testing.T) {
+	scenarios := []struct {
+		url          string
+		allowedHosts []string
+		expectError  bool
+	}{
+		{"https://example.com", nil, false},
+		{"ftp://example.com", nil, true},
+		{"https://example.com", []string{"test.com"}, true},
+		{"https://example.com:8090/test", []string{"test.com", "EXAMPLE.com"}, false},
+		{"https://example.com", []string{"*.example.com"}, true},
+		{"https://a.example.com", []string{"*.example.com"}, false},
+		{"https://a.b.example.com", []string{"*.example.com"}, false},
+		{"https://aexample.com", []string{"*example.com"}, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.url+"_"+strings.Join(s.allowedHosts, ","), func(t *testing.T) {
+			u, err := url.Parse(s.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = checkHttpAllowedHost(u, s.allowedHosts)
+
+			hasErr := err != nil
+			if hasErr != s.expectError {
+				t.Fatalf("Expected hasErr %v, got %v (%v)", s.expectError, hasErr, err)
+			}
+		})
+	}
+}
+
+func TestCallerSource(t *testing.T) {
+	vm := goja.New()
+
+	var source string
+	vm.Set("capture", func() {
+		source = callerSource(vm)
+	})
+
+	_, err := vm.RunScript("test.pb.js", "\n\ncapture()")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if source != "test.pb.js:3" {
+		t.Fatalf("Expected source %q, got %q", "test.pb.js:3", source)
+	}
+}
+
 func TestCronBindsCount(t *testing.T) {
 	app, _ := tests.NewTestApp()
 	defer app.Cleanup()
@@ -1282,7 +1424,7 @@ func TestHooksBindsCount(t *testing.T) {
 	vm := goja.New()
 	hooksBinds(app, vm, nil)
 
-	testBindsCount(vm, "this", 88, t)
+	testBindsCount(vm, "this", 94, t)
 }
 
 func TestHooksBinds(t *testing.T) {
@@ -1367,6 +1509,51 @@ func TestHooksBinds(t *testing.T) {
 	}
 }
 
+func TestHooksBindsImports(t *testing.T) {
+	app, _ := tests.NewTestApp()
+	defer app.Cleanup()
+
+	hooksDir := t.TempDir()
+
+	writeTestFiles(t, hooksDir, map[string]string{
+		"utils.ts": `
+			export function importedTitle(title: string): string {
+				return "imported_" + title
+			}
+		`,
+		"main.pb.ts": `
+			import { importedTitle } from "./utils"
+
+			onRecordBeforeCreateRequest((e) => {
+				e.record.set("title", importedTitle(e.record.getString("title")))
+			}, "demo2")
+		`,
+	})
+
+	if err := Register(app, Config{HooksDir: hooksDir, HooksPoolSize: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	collection, err := app.Dao().FindCollectionByNameOrId("demo2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := models.NewRecord(collection)
+	record.Set("title", "test")
+
+	event := &core.RecordCreateEvent{Record: record}
+	event.Collection = collection
+
+	if err := app.OnRecordBeforeCreateRequest().Trigger(event); err != nil {
+		t.Fatal(err)
+	}
+
+	if title := record.GetString("title"); title != "imported_test" {
+		t.Fatalf("Expected title %q, got %q", "imported_test", title)
+	}
+}
+
 func TestRouterBindsCount(t *testing.T) {
 	app, _ := tests.NewTestApp()
 	defer app.Cleanup()
@@ -0,0 +1,322 @@
+package jsvm
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// esmSyntaxRegex is a loose check for top-level ES module import/export statements.
+var esmSyntaxRegex = regexp.MustCompile(`(?m)^\s*(import\s*[\w\*\{"']|export\s+[\w\*\{])`)
+
+// transpileTarget is the max ECMAScript version supported by the goja runtime.
+const transpileTarget = api.ES2017
+
+// tsExtensions lists the file extensions that are always transpiled.
+var tsExtensions = []string{".ts", ".mts", ".cts"}
+
+// needsTranspile reports whether the JS file with the provided path
+// and content has to be transpiled before its execution
+// (aka. it is a TypeScript file or contains ES module syntax).
+func needsTranspile(path string, content []byte) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	for _, tsExt := range tsExtensions {
+		if ext == tsExt {
+			return true
+		}
+	}
+
+	return ext == ".mjs" || esmSyntaxRegex.Match(content)
+}
+
+// transpileScript transpiles the provided TypeScript or ES module entry
+// file content (eg. a hook or migration file) to a plain script.
+//
+// The imports are converted to require() calls and the exports are ignored.
+// The result has an inline source map so that the runtime errors
+// positions point to the original file.
+func transpileScript(path string, content []byte) (string, error) {
+	result := api.Transform(string(content), api.TransformOptions{
+		Loader:     transpileLoader(path),
+		Format:     api.FormatIIFE,
+		Target:     transpileTarget,
+		Sourcemap:  api.SourceMapInline,
+		Sourcefile: path,
+		LogLevel:   api.LogLevelSilent,
+	})
+
+	if err := esbuildError(path, result.Errors); err != nil {
+		return "", err
+	}
+
+	return string(result.Code), nil
+}
+
+// transpileModule transpiles the provided TypeScript or ES module file content
+// to a CommonJS module that can be loaded with require().
+func transpileModule(path string, content []byte) ([]byte, error) {
+	result := api.Transform(string(content), api.TransformOptions{
+		Loader:     transpileLoader(path),
+		Format:     api.FormatCommonJS,
+		Target:     transpileTarget,
+		Sourcemap:  api.SourceMapInline,
+		Sourcefile: path,
+		LogLevel:   api.LogLevelSilent,
+	})
+
+	if err := esbuildError(path, result.Errors); err != nil {
+		return nil, err
+	}
+
+	return result.Code, nil
+}
+
+// bundlePackageModule bundles the node_modules package file located
+// at path together with its dependencies into a single CommonJS module.
+//
+// Bundling allows the package imports to be resolved with the full
+// Node.js resolution rules (package.json "exports", "module", etc.).
+func bundlePackageModule(path string) ([]byte, error) {
+	result := api.Build(api.BuildOptions{
+		EntryPoints: []string{path},
+		Bundle:      true,
+		Write:       false,
+		Format:      api.FormatCommonJS,
+		Platform:    api.PlatformNeutral,
+		MainFields:  []string{"module", "main"},
+		Target:      transpileTarget,
+		Sourcemap:   api.SourceMapInline,
+		LogLevel:    api.LogLevelSilent,
+	})
+
+	if err := esbuildError(path, result.Errors); err != nil {
+		return nil, err
+	}
+
+	if len(result.OutputFiles) == 0 {
+		return nil, errors.New("failed to bundle " + path + ": missing output")
+	}
+
+	return result.OutputFiles[0].Contents, nil
+}
+
+// moduleSourceLoader is a [require.SourceLoader] that transpiles
+// the required TypeScript and ES module files on the fly.
+//
+// It also extends the default require() resolution with:
+//   - ".ts" files fallback for the ".js" imports (eg. require("./utils") -> "./utils.ts")
+//   - package.json "exports" and "module" entry fields
+//   - bundling of the node_modules packages
+func moduleSourceLoader(path string) ([]byte, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	if ext == ".map" {
+		return require.DefaultSourceLoader(path)
+	}
+
+	if filepath.Base(path) == "package.json" {
+		raw, err := require.DefaultSourceLoader(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return normalizePackageMain(raw), nil
+	}
+
+	content, err := require.DefaultSourceLoader(path)
+	if errors.Is(err, require.ModuleFileDoesNotExistError) && ext == ".js" {
+		// try with the TypeScript equivalent
+		for _, tsExt := range tsExtensions {
+			tsPath := strings.TrimSuffix(path, filepath.Ext(path)) + tsExt
+			if content, err = require.DefaultSourceLoader(tsPath); err == nil {
+				path = tsPath
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ext == ".json" {
+		return content, nil
+	}
+
+	if isNodeModulesPath(path) {
+		return bundlePackageModule(path)
+	}
+
+	if needsTranspile(path, content) {
+		return transpileModule(path, content)
+	}
+
+	return content, nil
+}
+
+// normalizePackageMain replaces the package.json "main" field with
+// the package entry from the "exports" or "module" fields (if any)
+// because the default require() resolution checks only "main".
+func normalizePackageMain(raw []byte) []byte {
+	pkg := map[string]any{}
+	if err := json.Unmarshal(raw, &pkg); err != nil {
+		return raw
+	}
+
+	var main string
+
+	if exports, ok := pkg["exports"]; ok {
+		// {"exports": {".": ...}} or {"exports": {"import": ...}}
+		if m, ok := exports.(map[string]any); ok {
+			if v, ok := m["."]; ok {
+				exports = v
+			}
+		}
+		main = resolveExportsEntry(exports)
+	}
+
+	if main == "" {
+		main, _ = pkg["module"].(string)
+	}
+
+	if main == "" {
+		return raw
+	}
+
+	normalized, err := json.Marshal(map[string]any{"main": main})
+	if err != nil {
+		return raw
+	}
+
+	return normalized
+}
+
+// resolveExportsEntry returns the first matching path
+// from a package.json "exports" conditions value.
+func resolveExportsEntry(exports any) string {
+	switch v := exports.(type) {
+	case string:
+		return v
+	case []any:
+		for _, item := range v {
+			if entry := resolveExportsEntry(item); entry != "" {
+				return entry
+			}
+		}
+	case map[string]any:
+		for _, condition := range []string{"import", "module", "default", "require"} {
+			if entry := resolveExportsEntry(v[condition]); entry != "" {
+				return entry
+			}
+		}
+	}
+
+	return ""
+}
+
+func isNodeModulesPath(path string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part == "node_modules" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func transpileLoader(path string) api.Loader {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ts", ".mts", ".cts":
+		return api.LoaderTS
+	default:
+		return api.LoaderJS
+	}
+}
+
+func esbuildError(path string, messages []api.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	errs := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Location != nil {
+			errs = append(errs, msg.Location.File+":"+strconv.Itoa(msg.Location.Line)+": "+msg.Text)
+		} else {
+			errs = append(errs, msg.Text)
+		}
+	}
+
+	return errors.New("failed to transpile " + path + ":\n - " + strings.Join(errs, "\n - "))
+}
+
+// importDeclRegex matches the first line of a transpiled hook file
+// import declaration (eg. `  var import_utils = __toESM(require("./utils"));`).
+var importDeclRegex = regexp.MustCompile(`^  var [\w$]+ = (__toESM\()?require\("(?:[^"\\]|\\.)*"\)\)?;$`)
+
+// esbuildHelperRegex matches the first line of an esbuild runtime
+// helper declaration (eg. `  var __toESM = (mod, isNodeMode, target) => ...`).
+var esbuildHelperRegex = regexp.MustCompile(`^  var __[\w$]+ = `)
+
+// extractImportDeclarations extracts the top-level import declarations
+// (and the esbuild runtime helpers they depend on) from the provided
+// transpiled hook file source.
+//
+// The declarations are returned as block scoped "const" statements so that
+// they could be prepended to the file handlers executed on the pool vms
+// (the handlers are serialized and don't have access to the hook file scope).
+//
+// Returns an empty string if the transpiled source doesn't have imports.
+func extractImportDeclarations(transpiled string) string {
+	var helpers, imports []string
+	var current *[]string // the currently matched statement destination
+
+	for _, line := range strings.Split(transpiled, "\n") {
+		// a new top-level IIFE statement
+		// (the continuation lines are more indented or start with a closing bracket)
+		if len(line) > 2 && strings.HasPrefix(line, "  ") && !strings.ContainsAny(line[2:3], " })]") {
+			current = nil
+
+			switch {
+			case importDeclRegex.MatchString(line):
+				current = &imports
+			case esbuildHelperRegex.MatchString(line):
+				current = &helpers
+			}
+
+			if current != nil {
+				*current = append(*current, "const "+strings.TrimPrefix(line, "  var "))
+			}
+
+			continue
+		}
+
+		if current != nil && strings.HasPrefix(line, "  ") {
+			(*current)[len(*current)-1] += "\n" + line
+		} else {
+			current = nil
+		}
+	}
+
+	if len(imports) == 0 {
+		return ""
+	}
+
+	return strings.Join(append(helpers, imports...), "\n")
+}
+
+// scriptSource returns the JS source of the provided hook or
+// migration file content (transpiling it if necessary).
+func scriptSource(path string, content []byte) (string, error) {
+	if needsTranspile(path, content) {
+		return transpileScript(path, content)
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,316 @@
+package jsvm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
+)
+
+func TestNeedsTranspile(t *testing.T) {
+	scenarios := []struct {
+		path     string
+		content  string
+		expected bool
+	}{
+		{"test.js", `console.log("test")`, false},
+		{"test.js", `const a = require("a"); // import a from "a"`, false},
+		{"test.js", `let important = 1;`, false},
+		{"test.pb.js", `import a from "a"`, true},
+		{"test.pb.js", `import { a } from "a"`, true},
+		{"test.pb.js", `import * as a from "a"`, true},
+		{"test.pb.js", `import "a"`, true},
+		{"test.pb.js", "  export const a = 1", true},
+		{"test.pb.js", `export { a }`, true},
+		{"test.mjs", `console.log("test")`, true},
+		{"test.pb.ts", `console.log("test")`, true},
+		{"test.MTS", `console.log("test")`, true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.path+"_"+s.content, func(t *testing.T) {
+			result := needsTranspile(s.path, []byte(s.content))
+			if result != s.expected {
+				t.Fatalf("Expected %v, got %v", s.expected, result)
+			}
+		})
+	}
+}
+
+func TestScriptSource(t *testing.T) {
+	dir := t.TempDir()
+
+	writeTestFiles(t, dir, map[string]string{
+		// local ts module
+		"utils.ts": `
+			export function sum(a: number, b: number): number {
+				return a + b
+			}
+		`,
+
+		// esm only package with "exports" field
+		"node_modules/esm-pkg/package.json": `{"name":"esm-pkg","type":"module","exports":{".":{"import":"./lib/index.js"}}}`,
+		"node_modules/esm-pkg/lib/index.js": `
+			import { helper } from "./helper.js"
+			export default function greet(name) { return helper() + " " + name }
+		`,
+		"node_modules/esm-pkg/lib/helper.js": `export const helper = () => "hello"`,
+
+		// commonjs package
+		"node_modules/cjs-pkg/package.json": `{"name":"cjs-pkg","main":"main.js"}`,
+		"node_modules/cjs-pkg/main.js":      `module.exports = { value: 123 }`,
+	})
+
+	mainPath := filepath.Join(dir, "main.pb.ts")
+
+	src, err := scriptSource(mainPath, []byte(`
+		import greet from "esm-pkg"
+		import { value } from "cjs-pkg"
+		import { sum } from "./utils"
+
+		const typed: string = greet("world") + " " + sum(value, 1)
+
+		result.value = typed
+	`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := goja.New()
+	require.NewRegistry(require.WithLoader(moduleSourceLoader)).Enable(vm)
+
+	result := map[string]any{}
+	vm.Set("result", result)
+
+	if _, err := vm.RunScript(mainPath, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if result["value"] != "hello world 124" {
+		t.Fatalf("Expected %q, got %v", "hello world 124", result["value"])
+	}
+}
+
+func TestScriptSourcePlainJS(t *testing.T) {
+	content := `let a = 1; // import b from "b"`
+
+	src, err := scriptSource("test.pb.js", []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if src != content {
+		t.Fatalf("Expected the plain script to be unchanged, got %q", src)
+	}
+}
+
+func TestScriptSourceInvalid(t *testing.T) {
+	_, err := scriptSource("test.pb.ts", []byte(`const a: number = ;`))
+	if err == nil || !strings.Contains(err.Error(), "test.pb.ts:1") {
+		t.Fatalf("Expected transpile error with the file position, got %v", err)
+	}
+}
+
+func TestScriptSourceMap(t *testing.T) {
+	dir := t.TempDir()
+
+	writeTestFiles(t, dir, map[string]string{
+		"fail.ts": "type A = string\n\nexport function fail(): A {\n\n\tthrow new Error(\"test\")\n}\n",
+	})
+
+	mainPath := filepath.Join(dir, "main.pb.ts")
+
+	src, err := scriptSource(mainPath, []byte("import { fail } from \"./fail\"\n\nconst a: number = 1\n\nfail()\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vm := goja.New()
+	require.NewRegistry(require.WithLoader(moduleSourceLoader)).Enable(vm)
+
+	_, err = vm.RunScript(mainPath, src)
+
+	exception, ok := err.(*goja.Exception)
+	if !ok {
+		t.Fatalf("Expected goja.Exception, got %v", err)
+	}
+
+	stack := exception.String()
+
+	expectations := []string{
+		"fail.ts:5:",
+		"main.pb.ts:5:",
+	}
+	for _, item := range expectations {
+		if !strings.Contains(stack, item) {
+			t.Errorf("Cannot find %q in the error stack:\n%s", item, stack)
+		}
+	}
+}
+
+func TestNormalizePackageMain(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		raw      string
+		expected string
+	}{
+		{"invalid json", `invalid`, `invalid`},
+		{"main only", `{"main":"a.js"}`, `{"main":"a.js"}`},
+		{"module", `{"main":"a.js","module":"b.js"}`, `{"main":"b.js"}`},
+		{"exports string", `{"main":"a.js","exports":"./c.js"}`, `{"main":"./c.js"}`},
+		{"exports dot", `{"exports":{".":"./c.js","./sub":"./d.js"}}`, `{"main":"./c.js"}`},
+		{"exports conditions", `{"exports":{"require":"./r.js","import":"./i.js"}}`, `{"main":"./i.js"}`},
+		{"exports nested conditions", `{"exports":{".":{"node":"./n.js","default":{"import":"./i.js"}}}}`, `{"main":"./i.js"}`},
+		{"exports array", `{"exports":[{"browser":"./b.js"},"./c.js"]}`, `{"main":"./c.js"}`},
+		{"unmatched exports", `{"main":"a.js","exports":{"browser":"./b.js"}}`, `{"main":"a.js","exports":{"browser":"./b.js"}}`},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			result := string(normalizePackageMain([]byte(s.raw)))
+			if result != s.expected {
+				t.Fatalf("Expected %s, got %s", s.expected, result)
+			}
+		})
+	}
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExtractImportDeclarations(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			"without imports",
+			`export const a: number = 1; console.log(a)`,
+			nil,
+		},
+		{
+			"with imports",
+			`
+				import def, { a } from "./a"
+				import * as ns from "b"
+				import "./c"
+				import type { T } from "./d"
+
+				const x: T = 1
+				onTest(() => { def(); a(); ns.b(x) })
+			`,
+			[]string{
+				`const __toESM = `,
+				`const import_a = __toESM(require("./a"));`,
+				`const ns = __toESM(require("b"));`,
+				`const import_c = require("./c");`,
+			},
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			src, err := transpileScript("test.ts", []byte(s.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := extractImportDeclarations(src)
+
+			if len(s.expected) == 0 {
+				if result != "" {
+					t.Fatalf("Expected no declarations, got\n%s", result)
+				}
+				return
+			}
+
+			for _, str := range s.expected {
+				if !strings.Contains(result, str) {
+					t.Fatalf("Missing %q in\n%s", str, result)
+				}
+			}
+
+			for _, str := range []string{"./d", "const x", "onTest", "var "} {
+				if strings.Contains(result, str) {
+					t.Fatalf("Didn't expect %q in\n%s", str, result)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerImports(t *testing.T) {
+	dir := t.TempDir()
+
+	writeTestFiles(t, dir, map[string]string{
+		"utils.ts": `export function title(name: string): string { return "imported " + name }`,
+	})
+
+	mainPath := filepath.Join(dir, "main.pb.ts")
+	mainContent := []byte(`
+		import { title } from "./utils"
+
+		register((name) => title(name))
+	`)
+
+	src, err := scriptSource(mainPath, mainContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := require.NewRegistry(require.WithLoader(moduleSourceLoader))
+
+	pool := newPool(1, func() *goja.Runtime {
+		vm := goja.New()
+		registry.Enable(vm)
+		return vm
+	})
+	pool.setImports(mainPath, extractImportDeclarations(src))
+
+	loader := goja.New()
+	registry.Enable(loader)
+
+	var pr *goja.Program
+	loader.Set("register", func(handler goja.Value) {
+		pr = callerImports(loader, pool).compile("(" + handler.String() + ").apply(undefined, __args)")
+	})
+
+	if _, err := loader.RunScript(mainPath, src); err != nil {
+		t.Fatal(err)
+	}
+
+	// execute the serialized handler on the (different) pool vm
+	var result string
+	err = pool.run(context.Background(), "", func(executor *goja.Runtime) error {
+		executor.Set("__args", []any{"test"})
+		res, err := executor.RunProgram(pr)
+		if err != nil {
+			return err
+		}
+		result = res.String()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result != "imported test" {
+		t.Fatalf("Expected %q, got %q", "imported test", result)
+	}
+}
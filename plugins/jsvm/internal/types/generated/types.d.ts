@@ -993,6 +993,10 @@ declare namespace $http {
    * console.log(res.cookies)    // the response cookies (eg. res.cookies.sessionId.value)
    * console.log(res.raw)        // the response body as plain text
    * console.log(res.json)       // the response body as parsed json array or map
+   *
+   * Note that the request host must be in the jsvm.Config.HttpAllowedHosts list (if set),
+   * the response body size is limited by jsvm.Config.HttpMaxResponseSize (10MB by default)
+   * and the request timeout cannot exceed jsvm.Config.HttpTimeout (120s by default).
    * ```
    */
   function send(config: {
@@ -1000,7 +1004,7 @@ declare namespace $http {
     body?:    string,
     method?:  string, // default to "GET"
     headers?: { [key:string]: string },
-    timeout?: number, // default to 120
+    timeout?: number, // in seconds (default to and max jsvm.Config.HttpTimeout)
 
     // deprecated, please use body instead
     data?: { [key:string]: any },
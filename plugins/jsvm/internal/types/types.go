@@ -61,6 +61,51 @@ declare function cronAdd(
  */
 declare function cronRemove(jobId: string): void;
 
+// -------------------------------------------------------------------
+// jobsBinds
+// -------------------------------------------------------------------
+
+/**
+ * JobsRegister registers a new background jobs handler for the
+ * jobs with the specified name (see also ` + "`" + `$jobs.enqueue()` + "`" + `).
+ *
+ * Throwing an error from the handler marks the job run as failed
+ * and the job will be retried with exponential backoff.
+ *
+ * Example:
+ *
+ * ` + "```" + `js
+ * jobsRegister("sendWelcome", (job) => {
+ *     const payload = JSON.parse(job.payload)
+ *
+ *     console.log("Sending welcome email to", payload.email)
+ * })
+ * ` + "```" + `
+ *
+ * _Note that this method is available only in pb_hooks context._
+ *
+ * @group PocketBase
+ */
+declare function jobsRegister(
+  name:    string,
+  handler: (job: models.Job) => void,
+): void;
+
+/**
+ * JobsRemove removes a single registered background jobs handler by its name.
+ *
+ * Example:
+ *
+ * ` + "```" + `js
+ * jobsRemove("sendWelcome")
+ * ` + "```" + `
+ *
+ * _Note that this method is available only in pb_hooks context._
+ *
+ * @group PocketBase
+ */
+declare function jobsRemove(name: string): void;
+
 // -------------------------------------------------------------------
 // routerBinds
 // -------------------------------------------------------------------
@@ -591,6 +636,40 @@ declare namespace $mails {
   let sendRecordChangeEmail:   mails.sendRecordChangeEmail
 }
 
+// -------------------------------------------------------------------
+// jobsQueueBinds
+// -------------------------------------------------------------------
+
+interface JobEnqueueOptions {
+  priority?:    number
+  delay?:       number // in milliseconds
+  runAt?:       string|types.DateTime
+  maxAttempts?: number
+  uniqueKey?:   string
+}
+
+/**
+ * ` + "`" + `$jobs` + "`" + ` defines helpers for enqueueing background jobs.
+ *
+ * @group PocketBase
+ */
+declare namespace $jobs {
+  /**
+   * Enqueues a new background job with the specified name and
+   * JSON serializable payload.
+   *
+   * Example:
+   *
+   * ` + "```" + `js
+   * $jobs.enqueue("sendWelcome", { email: "test@example.com" }, {
+   *     delay:     5000,
+   *     uniqueKey: "welcome_test@example.com",
+   * })
+   * ` + "```" + `
+   */
+  export function enqueue(name: string, payload?: any, options?: JobEnqueueOptions): models.Job
+}
+
 // -------------------------------------------------------------------
 // securityBinds
 // -------------------------------------------------------------------
@@ -1009,6 +1088,10 @@ declare namespace $http {
    * console.log(res.cookies)    // the response cookies (eg. res.cookies.sessionId.value)
    * console.log(res.raw)        // the response body as plain text
    * console.log(res.json)       // the response body as parsed json array or map
+   *
+   * Note that the request host must be in the jsvm.Config.HttpAllowedHosts list (if set),
+   * the response body size is limited by jsvm.Config.HttpMaxResponseSize (10MB by default)
+   * and the request timeout cannot exceed jsvm.Config.HttpTimeout (120s by default).
    * ` + "```" + `
    */
   function send(config: {
@@ -1016,7 +1099,7 @@ declare namespace $http {
     body?:    string,
     method?:  string, // default to "GET"
     headers?: { [key:string]: string },
-    timeout?: number, // default to 120
+    timeout?: number, // in seconds (default to and max jsvm.Config.HttpTimeout)
 
     // deprecated, please use body instead
     data?: { [key:string]: any },
@@ -1,6 +1,9 @@
 // Package jsvm implements pluggable utilities for binding a JS goja runtime
 // to the PocketBase instance (loading migrations, attaching to app hooks, etc.).
 //
+// TypeScript and ES module files (including the ones required from a local
+// node_modules directory) are transpiled on the fly with an embedded esbuild.
+//
 // Example:
 //
 //	jsvm.MustRegister(app, jsvm.Config{
@@ -35,8 +38,11 @@ import (
 	"github.com/pocketbase/dbx"
 )
 
+const typesFileName = "types.d.ts"
+
 const (
-	typesFileName = "types.d.ts"
+	defaultHooksTimeout           = 3 * time.Minute
+	defaultBackgroundHooksTimeout = 15 * time.Minute
 )
 
 // Config defines the config options of the jsvm plugin.
@@ -61,7 +67,13 @@ type Config struct {
 	// identify which file to load by the hook vm(s).
 	//
 	// If not set it fallbacks to `^.*(\.pb\.js|\.pb\.ts)$`, aka. any
-	// HookdsDir file ending in ".pb.js" or ".pb.ts" (the last one is to enforce IDE linters).
+	// HookdsDir file ending in ".pb.js" or ".pb.ts".
+	//
+	// TypeScript files and files with ES module import/export statements
+	// are transpiled before their execution. The top-level imports are
+	// re-evaluated together with the file handlers so that they are also
+	// accessible inside the handlers (note that this doesn't apply to the
+	// other top-level variables and require() calls).
 	HooksFilesPattern string
 
 	// HooksPoolSize specifies how many goja.Runtime instances to prewarm
@@ -71,13 +83,54 @@ type Config struct {
 	// on every fired goroutine.
 	HooksPoolSize int
 
+	// HooksTimeout specifies the max execution time of a single JS hook,
+	// route or middleware handler call.
+	//
+	// The handler execution is also interrupted when its request context is canceled
+	// (eg. on client disconnect) and the interrupted runtime is returned back to the pool.
+	//
+	// If not set it fallbacks to 3 minutes (aka. longer than the default
+	// $http.send timeout). Negative value means no timeout.
+	HooksTimeout time.Duration
+
+	// BackgroundHooksTimeout specifies the max execution time of a single
+	// JS cron (cronAdd) or job (jobsRegister) handler call.
+	//
+	// The job handler execution is also interrupted when its context is canceled
+	// (eg. when the job claim is lost).
+	//
+	// If not set it fallbacks to 15 minutes. Negative value means no timeout.
+	BackgroundHooksTimeout time.Duration
+
+	// HooksMemoryPressureLimit specifies a process wide heap size
+	// in bytes above which the running JS handlers are interrupted.
+	//
+	// This is a global memory pressure guard and NOT a per-handler limit:
+	// the JS runtimes share the same Go heap, so when the total process
+	// heap exceeds the limit, all handlers that are running at that moment
+	// are interrupted with [ErrMemoryPressure], including the well-behaved
+	// ones that didn't cause the heap growth.
+	//
+	// Per-runtime allocation budgets are intentionally not supported
+	// because goja doesn't expose (or attribute) the allocations of a
+	// single runtime.
+	//
+	// Zero or negative value (the default) disables the guard.
+	HooksMemoryPressureLimit int64
+
+	// HooksMaxCallStackSize specifies the max JS function call depth
+	// of the hooks runtimes (eg. to stop infinite recursions early).
+	//
+	// Zero or negative value fallbacks to the goja default (math.MaxInt32).
+	HooksMaxCallStackSize int
+
 	// MigrationsDir specifies the JS migrations directory.
 	//
 	// If not set it fallbacks to a relative "pb_data/../pb_migrations" directory.
 	MigrationsDir string
 
 	// If not set it fallbacks to `^.*(\.js|\.ts)$`, aka. any MigrationDir file
-	// ending in ".js" or ".ts" (the last one is to enforce IDE linters).
+	// ending in ".js" or ".ts" (TypeScript files are transpiled before their execution).
 	MigrationsFilesPattern string
 
 	// TypesDir specifies the directory where to store the embedded
@@ -88,6 +141,25 @@ type Config struct {
 	// Note: Avoid using the same directory as the HooksDir when HooksWatch is enabled
 	// to prevent unnecessary app restarts when the types file is initially created.
 	TypesDir string
+
+	// HttpAllowedHosts specifies the list of hosts that could be
+	// requested with the JS $http.send() binding.
+	//
+	// Each entry could be an exact host name (eg. "api.example.com")
+	// or a subdomains wildcard pattern (eg. "*.example.com").
+	//
+	// If not set, requests to any host are allowed.
+	HttpAllowedHosts []string
+
+	// HttpMaxResponseSize specifies the max allowed $http.send() response body size in bytes.
+	//
+	// If not set it fallbacks to 10MB.
+	HttpMaxResponseSize int64
+
+	// HttpTimeout specifies the default and max allowed $http.send() request timeout.
+	//
+	// If not set it fallbacks to 120 seconds.
+	HttpTimeout time.Duration
 }
 
 // MustRegister registers the jsvm plugin in the provided app instance
@@ -131,6 +203,22 @@ func Register(app core.App, config Config) error {
 		p.config.TypesDir = app.DataDir()
 	}
 
+	if p.config.HttpMaxResponseSize <= 0 {
+		p.config.HttpMaxResponseSize = defaultHttpMaxResponseSize
+	}
+
+	if p.config.HttpTimeout <= 0 {
+		p.config.HttpTimeout = defaultHttpTimeout
+	}
+
+	if p.config.HooksTimeout == 0 {
+		p.config.HooksTimeout = defaultHooksTimeout
+	}
+
+	if p.config.BackgroundHooksTimeout == 0 {
+		p.config.BackgroundHooksTimeout = defaultBackgroundHooksTimeout
+	}
+
 	p.app.OnAfterBootstrap().Add(func(e *core.BootstrapEvent) error {
 		// ensure that the user has the latest types declaration
 		if err := p.refreshTypesFile(); err != nil {
@@ -164,7 +252,8 @@ func (p *plugin) registerMigrations() error {
 		return err
 	}
 
-	registry := new(require.Registry) // this can be shared by multiple runtimes
+	// this can be shared by multiple runtimes
+	registry := require.NewRegistry(require.WithLoader(moduleSourceLoader))
 
 	for file, content := range files {
 		vm := goja.New()
@@ -177,7 +266,7 @@ func (p *plugin) registerMigrations() error {
 		securityBinds(vm)
 		osBinds(vm)
 		filepathBinds(vm)
-		httpClientBinds(vm)
+		httpClientBinds(vm, p.config)
 
 		vm.Set("migrate", func(up, down func(db dbx.Builder) error) {
 			m.AppMigrations.Register(up, down, file)
@@ -187,7 +276,14 @@ func (p *plugin) registerMigrations() error {
 			p.config.OnInit(vm)
 		}
 
-		_, err := vm.RunString(string(content))
+		path := filepath.Join(p.config.MigrationsDir, file)
+
+		src, err := scriptSource(path, content)
+		if err != nil {
+			return fmt.Errorf("failed to load migration %s: %w", file, err)
+		}
+
+		_, err = vm.RunScript(path, src)
 		if err != nil {
 			return fmt.Errorf("failed to run migration %s: %w", file, err)
 		}
@@ -244,7 +340,7 @@ func (p *plugin) registerHooks() error {
 	})
 
 	// safe to be shared across multiple vms
-	requireRegistry := new(require.Registry)
+	requireRegistry := require.NewRegistry(require.WithLoader(moduleSourceLoader))
 	templateRegistry := template.NewRegistry()
 
 	sharedBinds := func(vm *goja.Runtime) {
@@ -259,10 +355,15 @@ func (p *plugin) registerHooks() error {
 		securityBinds(vm)
 		osBinds(vm)
 		filepathBinds(vm)
-		httpClientBinds(vm)
+		httpClientBinds(vm, p.config)
 		formsBinds(vm)
 		apisBinds(vm)
 		mailsBinds(vm)
+		jobsQueueBinds(p.app, vm)
+
+		if p.config.HooksMaxCallStackSize > 0 {
+			vm.SetMaxCallStackSize(p.config.HooksMaxCallStackSize)
+		}
 
 		vm.Set("$app", p.app)
 		vm.Set("$template", templateRegistry)
@@ -279,12 +380,17 @@ func (p *plugin) registerHooks() error {
 		sharedBinds(executor)
 		return executor
 	})
+	executors.timeout = p.config.HooksTimeout
+	executors.backgroundTimeout = p.config.BackgroundHooksTimeout
+	executors.memoryPressureLimit = p.config.HooksMemoryPressureLimit
+	executors.logger = p.app.Logger
 
 	// initialize the loader vm
 	loader := goja.New()
 	sharedBinds(loader)
 	hooksBinds(p.app, loader, executors)
 	cronBinds(p.app, loader, executors)
+	jobsBinds(p.app, loader, executors)
 	routerBinds(p.app, loader, executors)
 
 	for file, content := range files {
@@ -301,7 +407,23 @@ func (p *plugin) registerHooks() error {
 				}
 			}()
 
-			_, err := loader.RunString(string(content))
+			// note: the absolute path is used so that the relative
+			// and node_modules require() calls are resolved from the hooks dir
+			path := filepath.Join(absHooksDir, file)
+
+			src, err := scriptSource(path, content)
+			if err != nil {
+				panic(err)
+			}
+
+			// register the file imports so that they are accessible also in its handlers
+			if needsTranspile(path, content) {
+				if declarations := extractImportDeclarations(src); declarations != "" {
+					executors.setImports(path, declarations)
+				}
+			}
+
+			_, err = loader.RunScript(path, src)
 			if err != nil {
 				panic(err)
 			}
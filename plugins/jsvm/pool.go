@@ -1,11 +1,34 @@
 package jsvm
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/metrics"
 	"sync"
+	"time"
 
 	"github.com/dop251/goja"
 )
 
+var (
+	// ErrHandlerTimeout is the error returned when a JS handler execution
+	// exceeds the configured [Config.HooksTimeout] or [Config.BackgroundHooksTimeout].
+	ErrHandlerTimeout = errors.New("JS handler execution timeout")
+
+	// ErrMemoryPressure is the error returned when a JS handler execution
+	// is interrupted because the process heap size exceeded the
+	// configured [Config.HooksMemoryPressureLimit].
+	ErrMemoryPressure = errors.New("JS handler interrupted due to process memory pressure")
+)
+
+// heapCheckInterval is the interval between the process heap
+// size checks while a JS handler is running.
+const heapCheckInterval = 20 * time.Millisecond
+
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
 type poolItem struct {
 	mux  sync.Mutex
 	busy bool
@@ -16,6 +39,27 @@ type vmsPool struct {
 	mux     sync.RWMutex
 	factory func() *goja.Runtime
 	items   []*poolItem
+
+	// timeout is the max execution time of a single run call
+	// (zero or negative value means no timeout).
+	timeout time.Duration
+
+	// backgroundTimeout is the max execution time of a single runBackground call
+	// (zero or negative value means no timeout).
+	backgroundTimeout time.Duration
+
+	// memoryPressureLimit is the process heap size in bytes above which
+	// all running calls are interrupted (zero or negative value means no limit).
+	memoryPressureLimit int64
+
+	// logger is an optional function that returns the logger
+	// used to report the interrupted and failed executions.
+	logger func() *slog.Logger
+
+	// imports stores the transpiled hook files import declarations
+	// (keyed by the file path) that are evaluated together with the
+	// file handlers (see [extractImportDeclarations]).
+	imports map[string]string
 }
 
 // newPool creates a new pool with pre-warmed vms generated from the specified factory.
@@ -33,9 +77,54 @@ func newPool(size int, factory func() *goja.Runtime) *vmsPool {
 	return pool
 }
 
+// setImports registers the import declarations of the hook file with the specified path.
+func (p *vmsPool) setImports(path string, declarations string) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.imports == nil {
+		p.imports = map[string]string{}
+	}
+
+	p.imports[path] = declarations
+}
+
+// importsOf returns the registered import declarations
+// of the hook file with the specified path (if any).
+func (p *vmsPool) importsOf(path string) string {
+	if p == nil {
+		return ""
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	return p.imports[path]
+}
+
 // run executes "call" with a vm created from the pool
 // (either from the buffer or a new one if all buffered vms are busy)
-func (p *vmsPool) run(call func(vm *goja.Runtime) error) error {
+//
+// The execution is interrupted if ctx is done or the pool limits are exceeded.
+// source is the handler registration position (eg. "main.pb.js:10")
+// and it is used only for the error reporting.
+func (p *vmsPool) run(ctx context.Context, source string, call func(vm *goja.Runtime) error) error {
+	return p.runWithTimeout(ctx, p.timeout, source, call)
+}
+
+// runBackground is similar to [vmsPool.run] but it is intended for the
+// handlers that are not bound to a request (eg. cron and jobs handlers)
+// and it is limited by p.backgroundTimeout instead of p.timeout.
+func (p *vmsPool) runBackground(ctx context.Context, source string, call func(vm *goja.Runtime) error) error {
+	return p.runWithTimeout(ctx, p.backgroundTimeout, source, call)
+}
+
+func (p *vmsPool) runWithTimeout(
+	ctx context.Context,
+	timeout time.Duration,
+	source string,
+	call func(vm *goja.Runtime) error,
+) error {
 	p.mux.RLock()
 
 	// try to find a free item
@@ -59,15 +148,109 @@ func (p *vmsPool) run(call func(vm *goja.Runtime) error) error {
 	// note: if turned out not efficient we may change this in the future
 	// by adding the created item in the pool with some timer for removal
 	if freeItem == nil {
-		return call(p.factory())
+		return p.exec(ctx, timeout, source, p.factory(), call)
 	}
 
-	execErr := call(freeItem.vm)
+	execErr := p.exec(ctx, timeout, source, freeItem.vm, call)
 
 	// "free" the vm
+	//
+	// note: the vm interrupt flag is always cleared by exec so
+	// an interrupted vm can be safely reused by the next run call
 	freeItem.mux.Lock()
 	freeItem.busy = false
 	freeItem.mux.Unlock()
 
 	return execErr
 }
+
+// exec executes "call" with the provided vm enforcing the pool limits
+// and the specified timeout (zero or negative value means no timeout).
+func (p *vmsPool) exec(
+	ctx context.Context,
+	timeout time.Duration,
+	source string,
+	vm *goja.Runtime,
+	call func(vm *goja.Runtime) error,
+) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, ErrHandlerTimeout)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	if ctx.Done() != nil || p.memoryPressureLimit > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.watch(ctx, vm, done)
+		}()
+	}
+
+	err := call(vm)
+
+	close(done)
+	wg.Wait()
+
+	// clear any pending interrupt (eg. in case the watcher
+	// triggered it right after the call completion)
+	vm.ClearInterrupt()
+
+	var interruptedErr *goja.InterruptedError
+	var stackOverflowErr *goja.StackOverflowError
+	if errors.As(err, &interruptedErr) || errors.As(err, &stackOverflowErr) {
+		if source != "" {
+			err = fmt.Errorf("%s: %w", source, err)
+		}
+
+		if p.logger != nil {
+			p.logger().Error(
+				"JS handler execution interrupted",
+				slog.String("source", source),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
+	return err
+}
+
+// watch interrupts the vm when ctx is done or when the process heap
+// size exceeds p.memoryPressureLimit, until the done channel is closed.
+//
+// Note that the heap size is not attributable to a single vm so all
+// vms that are running while over the limit are interrupted.
+func (p *vmsPool) watch(ctx context.Context, vm *goja.Runtime, done <-chan struct{}) {
+	var heapCheck <-chan time.Time
+	if p.memoryPressureLimit > 0 {
+		ticker := time.NewTicker(heapCheckInterval)
+		defer ticker.Stop()
+		heapCheck = ticker.C
+	}
+
+	samples := []metrics.Sample{{Name: heapMetric}}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			vm.Interrupt(context.Cause(ctx))
+			return
+		case <-heapCheck:
+			metrics.Read(samples)
+			if samples[0].Value.Kind() == metrics.KindUint64 &&
+				samples[0].Value.Uint64() > uint64(p.memoryPressureLimit) {
+				vm.Interrupt(ErrMemoryPressure)
+				return
+			}
+		}
+	}
+}
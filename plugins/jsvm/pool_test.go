@@ -0,0 +1,137 @@
+package jsvm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+func TestPoolRunTimeout(t *testing.T) {
+	pool := newPool(1, goja.New)
+	pool.timeout = 100 * time.Millisecond
+
+	vm := pool.items[0].vm
+
+	err := pool.run(context.Background(), "test.pb.js:1", func(vm *goja.Runtime) error {
+		_, err := vm.RunString(`while(true) {}`)
+		return err
+	})
+	if !errors.Is(err, ErrHandlerTimeout) {
+		t.Fatalf("Expected ErrHandlerTimeout, got %v", err)
+	}
+
+	if !strings.HasPrefix(err.Error(), "test.pb.js:1: ") {
+		t.Fatalf("Expected the error to start with the handler source, got %q", err.Error())
+	}
+
+	// the interrupted vm should be released and reusable
+	if pool.items[0].busy {
+		t.Fatal("Expected the pool item to be released")
+	}
+
+	var result int64
+	err = pool.run(context.Background(), "", func(executor *goja.Runtime) error {
+		if executor != vm {
+			t.Fatal("Expected the pool vm to be reused")
+		}
+
+		res, err := executor.RunString(`1 + 2`)
+		if err != nil {
+			return err
+		}
+
+		result = res.ToInteger()
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	if result != 3 {
+		t.Fatalf("Expected result 3, got %d", result)
+	}
+}
+
+func TestPoolRunBackgroundTimeout(t *testing.T) {
+	pool := newPool(1, goja.New)
+	pool.timeout = 50 * time.Millisecond
+	pool.backgroundTimeout = 200 * time.Millisecond
+
+	start := time.Now()
+
+	// the regular timeout shouldn't apply to the background handlers
+	err := pool.runBackground(context.Background(), "", func(vm *goja.Runtime) error {
+		_, err := vm.RunString(`while(true) {}`)
+		return err
+	})
+	if !errors.Is(err, ErrHandlerTimeout) {
+		t.Fatalf("Expected ErrHandlerTimeout, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < pool.backgroundTimeout {
+		t.Fatalf("Expected the handler to run at least %v, got %v", pool.backgroundTimeout, elapsed)
+	}
+}
+
+func TestPoolRunContextCancel(t *testing.T) {
+	pool := newPool(1, goja.New)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	err := pool.run(ctx, "", func(vm *goja.Runtime) error {
+		_, err := vm.RunString(`while(true) {}`)
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled error, got %v", err)
+	}
+}
+
+func TestPoolRunMemoryPressure(t *testing.T) {
+	pool := newPool(1, goja.New)
+	pool.memoryPressureLimit = 1 // always exceeded
+
+	err := pool.run(context.Background(), "", func(vm *goja.Runtime) error {
+		_, err := vm.RunString(`while(true) {}`)
+		return err
+	})
+	if !errors.Is(err, ErrMemoryPressure) {
+		t.Fatalf("Expected ErrMemoryPressure, got %v", err)
+	}
+}
+
+func TestPoolRunMaxCallStackSize(t *testing.T) {
+	pool := newPool(1, func() *goja.Runtime {
+		vm := goja.New()
+		vm.SetMaxCallStackSize(100)
+		return vm
+	})
+
+	err := pool.run(context.Background(), "", func(vm *goja.Runtime) error {
+		_, err := vm.RunString(`function test() { test() }; test()`)
+		return err
+	})
+
+	var stackOverflowErr *goja.StackOverflowError
+	if !errors.As(err, &stackOverflowErr) {
+		t.Fatalf("Expected StackOverflowError, got %v", err)
+	}
+}
+
+func TestPoolRunWithoutLimits(t *testing.T) {
+	pool := newPool(0, goja.New)
+
+	err := pool.run(context.Background(), "", func(vm *goja.Runtime) error {
+		_, err := vm.RunString(`let a = 0; for (let i = 0; i < 1000; i++) { a++ }`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+}
@@ -16,6 +16,7 @@
 package migratecmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -111,12 +112,15 @@ type plugin struct {
 func (p *plugin) createCommand() *cobra.Command {
 	const cmdDesc = `Supported arguments are:
 - up            - runs all available migrations
+                  (with --dry-run prints their SQL statements without applying them)
 - down [number] - reverts the last [number] applied migrations
 - create name   - creates new blank migration template file
 - collections   - creates new migration file with snapshot of the local collections configuration
 - history-sync  - ensures that the _migrations history table doesn't have references to deleted migration files
 `
 
+	var dryRun bool
+
 	command := &cobra.Command{
 		Use:          "migrate",
 		Short:        "Executes app DB migration scripts",
@@ -139,6 +143,14 @@ func (p *plugin) createCommand() *cobra.Command {
 					return err
 				}
 			default:
+				if dryRun {
+					if cmd != "" && cmd != "up" {
+						return errors.New("The --dry-run flag is supported only by the up command.")
+					}
+
+					args = []string{"up", "--dry-run"}
+				}
+
 				runner, err := migrate.NewRunner(p.app.DB(), migrations.AppMigrations)
 				if err != nil {
 					return err
@@ -153,6 +165,8 @@ func (p *plugin) createCommand() *cobra.Command {
 		},
 	}
 
+	command.Flags().BoolVar(&dryRun, "dry-run", false, "print the SQL statements of the pending migrations without applying them (up only)")
+
 	return command
 }
 
@@ -381,6 +381,18 @@ func NewTestApp(optTestDataDir ...string) (*TestApp, error) {
 		return t.registerEventCall("OnRealtimeAfterSubscribeRequest")
 	})
 
+	t.OnRealtimeBeforeChannelPublish().Add(func(e *core.RealtimeChannelEvent) error {
+		return t.registerEventCall("OnRealtimeBeforeChannelPublish")
+	})
+
+	t.OnRealtimeAfterChannelPublish().Add(func(e *core.RealtimeChannelEvent) error {
+		return t.registerEventCall("OnRealtimeAfterChannelPublish")
+	})
+
+	t.OnRealtimePresenceChange().Add(func(e *core.RealtimePresenceEvent) error {
+		return t.registerEventCall("OnRealtimePresenceChange")
+	})
+
 	t.OnSettingsListRequest().Add(func(e *core.SettingsListEvent) error {
 		return t.registerEventCall("OnSettingsListRequest")
 	})
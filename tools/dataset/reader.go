@@ -0,0 +1,131 @@
+package dataset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Reader defines a streaming dataset items decoder.
+type Reader interface {
+	// Read decodes the next data item.
+	//
+	// It returns io.EOF when there are no more items.
+	Read() (map[string]any, error)
+}
+
+// NewReader creates a new dataset Reader for the specified format.
+//
+// The first row of the tabular formats is used as header and
+// an optional leading UTF-8 BOM is ignored.
+//
+// The formula escaped cells of [FormatExcel] are restored to their original value.
+func NewReader(r io.Reader, format Format) (Reader, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonReader{scanner: newLinesScanner(r)}, nil
+	case FormatCSV, FormatExcel:
+		br := bufio.NewReader(r)
+
+		// skip the BOM (if any)
+		if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\ufeff")) {
+			br.Discard(3)
+		}
+
+		cr := csv.NewReader(br)
+		cr.FieldsPerRecord = -1 // the row length is checked manually for a better error message
+
+		return &csvReader{reader: cr, excel: format == FormatExcel}, nil
+	}
+
+	return nil, format.Validate()
+}
+
+// -------------------------------------------------------------------
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newLinesScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10<<20) // max 10MB per line
+	return scanner
+}
+
+// Read implements [Reader.Read] interface method.
+//
+// Blank lines are skipped.
+func (r *ndjsonReader) Read() (map[string]any, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		item := map[string]any{}
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("invalid json object on line %d: %w", r.line, err)
+		}
+
+		return item, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+// -------------------------------------------------------------------
+
+type csvReader struct {
+	reader *csv.Reader
+	header []string
+	excel  bool
+}
+
+// Read implements [Reader.Read] interface method.
+//
+// All item values are returned as plain strings.
+func (r *csvReader) Read() (map[string]any, error) {
+	if r.header == nil {
+		header, err := r.reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("failed to read the csv header: %w", err)
+		}
+		r.header = header
+	}
+
+	row, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(row) != len(r.header) {
+		line, _ := r.reader.FieldPos(0)
+		return nil, fmt.Errorf("expected %d columns on line %d, got %d", len(r.header), line, len(row))
+	}
+
+	item := make(map[string]any, len(row))
+	for i, col := range r.header {
+		if r.excel {
+			item[col] = unescapeFormula(row[i])
+		} else {
+			item[col] = row[i]
+		}
+	}
+
+	return item, nil
+}
@@ -0,0 +1,110 @@
+package dataset_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/tools/dataset"
+)
+
+func TestNewReaderInvalidFormat(t *testing.T) {
+	if _, err := dataset.NewReader(strings.NewReader(""), "invalid"); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+}
+
+func TestReader(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		format       dataset.Format
+		content      string
+		expectedJson string
+		expectError  bool
+	}{
+		{
+			"empty csv",
+			dataset.FormatCSV,
+			"",
+			`[]`,
+			false,
+		},
+		{
+			"csv with header only",
+			dataset.FormatCSV,
+			"id,title\n",
+			`[]`,
+			false,
+		},
+		{
+			"csv with items",
+			dataset.FormatCSV,
+			"id,title\n1,\"a,b\"\n2,\n",
+			`[{"id":"1","title":"a,b"},{"id":"2","title":""}]`,
+			false,
+		},
+		{
+			"csv with BOM and CRLF",
+			dataset.FormatExcel,
+			"\ufeffid,title\r\n1,test\r\n",
+			`[{"id":"1","title":"test"}]`,
+			false,
+		},
+		{
+			"csv with invalid row length",
+			dataset.FormatCSV,
+			"id,title\n1,test\n2\n",
+			`[{"id":"1","title":"test"}]`,
+			true,
+		},
+		{
+			"ndjson with items and blank lines",
+			dataset.FormatNDJSON,
+			"{\"id\":\"1\",\"n\":1.5}\n\n  \n{\"id\":\"2\",\"tags\":[\"a\"]}",
+			`[{"id":"1","n":1.5},{"id":"2","tags":["a"]}]`,
+			false,
+		},
+		{
+			"ndjson with invalid line",
+			dataset.FormatNDJSON,
+			"{\"id\":\"1\"}\n[1,2]\n",
+			`[{"id":"1"}]`,
+			true,
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			r, err := dataset.NewReader(strings.NewReader(s.content), s.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			items := []map[string]any{}
+
+			var readErr error
+			for {
+				item, err := r.Read()
+				if err != nil {
+					if !errors.Is(err, io.EOF) {
+						readErr = err
+					}
+					break
+				}
+				items = append(items, item)
+			}
+
+			hasErr := readErr != nil
+			if hasErr != s.expectError {
+				t.Fatalf("Expected hasErr %v, got %v (%v)", s.expectError, hasErr, readErr)
+			}
+
+			encoded, _ := json.Marshal(items)
+			if string(encoded) != s.expectedJson {
+				t.Fatalf("Expected %s, got %s", s.expectedJson, encoded)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Package dataset implements streaming CSV and NDJSON encoders and
+// decoders for plain map data items (eg. records export and import).
+package dataset
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// Format defines a dataset file format.
+type Format string
+
+// Supported dataset formats.
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+
+	// FormatExcel is a CSV variant prefixed with UTF-8 BOM and using
+	// CRLF line endings so that it can be opened directly in MS Excel.
+	//
+	// The formula-like text cells are escaped with a single quote prefix
+	// (which is stripped back when the file is read with [NewReader]).
+	FormatExcel Format = "excel"
+)
+
+// Formats returns all supported dataset formats.
+func Formats() []Format {
+	return []Format{FormatCSV, FormatNDJSON, FormatExcel}
+}
+
+// Validate checks whether the current format is supported.
+func (f Format) Validate() error {
+	if !slices.Contains(Formats(), f) {
+		return fmt.Errorf("unsupported format %q (expected one of %v)", f, Formats())
+	}
+
+	return nil
+}
+
+// ContentType returns the HTTP content type of the current format.
+func (f Format) ContentType() string {
+	if f == FormatNDJSON {
+		return "application/x-ndjson"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// Extension returns the file extension (without the dot) of the current format.
+func (f Format) Extension() string {
+	if f == FormatNDJSON {
+		return "ndjson"
+	}
+
+	return "csv"
+}
+
+// Writer defines a streaming dataset items encoder.
+type Writer interface {
+	// Write encodes a single data item.
+	Write(item map[string]any) error
+
+	// Flush writes any buffered data to the underlying io.Writer.
+	Flush() error
+}
+
+// NewWriter creates a new dataset Writer for the specified format.
+//
+// The optional columns define the header of the tabular formats
+// (the item keys that are not listed are ignored).
+// If no columns are specified, the header is resolved from the first written item.
+func NewWriter(w io.Writer, format Format, columns ...string) (Writer, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatCSV, FormatExcel:
+		cw := &csvWriter{
+			raw:         w,
+			writer:      csv.NewWriter(w),
+			columnsHint: columns,
+			excel:       format == FormatExcel,
+		}
+		cw.writer.UseCRLF = format == FormatExcel
+		return cw, nil
+	}
+
+	return nil, format.Validate()
+}
+
+// -------------------------------------------------------------------
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+// Write implements [Writer.Write] interface method.
+func (w *ndjsonWriter) Write(item map[string]any) error {
+	// note: Encode always terminates the value with a newline
+	return w.encoder.Encode(item)
+}
+
+// Flush implements [Writer.Flush] interface method.
+func (w *ndjsonWriter) Flush() error {
+	return nil
+}
+
+// -------------------------------------------------------------------
+
+type csvWriter struct {
+	raw         io.Writer
+	writer      *csv.Writer
+	columnsHint []string
+	columns     []string
+	excel       bool
+	initialized bool
+}
+
+// Write implements [Writer.Write] interface method.
+//
+// The CSV header is written from the columns hint or,
+// if there is no hint, from the keys of the first written item.
+func (w *csvWriter) Write(item map[string]any) error {
+	if !w.initialized {
+		columns := w.columnsHint
+		if len(columns) == 0 {
+			columns = resolveColumns(item)
+		}
+
+		if err := w.writeHeader(columns); err != nil {
+			return err
+		}
+	}
+
+	row := make([]string, len(w.columns))
+	for i, col := range w.columns {
+		cell, err := csvCell(item[col], w.excel)
+		if err != nil {
+			return fmt.Errorf("failed to encode column %q: %w", col, err)
+		}
+		row[i] = cell
+	}
+
+	return w.writer.Write(row)
+}
+
+// Flush implements [Writer.Flush] interface method.
+//
+// If no items were written, Flush writes only the CSV header from the columns hint.
+func (w *csvWriter) Flush() error {
+	if !w.initialized && len(w.columnsHint) > 0 {
+		if err := w.writeHeader(w.columnsHint); err != nil {
+			return err
+		}
+	}
+
+	w.writer.Flush()
+
+	return w.writer.Error()
+}
+
+func (w *csvWriter) writeHeader(columns []string) error {
+	w.initialized = true
+	w.columns = columns
+
+	if w.excel {
+		if _, err := io.WriteString(w.raw, "\ufeff"); err != nil {
+			return err
+		}
+	}
+
+	return w.writer.Write(w.columns)
+}
+
+// resolveColumns returns the item keys in alphabetical order.
+func resolveColumns(item map[string]any) []string {
+	result := make([]string, 0, len(item))
+
+	for k := range item {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// csvCell normalizes a single item value to its CSV cell representation.
+//
+// Scalars are stringified and all other values (slices, maps, etc.) are JSON encoded.
+// If escape is set, the formula-like text values are escaped with [escapeFormula].
+func csvCell(value any, escape bool) (string, error) {
+	var text string
+
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		text = v
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return cast.ToStringE(v)
+	case fmt.Stringer:
+		text = v.String()
+	default:
+		return jsonCell(value)
+	}
+
+	if escape {
+		return escapeFormula(text), nil
+	}
+
+	return text, nil
+}
+
+// jsonCell returns the JSON encoded cell representation of a non-scalar value.
+func jsonCell(value any) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	// a nil slice or map has no meaningful cell value
+	if string(encoded) == "null" {
+		return "", nil
+	}
+
+	return string(encoded), nil
+}
+
+// formulaChars lists the leading characters of the text values that
+// spreadsheet applications could interpret as formula (aka. CSV injection).
+//
+// The single quote is included so that the already quoted values
+// remain unchanged after [unescapeFormula].
+const formulaChars = "=+-@\t\r'"
+
+// escapeFormula prefixes the formula-like text values with a single quote.
+func escapeFormula(v string) string {
+	if v != "" && strings.ContainsRune(formulaChars, rune(v[0])) {
+		return "'" + v
+	}
+
+	return v
+}
+
+// unescapeFormula reverts [escapeFormula].
+func unescapeFormula(v string) string {
+	if len(v) > 1 && v[0] == '\'' && strings.ContainsRune(formulaChars, rune(v[1])) {
+		return v[1:]
+	}
+
+	return v
+}
@@ -0,0 +1,205 @@
+package dataset_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/tools/dataset"
+)
+
+func TestFormatValidate(t *testing.T) {
+	scenarios := []struct {
+		format      dataset.Format
+		expectError bool
+	}{
+		{"", true},
+		{"xlsx", true},
+		{dataset.FormatCSV, false},
+		{dataset.FormatNDJSON, false},
+		{dataset.FormatExcel, false},
+	}
+
+	for i, s := range scenarios {
+		err := s.format.Validate()
+
+		hasErr := err != nil
+		if hasErr != s.expectError {
+			t.Errorf("(%d) Expected hasErr %v, got %v (%v)", i, s.expectError, hasErr, err)
+		}
+	}
+}
+
+func TestNewWriterInvalidFormat(t *testing.T) {
+	if _, err := dataset.NewWriter(&bytes.Buffer{}, "invalid"); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	items := []map[string]any{
+		{"b": 1.5, "id": "a1", "c": []any{"x", "y"}, "z": nil},
+		{"b": true, "id": "a2", "c": map[string]any{"k": "v"}, "z": "line1\nline2"},
+	}
+
+	scenarios := []struct {
+		name     string
+		format   dataset.Format
+		columns  []string
+		items    []map[string]any
+		expected string
+	}{
+		{
+			"csv without items and hint",
+			dataset.FormatCSV,
+			nil,
+			nil,
+			"",
+		},
+		{
+			"csv without items",
+			dataset.FormatCSV,
+			[]string{"id", "b"},
+			nil,
+			"id,b\n",
+		},
+		{
+			"csv with items and hint",
+			dataset.FormatCSV,
+			[]string{"id", "missing", "z", "c"},
+			items,
+			"id,missing,z,c\n" +
+				`a1,,,"[""x"",""y""]"` + "\n" +
+				`a2,,"line1` + "\n" + `line2","{""k"":""v""}"` + "\n",
+		},
+		{
+			"csv with items and without hint",
+			dataset.FormatCSV,
+			nil,
+			items,
+			"b,c,id,z\n" +
+				`1.5,"[""x"",""y""]",a1,` + "\n" +
+				`true,"{""k"":""v""}",a2,"line1` + "\n" + `line2"` + "\n",
+		},
+		{
+			"csv with keys missing in the first item",
+			dataset.FormatCSV,
+			[]string{"id", "expand"},
+			[]map[string]any{
+				{"id": "a1"},
+				{"id": "a2", "expand": map[string]any{"rel": "r1"}},
+			},
+			"id,expand\na1,\n" + `a2,"{""rel"":""r1""}"` + "\n",
+		},
+		{
+			"csv with formula-like values",
+			dataset.FormatCSV,
+			[]string{"a", "b", "c", "d", "e", "f"},
+			[]map[string]any{
+				{"a": "=1+1", "b": "+1", "c": "-1", "d": "@SUM(A1)", "e": -1, "f": "x=1"},
+			},
+			"a,b,c,d,e,f\n=1+1,+1,-1,@SUM(A1),-1,x=1\n",
+		},
+		{
+			"excel with formula-like values",
+			dataset.FormatExcel,
+			[]string{"a", "b", "c", "d", "e", "f", "g"},
+			[]map[string]any{
+				{"a": "=1+1", "b": "+1", "c": "-1", "d": "@SUM(A1)", "e": -1, "f": "x=1", "g": "'=1"},
+			},
+			"\ufeffa,b,c,d,e,f,g\r\n'=1+1,'+1,'-1,'@SUM(A1),-1,x=1,''=1\r\n",
+		},
+		{
+			"excel",
+			dataset.FormatExcel,
+			[]string{"id", "b", "c", "z"},
+			items[:1],
+			"\ufeffid,b,c,z\r\n" + `a1,1.5,"[""x"",""y""]",` + "\r\n",
+		},
+		{
+			"ndjson",
+			dataset.FormatNDJSON,
+			[]string{"id"},
+			items,
+			`{"b":1.5,"c":["x","y"],"id":"a1","z":null}` + "\n" +
+				`{"b":true,"c":{"k":"v"},"id":"a2","z":"line1\nline2"}` + "\n",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			w, err := dataset.NewWriter(buf, s.format, s.columns...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, item := range s.items {
+				if err := w.Write(item); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != s.expected {
+				t.Fatalf("Expected\n%q\ngot\n%q", s.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	item := map[string]any{
+		"a": "=1+1",
+		"b": "+1 555 0100",
+		"c": "-5",
+		"d": "@handle",
+		"e": "'=quoted",
+		"f": "'",
+		"g": "plain",
+		"h": "",
+	}
+
+	for _, format := range dataset.Formats() {
+		t.Run(string(format), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			w, err := dataset.NewWriter(buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write(item); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := dataset.NewReader(buf, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := r.Read(); !errors.Is(err, io.EOF) {
+				t.Fatalf("Expected io.EOF, got %v", err)
+			}
+
+			expected, _ := json.Marshal(item)
+			raw, _ := json.Marshal(result)
+			if string(raw) != string(expected) {
+				t.Fatalf("Expected\n%s\ngot\n%s", expected, raw)
+			}
+		})
+	}
+}
@@ -21,6 +21,9 @@ import (
 	"github.com/disintegration/imaging"
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/hylarucoder/rocketbase/tools/list"
+	"github.com/hylarucoder/rocketbase/tools/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gocloud.dev/blob"
 	"gocloud.dev/blob/fileblob"
 	"gocloud.dev/blob/s3blob"
@@ -90,6 +93,13 @@ func (s *System) SetContext(ctx context.Context) {
 	s.ctx = ctx
 }
 
+// startSpan starts a new filesystem operation span
+// (if the filesystem context is part of a traced operation).
+func (s *System) startSpan(operation string, key string) trace.Span {
+	_, span := tracing.StartChild(s.ctx, "filesystem."+operation, attribute.String("filesystem.key", key))
+	return span
+}
+
 // Close releases any resources used for the related filesystem.
 func (s *System) Close() error {
 	return s.bucket.Close()
@@ -120,7 +130,10 @@ func (s *System) GetFile(fileKey string) (*blob.Reader, error) {
 // Copy copies the file stored at srcKey to dstKey.
 //
 // If dstKey file already exists, it is overwritten.
-func (s *System) Copy(srcKey, dstKey string) error {
+func (s *System) Copy(srcKey, dstKey string) (err error) {
+	span := s.startSpan("Copy", srcKey)
+	defer func() { tracing.End(span, err) }()
+
 	return s.bucket.Copy(s.ctx, dstKey, srcKey, nil)
 }
 
@@ -147,7 +160,10 @@ func (s *System) List(prefix string) ([]*blob.ListObject, error) {
 }
 
 // Upload writes content into the fileKey location.
-func (s *System) Upload(content []byte, fileKey string) error {
+func (s *System) Upload(content []byte, fileKey string) (err error) {
+	span := s.startSpan("Upload", fileKey)
+	defer func() { tracing.End(span, err) }()
+
 	opts := &blob.WriterOptions{
 		ContentType: mimetype.Detect(content).String(),
 	}
@@ -166,7 +182,10 @@ func (s *System) Upload(content []byte, fileKey string) error {
 }
 
 // UploadFile uploads the provided multipart file to the fileKey location.
-func (s *System) UploadFile(file *File, fileKey string) error {
+func (s *System) UploadFile(file *File, fileKey string) (err error) {
+	span := s.startSpan("UploadFile", fileKey)
+	defer func() { tracing.End(span, err) }()
+
 	f, err := file.Reader.Open()
 	if err != nil {
 		return err
@@ -208,7 +227,10 @@ func (s *System) UploadFile(file *File, fileKey string) error {
 }
 
 // UploadMultipart uploads the provided multipart file to the fileKey location.
-func (s *System) UploadMultipart(fh *multipart.FileHeader, fileKey string) error {
+func (s *System) UploadMultipart(fh *multipart.FileHeader, fileKey string) (err error) {
+	span := s.startSpan("UploadMultipart", fileKey)
+	defer func() { tracing.End(span, err) }()
+
 	f, err := fh.Open()
 	if err != nil {
 		return err
@@ -250,13 +272,19 @@ func (s *System) UploadMultipart(fh *multipart.FileHeader, fileKey string) error
 }
 
 // Delete deletes stored file at fileKey location.
-func (s *System) Delete(fileKey string) error {
+func (s *System) Delete(fileKey string) (err error) {
+	span := s.startSpan("Delete", fileKey)
+	defer func() { tracing.End(span, err) }()
+
 	return s.bucket.Delete(s.ctx, fileKey)
 }
 
 // DeletePrefix deletes everything starting with the specified prefix.
-func (s *System) DeletePrefix(prefix string) []error {
-	failed := []error{}
+func (s *System) DeletePrefix(prefix string) (failed []error) {
+	span := s.startSpan("DeletePrefix", prefix)
+	defer func() { tracing.End(span, errors.Join(failed...)) }()
+
+	failed = []error{}
 
 	if prefix == "" {
 		failed = append(failed, errors.New("Prefix mustn't be empty."))
@@ -339,7 +367,10 @@ const forceAttachmentParam = "download"
 //
 // If the `download` query parameter is used the file will be always served for
 // download no matter of its type (aka. with "Content-Disposition: attachment").
-func (s *System) Serve(res http.ResponseWriter, req *http.Request, fileKey string, name string) error {
+func (s *System) Serve(res http.ResponseWriter, req *http.Request, fileKey string, name string) (err error) {
+	span := s.startSpan("Serve", fileKey)
+	defer func() { tracing.End(span, err) }()
+
 	br, readErr := s.bucket.NewReader(s.ctx, fileKey, nil)
 	if readErr != nil {
 		return readErr
@@ -397,7 +428,10 @@ var ThumbSizeRegex = regexp.MustCompile(`^(\d+)x(\d+)(t|b|f)?$`)
 // - WxHt (eg. 300x100t) - resize and crop to WxH viewbox (from top)
 // - WxHb (eg. 300x100b) - resize and crop to WxH viewbox (from bottom)
 // - WxHf (eg. 300x100f) - fit inside a WxH viewbox (without cropping)
-func (s *System) CreateThumb(originalKey string, thumbKey, thumbSize string) error {
+func (s *System) CreateThumb(originalKey string, thumbKey, thumbSize string) (err error) {
+	span := s.startSpan("CreateThumb", thumbKey)
+	defer func() { tracing.End(span, err) }()
+
 	sizeParts := ThumbSizeRegex.FindStringSubmatch(thumbSize)
 	if len(sizeParts) != 4 {
 		return errors.New("Thumb size must be in WxH, WxHt, WxHb or WxHf format.")
@@ -4,12 +4,35 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
+	"github.com/hylarucoder/rocketbase/tools/list"
 	"github.com/hylarucoder/rocketbase/tools/security"
 )
 
 var StopPropagation = errors.New("Event hook propagation stopped")
 
+// TriggerObserver defines a function that is called at the start of
+// each [Hook.Trigger] call with the triggered event data.
+//
+// The returned done func (if non-nil) is called at the end
+// of the Trigger call with its result error.
+type TriggerObserver func(data any) (done func(err error))
+
+var observer atomic.Pointer[TriggerObserver]
+
+// SetTriggerObserver registers a global observer for the
+// [Hook.Trigger] calls of all hooks (eg. for tracing).
+//
+// Set it to nil to remove the current observer.
+func SetTriggerObserver(fn TriggerObserver) {
+	if fn == nil {
+		observer.Store(nil)
+	} else {
+		observer.Store(&fn)
+	}
+}
+
 // Handler defines a hook handler function.
 type Handler[T any] func(e T) error
 
@@ -17,6 +40,10 @@ type Handler[T any] func(e T) error
 type handlerPair[T any] struct {
 	id      string
 	handler Handler[T]
+
+	// tags are the optional event tags that the handler is limited to
+	// (see [TaggedHook]), empty means that it is triggered for all events.
+	tags []string
 }
 
 // Hook defines a concurrent safe structure for handling event hooks
@@ -30,6 +57,10 @@ type Hook[T any] struct {
 //
 // Returns an autogenerated hook id that could be used later to remove the hook with Hook.Remove(id).
 func (h *Hook[T]) PreAdd(fn Handler[T]) string {
+	return h.preAdd(fn, nil)
+}
+
+func (h *Hook[T]) preAdd(fn Handler[T], tags []string) string {
 	h.mux.Lock()
 	defer h.mux.Unlock()
 
@@ -38,7 +69,7 @@ func (h *Hook[T]) PreAdd(fn Handler[T]) string {
 	// minimize allocations by shifting the slice
 	h.handlers = append(h.handlers, nil)
 	copy(h.handlers[1:], h.handlers)
-	h.handlers[0] = &handlerPair[T]{id, fn}
+	h.handlers[0] = &handlerPair[T]{id: id, handler: fn, tags: tags}
 
 	return id
 }
@@ -47,12 +78,16 @@ func (h *Hook[T]) PreAdd(fn Handler[T]) string {
 //
 // Returns an autogenerated hook id that could be used later to remove the hook with Hook.Remove(id).
 func (h *Hook[T]) Add(fn Handler[T]) string {
+	return h.add(fn, nil)
+}
+
+func (h *Hook[T]) add(fn Handler[T], tags []string) string {
 	h.mux.Lock()
 	defer h.mux.Unlock()
 
 	id := generateHookId()
 
-	h.handlers = append(h.handlers, &handlerPair[T]{id, fn})
+	h.handlers = append(h.handlers, &handlerPair[T]{id: id, handler: fn, tags: tags})
 
 	return id
 }
@@ -78,6 +113,38 @@ func (h *Hook[T]) RemoveAll() {
 	h.handlers = nil
 }
 
+// Length returns the total number of registered hook handlers.
+func (h *Hook[T]) Length() int {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	return len(h.handlers)
+}
+
+// CanTriggerOn checks if at least one of the registered hook handlers
+// could be triggered for an event with the provided tags.
+//
+// The handlers registered via [TaggedHook] are matched against their
+// tags and all other handlers are matched always.
+func (h *Hook[T]) CanTriggerOn(tags []string) bool {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	for _, pair := range h.handlers {
+		if len(pair.tags) == 0 {
+			return true
+		}
+
+		for _, t := range tags {
+			if list.ExistInSlice(t, pair.tags) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // Trigger executes all registered hook handlers one by one
 // with the specified `data` as an argument.
 //
@@ -87,7 +154,15 @@ func (h *Hook[T]) RemoveAll() {
 // The execution stops when:
 // - hook.StopPropagation is returned in one of the handlers
 // - any non-nil error is returned in one of the handlers
-func (h *Hook[T]) Trigger(data T, oneOffHandlers ...Handler[T]) error {
+func (h *Hook[T]) Trigger(data T, oneOffHandlers ...Handler[T]) (err error) {
+	if observe := observer.Load(); observe != nil {
+		if done := (*observe)(data); done != nil {
+			defer func() {
+				done(err)
+			}()
+		}
+	}
+
 	h.mux.RLock()
 
 	handlers := make([]*handlerPair[T], 0, len(h.handlers)+len(oneOffHandlers))
@@ -89,6 +89,27 @@ func TestHookRemoveAll(t *testing.T) {
 	}
 }
 
+func TestHookLength(t *testing.T) {
+	h := Hook[int]{}
+
+	if total := h.Length(); total != 0 {
+		t.Fatalf("Expected 0 handlers, got %d", total)
+	}
+
+	id := h.Add(func(data int) error { return nil })
+	h.PreAdd(func(data int) error { return nil })
+
+	if total := h.Length(); total != 2 {
+		t.Fatalf("Expected 2 handlers, got %d", total)
+	}
+
+	h.Remove(id)
+
+	if total := h.Length(); total != 1 {
+		t.Fatalf("Expected 1 handler, got %d", total)
+	}
+}
+
 func TestHookTrigger(t *testing.T) {
 	err1 := errors.New("demo")
 	err2 := errors.New("demo")
@@ -177,3 +198,37 @@ func TestHookTriggerStopPropagation(t *testing.T) {
 		}
 	}
 }
+
+func TestHookTriggerObserver(t *testing.T) {
+	var observedData []any
+	var observedErrs []error
+
+	SetTriggerObserver(func(data any) func(err error) {
+		observedData = append(observedData, data)
+
+		return func(err error) {
+			observedErrs = append(observedErrs, err)
+		}
+	})
+	defer SetTriggerObserver(nil)
+
+	testErr := errors.New("test")
+
+	h := Hook[int]{}
+	h.Add(func(data int) error { return nil })
+
+	h.Trigger(1)
+	h.Trigger(2, func(data int) error { return testErr })
+
+	SetTriggerObserver(nil)
+
+	h.Trigger(3) // shouldn't be observed
+
+	if len(observedData) != 2 || observedData[0] != 1 || observedData[1] != 2 {
+		t.Fatalf("Expected observed data [1 2], got %v", observedData)
+	}
+
+	if len(observedErrs) != 2 || observedErrs[0] != nil || observedErrs[1] != testErr {
+		t.Fatalf("Expected observed errors [nil %v], got %v", testErr, observedErrs)
+	}
+}
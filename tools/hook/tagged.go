@@ -51,24 +51,24 @@ func (h *TaggedHook[T]) CanTriggerOn(tags []string) bool {
 //
 // The fn handler will be called only if the event data tags satisfy h.CanTriggerOn.
 func (h *TaggedHook[T]) PreAdd(fn Handler[T]) string {
-	return h.mainHook.PreAdd(func(e T) error {
+	return h.mainHook.preAdd(func(e T) error {
 		if h.CanTriggerOn(e.Tags()) {
 			return fn(e)
 		}
 
 		return nil
-	})
+	}, h.tags)
 }
 
 // Add registers a new handler to the hook by appending it to the existing queue.
 //
 // The fn handler will be called only if the event data tags satisfy h.CanTriggerOn.
 func (h *TaggedHook[T]) Add(fn Handler[T]) string {
-	return h.mainHook.Add(func(e T) error {
+	return h.mainHook.add(func(e T) error {
 		if h.CanTriggerOn(e.Tags()) {
 			return fn(e)
 		}
 
 		return nil
-	})
+	}, h.tags)
 }
@@ -67,3 +67,42 @@ func TestTaggedHook(t *testing.T) {
 		}
 	}
 }
+
+func TestHookCanTriggerOn(t *testing.T) {
+	base := &Hook[mockTagsData]{}
+
+	if base.CanTriggerOn([]string{"a"}) {
+		t.Fatal("Expected false for hook without handlers")
+	}
+
+	NewTaggedHook(base, "b1", "b2").Add(func(data mockTagsData) error { return nil })
+	NewTaggedHook(base, "c1").PreAdd(func(data mockTagsData) error { return nil })
+
+	scenarios := []struct {
+		tags     []string
+		expected bool
+	}{
+		{nil, false},
+		{[]string{"missing"}, false},
+		{[]string{"missing", "b2"}, true},
+		{[]string{"c1"}, true},
+	}
+
+	for i, s := range scenarios {
+		if result := base.CanTriggerOn(s.tags); result != s.expected {
+			t.Fatalf("[%d] Expected %v, got %v", i, s.expected, result)
+		}
+	}
+
+	// untagged handlers match all events
+	id := NewTaggedHook(base).Add(func(data mockTagsData) error { return nil })
+	if !base.CanTriggerOn([]string{"missing"}) {
+		t.Fatal("Expected true after registering untagged handler")
+	}
+
+	base.Remove(id)
+	base.Add(func(data mockTagsData) error { return nil })
+	if !base.CanTriggerOn(nil) {
+		t.Fatal("Expected true after registering plain handler")
+	}
+}
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var _ io.WriteCloser = (*RotatingFile)(nil)
+
+// DefaultRotatingFileMaxSize is the default max size of a [RotatingFile] (100MB).
+const DefaultRotatingFileMaxSize int64 = 100 << 20
+
+// RotatingFile is an append only [io.WriteCloser] file that is
+// rotated once its size exceeds the configured max size.
+//
+// The rotated files are named after the original one with a numeric
+// suffix (eg. "app.log.1" is the most recent one, "app.log.2" the one before it, etc.).
+type RotatingFile struct {
+	mux        sync.Mutex
+	file       *os.File
+	path       string
+	size       int64
+	maxSize    int64
+	maxBackups int
+}
+
+// OpenRotatingFile opens (or creates) the file at path for appending.
+//
+// If maxSize is <= 0, fallbacks to [DefaultRotatingFileMaxSize].
+// maxBackups specifies how many rotated files to keep (0 means that the rotated data is discarded).
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultRotatingFileMaxSize
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Write implements [io.Writer] and appends p to the file,
+// rotating it first if the write would exceed the file max size.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// Close implements [io.Closer].
+func (f *RotatingFile) Close() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	if f.maxBackups <= 0 {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return f.open()
+	}
+
+	// shift the existing backups (the oldest one is overwritten)
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(f.backupPath(i), f.backupPath(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if err := os.Rename(f.path, f.backupPath(1)); err != nil {
+		return err
+	}
+
+	return f.open()
+}
+
+func (f *RotatingFile) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
@@ -0,0 +1,179 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Sink defines a log shipping destination that receives
+// the logs batches written by the [BatchHandler].
+type Sink interface {
+	// Write writes the provided logs batch to the sink.
+	Write(ctx context.Context, logs []*Log) error
+
+	// Close flushes and releases the sink resources.
+	Close() error
+}
+
+// WriteSinks writes the provided logs batch to each of the sinks.
+//
+// A sink failure doesn't prevent writing to the rest of the sinks
+// and all errors are returned joined.
+func WriteSinks(ctx context.Context, sinks []Sink, logs []*Log) error {
+	var errs []error
+
+	for _, s := range sinks {
+		if err := s.Write(ctx, logs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// CloseSinks closes each of the provided sinks and returns all errors joined.
+func CloseSinks(sinks []Sink) error {
+	var errs []error
+
+	for _, s := range sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// -------------------------------------------------------------------
+
+// NewFilterSink wraps sink and forwards to it only the logs with
+// level equal or higher than minLevel.
+//
+// sampleRate specifies the fraction (0-1] of the logs to forward
+// (0 and 1 means that all logs matching the level filter are forwarded).
+func NewFilterSink(sink Sink, minLevel slog.Level, sampleRate float64) Sink {
+	return &filterSink{
+		sink:       sink,
+		minLevel:   minLevel,
+		sampleRate: sampleRate,
+		random:     rand.Float64,
+	}
+}
+
+type filterSink struct {
+	sink       Sink
+	minLevel   slog.Level
+	sampleRate float64
+	random     func() float64
+}
+
+// Write implements [Sink.Write].
+func (s *filterSink) Write(ctx context.Context, logs []*Log) error {
+	filtered := make([]*Log, 0, len(logs))
+
+	for _, l := range logs {
+		if l.Level < s.minLevel {
+			continue
+		}
+
+		if s.sampleRate > 0 && s.sampleRate < 1 && s.random() >= s.sampleRate {
+			continue
+		}
+
+		filtered = append(filtered, l)
+	}
+
+	if len(filtered) == 0 {
+		return nil
+	}
+
+	return s.sink.Write(ctx, filtered)
+}
+
+// Close implements [Sink.Close].
+func (s *filterSink) Close() error {
+	return s.sink.Close()
+}
+
+// -------------------------------------------------------------------
+
+// jsonLine is the JSON lines representation of a single Log.
+type jsonLine struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Data    map[string]any `json:"data"`
+}
+
+// MarshalJSONLine serializes the provided log as single JSON line (without the trailing new line).
+func MarshalJSONLine(l *Log) ([]byte, error) {
+	return json.Marshal(jsonLine{
+		Time:    l.Time.UTC(),
+		Level:   l.Level.String(),
+		Message: l.Message,
+		Data:    l.Data,
+	})
+}
+
+// NewJSONSink creates a new sink that writes the logs
+// as JSON lines to the provided writer (eg. os.Stdout).
+//
+// Note that the writer is not closed on sink close.
+func NewJSONSink(w io.Writer) Sink {
+	return &jsonSink{w: w}
+}
+
+// NewFileSink creates a new sink that writes the logs as JSON lines
+// to the file at path, rotating it once it exceeds maxSize bytes
+// (see [OpenRotatingFile]).
+func NewFileSink(path string, maxSize int64, maxBackups int) (Sink, error) {
+	f, err := OpenRotatingFile(path, maxSize, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jsonSink{w: f, closer: f}, nil
+}
+
+type jsonSink struct {
+	mux    sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// Write implements [Sink.Write].
+func (s *jsonSink) Write(ctx context.Context, logs []*Log) error {
+	buf := make([]byte, 0, 256*len(logs))
+
+	for _, l := range logs {
+		line, err := MarshalJSONLine(l)
+		if err != nil {
+			return err
+		}
+
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	_, err := s.w.Write(buf)
+
+	return err
+}
+
+// Close implements [Sink.Close].
+func (s *jsonSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	return s.closer.Close()
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// asyncSinkCloseTimeout is the max duration to wait for the queued
+// batches to be written on close before the pending writes are canceled.
+const asyncSinkCloseTimeout = 5 * time.Second
+
+// ErrSinkQueueFull is the error reported when a logs batch is dropped
+// because the async sink queue is full (eg. due to unreachable destination).
+var ErrSinkQueueFull = errors.New("logs sink queue is full, the batch was dropped")
+
+// NewAsyncSink wraps sink and writes the logs batches to it in a separate
+// goroutine so that a slow or unreachable destination doesn't block the caller.
+//
+// Up to queueSize batches are buffered and the new batches are dropped
+// while the queue is full. The write failures and the dropped batches
+// are reported to the optional onError function.
+//
+// On close the already queued batches are written for up to
+// [asyncSinkCloseTimeout] before the wrapped sink is closed.
+func NewAsyncSink(sink Sink, queueSize int, onError func(err error)) Sink {
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &asyncSink{
+		sink:    sink,
+		onError: onError,
+		queue:   make(chan []*Log, queueSize),
+		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	go s.run()
+
+	return s
+}
+
+type asyncSink struct {
+	mux     sync.RWMutex
+	sink    Sink
+	onError func(err error)
+	queue   chan []*Log
+	done    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	closed  bool
+
+	closeErr error
+}
+
+// Write implements [Sink.Write].
+//
+// It only queues the logs batch and returns immediately
+// (the provided ctx is not used for the actual write).
+func (s *asyncSink) Write(ctx context.Context, logs []*Log) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if s.closed {
+		return nil
+	}
+
+	select {
+	case s.queue <- logs:
+	default:
+		s.reportError(ErrSinkQueueFull)
+	}
+
+	return nil
+}
+
+// Close implements [Sink.Close].
+func (s *asyncSink) Close() error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mux.Unlock()
+
+	select {
+	case <-s.done:
+	case <-time.After(asyncSinkCloseTimeout):
+		// abort the pending writes
+		s.cancel()
+		<-s.done
+	}
+
+	s.cancel()
+
+	return s.closeErr
+}
+
+func (s *asyncSink) run() {
+	defer close(s.done)
+
+	for logs := range s.queue {
+		if s.ctx.Err() != nil {
+			continue // canceled, drain the remaining batches
+		}
+
+		if err := s.sink.Write(s.ctx, logs); err != nil {
+			s.reportError(err)
+		}
+	}
+
+	s.closeErr = s.sink.Close()
+}
+
+func (s *asyncSink) reportError(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// httpSinkTimeout is the max duration of a single push request.
+const httpSinkTimeout = 30 * time.Second
+
+// NewHTTPSink creates a new sink that pushes the logs batches to
+// a Grafana Loki compatible push endpoint (eg. "http://localhost:3100/loki/api/v1/push").
+//
+// The logs are grouped in streams by their level and the provided
+// labels are attached to all of them.
+// The headers are sent with each push request (eg. for authorization).
+func NewHTTPSink(url string, headers map[string]string, labels map[string]string) Sink {
+	return &httpSink{
+		url:     url,
+		headers: headers,
+		labels:  labels,
+		client:  &http.Client{Timeout: httpSinkTimeout},
+	}
+}
+
+type httpSink struct {
+	url     string
+	headers map[string]string
+	labels  map[string]string
+	client  *http.Client
+}
+
+type lokiPush struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// Write implements [Sink.Write].
+func (s *httpSink) Write(ctx context.Context, logs []*Log) error {
+	push := lokiPush{}
+	streams := map[string]*lokiStream{}
+
+	for _, l := range logs {
+		line, err := MarshalJSONLine(l)
+		if err != nil {
+			return err
+		}
+
+		level := l.Level.String()
+
+		stream, ok := streams[level]
+		if !ok {
+			labels := maps.Clone(s.labels)
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels["level"] = level
+
+			stream = &lokiStream{Stream: labels}
+			streams[level] = stream
+			push.Streams = append(push.Streams, stream)
+		}
+
+		stream.Values = append(stream.Values, [2]string{
+			strconv.FormatInt(l.Time.UnixNano(), 10),
+			string(line),
+		})
+	}
+
+	body, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		// read a small portion of the response body for debugging
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+
+		return fmt.Errorf("logs push failed with status %d: %s", res.StatusCode, resBody)
+	}
+
+	return nil
+}
+
+// Close implements [Sink.Close].
+func (s *httpSink) Close() error {
+	s.client.CloseIdleConnections()
+
+	return nil
+}
@@ -0,0 +1,66 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"log/syslog"
+	"sync"
+)
+
+// NewSyslogSink creates a new sink that writes the logs as JSON lines
+// to the syslog daemon at the specified network address
+// (if network is empty, it connects to the local syslog server).
+//
+// The log level is mapped to the related syslog severity.
+func NewSyslogSink(network string, address string, tag string) (Sink, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &syslogSink{w: w}, nil
+}
+
+type syslogSink struct {
+	mux sync.Mutex
+	w   *syslog.Writer
+}
+
+// Write implements [Sink.Write].
+func (s *syslogSink) Write(ctx context.Context, logs []*Log) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for _, l := range logs {
+		line, err := MarshalJSONLine(l)
+		if err != nil {
+			return err
+		}
+
+		msg := string(line)
+
+		switch {
+		case l.Level >= slog.LevelError:
+			err = s.w.Err(msg)
+		case l.Level >= slog.LevelWarn:
+			err = s.w.Warning(msg)
+		case l.Level >= slog.LevelInfo:
+			err = s.w.Info(msg)
+		default:
+			err = s.w.Debug(msg)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close implements [Sink.Close].
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,10 @@
+//go:build windows || plan9
+
+package logger
+
+import "errors"
+
+// NewSyslogSink is not supported on this platform and always returns an error.
+func NewSyslogSink(network string, address string, tag string) (Sink, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,320 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/tools/types"
+)
+
+type testSink struct {
+	logs   []*Log
+	err    error
+	closed bool
+}
+
+func (s *testSink) Write(ctx context.Context, logs []*Log) error {
+	s.logs = append(s.logs, logs...)
+	return s.err
+}
+
+func (s *testSink) Close() error {
+	s.closed = true
+	return s.err
+}
+
+func testLogs() []*Log {
+	return []*Log{
+		{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Level: slog.LevelDebug, Message: "a", Data: types.JsonMap{"n": 1}},
+		{Time: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC), Level: slog.LevelInfo, Message: "b", Data: types.JsonMap{}},
+		{Time: time.Date(2024, 1, 2, 3, 4, 7, 0, time.UTC), Level: slog.LevelError, Message: "c", Data: types.JsonMap{"x": "y"}},
+	}
+}
+
+func TestWriteAndCloseSinks(t *testing.T) {
+	s1 := &testSink{}
+	s2 := &testSink{err: errors.New("test")}
+	s3 := &testSink{}
+
+	sinks := []Sink{s1, s2, s3}
+
+	if err := WriteSinks(context.Background(), sinks, testLogs()); err == nil || err.Error() != "test" {
+		t.Fatalf("Expected the s2 error, got %v", err)
+	}
+
+	for i, s := range []*testSink{s1, s2, s3} {
+		if len(s.logs) != 3 {
+			t.Fatalf("[%d] Expected 3 written logs, got %d", i, len(s.logs))
+		}
+	}
+
+	if err := CloseSinks(sinks); err == nil {
+		t.Fatal("Expected close error, got nil")
+	}
+
+	for i, s := range []*testSink{s1, s2, s3} {
+		if !s.closed {
+			t.Fatalf("[%d] Expected the sink to be closed", i)
+		}
+	}
+}
+
+func TestFilterSink(t *testing.T) {
+	scenarios := []struct {
+		name       string
+		minLevel   slog.Level
+		sampleRate float64
+		random     float64
+		expected   []string
+	}{
+		{"no filters", -100, 0, 0.99, []string{"a", "b", "c"}},
+		{"min level", slog.LevelInfo, 1, 0.99, []string{"b", "c"}},
+		{"sampled in", slog.LevelInfo, 0.5, 0.49, []string{"b", "c"}},
+		{"sampled out", slog.LevelInfo, 0.5, 0.5, nil},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			target := &testSink{}
+
+			sink := NewFilterSink(target, s.minLevel, s.sampleRate).(*filterSink)
+			sink.random = func() float64 { return s.random }
+
+			if err := sink.Write(context.Background(), testLogs()); err != nil {
+				t.Fatal(err)
+			}
+
+			messages := []string{}
+			for _, l := range target.logs {
+				messages = append(messages, l.Message)
+			}
+
+			if strings.Join(messages, ",") != strings.Join(s.expected, ",") {
+				t.Fatalf("Expected messages %v, got %v", s.expected, messages)
+			}
+
+			sink.Close()
+			if !target.closed {
+				t.Fatal("Expected the wrapped sink to be closed")
+			}
+		})
+	}
+}
+
+type blockingSink struct {
+	testSink
+	release chan struct{}
+}
+
+func (s *blockingSink) Write(ctx context.Context, logs []*Log) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return s.testSink.Write(ctx, logs)
+}
+
+func TestAsyncSink(t *testing.T) {
+	target := &testSink{}
+
+	sink := NewAsyncSink(target, 2, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := sink.Write(context.Background(), testLogs()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// wait for the queued batches to be written
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(target.logs) != 6 {
+		t.Fatalf("Expected 6 written logs, got %d", len(target.logs))
+	}
+
+	if !target.closed {
+		t.Fatal("Expected the wrapped sink to be closed")
+	}
+
+	// writes after close are ignored
+	if err := sink.Write(context.Background(), testLogs()); err != nil {
+		t.Fatal(err)
+	}
+	if len(target.logs) != 6 {
+		t.Fatalf("Expected the logs after close to be ignored, got %d", len(target.logs))
+	}
+}
+
+func TestAsyncSinkQueueFull(t *testing.T) {
+	target := &blockingSink{release: make(chan struct{})}
+
+	var errs []error
+	sink := NewAsyncSink(target, 1, func(err error) {
+		errs = append(errs, err)
+	})
+
+	// the first batch is picked by the worker (and blocked),
+	// the second is queued and the rest should be dropped
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := sink.Write(context.Background(), testLogs()); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Expected the writes to not block, took %v", d)
+	}
+
+	close(target.release)
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(target.logs) != 6 {
+		t.Fatalf("Expected 6 written logs, got %d", len(target.logs))
+	}
+
+	if len(errs) != 3 {
+		t.Fatalf("Expected 3 dropped batches errors, got %v", errs)
+	}
+	for _, err := range errs {
+		if !errors.Is(err, ErrSinkQueueFull) {
+			t.Fatalf("Expected ErrSinkQueueFull, got %v", err)
+		}
+	}
+}
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+
+	sink := NewJSONSink(&buf)
+
+	if err := sink.Write(context.Background(), testLogs()[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"time":"2024-01-02T03:04:06Z","level":"INFO","message":"b","data":{}}` + "\n" +
+		`{"time":"2024-01-02T03:04:07Z","level":"ERROR","message":"c","data":{"x":"y"}}` + "\n"
+
+	if buf.String() != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+
+	sink, err := NewFileSink(path, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each line is > 50 bytes so every write after the first should trigger a rotation
+	for i := 0; i < 4; i++ {
+		if err := sink.Write(context.Background(), testLogs()[1:2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"app.log", "app.log.1", "app.log.2"} {
+		raw, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", name, err)
+		}
+
+		if !strings.Contains(string(raw), `"message":"b"`) {
+			t.Fatalf("Expected %s to contain the log line, got %s", name, raw)
+		}
+	}
+
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Fatal("Expected max 2 backups")
+	}
+
+	// writing after close should fail
+	if err := sink.Write(context.Background(), testLogs()); err == nil {
+		t.Fatal("Expected write error after close")
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received lokiPush
+	var authHeader string
+
+	status := http.StatusNoContent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+
+		body, _ := io.ReadAll(r.Body)
+		received = lokiPush{}
+		json.Unmarshal(body, &received)
+
+		w.WriteHeader(status)
+		w.Write([]byte("error details"))
+	}))
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL, map[string]string{"Authorization": "test"}, map[string]string{"app": "demo"})
+	defer sink.Close()
+
+	logs := testLogs()
+	logs = append(logs, &Log{Time: logs[0].Time, Level: slog.LevelInfo, Message: "d", Data: types.JsonMap{}})
+
+	if err := sink.Write(context.Background(), logs); err != nil {
+		t.Fatal(err)
+	}
+
+	if authHeader != "test" {
+		t.Fatalf("Expected Authorization header test, got %q", authHeader)
+	}
+
+	if len(received.Streams) != 3 {
+		t.Fatalf("Expected 3 streams (one per level), got %d", len(received.Streams))
+	}
+
+	info := received.Streams[1]
+	if info.Stream["level"] != "INFO" || info.Stream["app"] != "demo" {
+		t.Fatalf("Expected INFO stream with app label, got %v", info.Stream)
+	}
+	if len(info.Values) != 2 {
+		t.Fatalf("Expected 2 INFO values, got %v", info.Values)
+	}
+	if info.Values[0][0] != "1704164646000000000" || !strings.Contains(info.Values[0][1], `"message":"b"`) {
+		t.Fatalf("Unexpected INFO value %v", info.Values[0])
+	}
+
+	// failure response
+	status = http.StatusBadRequest
+	err := sink.Write(context.Background(), logs)
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "error details") {
+		t.Fatalf("Expected status 400 error, got %v", err)
+	}
+}
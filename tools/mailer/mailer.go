@@ -1,8 +1,13 @@
 package mailer
 
 import (
+	"context"
 	"io"
 	"net/mail"
+
+	"github.com/hylarucoder/rocketbase/tools/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Message defines a generic email message struct.
@@ -16,6 +21,10 @@ type Message struct {
 	Text        string               `json:"text"`
 	Headers     map[string]string    `json:"headers"`
 	Attachments map[string]io.Reader `json:"attachments"`
+
+	// Context is an optional context of the send operation
+	// (eg. the request context) that the send span is attached to.
+	Context context.Context `json:"-"`
 }
 
 // Mailer defines a base mail client interface.
@@ -41,3 +50,16 @@ func addressesToStrings(addresses []mail.Address, withName bool) []string {
 
 	return result
 }
+
+// startSpan starts a new span for sending the provided message with the specified client
+// (only if the message context is part of a traced operation).
+func startSpan(client string, m *Message) trace.Span {
+	_, span := tracing.StartChild(
+		m.Context,
+		"mailer.Send",
+		attribute.String("mailer.client", client),
+		attribute.Int("mailer.recipients", len(m.To)+len(m.Cc)+len(m.Bcc)),
+	)
+
+	return span
+}
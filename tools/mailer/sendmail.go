@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os/exec"
 	"strings"
+
+	"github.com/hylarucoder/rocketbase/tools/tracing"
 )
 
 var _ Mailer = (*Sendmail)(nil)
@@ -19,7 +21,10 @@ type Sendmail struct {
 }
 
 // Send implements `mailer.Mailer` interface.
-func (c *Sendmail) Send(m *Message) error {
+func (c *Sendmail) Send(m *Message) (err error) {
+	span := startSpan("sendmail", m)
+	defer func() { tracing.End(span, err) }()
+
 	toAddresses := addressesToStrings(m.To, false)
 
 	headers := make(http.Header)
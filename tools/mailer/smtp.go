@@ -8,6 +8,7 @@ import (
 
 	"github.com/domodwyer/mailyak/v3"
 	"github.com/hylarucoder/rocketbase/tools/security"
+	"github.com/hylarucoder/rocketbase/tools/tracing"
 )
 
 var _ Mailer = (*SmtpClient)(nil)
@@ -57,7 +58,10 @@ type SmtpClient struct {
 }
 
 // Send implements `mailer.Mailer` interface.
-func (c *SmtpClient) Send(m *Message) error {
+func (c *SmtpClient) Send(m *Message) (err error) {
+	span := startSpan("smtp", m)
+	defer func() { tracing.End(span, err) }()
+
 	var smtpAuth smtp.Auth
 	if c.Username != "" || c.Password != "" {
 		switch c.AuthMethod {
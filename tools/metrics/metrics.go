@@ -0,0 +1,439 @@
+// Package metrics implements a minimal concurrent safe metrics registry
+// with counters, gauges and histograms that could be exported
+// in the Prometheus text exposition format.
+//
+// Example:
+//
+//	r := metrics.NewRegistry()
+//
+//	requests := r.Counter("http_requests_total", "Total HTTP requests.", "method", "status")
+//	requests.Inc("GET", "200")
+//
+//	r.GaugeFunc("goroutines", "Number of goroutines.", func() []metrics.Sample {
+//		return []metrics.Sample{{Value: float64(runtime.NumGoroutine())}}
+//	})
+//
+//	r.WriteText(os.Stdout)
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the default histogram buckets (in seconds)
+// suitable for measuring the duration of requests and queries.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metric types.
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+)
+
+// Labels defines a set of metric label name-value pairs.
+type Labels map[string]string
+
+// Sample defines a single dynamically collected metric value.
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// collector defines a single registered metric family.
+type collector interface {
+	meta() (name, help, typ string)
+	write(w *bufio.Writer)
+}
+
+// Registry is a collection of named metrics.
+type Registry struct {
+	mux        sync.RWMutex
+	collectors map[string]collector
+}
+
+// NewRegistry creates a new empty Registry instance.
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: map[string]collector{},
+	}
+}
+
+// Counter returns the counter with the specified name
+// (registering a new one if missing).
+//
+// It panics if a metric with the same name but different type is already registered.
+func (r *Registry) Counter(name, help string, labelNames ...string) *Counter {
+	return register(r, name, TypeCounter, func() *Counter {
+		return &Counter{name: name, help: help, labelNames: labelNames, values: map[string]*counterValue{}}
+	})
+}
+
+// Histogram returns the histogram with the specified name
+// (registering a new one if missing).
+//
+// If buckets is empty, [DefaultBuckets] are used.
+//
+// It panics if a metric with the same name but different type is already registered.
+func (r *Registry) Histogram(name, help string, buckets []float64, labelNames ...string) *Histogram {
+	return register(r, name, TypeHistogram, func() *Histogram {
+		if len(buckets) == 0 {
+			buckets = DefaultBuckets
+		}
+
+		sorted := make([]float64, len(buckets))
+		copy(sorted, buckets)
+		sort.Float64s(sorted)
+
+		return &Histogram{name: name, help: help, labelNames: labelNames, buckets: sorted, values: map[string]*histogramValue{}}
+	})
+}
+
+// GaugeFunc registers a gauge which samples are collected on export by calling fn.
+//
+// It replaces any previously registered gauge func with the same name.
+func (r *Registry) GaugeFunc(name, help string, fn func() []Sample) {
+	r.setFunc(&funcCollector{name: name, help: help, typ: TypeGauge, fn: fn})
+}
+
+// CounterFunc registers a counter which samples are collected on export by calling fn.
+//
+// It replaces any previously registered counter func with the same name.
+func (r *Registry) CounterFunc(name, help string, fn func() []Sample) {
+	r.setFunc(&funcCollector{name: name, help: help, typ: TypeCounter, fn: fn})
+}
+
+func (r *Registry) setFunc(c *funcCollector) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if existing, ok := r.collectors[c.name]; ok {
+		if _, isFunc := existing.(*funcCollector); !isFunc {
+			panic("metrics: " + c.name + " is already registered with a different type")
+		}
+	}
+
+	r.collectors[c.name] = c
+}
+
+// WriteText writes all registered metrics to w in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mux.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mux.RUnlock()
+
+	bw := bufio.NewWriter(w)
+
+	for _, c := range collectors {
+		name, help, typ := c.meta()
+
+		if help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", name, escapeHelp(help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, typ)
+
+		c.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+func register[T collector](r *Registry, name string, typ string, factory func() T) T {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if existing, ok := r.collectors[name]; ok {
+		if c, ok := existing.(T); ok {
+			return c
+		}
+
+		panic("metrics: " + name + " is already registered with a different type")
+	}
+
+	c := factory()
+	r.collectors[name] = c
+
+	return c
+}
+
+// -------------------------------------------------------------------
+
+// Counter is a monotonically increasing metric partitioned by its label values.
+type Counter struct {
+	mux        sync.RWMutex
+	name       string
+	help       string
+	labelNames []string
+	values     map[string]*counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+// Inc increments by 1 the counter with the specified label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter with the specified label values by v.
+//
+// Negative v values are ignored.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+
+	key := labelsKey(labelValues)
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	item, ok := c.values[key]
+	if !ok {
+		item = &counterValue{labelValues: normalizeLabelValues(c.labelNames, labelValues)}
+		c.values[key] = item
+	}
+
+	item.value += v
+}
+
+// Value returns the current counter value for the specified label values.
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	if item, ok := c.values[labelsKey(labelValues)]; ok {
+		return item.value
+	}
+
+	return 0
+}
+
+func (c *Counter) meta() (string, string, string) {
+	return c.name, c.help, TypeCounter
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	for _, key := range sortedKeys(c.values) {
+		item := c.values[key]
+		writeSample(w, c.name, formatLabels(c.labelNames, item.labelValues, ""), item.value)
+	}
+}
+
+// -------------------------------------------------------------------
+
+// Histogram samples observations into configurable buckets
+// partitioned by their label values.
+type Histogram struct {
+	mux        sync.RWMutex
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64
+	values     map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64 // non-cumulative counts per bucket
+	count       uint64
+	sum         float64
+}
+
+// Observe adds a single observation to the histogram with the specified label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := labelsKey(labelValues)
+
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	item, ok := h.values[key]
+	if !ok {
+		item = &histogramValue{
+			labelValues: normalizeLabelValues(h.labelNames, labelValues),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = item
+	}
+
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		item.counts[i]++
+	}
+	item.count++
+	item.sum += v
+}
+
+// Count returns the total number of observations for the specified label values.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	if item, ok := h.values[labelsKey(labelValues)]; ok {
+		return item.count
+	}
+
+	return 0
+}
+
+func (h *Histogram) meta() (string, string, string) {
+	return h.name, h.help, TypeHistogram
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	for _, key := range sortedKeys(h.values) {
+		item := h.values[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += item.counts[i]
+			writeSample(w, h.name+"_bucket", formatLabels(h.labelNames, item.labelValues, formatFloat(bound)), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", formatLabels(h.labelNames, item.labelValues, "+Inf"), float64(item.count))
+
+		labels := formatLabels(h.labelNames, item.labelValues, "")
+		writeSample(w, h.name+"_sum", labels, item.sum)
+		writeSample(w, h.name+"_count", labels, float64(item.count))
+	}
+}
+
+// -------------------------------------------------------------------
+
+// funcCollector is a metric which samples are collected on export.
+type funcCollector struct {
+	name string
+	help string
+	typ  string
+	fn   func() []Sample
+}
+
+func (c *funcCollector) meta() (string, string, string) {
+	return c.name, c.help, c.typ
+}
+
+func (c *funcCollector) write(w *bufio.Writer) {
+	for _, s := range c.fn() {
+		names := make([]string, 0, len(s.Labels))
+		for name := range s.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		values := make([]string, len(names))
+		for i, name := range names {
+			values[i] = s.Labels[name]
+		}
+
+		writeSample(w, c.name, formatLabels(names, values, ""), s.Value)
+	}
+}
+
+// -------------------------------------------------------------------
+
+func writeSample(w *bufio.Writer, name string, labels string, value float64) {
+	w.WriteString(name)
+	w.WriteString(labels)
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// formatLabels returns the `{name="value",...}` representation of the
+// provided labels (optionally with an extra histogram "le" label).
+func formatLabels(names []string, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabelValue(values[i]))
+		sb.WriteByte('"')
+	}
+	if le != "" {
+		if len(names) > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`le="`)
+		sb.WriteString(le)
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
+
+// normalizeLabelValues returns a copy of values with the same length as names
+// (the missing values are filled with empty strings and the extra ones are ignored).
+func normalizeLabelValues(names []string, values []string) []string {
+	result := make([]string, len(names))
+	copy(result, values)
+	return result
+}
+
+func labelsKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(v string) string {
+	return helpReplacer.Replace(v)
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRegistryCounter(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	c := r.Counter("test_total", "Test counter.", "method", "status")
+	c.Inc("GET", "200")
+	c.Inc("GET", "200")
+	c.Add(3, "POST", "400")
+	c.Add(-1, "POST", "400") // should be ignored
+
+	if v := c.Value("GET", "200"); v != 2 {
+		t.Fatalf("Expected GET 200 value 2, got %v", v)
+	}
+
+	if v := c.Value("POST", "400"); v != 3 {
+		t.Fatalf("Expected POST 400 value 3, got %v", v)
+	}
+
+	if v := c.Value("missing"); v != 0 {
+		t.Fatalf("Expected missing value 0, got %v", v)
+	}
+
+	// the same counter instance should be returned for the same name
+	if c2 := r.Counter("test_total", "Test counter.", "method", "status"); c2 != c {
+		t.Fatal("Expected the same counter instance to be returned")
+	}
+}
+
+func TestRegistryTypeMismatch(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	r.Counter("test", "")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected panic for the mismatched metric type")
+		}
+	}()
+
+	r.Histogram("test", "", nil)
+}
+
+func TestRegistryHistogram(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	h := r.Histogram("test_seconds", "Test histogram.", []float64{1, 0.1, 0.5}, "route")
+	h.Observe(0.05, "/a")
+	h.Observe(0.5, "/a")
+	h.Observe(0.7, "/a")
+	h.Observe(3, "/a")
+
+	if c := h.Count("/a"); c != 4 {
+		t.Fatalf("Expected count 4, got %d", c)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		`# HELP test_seconds Test histogram.`,
+		`# TYPE test_seconds histogram`,
+		`test_seconds_bucket{route="/a",le="0.1"} 1`,
+		`test_seconds_bucket{route="/a",le="0.5"} 2`,
+		`test_seconds_bucket{route="/a",le="1"} 3`,
+		`test_seconds_bucket{route="/a",le="+Inf"} 4`,
+		`test_seconds_sum{route="/a"} 4.25`,
+		`test_seconds_count{route="/a"} 4`,
+		``,
+	}, "\n")
+
+	if buf.String() != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestRegistryWriteText(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	r.GaugeFunc("b_gauge", "Test\ngauge.", func() []Sample {
+		return []Sample{
+			{Labels: Labels{"z": "1", "a": `quoted "value"`}, Value: 1.5},
+			{Value: 2},
+		}
+	})
+
+	r.CounterFunc("c_counter_func", "", func() []Sample {
+		return []Sample{{Value: 10}}
+	})
+
+	c := r.Counter("a_total", "Test counter.", "label")
+	c.Inc("x")
+	c.Inc() // missing label value
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		`# HELP a_total Test counter.`,
+		`# TYPE a_total counter`,
+		`a_total{label=""} 1`,
+		`a_total{label="x"} 1`,
+		`# HELP b_gauge Test\ngauge.`,
+		`# TYPE b_gauge gauge`,
+		`b_gauge{a="quoted \"value\"",z="1"} 1.5`,
+		`b_gauge 2`,
+		`# TYPE c_counter_func counter`,
+		`c_counter_func 10`,
+		``,
+	}, "\n")
+
+	if buf.String() != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Counter("test_total", "", "label").Inc("a")
+			r.Histogram("test_seconds", "", nil).Observe(0.1)
+			r.WriteText(&bytes.Buffer{})
+		}()
+	}
+
+	wg.Wait()
+
+	if v := r.Counter("test_total", "", "label").Value("a"); v != 50 {
+		t.Fatalf("Expected value 50, got %v", v)
+	}
+
+	if c := r.Histogram("test_seconds", "", nil).Count(); c != 50 {
+		t.Fatalf("Expected count 50, got %d", c)
+	}
+}
@@ -1,6 +1,9 @@
 package migrate
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -38,8 +41,9 @@ func NewRunner(db *dbx.DB, migrationsList MigrationsList) (*Runner, error) {
 // Run interactively executes the current runner with the provided args.
 //
 // The following commands are supported:
-// - up       - applies all migrations
-// - down [n] - reverts the last n applied migrations
+// - up           - applies all migrations
+// - up --dry-run - prints the SQL statements of the unapplied migrations without applying them
+// - down [n]     - reverts the last n applied migrations
 func (r *Runner) Run(args ...string) error {
 	cmd := "up"
 	if len(args) > 0 {
@@ -48,6 +52,27 @@ func (r *Runner) Run(args ...string) error {
 
 	switch cmd {
 	case "up":
+		if len(args) > 1 && args[1] == "--dry-run" {
+			pending, err := r.DryRun()
+			if err != nil {
+				return err
+			}
+
+			if len(pending) == 0 {
+				color.Green("No new migrations to apply.")
+			} else {
+				for _, m := range pending {
+					fmt.Printf("-- %s\n", m.File)
+					for _, stmt := range m.Statements {
+						fmt.Printf("%s;\n", stmt)
+					}
+					fmt.Println()
+				}
+			}
+
+			return nil
+		}
+
 		applied, err := r.Up()
 		if err != nil {
 			return err
@@ -123,7 +148,61 @@ func (r *Runner) Run(args ...string) error {
 func (r *Runner) Up() ([]string, error) {
 	applied := []string{}
 
-	err := r.db.Transactional(func(tx *dbx.Tx) error {
+	err := r.up(r.db, false, func(file string) {
+		applied = append(applied, file)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// DryRunMigration defines a single migration dry run result.
+type DryRunMigration struct {
+	File       string
+	Statements []string
+}
+
+// DryRun executes all unapplied migrations in a transaction that is
+// always rolled back and returns the SQL statements executed by each of them
+// (including the applied migration info insert).
+//
+// Note that only the statements executed through the migration db builder are captured.
+func (r *Runner) DryRun() ([]*DryRunMigration, error) {
+	result := []*DryRunMigration{}
+	current := &DryRunMigration{}
+
+	captureDB := r.db.Clone()
+	execLogFunc := r.db.ExecLogFunc
+	captureDB.ExecLogFunc = func(ctx context.Context, t time.Duration, sql string, res sql.Result, err error) {
+		current.Statements = append(current.Statements, sql)
+
+		if execLogFunc != nil {
+			execLogFunc(ctx, t, sql, res, err)
+		}
+	}
+
+	err := r.up(captureDB, true, func(file string) {
+		current.File = file
+		result = append(result, current)
+		current = &DryRunMigration{}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+var errDryRunRollback = errors.New("dry run rollback")
+
+// up applies the unapplied migrations in a single transaction
+// and calls onApplied after each applied migration.
+//
+// If rollback is set, the transaction is always rolled back.
+func (r *Runner) up(db *dbx.DB, rollback bool, onApplied func(file string)) error {
+	err := db.Transactional(func(tx *dbx.Tx) error {
 		for _, m := range r.migrationsList.Items() {
 			// skip applied
 			if r.isMigrationApplied(tx, m.File) {
@@ -141,16 +220,21 @@ func (r *Runner) Up() ([]string, error) {
 				return fmt.Errorf("Failed to save applied migration info for %s: %w", m.File, err)
 			}
 
-			applied = append(applied, m.File)
+			onApplied(m.File)
+		}
+
+		if rollback {
+			return errDryRunRollback
 		}
 
 		return nil
 	})
 
-	if err != nil {
-		return nil, err
+	if errors.Is(err, errDryRunRollback) {
+		return nil
 	}
-	return applied, nil
+
+	return err
 }
 
 // Down reverts the last `toRevertCount` applied migrations
@@ -202,6 +286,56 @@ func (r *Runner) Down(toRevertCount int) ([]string, error) {
 	return reverted, nil
 }
 
+// Pending returns the file names of the registered migrations
+// that are not applied yet (in the order they will be applied).
+func (r *Runner) Pending() ([]string, error) {
+	return pendingMigrations(r.db, r.tableName, r.migrationsList)
+}
+
+// PendingMigrations returns the file names of the registered migrations
+// that are not applied yet to the provided db.
+//
+// Unlike [Runner.Pending], it doesn't create the migrations table if missing
+// (aka. all migrations are pending) and it is safe to be used for read-only checks.
+func PendingMigrations(db dbx.Builder, migrationsList MigrationsList) ([]string, error) {
+	return pendingMigrations(db, DefaultMigrationsTable, migrationsList)
+}
+
+func pendingMigrations(db dbx.Builder, tableName string, migrationsList MigrationsList) ([]string, error) {
+	var hasTable bool
+
+	err := db.NewQuery("SELECT to_regclass({:table}) IS NOT NULL").
+		Bind(dbx.Params{"table": tableName}).
+		Row(&hasTable)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []string{}
+
+	if hasTable {
+		err := db.Select("file").From(tableName).Column(&applied)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	appliedMap := make(map[string]struct{}, len(applied))
+	for _, file := range applied {
+		appliedMap[file] = struct{}{}
+	}
+
+	pending := []string{}
+
+	for _, m := range migrationsList.Items() {
+		if _, ok := appliedMap[m.File]; !ok {
+			pending = append(pending, m.File)
+		}
+	}
+
+	return pending, nil
+}
+
 func (r *Runner) createMigrationsTable() error {
 	rawQuery := fmt.Sprintf(
 		"CREATE TABLE IF NOT EXISTS %v (file VARCHAR(255) PRIMARY KEY NOT NULL, applied TIMESTAMPTZ NOT NULL)",
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -140,6 +141,144 @@ func TestRunnerUpAndDown(t *testing.T) {
 	}
 }
 
+func TestRunnerPending(t *testing.T) {
+	testDB, err := createTestDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testDB.Close()
+
+	l := MigrationsList{}
+	l.Register(nil, nil, "3_test")
+	l.Register(nil, nil, "1_test")
+	l.Register(nil, nil, "2_test")
+
+	r, err := NewRunner(testDB.DB, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate partially applied migrations
+	r.saveAppliedMigration(testDB, "2_test")
+
+	pending, err := r.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `["1_test","3_test"]`
+
+	raw, err := json.Marshal(pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := string(raw); v != expected {
+		t.Fatalf("Expected pending migrations %s, got %s", expected, v)
+	}
+
+	if _, err := r.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err = r.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 0 {
+		t.Fatalf("Expected no pending migrations after Up(), got %v", pending)
+	}
+}
+
+func TestPendingMigrationsWithoutTable(t *testing.T) {
+	testDB, err := createTestDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testDB.Close()
+
+	l := MigrationsList{}
+	l.Register(nil, nil, "1_test")
+	l.Register(nil, nil, "2_test")
+
+	pending, err := pendingMigrations(testDB, "_missing_migrations_test", l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pending) != 2 {
+		t.Fatalf("Expected all migrations to be pending, got %v", pending)
+	}
+
+	for _, q := range testDB.CalledQueries {
+		if strings.Contains(strings.ToUpper(q), "CREATE") {
+			t.Fatalf("Expected no create statements, got %q", q)
+		}
+	}
+}
+
+func TestRunnerDryRun(t *testing.T) {
+	testDB, err := createTestDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testDB.Close()
+
+	l := MigrationsList{}
+	l.Register(func(db dbx.Builder) error {
+		_, err := db.NewQuery("CREATE TABLE {{dry_run_test}} (id TEXT)").Execute()
+		return err
+	}, nil, "1_dry_run_test")
+	l.Register(nil, nil, "2_dry_run_test")
+
+	r, err := NewRunner(testDB.DB, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := r.DryRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 dry run migrations, got %d", len(result))
+	}
+
+	if result[0].File != "1_dry_run_test" || result[1].File != "2_dry_run_test" {
+		t.Fatalf("Unexpected dry run migrations order: %s, %s", result[0].File, result[1].File)
+	}
+
+	// create table + applied migration insert
+	if len(result[0].Statements) != 2 || result[0].Statements[0] != `CREATE TABLE "dry_run_test" (id TEXT)` {
+		t.Fatalf("Unexpected 1_dry_run_test statements %v", result[0].Statements)
+	}
+
+	// applied migration insert only
+	if len(result[1].Statements) != 1 {
+		t.Fatalf("Unexpected 2_dry_run_test statements %v", result[1].Statements)
+	}
+
+	// the changes must be rolled back
+	pending, err := r.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected the dry run migrations to remain pending, got %v", pending)
+	}
+
+	var exists bool
+	err = testDB.NewQuery("SELECT to_regclass('dry_run_test') IS NOT NULL").Row(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("Expected the dry_run_test table to be rolled back")
+	}
+}
+
 func TestHistorySync(t *testing.T) {
 	testDB, err := createTestDB()
 	if err != nil {
@@ -0,0 +1,573 @@
+package search
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/spf13/cast"
+)
+
+// MaxAggregateGroups specifies the max allowed number of groups returned by a single aggregation.
+const MaxAggregateGroups int = 1000
+
+// url aggregate query params
+const (
+	GroupByQueryParam   string = "groupBy"
+	AggregateQueryParam string = "agg"
+)
+
+// supported aggregate functions
+const (
+	AggregateCount string = "count"
+	AggregateSum   string = "sum"
+	AggregateAvg   string = "avg"
+	AggregateMin   string = "min"
+	AggregateMax   string = "max"
+)
+
+var aggregateFuncRegex = regexp.MustCompile(`^(\w+)\(\s*([^\(\)]*?)\s*\)$`)
+
+// AggregateField defines a single aggregate function call (eg. "sum(total)").
+type AggregateField struct {
+	Func  string `json:"func"`
+	Field string `json:"field"`
+}
+
+// Key returns the normalized aggregate expression used as result item key.
+func (a *AggregateField) Key() string {
+	return strings.ToLower(a.Func) + "(" + a.Field + ")"
+}
+
+// BuildExpr resolves the aggregate field into a valid db select expression.
+//
+// countCol is used as COUNT argument when no explicit field is set (aka. "count()").
+func (a *AggregateField) BuildExpr(fieldResolver FieldResolver, countCol string) (string, error) {
+	fn := strings.ToLower(a.Func)
+
+	switch fn {
+	case AggregateCount, AggregateSum, AggregateAvg, AggregateMin, AggregateMax:
+	default:
+		return "", fmt.Errorf("unsupported aggregate function %q", a.Func)
+	}
+
+	if a.Field == "" {
+		if fn != AggregateCount {
+			return "", fmt.Errorf("missing %s() field argument", fn)
+		}
+
+		return "COUNT(DISTINCT [[" + countCol + "]])", nil
+	}
+
+	identifier, err := resolveColumnIdentifier(fieldResolver, a.Field)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s(%s)", strings.ToUpper(fn), identifier), nil
+}
+
+// ParseAggregatesFromString parses the provided comma separated
+// aggregate functions string into a slice of AggregateFields.
+//
+// Example:
+//
+//	fields, err := search.ParseAggregatesFromString("count(),sum(total),avg(rating)")
+func ParseAggregatesFromString(str string) ([]AggregateField, error) {
+	result := []AggregateField{}
+
+	var depth int
+	var current strings.Builder
+
+	parts := []string{}
+	for _, ch := range str {
+		switch {
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == ',' && depth == 0:
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(ch)
+	}
+	parts = append(parts, current.String())
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		matches := aggregateFuncRegex.FindStringSubmatch(part)
+		if len(matches) != 3 {
+			return nil, fmt.Errorf("invalid aggregate expression %q", part)
+		}
+
+		result = append(result, AggregateField{
+			Func:  strings.ToLower(matches[1]),
+			Field: matches[2],
+		})
+	}
+
+	return result, nil
+}
+
+// ParseGroupByFromString parses the provided comma separated
+// group by fields string into a slice of field names.
+func ParseGroupByFromString(str string) []string {
+	result := []string{}
+
+	for _, field := range strings.Split(str, ",") {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			result = append(result, field)
+		}
+	}
+
+	return result
+}
+
+// AggregateResult defines the returned aggregation result structure.
+//
+// Each item contains the group by field values and the aggregate
+// results, keyed respectively by the field name and the normalized
+// aggregate expression (eg. {"status": "active", "count()": 10, "sum(total)": 123.5}).
+type AggregateResult struct {
+	Items []map[string]any `json:"items"`
+}
+
+// Aggregator represents a single configured aggregation instance.
+//
+// It is similar to the search Provider but instead of paginated rows
+// it returns grouped aggregate values.
+type Aggregator struct {
+	fieldResolver FieldResolver
+	query         *dbx.SelectQuery
+	countCol      string
+	limit         int
+	groupBy       []string
+	aggregates    []AggregateField
+	sort          []SortField
+	filter        []FilterData
+	filterParams  []dbx.Params
+}
+
+// NewAggregator creates and returns a new aggregator.
+//
+// Example:
+//
+//	baseQuery := db.Select("*").From("orders")
+//	fieldResolver := search.NewSimpleFieldResolver("id", "status", "total")
+//
+//	result, err := search.NewAggregator(fieldResolver).
+//		Query(baseQuery).
+//		ParseAndExec("groupBy=status&agg=count(),sum(total)&filter=total>0")
+func NewAggregator(fieldResolver FieldResolver) *Aggregator {
+	return &Aggregator{
+		fieldResolver: fieldResolver,
+		countCol:      "id",
+		limit:         MaxAggregateGroups,
+		groupBy:       []string{},
+		aggregates:    []AggregateField{},
+		sort:          []SortField{},
+		filter:        []FilterData{},
+	}
+}
+
+// Query sets the base query that will be used for the aggregation.
+func (s *Aggregator) Query(query *dbx.SelectQuery) *Aggregator {
+	s.query = query
+	return s
+}
+
+// CountCol allows changing the default column (id) that is used
+// to generate the "count()" SQL expression and to match the filtered rows.
+func (s *Aggregator) CountCol(name string) *Aggregator {
+	s.countCol = name
+	return s
+}
+
+// Limit sets the max number of returned groups.
+//
+// Normalization on the `limit` value is done during `Exec()`.
+func (s *Aggregator) Limit(limit int) *Aggregator {
+	s.limit = limit
+	return s
+}
+
+// GroupBy sets the `groupBy` field of the current aggregator.
+func (s *Aggregator) GroupBy(fields []string) *Aggregator {
+	s.groupBy = fields
+	return s
+}
+
+// AddGroupBy appends the provided field to the existing aggregator's groupBy field.
+func (s *Aggregator) AddGroupBy(field string) *Aggregator {
+	s.groupBy = append(s.groupBy, field)
+	return s
+}
+
+// Aggregates sets the `aggregates` field of the current aggregator.
+func (s *Aggregator) Aggregates(aggregates []AggregateField) *Aggregator {
+	s.aggregates = aggregates
+	return s
+}
+
+// AddAggregate appends the provided AggregateField to the existing aggregator's aggregates field.
+func (s *Aggregator) AddAggregate(aggregate AggregateField) *Aggregator {
+	s.aggregates = append(s.aggregates, aggregate)
+	return s
+}
+
+// Sort sets the `sort` field of the current aggregator.
+//
+// The sort field names must match either a group by field
+// or a normalized aggregate expression (eg. "-count()").
+func (s *Aggregator) Sort(sort []SortField) *Aggregator {
+	s.sort = sort
+	return s
+}
+
+// AddSort appends the provided SortField to the existing aggregator's sort field.
+func (s *Aggregator) AddSort(field SortField) *Aggregator {
+	s.sort = append(s.sort, field)
+	return s
+}
+
+// Filter sets the `filter` field of the current aggregator.
+func (s *Aggregator) Filter(filter []FilterData) *Aggregator {
+	s.filter = filter
+	return s
+}
+
+// AddFilter appends the provided FilterData to the existing aggregator's filter field.
+func (s *Aggregator) AddFilter(filter FilterData) *Aggregator {
+	if filter != "" {
+		s.filter = append(s.filter, filter)
+	}
+	return s
+}
+
+// FilterParams sets the placeholder parameters that will be
+// replaced in all aggregator filter expressions (see FilterData.BuildExpr).
+func (s *Aggregator) FilterParams(params ...dbx.Params) *Aggregator {
+	s.filterParams = params
+	return s
+}
+
+// Parse parses the aggregation query parameters from the provided query string
+// and assigns the found fields to the current aggregator.
+//
+// The data from the "groupBy", "agg", "sort" and "filter" query parameters
+// are appended to the existing aggregator's fields.
+func (s *Aggregator) Parse(urlQuery string) error {
+	params, err := url.ParseQuery(urlQuery)
+	if err != nil {
+		return err
+	}
+
+	if raw := params.Get(GroupByQueryParam); raw != "" {
+		for _, field := range ParseGroupByFromString(raw) {
+			s.AddGroupBy(field)
+		}
+	}
+
+	if raw := params.Get(AggregateQueryParam); raw != "" {
+		aggregates, err := ParseAggregatesFromString(raw)
+		if err != nil {
+			return err
+		}
+		for _, a := range aggregates {
+			s.AddAggregate(a)
+		}
+	}
+
+	if raw := params.Get(PerPageQueryParam); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		s.Limit(v)
+	}
+
+	if raw := params.Get(SortQueryParam); raw != "" {
+		for _, sortField := range ParseSortFromString(raw) {
+			s.AddSort(sortField)
+		}
+	}
+
+	if raw := params.Get(FilterQueryParam); raw != "" {
+		s.AddFilter(FilterData(raw))
+	}
+
+	return nil
+}
+
+// Exec executes the aggregation and returns the grouped results.
+//
+// To avoid inflating the aggregate values with duplicated rows from
+// the filter joins (eg. back-relations), the filters are applied in
+// a separate "id IN (subquery)" condition and only the group by and
+// aggregate fields joins are attached to the main query.
+func (s *Aggregator) Exec() (*AggregateResult, error) {
+	if s.query == nil {
+		return nil, errors.New("query is not set")
+	}
+
+	if len(s.aggregates) == 0 {
+		s.aggregates = []AggregateField{{Func: AggregateCount}}
+	}
+
+	// shallow clone the aggregator's query
+	aggQuery := *s.query
+
+	countCol := s.countCol
+	if info := aggQuery.Info(); len(info.From) > 0 {
+		countCol = info.From[0] + "." + countCol
+	}
+
+	selects := make([]string, 0, len(s.groupBy)+len(s.aggregates))
+	groupExprs := make([]string, 0, len(s.groupBy))
+	aliases := map[string]string{}
+
+	for i, field := range s.groupBy {
+		identifier, err := resolveColumnIdentifier(s.fieldResolver, field)
+		if err != nil {
+			return nil, err
+		}
+
+		alias := "g" + strconv.Itoa(i)
+		aliases[field] = alias
+		groupExprs = append(groupExprs, identifier)
+
+		// select the group values as json so that they could be
+		// decoded with their column type (number, bool, etc.)
+		selects = append(selects, "to_jsonb("+identifier+") AS [["+alias+"]]")
+	}
+
+	if err := s.checkMultiValuedAggregates(); err != nil {
+		return nil, err
+	}
+
+	for i, a := range s.aggregates {
+		expr, err := a.BuildExpr(s.fieldResolver, countCol)
+		if err != nil {
+			return nil, err
+		}
+
+		alias := "a" + strconv.Itoa(i)
+		aliases[a.Key()] = alias
+		selects = append(selects, expr+" AS [["+alias+"]]")
+	}
+
+	orderBy := make([]string, 0, len(s.sort))
+	for _, sortField := range s.sort {
+		alias, ok := aliases[sortField.Name]
+		if !ok {
+			return nil, fmt.Errorf("invalid sort field %q (must be a group by field or aggregate expression)", sortField.Name)
+		}
+		orderBy = append(orderBy, "[["+alias+"]] "+sortField.Direction)
+	}
+
+	// attach only the group by and aggregate fields joins (if any)
+	if err := s.fieldResolver.UpdateQuery(&aggQuery); err != nil {
+		return nil, err
+	}
+
+	// build the filtered rows subquery
+	if len(s.filter) > 0 {
+		filterQuery := *s.query // shallow clone
+
+		for _, f := range s.filter {
+			expr, err := f.BuildExpr(s.fieldResolver, s.filterParams...)
+			if err != nil {
+				return nil, err
+			}
+			if expr != nil {
+				filterQuery.AndWhere(expr)
+			}
+		}
+
+		// note: the resolver joins are accumulated and here will contain
+		// also the group by joins but for the subquery they are harmless
+		if err := s.fieldResolver.UpdateQuery(&filterQuery); err != nil {
+			return nil, err
+		}
+
+		filterQuery.Distinct(false).Select("[[" + countCol + "]]").OrderBy( /* reset */ )
+
+		aggQuery.AndWhere(&inSubqueryExpr{column: countCol, query: &filterQuery})
+	}
+
+	// normalize limit
+	if s.limit <= 0 || s.limit > MaxAggregateGroups {
+		s.limit = MaxAggregateGroups
+	}
+
+	aggQuery.Distinct(false).
+		Select(selects...).
+		GroupBy(groupExprs...).
+		OrderBy(orderBy...).
+		Limit(int64(s.limit))
+
+	rows := []dbx.NullStringMap{}
+	if err := aggQuery.All(&rows); err != nil {
+		return nil, err
+	}
+
+	result := &AggregateResult{Items: make([]map[string]any, 0, len(rows))}
+
+	for _, row := range rows {
+		item := make(map[string]any, len(aliases))
+
+		for _, field := range s.groupBy {
+			item[field] = decodeAggregateGroupValue(row[aliases[field]])
+		}
+
+		for _, a := range s.aggregates {
+			fn := strings.ToLower(a.Func)
+			v := row[aliases[a.Key()]]
+			switch {
+			case !v.Valid:
+				item[a.Key()] = nil
+			case fn == AggregateCount:
+				item[a.Key()] = cast.ToInt(v.String)
+			case fn == AggregateMin || fn == AggregateMax:
+				// min/max could be also used with non-numeric fields
+				if f, err := strconv.ParseFloat(v.String, 64); err == nil {
+					item[a.Key()] = f
+				} else {
+					item[a.Key()] = v.String
+				}
+			default:
+				item[a.Key()] = cast.ToFloat64(v.String)
+			}
+		}
+
+		result.Items = append(result.Items, item)
+	}
+
+	return result, nil
+}
+
+// ParseAndExec is a short convenient method to trigger both
+// `Parse()` and `Exec()` in a single call.
+func (s *Aggregator) ParseAndExec(urlQuery string) (*AggregateResult, error) {
+	if err := s.Parse(urlQuery); err != nil {
+		return nil, err
+	}
+
+	return s.Exec()
+}
+
+// checkMultiValuedAggregates rejects the sum() and avg() aggregates when
+// any of the aggregate fields is resolved through a multi-valued join
+// (eg. a multiple relation field) because the joined rows duplicates
+// would inflate the aggregated totals.
+//
+// Note that count() counts the distinct rows and min()/max()
+// are not affected by the duplicates.
+func (s *Aggregator) checkMultiValuedAggregates() error {
+	var hasSumOrAvg bool
+	var multiValuedField string
+
+	for _, a := range s.aggregates {
+		fn := strings.ToLower(a.Func)
+		if fn == AggregateSum || fn == AggregateAvg {
+			hasSumOrAvg = true
+		}
+
+		if a.Field == "" || multiValuedField != "" {
+			continue
+		}
+
+		r, err := s.fieldResolver.Resolve(a.Field)
+		if err != nil {
+			continue // the invalid field error is returned by BuildExpr
+		}
+
+		if r.MultiMatchSubQuery != nil {
+			multiValuedField = a.Field
+		}
+	}
+
+	if hasSumOrAvg && multiValuedField != "" {
+		return fmt.Errorf("sum() and avg() are not supported together with the multi-valued aggregate field %q", multiValuedField)
+	}
+
+	return nil
+}
+
+// decodeAggregateGroupValue decodes the json encoded group value.
+func decodeAggregateGroupValue(v sql.NullString) any {
+	if !v.Valid {
+		return nil
+	}
+
+	var result any
+	if err := json.Unmarshal([]byte(v.String), &result); err != nil {
+		return v.String
+	}
+
+	return result
+}
+
+// resolveColumnIdentifier resolves the provided field name into
+// a plain column identifier (placeholder params are not allowed).
+func resolveColumnIdentifier(fieldResolver FieldResolver, field string) (string, error) {
+	r, err := fieldResolver.Resolve(field)
+	if err != nil || len(r.Params) > 0 || r.Identifier == "" || strings.ToLower(r.Identifier) == "null" {
+		return "", fmt.Errorf("invalid field %q", field)
+	}
+
+	return r.Identifier, nil
+}
+
+var _ dbx.Expression = (*inSubqueryExpr)(nil)
+
+// inSubqueryExpr defines a "column IN (SELECT ...)" expression.
+type inSubqueryExpr struct {
+	column string
+	query  *dbx.SelectQuery
+}
+
+// Build converts the expression into a SQL fragment.
+//
+// Implements [dbx.Expression] interface.
+func (e *inSubqueryExpr) Build(db *dbx.DB, params dbx.Params) string {
+	info := e.query.Info()
+
+	if params == nil {
+		params = dbx.Params{}
+	}
+	for k, v := range info.Params {
+		params[k] = v
+	}
+
+	qb := db.QueryBuilder()
+
+	clauses := []string{
+		qb.BuildSelect(info.Selects, info.Distinct, info.SelectOption),
+		qb.BuildFrom(info.From),
+		qb.BuildJoin(info.Join, params),
+		qb.BuildWhere(info.Where, params),
+	}
+
+	parts := make([]string, 0, len(clauses))
+	for _, clause := range clauses {
+		if clause != "" {
+			parts = append(parts, clause)
+		}
+	}
+
+	return db.QuoteColumnName(e.column) + " IN (" + strings.Join(parts, " ") + ")"
+}
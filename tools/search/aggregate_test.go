@@ -0,0 +1,158 @@
+package search_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/tools/search"
+	"github.com/pocketbase/dbx"
+)
+
+func TestAggregateFieldBuildExpr(t *testing.T) {
+	resolver := search.NewSimpleFieldResolver("test1", "test2", "test3.sub")
+
+	scenarios := []struct {
+		aggregate        search.AggregateField
+		expectError      bool
+		expectExpression string
+	}{
+		// unknown function
+		{search.AggregateField{"median", "test1"}, true, ""},
+		// unknown field
+		{search.AggregateField{"sum", "unknown"}, true, ""},
+		// placeholder field
+		{search.AggregateField{"sum", "'test'"}, true, ""},
+		// null field
+		{search.AggregateField{"max", "null"}, true, ""},
+		// missing non-count field
+		{search.AggregateField{"avg", ""}, true, ""},
+		// count without field
+		{search.AggregateField{"count", ""}, false, "COUNT(DISTINCT [[demo.id]])"},
+		// count with field
+		{search.AggregateField{"count", "test1"}, false, "COUNT([[test1]])"},
+		// sum
+		{search.AggregateField{"sum", "test1"}, false, "SUM([[test1]])"},
+		// avg
+		{search.AggregateField{"avg", "test2"}, false, "AVG([[test2]])"},
+		// min (case insensitive func)
+		{search.AggregateField{"MIN", "test3.sub"}, false, "MIN(JSON_EXTRACT([[test3]], '$.sub'))"},
+		// max
+		{search.AggregateField{"max", "test1"}, false, "MAX([[test1]])"},
+	}
+
+	for i, s := range scenarios {
+		result, err := s.aggregate.BuildExpr(resolver, "demo.id")
+
+		hasErr := err != nil
+		if hasErr != s.expectError {
+			t.Errorf("(%d) Expected hasErr %v, got %v (%v)", i, s.expectError, hasErr, err)
+			continue
+		}
+
+		if result != s.expectExpression {
+			t.Errorf("(%d) Expected expression %v, got %v", i, s.expectExpression, result)
+		}
+	}
+}
+
+func TestParseAggregatesFromString(t *testing.T) {
+	scenarios := []struct {
+		value        string
+		expectError  bool
+		expectedJson string
+	}{
+		{"", false, `[]`},
+		{"count", true, `null`},
+		{"sum(a", true, `null`},
+		{"sum(a(b))", true, `null`},
+		{"count()", false, `[{"func":"count","field":""}]`},
+		{"SUM( total )", false, `[{"func":"sum","field":"total"}]`},
+		{
+			"count(), sum(total),avg(author.rating)",
+			false,
+			`[{"func":"count","field":""},{"func":"sum","field":"total"},{"func":"avg","field":"author.rating"}]`,
+		},
+	}
+
+	for i, s := range scenarios {
+		result, err := search.ParseAggregatesFromString(s.value)
+
+		hasErr := err != nil
+		if hasErr != s.expectError {
+			t.Errorf("(%d) Expected hasErr %v, got %v (%v)", i, s.expectError, hasErr, err)
+			continue
+		}
+
+		encoded, _ := json.Marshal(result)
+
+		if string(encoded) != s.expectedJson {
+			t.Errorf("(%d) Expected %v, got %v", i, s.expectedJson, string(encoded))
+		}
+	}
+}
+
+func TestParseGroupByFromString(t *testing.T) {
+	scenarios := []struct {
+		value        string
+		expectedJson string
+	}{
+		{"", `[]`},
+		{"status", `["status"]`},
+		{" status , author.name,, ", `["status","author.name"]`},
+	}
+
+	for i, s := range scenarios {
+		result := search.ParseGroupByFromString(s.value)
+		encoded, _ := json.Marshal(result)
+
+		if string(encoded) != s.expectedJson {
+			t.Errorf("(%d) Expected %v, got %v", i, s.expectedJson, string(encoded))
+		}
+	}
+}
+
+func TestAggregatorExecMultiValuedSumAndAvg(t *testing.T) {
+	scenarios := []struct {
+		name string
+		agg  string
+	}{
+		{"sum over multi-valued field", "sum(multi)"},
+		{"avg over multi-valued field", "avg(multi)"},
+		{"sum with another multi-valued aggregate field", "sum(test1),min(multi)"},
+		{"avg with another multi-valued aggregate field", "count(),max(multi),avg(test1)"},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			query := dbx.NewFromDB(nil, "postgres").Select("*").From("demo")
+
+			_, err := search.NewAggregator(&multiValuedFieldResolver{search.NewSimpleFieldResolver("test1", "multi")}).
+				Query(query).
+				ParseAndExec("agg=" + url.QueryEscape(s.agg))
+			if err == nil || !strings.Contains(err.Error(), "multi-valued") {
+				t.Fatalf("Expected multi-valued aggregate error, got %v", err)
+			}
+		})
+	}
+}
+
+// multiValuedFieldResolver marks the "multi" field as resolved
+// through a multi-valued join.
+type multiValuedFieldResolver struct {
+	*search.SimpleFieldResolver
+}
+
+func (r *multiValuedFieldResolver) Resolve(field string) (*search.ResolverResult, error) {
+	result, err := r.SimpleFieldResolver.Resolve(field)
+	if err != nil {
+		return nil, err
+	}
+
+	if field == "multi" {
+		result.MultiMatchSubQuery = dbx.NewExp("1")
+	}
+
+	return result, nil
+}
@@ -0,0 +1,190 @@
+package search
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pocketbase/dbx"
+	"github.com/spf13/cast"
+)
+
+// cursorData defines the decoded opaque cursor structure.
+type cursorData struct {
+	// Checksum is a short hash of the cursor sort fields
+	// used to reject cursors generated for a different sort.
+	Checksum string `json:"c"`
+
+	// Values are the last item sort values (nil for NULL).
+	Values []*string `json:"v"`
+}
+
+// cursorSortExpr defines a single resolved cursor sort expression.
+type cursorSortExpr struct {
+	identifier string
+	desc       bool
+}
+
+// encodeCursor encodes the provided sort values into an opaque cursor string.
+func encodeCursor(sort []SortField, values []*string) (string, error) {
+	raw, err := json.Marshal(cursorData{
+		Checksum: cursorChecksum(sort),
+		Values:   values,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor decodes the provided opaque cursor string and
+// validates it against the current sort fields.
+func decodeCursor(cursor string, sort []SortField) ([]*string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	data := cursorData{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	if data.Checksum != cursorChecksum(sort) || len(data.Values) != len(sort) {
+		return nil, errors.New("the cursor doesn't match the current sort fields")
+	}
+
+	return data.Values, nil
+}
+
+func cursorChecksum(sort []SortField) string {
+	parts := make([]string, len(sort))
+	for i, s := range sort {
+		parts[i] = s.Name + " " + s.Direction
+	}
+
+	return strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(strings.Join(parts, ",")))), 36)
+}
+
+// buildCursorExpr builds the keyset WHERE expression that matches
+// all rows positioned after the one with the provided sort values.
+//
+// For the sort fields (a ASC, b DESC, id ASC) and values (va, vb, vid)
+// the generated expression is similar to:
+//
+//	a > va OR (a = va AND b < vb) OR (a = va AND b = vb AND id > vid)
+//
+// NULL values are handled according to the Postgres default
+// ordering (aka. NULLS LAST for ASC and NULLS FIRST for DESC).
+func buildCursorExpr(exprs []cursorSortExpr, values []*string) dbx.Expression {
+	ors := make([]dbx.Expression, 0, len(exprs))
+
+	for i, expr := range exprs {
+		ands := make([]dbx.Expression, 0, i+1)
+
+		for j := 0; j < i; j++ {
+			ands = append(ands, cursorEqualExpr(exprs[j].identifier, values[j], j))
+		}
+
+		after := cursorAfterExpr(expr, values[i], i)
+		if after == nil {
+			continue // nothing can be positioned after
+		}
+		ands = append(ands, after)
+
+		ors = append(ors, dbx.And(ands...))
+	}
+
+	if len(ors) == 0 {
+		return dbx.NewExp("1=0")
+	}
+
+	return dbx.Or(ors...)
+}
+
+func cursorEqualExpr(identifier string, value *string, index int) dbx.Expression {
+	if value == nil {
+		return dbx.NewExp(identifier + " IS NULL")
+	}
+
+	placeholder := "cursor" + strconv.Itoa(index)
+
+	return dbx.NewExp(identifier+" = {:"+placeholder+"}", dbx.Params{placeholder: *value})
+}
+
+func cursorAfterExpr(expr cursorSortExpr, value *string, index int) dbx.Expression {
+	if value == nil {
+		if expr.desc {
+			// NULLS FIRST -> all non-null values are after
+			return dbx.NewExp(expr.identifier + " IS NOT NULL")
+		}
+
+		// NULLS LAST -> only other nulls are left and they are handled by the next sort field
+		return nil
+	}
+
+	placeholder := "cursor" + strconv.Itoa(index)
+	params := dbx.Params{placeholder: *value}
+
+	if expr.desc {
+		return dbx.NewExp(expr.identifier+" < {:"+placeholder+"}", params)
+	}
+
+	// NULLS LAST -> the nulls are after any non-null value
+	return dbx.NewExp("("+expr.identifier+" > {:"+placeholder+"} OR "+expr.identifier+" IS NULL)", params)
+}
+
+// extractItemId returns the countCol value of the provided item
+// (model with GetId() method, map or struct with a matching db tag).
+func extractItemId(item reflect.Value, countCol string) (string, error) {
+	for item.Kind() == reflect.Interface || (item.Kind() == reflect.Pointer && !item.IsNil() && item.Elem().Kind() == reflect.Pointer) {
+		item = item.Elem()
+	}
+
+	if item.CanInterface() {
+		if v, ok := item.Interface().(interface{ GetId() string }); ok {
+			return v.GetId(), nil
+		}
+	}
+
+	if item.Kind() == reflect.Pointer {
+		if item.IsNil() {
+			return "", errors.New("nil cursor item")
+		}
+		item = item.Elem()
+
+		if item.CanAddr() {
+			if v, ok := item.Addr().Interface().(interface{ GetId() string }); ok {
+				return v.GetId(), nil
+			}
+		}
+	}
+
+	switch item.Kind() {
+	case reflect.Map:
+		v := item.MapIndex(reflect.ValueOf(countCol))
+		if !v.IsValid() {
+			break
+		}
+		if ns, ok := v.Interface().(sql.NullString); ok {
+			return ns.String, nil
+		}
+		return cast.ToStringE(v.Interface())
+	case reflect.Struct:
+		t := item.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if strings.Split(t.Field(i).Tag.Get("db"), ",")[0] == countCol {
+				return cast.ToStringE(item.Field(i).Interface())
+			}
+		}
+	}
+
+	return "", fmt.Errorf("failed to extract the cursor item %q value", countCol)
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/tools/types"
+	"github.com/pocketbase/dbx"
+)
+
+func TestCursorEncodeDecode(t *testing.T) {
+	sort := []SortField{{"a", SortAsc}, {"id", SortDesc}}
+	values := []*string{nil, types.Pointer("123")}
+
+	cursor, err := encodeCursor(sort, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		name        string
+		cursor      string
+		sort        []SortField
+		expectError bool
+	}{
+		{"invalid base64", "!@#", sort, true},
+		{"invalid json", "dGVzdA", sort, true},
+		{"different sort direction", cursor, []SortField{{"a", SortDesc}, {"id", SortDesc}}, true},
+		{"different sort fields", cursor, []SortField{{"a", SortAsc}}, true},
+		{"matching sort", cursor, sort, false},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			decoded, err := decodeCursor(s.cursor, s.sort)
+
+			hasErr := err != nil
+			if hasErr != s.expectError {
+				t.Fatalf("Expected hasErr %v, got %v (%v)", s.expectError, hasErr, err)
+			}
+
+			if hasErr {
+				return
+			}
+
+			if !reflect.DeepEqual(decoded, values) {
+				t.Fatalf("Expected values %v, got %v", values, decoded)
+			}
+		})
+	}
+}
+
+func TestBuildCursorExpr(t *testing.T) {
+	db := dbx.NewFromDB(nil, "postgres")
+
+	scenarios := []struct {
+		name     string
+		exprs    []cursorSortExpr
+		values   []*string
+		expected string
+	}{
+		{
+			"single asc",
+			[]cursorSortExpr{{"id", false}},
+			[]*string{types.Pointer("1")},
+			"(id > {:cursor0} OR id IS NULL)",
+		},
+		{
+			"single desc",
+			[]cursorSortExpr{{"id", true}},
+			[]*string{types.Pointer("1")},
+			"id < {:cursor0}",
+		},
+		{
+			"single asc null (nothing after)",
+			[]cursorSortExpr{{"id", false}},
+			[]*string{nil},
+			"1=0",
+		},
+		{
+			"multiple with nulls",
+			[]cursorSortExpr{{"a", false}, {"b", true}, {"id", false}},
+			[]*string{nil, nil, types.Pointer("1")},
+			"((a IS NULL) AND (b IS NOT NULL)) OR ((a IS NULL) AND (b IS NULL) AND ((id > {:cursor2} OR id IS NULL)))",
+		},
+		{
+			"multiple with values",
+			[]cursorSortExpr{{"a", true}, {"id", false}},
+			[]*string{types.Pointer("test"), types.Pointer("1")},
+			"(a < {:cursor0}) OR ((a = {:cursor0}) AND ((id > {:cursor1} OR id IS NULL)))",
+		},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			params := dbx.Params{}
+
+			result := buildCursorExpr(s.exprs, s.values).Build(db, params)
+
+			if result != s.expected {
+				t.Fatalf("Expected \n%s, \ngot \n%s", s.expected, result)
+			}
+		})
+	}
+}
+
+func TestExtractItemId(t *testing.T) {
+	type withTag struct {
+		Key string `db:"id"`
+	}
+
+	scenarios := []struct {
+		name        string
+		item        any
+		expected    string
+		expectError bool
+	}{
+		{"struct with db tag", withTag{"a"}, "a", false},
+		{"pointer struct with db tag", &withTag{"b"}, "b", false},
+		{"struct without db tag", struct{ Test string }{"c"}, "", true},
+		{"map", map[string]any{"id": 123}, "123", false},
+		{"map without key", map[string]any{"test": 123}, "", true},
+		{"nullstring map", dbx.NullStringMap{"id": {String: "d", Valid: true}}, "d", false},
+		{"nil pointer", (*withTag)(nil), "", true},
+	}
+
+	for _, s := range scenarios {
+		t.Run(s.name, func(t *testing.T) {
+			result, err := extractItemId(reflect.ValueOf(s.item), "id")
+
+			hasErr := err != nil
+			if hasErr != s.expectError {
+				t.Fatalf("Expected hasErr %v, got %v (%v)", s.expectError, hasErr, err)
+			}
+
+			if result != s.expected {
+				t.Fatalf("Expected %q, got %q", s.expected, result)
+			}
+		})
+	}
+}
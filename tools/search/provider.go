@@ -2,8 +2,10 @@ package search
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"net/url"
+	"reflect"
 	"strconv"
 
 	"github.com/pocketbase/dbx"
@@ -23,6 +25,8 @@ const (
 	SortQueryParam      string = "sort"
 	FilterQueryParam    string = "filter"
 	SkipTotalQueryParam string = "skipTotal"
+	CursorQueryParam    string = "cursor"
+	AfterQueryParam     string = "after" // alias of CursorQueryParam
 )
 
 // Result defines the returned search result structure.
@@ -32,6 +36,10 @@ type Result struct {
 	TotalItems int `json:"totalItems"`
 	TotalPages int `json:"totalPages"`
 	Items      any `json:"items"`
+
+	// NextCursor is the opaque cursor of the next page items
+	// (available only with cursor pagination and if there are more items).
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // Provider represents a single configured search provider instance.
@@ -39,6 +47,8 @@ type Provider struct {
 	fieldResolver FieldResolver
 	query         *dbx.SelectQuery
 	skipTotal     bool
+	cursorMode    bool
+	cursor        string
 	countCol      string
 	page          int
 	perPage       int
@@ -80,6 +90,20 @@ func (s *Provider) SkipTotal(skipTotal bool) *Provider {
 	return s
 }
 
+// Cursor enables the keyset (aka. cursor) pagination and sets the
+// opaque cursor returned as nextCursor from the previous search result.
+//
+// Use an empty cursor string to fetch the first page.
+//
+// With cursor pagination the page, skipTotal and the base query
+// ORDER BY clause are ignored and an extra countCol sort field
+// is added to the sort fields (if not already) to ensure stable ordering.
+func (s *Provider) Cursor(cursor string) *Provider {
+	s.cursorMode = true
+	s.cursor = cursor
+	return s
+}
+
 // CountCol allows changing the default column (id) that is used
 // to generate the COUNT SQL query statement.
 //
@@ -151,6 +175,16 @@ func (s *Provider) Parse(urlQuery string) error {
 		s.SkipTotal(v)
 	}
 
+	if params.Has(CursorQueryParam) && params.Has(AfterQueryParam) {
+		return fmt.Errorf("only one of the %q and %q query parameters could be set", CursorQueryParam, AfterQueryParam)
+	}
+
+	if params.Has(CursorQueryParam) {
+		s.Cursor(params.Get(CursorQueryParam))
+	} else if params.Has(AfterQueryParam) {
+		s.Cursor(params.Get(AfterQueryParam))
+	}
+
 	if raw := params.Get(PageQueryParam); raw != "" {
 		v, err := strconv.Atoi(raw)
 		if err != nil {
@@ -201,6 +235,10 @@ func (s *Provider) Exec(items any) (*Result, error) {
 		}
 	}
 
+	if s.cursorMode {
+		return s.execCursor(modelsQuery, items)
+	}
+
 	// apply sorting
 	for _, sortField := range s.sort {
 		expr, err := sortField.BuildExpr(s.fieldResolver)
@@ -217,17 +255,7 @@ func (s *Provider) Exec(items any) (*Result, error) {
 		return nil, err
 	}
 
-	// normalize page
-	if s.page <= 0 {
-		s.page = 1
-	}
-
-	// normalize perPage
-	if s.perPage <= 0 {
-		s.perPage = DefaultPerPage
-	} else if s.perPage > MaxPerPage {
-		s.perPage = MaxPerPage
-	}
+	s.normalizePagination()
 
 	// negative value to differentiate from the zero default
 	totalCount := -1
@@ -290,6 +318,134 @@ func (s *Provider) Exec(items any) (*Result, error) {
 	return result, nil
 }
 
+// execCursor executes the keyset paginated search using the
+// provided already filtered models query.
+func (s *Provider) execCursor(modelsQuery dbx.SelectQuery, items any) (*Result, error) {
+	sort := make([]SortField, 0, len(s.sort)+1)
+	hasCountCol := false
+	for _, sortField := range s.sort {
+		if sortField.Name == randomSortKey {
+			return nil, errors.New("the random sort is not supported with cursor pagination")
+		}
+		if sortField.Name == s.countCol {
+			hasCountCol = true
+		}
+		sort = append(sort, sortField)
+	}
+	if !hasCountCol {
+		direction := SortAsc
+		if len(sort) > 0 {
+			direction = sort[len(sort)-1].Direction
+		}
+		sort = append(sort, SortField{Name: s.countCol, Direction: direction})
+	}
+
+	exprs := make([]cursorSortExpr, len(sort))
+	orderBy := make([]string, len(sort))
+	for i, sortField := range sort {
+		identifier, err := resolveColumnIdentifier(s.fieldResolver, sortField.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sort field %q", sortField.Name)
+		}
+		exprs[i] = cursorSortExpr{identifier: identifier, desc: sortField.Direction == SortDesc}
+		orderBy[i] = identifier + " " + sortField.Direction
+	}
+	modelsQuery.OrderBy(orderBy...)
+
+	if s.cursor != "" {
+		values, err := decodeCursor(s.cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		modelsQuery.AndWhere(buildCursorExpr(exprs, values))
+	}
+
+	// apply field resolver query modifications (if any)
+	if err := s.fieldResolver.UpdateQuery(&modelsQuery); err != nil {
+		return nil, err
+	}
+
+	s.normalizePagination()
+
+	// fetch 1 extra item to check whether there is a next page
+	modelsQuery.Limit(int64(s.perPage + 1))
+	if err := modelsQuery.All(items); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Page:       s.page,
+		PerPage:    s.perPage,
+		TotalItems: -1,
+		TotalPages: -1,
+		Items:      items,
+	}
+
+	slice := reflect.ValueOf(items)
+	if slice.Kind() == reflect.Pointer {
+		slice = slice.Elem()
+	}
+	if slice.Kind() != reflect.Slice || slice.Len() <= s.perPage {
+		return result, nil // no more items
+	}
+
+	// trim the extra item
+	slice.Set(slice.Slice(0, s.perPage))
+
+	lastId, err := extractItemId(slice.Index(s.perPage-1), s.countCol)
+	if err != nil {
+		return nil, err
+	}
+
+	// load the sort values of the last item
+	valuesQuery := modelsQuery // shallow clone
+	selects := make([]string, len(exprs))
+	for i, expr := range exprs {
+		selects[i] = expr.identifier + " AS [[cursor" + strconv.Itoa(i) + "]]"
+	}
+	countCol := s.countCol
+	if info := valuesQuery.Info(); len(info.From) > 0 {
+		countCol = info.From[0] + "." + countCol
+	}
+	row := dbx.NullStringMap{}
+	err = valuesQuery.Distinct(false).
+		Select(selects...).
+		AndWhere(dbx.HashExp{countCol: lastId}).
+		Limit(1).
+		One(&row)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]*string, len(exprs))
+	for i := range exprs {
+		if v := row["cursor"+strconv.Itoa(i)]; v.Valid {
+			values[i] = &v.String
+		}
+	}
+
+	result.NextCursor, err = encodeCursor(sort, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *Provider) normalizePagination() {
+	// normalize page
+	if s.page <= 0 {
+		s.page = 1
+	}
+
+	// normalize perPage
+	if s.perPage <= 0 {
+		s.perPage = DefaultPerPage
+	} else if s.perPage > MaxPerPage {
+		s.perPage = MaxPerPage
+	}
+}
+
 // ParseAndExec is a short convenient method to trigger both
 // `Parse()` and `Exec()` in a single call.
 func (s *Provider) ParseAndExec(urlQuery string, modelsSlice any) (*Result, error) {
@@ -72,6 +72,50 @@ func TestProviderCountCol(t *testing.T) {
 	}
 }
 
+func TestProviderCursor(t *testing.T) {
+	p := NewProvider(&testFieldResolver{})
+
+	if p.cursorMode {
+		t.Fatalf("Expected the default cursorMode to be %v, got %v", false, p.cursorMode)
+	}
+
+	if err := p.Parse("cursor="); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.cursorMode || p.cursor != "" {
+		t.Fatalf("Expected cursorMode to be enabled with empty cursor, got %v (%q)", p.cursorMode, p.cursor)
+	}
+
+	p.Cursor("test")
+
+	if !p.cursorMode || p.cursor != "test" {
+		t.Fatalf("Expected cursorMode to be enabled with cursor %q, got %v (%q)", "test", p.cursorMode, p.cursor)
+	}
+}
+
+func TestProviderCursorAfterAlias(t *testing.T) {
+	p := NewProvider(&testFieldResolver{})
+
+	if err := p.Parse("after=test_after"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.cursorMode || p.cursor != "test_after" {
+		t.Fatalf("Expected cursorMode to be enabled with cursor %q, got %v (%q)", "test_after", p.cursorMode, p.cursor)
+	}
+
+	p2 := NewProvider(&testFieldResolver{})
+
+	if err := p2.Parse("cursor=a&after=b"); err == nil {
+		t.Fatal("Expected error when both cursor and after are set")
+	}
+
+	if p2.cursorMode {
+		t.Fatal("Expected cursorMode to remain disabled on error")
+	}
+}
+
 func TestProviderPage(t *testing.T) {
 	r := &testFieldResolver{}
 	p := NewProvider(r).Page(10)
@@ -559,6 +603,61 @@ func TestProviderParseAndExec(t *testing.T) {
 	}
 }
 
+func TestProviderExecCursor(t *testing.T) {
+	tb := "test_tb_4"
+	testDB, err := createTestDB(tb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testDB.Close()
+
+	query := testDB.Select("*").From(tb).OrderBy("test1 DESC")
+
+	p := NewProvider(&testFieldResolver{}).
+		Query(query).
+		PerPage(1).
+		Sort([]SortField{{"test2", SortAsc}}).
+		Cursor("")
+
+	var ids []int
+	for i := 0; i < 5; i++ {
+		items := []testTableStructWithId{}
+
+		result, err := p.Exec(&items)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.TotalItems != -1 || result.TotalPages != -1 {
+			t.Fatalf("Expected total items and pages to be skipped, got %d and %d", result.TotalItems, result.TotalPages)
+		}
+
+		for _, item := range items {
+			ids = append(ids, item.Id)
+		}
+
+		if result.NextCursor == "" {
+			break
+		}
+
+		p.Cursor(result.NextCursor)
+	}
+
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("Expected ids [1 2], got %v", ids)
+	}
+
+	// random sort is not allowed
+	_, err = NewProvider(&testFieldResolver{}).
+		Query(query).
+		Sort([]SortField{{randomSortKey, SortAsc}}).
+		Cursor("").
+		Exec(&[]testTableStructWithId{})
+	if err == nil {
+		t.Fatal("Expected error for the random sort, got nil")
+	}
+}
+
 // -------------------------------------------------------------------
 // Helpers
 // -------------------------------------------------------------------
@@ -570,6 +669,13 @@ type testTableStruct struct {
 	Test3 string `db:"test3" json:"test3"`
 }
 
+type testTableStructWithId struct {
+	Id    int    `db:"id" json:"id"`
+	Test1 int    `db:"test1" json:"test1"`
+	Test2 string `db:"test2" json:"test2"`
+	Test3 string `db:"test3" json:"test3"`
+}
+
 type testDB struct {
 	*dbx.DB
 	CalledQueries []string
@@ -13,8 +13,19 @@ import (
 
 const optionsParam = "options"
 
+// Subscription options query parameters that are
+// synced with the dedicated SubscriptionOptions fields.
+const (
+	filterQueryParam = "filter"
+	fieldsQueryParam = "fields"
+	expandQueryParam = "expand"
+)
+
 // Message defines a client's channel data.
 type Message struct {
+	// Id is the monotonically increasing (per client) message id
+	// assigned on send.
+	Id   uint64 `json:"id"`
 	Name string `json:"name"`
 	Data []byte `json:"data"`
 }
@@ -27,6 +38,18 @@ type SubscriptionOptions struct {
 
 	Query   map[string]any `json:"query"`
 	Headers map[string]any `json:"headers"`
+
+	// Filter is an optional filter expression that the subscription
+	// records must satisfy (alias of the "filter" query parameter).
+	Filter string `json:"filter"`
+
+	// Fields is an optional comma separated list of the record fields
+	// to return (alias of the "fields" query parameter).
+	Fields string `json:"fields"`
+
+	// Expand is an optional comma separated list of the record
+	// relations to expand (alias of the "expand" query parameter).
+	Expand string `json:"expand"`
 }
 
 // Client is an interface for a generic subscription client.
@@ -80,6 +103,10 @@ type Client interface {
 
 	// Send sends the specified message to the client's channel (if not discarded).
 	Send(m Message)
+
+	// MessagesSince returns the buffered subscription messages sent
+	// after the message with lastId (see [ReplayBuffer.Since]).
+	MessagesSince(lastId uint64) (messages []Message, overflowed []string)
 }
 
 // ensures that DefaultClient satisfies the Client interface
@@ -91,7 +118,9 @@ type DefaultClient struct {
 	subscriptions map[string]SubscriptionOptions
 	channel       chan Message
 	id            string
+	replay        *ReplayBuffer
 	mux           sync.RWMutex
+	sendMux       sync.Mutex
 	isDiscarded   bool
 }
 
@@ -102,6 +131,7 @@ func NewDefaultClient() *DefaultClient {
 		store:         map[string]any{},
 		channel:       make(chan Message),
 		subscriptions: map[string]SubscriptionOptions{},
+		replay:        NewReplayBuffer(DefaultReplayLimit),
 	}
 }
 
@@ -183,6 +213,14 @@ func (c *DefaultClient) Subscribe(subs ...string) {
 			options.Query[k] = cast.ToString(v)
 		}
 
+		// sync the dedicated filter, fields and expand options with their query parameters
+		if options.Query == nil && (options.Filter != "" || options.Fields != "" || options.Expand != "") {
+			options.Query = map[string]any{}
+		}
+		syncQueryOption(options.Query, filterQueryParam, &options.Filter)
+		syncQueryOption(options.Query, fieldsQueryParam, &options.Fields)
+		syncQueryOption(options.Query, expandQueryParam, &options.Expand)
+
 		// normalize headers name and values, eg. "X-Token" is converted to "x_token"
 		// (currently only single string values are supported for consistency with the default routes handling)
 		for k, v := range options.Headers {
@@ -194,13 +232,27 @@ func (c *DefaultClient) Subscribe(subs ...string) {
 	}
 }
 
+// syncQueryOption syncs a single dedicated subscription option value
+// with its query parameter (the dedicated option has precedence).
+func syncQueryOption(query map[string]any, param string, option *string) {
+	if *option != "" {
+		query[param] = *option
+	} else if query != nil {
+		*option, _ = query[param].(string)
+	}
+}
+
 // Unsubscribe implements the [Client.Unsubscribe] interface method.
 //
 // If subs is not set, this method removes all registered client's subscriptions.
+//
+// The replay buffer of the removed subscriptions is also cleared.
 func (c *DefaultClient) Unsubscribe(subs ...string) {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
+	c.replay.Forget(subs...)
+
 	if len(subs) > 0 {
 		for _, s := range subs {
 			delete(c.subscriptions, s)
@@ -264,10 +316,31 @@ func (c *DefaultClient) IsDiscarded() bool {
 }
 
 // Send sends the specified message to the client's channel (if not discarded).
+//
+// The message is assigned with the next client message id and,
+// if its name matches one of the client subscriptions, it is also
+// stored in the client's replay buffer.
+//
+// Concurrent Send calls are serialized so that the messages
+// are always delivered in the order of their ids.
 func (c *DefaultClient) Send(m Message) {
 	if c.IsDiscarded() {
 		return
 	}
 
+	c.sendMux.Lock()
+	defer c.sendMux.Unlock()
+
+	if c.IsDiscarded() {
+		return
+	}
+
+	m = c.replay.Push(m, c.HasSubscription(m.Name))
+
 	c.Channel() <- m
 }
+
+// MessagesSince implements the [Client.MessagesSince] interface method.
+func (c *DefaultClient) MessagesSince(lastId uint64) ([]Message, []string) {
+	return c.replay.Since(lastId)
+}
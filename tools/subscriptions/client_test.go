@@ -117,8 +117,10 @@ func TestSubscribeOptions(t *testing.T) {
 
 	sub1 := "test1"
 	sub2 := `test2?options={"query":{"name":123},"headers":{"X-Token":456}}`
+	sub3 := `test3?options={"filter":"a=1","fields":"id,a","expand":"rel"}`
+	sub4 := `test4?options={"query":{"filter":"a=1","fields":"id"},"fields":"id,b"}`
 
-	c.Subscribe(sub1, sub2)
+	c.Subscribe(sub1, sub2, sub3, sub4)
 
 	subs := c.Subscriptions()
 
@@ -126,8 +128,10 @@ func TestSubscribeOptions(t *testing.T) {
 		name            string
 		expectedOptions string
 	}{
-		{sub1, `{"query":null,"headers":null}`},
-		{sub2, `{"query":{"name":"123"},"headers":{"x_token":"456"}}`},
+		{sub1, `{"query":null,"headers":null,"filter":"","fields":"","expand":""}`},
+		{sub2, `{"query":{"name":"123"},"headers":{"x_token":"456"},"filter":"","fields":"","expand":""}`},
+		{sub3, `{"query":{"expand":"rel","fields":"id,a","filter":"a=1"},"headers":null,"filter":"a=1","fields":"id,a","expand":"rel"}`},
+		{sub4, `{"query":{"fields":"id,b","filter":"a=1"},"headers":null,"filter":"a=1","fields":"id,b","expand":""}`},
 	}
 
 	for _, s := range scenarios {
@@ -242,3 +246,43 @@ func TestSend(t *testing.T) {
 		}
 	}
 }
+
+func TestSendReplay(t *testing.T) {
+	c := subscriptions.NewDefaultClient()
+	c.Subscribe("sub1", "sub2")
+
+	received := []uint64{}
+	go func() {
+		for m := range c.Channel() {
+			received = append(received, m.Id)
+		}
+	}()
+
+	c.Send(subscriptions.Message{Name: "sub1"})
+	c.Send(subscriptions.Message{Name: "other"})
+	c.Send(subscriptions.Message{Name: "sub2"})
+	c.Send(subscriptions.Message{Name: "sub1"})
+	time.Sleep(5 * time.Millisecond)
+
+	if len(received) != 4 {
+		t.Fatalf("Expected 4 messages, got %v", received)
+	}
+	for i, id := range received {
+		if id != uint64(i+1) {
+			t.Fatalf("Expected monotonically increasing ids, got %v", received)
+		}
+	}
+
+	// non subscription messages are not stored
+	messages, _ := c.MessagesSince(1)
+	if len(messages) != 2 || messages[0].Id != 3 || messages[1].Id != 4 {
+		t.Fatalf("Expected messages 3 and 4, got %v", messages)
+	}
+
+	// unsubscribe should clear the subscription replay messages
+	c.Unsubscribe("sub2")
+	messages, _ = c.MessagesSince(0)
+	if len(messages) != 2 || messages[0].Id != 1 || messages[1].Id != 4 {
+		t.Fatalf("Expected messages 1 and 4, got %v", messages)
+	}
+}
@@ -0,0 +1,129 @@
+package subscriptions
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultReplayLimit is the default max number of recently sent
+// messages that are kept for replay per single subscription.
+const DefaultReplayLimit = 100
+
+// ReplayBuffer assigns monotonically increasing ids to the sent messages
+// and keeps a bounded list of the most recent ones per subscription,
+// allowing them to be replayed after a client reconnect.
+type ReplayBuffer struct {
+	mux    sync.Mutex
+	limit  int
+	lastId uint64
+	queues map[string]*replayQueue
+}
+
+type replayQueue struct {
+	messages []Message
+
+	// evictedId is the id of the last message dropped from the queue
+	// because of the buffer limit.
+	evictedId uint64
+}
+
+// NewReplayBuffer creates a new ReplayBuffer instance that keeps
+// up to limit messages per subscription.
+func NewReplayBuffer(limit int) *ReplayBuffer {
+	if limit < 1 {
+		limit = 1
+	}
+
+	return &ReplayBuffer{
+		limit:  limit,
+		queues: map[string]*replayQueue{},
+	}
+}
+
+// LastId returns the id of the last pushed message.
+func (b *ReplayBuffer) LastId() uint64 {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return b.lastId
+}
+
+// Push assigns the next message id to m and returns the updated message.
+//
+// If store is true, the message is also added to the replay queue
+// of the subscription matching the message name.
+func (b *ReplayBuffer) Push(m Message, store bool) Message {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.lastId++
+	m.Id = b.lastId
+
+	if !store {
+		return m
+	}
+
+	q, ok := b.queues[m.Name]
+	if !ok {
+		q = &replayQueue{}
+		b.queues[m.Name] = q
+	}
+
+	if len(q.messages) >= b.limit {
+		q.evictedId = q.messages[0].Id
+		q.messages = q.messages[1:]
+	}
+
+	q.messages = append(q.messages, m)
+
+	return m
+}
+
+// Since returns the buffered messages with id greater than lastId
+// (sorted by their id).
+//
+// overflowed lists the subscriptions that had messages after lastId
+// dropped because of the buffer limit and therefore cannot be fully replayed
+// (their messages are excluded from the returned result).
+func (b *ReplayBuffer) Since(lastId uint64) (messages []Message, overflowed []string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	for sub, q := range b.queues {
+		if q.evictedId > lastId {
+			overflowed = append(overflowed, sub)
+			continue
+		}
+
+		for _, m := range q.messages {
+			if m.Id > lastId {
+				messages = append(messages, m)
+			}
+		}
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Id < messages[j].Id
+	})
+
+	sort.Strings(overflowed)
+
+	return messages, overflowed
+}
+
+// Forget removes the replay queues of the specified subscriptions.
+//
+// If subs is not set, this method removes all replay queues.
+func (b *ReplayBuffer) Forget(subs ...string) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if len(subs) == 0 {
+		b.queues = map[string]*replayQueue{}
+		return
+	}
+
+	for _, sub := range subs {
+		delete(b.queues, sub)
+	}
+}
@@ -0,0 +1,100 @@
+package subscriptions_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hylarucoder/rocketbase/tools/subscriptions"
+)
+
+func TestReplayBufferPush(t *testing.T) {
+	b := subscriptions.NewReplayBuffer(2)
+
+	m1 := b.Push(subscriptions.Message{Name: "a"}, true)
+	m2 := b.Push(subscriptions.Message{Name: "b"}, false)
+	m3 := b.Push(subscriptions.Message{Name: "a"}, true)
+
+	if m1.Id != 1 || m2.Id != 2 || m3.Id != 3 {
+		t.Fatalf("Expected ids 1,2,3, got %d,%d,%d", m1.Id, m2.Id, m3.Id)
+	}
+
+	if v := b.LastId(); v != 3 {
+		t.Fatalf("Expected last id 3, got %d", v)
+	}
+
+	messages, overflowed := b.Since(0)
+
+	if len(overflowed) != 0 {
+		t.Fatalf("Expected no overflowed subscriptions, got %v", overflowed)
+	}
+
+	if len(messages) != 2 || messages[0].Id != 1 || messages[1].Id != 3 {
+		t.Fatalf("Expected only the stored messages 1 and 3, got %v", messages)
+	}
+}
+
+func TestReplayBufferSince(t *testing.T) {
+	b := subscriptions.NewReplayBuffer(2)
+
+	b.Push(subscriptions.Message{Name: "a"}, true) // 1 (evicted)
+	b.Push(subscriptions.Message{Name: "b"}, true) // 2
+	b.Push(subscriptions.Message{Name: "a"}, true) // 3
+	b.Push(subscriptions.Message{Name: "a"}, true) // 4
+	b.Push(subscriptions.Message{Name: "b"}, true) // 5
+
+	scenarios := []struct {
+		lastId             uint64
+		expectedIds        []uint64
+		expectedOverflowed []string
+	}{
+		{0, []uint64{2, 5}, []string{"a"}},
+		{1, []uint64{2, 3, 4, 5}, nil},
+		{3, []uint64{4, 5}, nil},
+		{5, nil, nil},
+		{100, nil, nil},
+	}
+
+	for i, s := range scenarios {
+		messages, overflowed := b.Since(s.lastId)
+
+		if len(messages) != len(s.expectedIds) {
+			t.Errorf("[%d] Expected %d messages, got %v", i, len(s.expectedIds), messages)
+			continue
+		}
+
+		for j, id := range s.expectedIds {
+			if messages[j].Id != id {
+				t.Errorf("[%d] Expected message %d to have id %d, got %d", i, j, id, messages[j].Id)
+			}
+		}
+
+		if strings.Join(overflowed, ",") != strings.Join(s.expectedOverflowed, ",") {
+			t.Errorf("[%d] Expected overflowed %v, got %v", i, s.expectedOverflowed, overflowed)
+		}
+	}
+}
+
+func TestReplayBufferForget(t *testing.T) {
+	b := subscriptions.NewReplayBuffer(10)
+
+	b.Push(subscriptions.Message{Name: "a"}, true)
+	b.Push(subscriptions.Message{Name: "b"}, true)
+	b.Push(subscriptions.Message{Name: "c"}, true)
+
+	b.Forget("a", "missing")
+
+	if messages, _ := b.Since(0); len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %v", messages)
+	}
+
+	b.Forget()
+
+	if messages, _ := b.Since(0); len(messages) != 0 {
+		t.Fatalf("Expected no messages, got %v", messages)
+	}
+
+	// the ids sequence should continue after forget
+	if m := b.Push(subscriptions.Message{Name: "a"}, true); m.Id != 4 {
+		t.Fatalf("Expected id 4, got %d", m.Id)
+	}
+}
@@ -0,0 +1,131 @@
+// Package tracing implements helpers for instrumenting the app
+// internals with OpenTelemetry spans.
+//
+// The spans are created with the global otel TracerProvider so
+// they are no-op until a TracerProvider is registered with
+// [otel.SetTracerProvider] (usually by the app itself based on its tracing settings).
+//
+// Example:
+//
+//	ctx, span := tracing.Start(ctx, "myOperation", attribute.String("key", "value"))
+//	err := myOperation(ctx)
+//	tracing.End(span, err)
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the app spans.
+const ScopeName = "github.com/hylarucoder/rocketbase"
+
+// Tracer returns the app tracer from the global otel TracerProvider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(ScopeName)
+}
+
+// Start creates a new span with the provided name and attributes
+// (as a child of the active span in ctx if there is one).
+//
+// The returned context holds the newly created span.
+// Don't forget to call [End] once the traced operation completes.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartChild is similar to [Start] but creates a new span ONLY if
+// ctx already has an active span (aka. it is part of a traced operation).
+//
+// If there is no active span, it returns the original ctx and a non-recording span
+// (this is useful for frequent operations like db queries to avoid cluttering
+// the exported data with unrelated root traces).
+func StartChild(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Record creates and immediately ends a new child span for an
+// already completed operation with the specified duration.
+//
+// Similar to [StartChild], nothing is recorded if ctx doesn't have an active span.
+func Record(ctx context.Context, name string, duration time.Duration, err error, attrs ...attribute.KeyValue) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	end := time.Now()
+
+	_, span := Tracer().Start(
+		ctx,
+		name,
+		trace.WithTimestamp(end.Add(-duration)),
+		trace.WithAttributes(attrs...),
+	)
+
+	setError(span, err)
+
+	span.End(trace.WithTimestamp(end))
+}
+
+// End records the provided error (if any) and ends the span.
+func End(span trace.Span, err error) {
+	setError(span, err)
+
+	span.End()
+}
+
+// TraceId returns the hex encoded trace id of the active span in ctx
+// (or empty string if ctx doesn't have an active span).
+func TraceId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ""
+	}
+
+	return spanContext.TraceID().String()
+}
+
+// SpanId returns the hex encoded span id of the active span in ctx
+// (or empty string if ctx doesn't have an active span).
+func SpanId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return ""
+	}
+
+	return spanContext.SpanID().String()
+}
+
+func setError(span trace.Span, err error) {
+	if err == nil || !span.IsRecording() {
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,152 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hylarucoder/rocketbase/tools/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// note: the tests are not parallel because they modify the global otel TracerProvider
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	})
+
+	return recorder
+}
+
+func TestStartAndEnd(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	ctx, root := tracing.Start(nil, "root", attribute.String("a", "1"))
+	_, child := tracing.Start(ctx, "child")
+
+	tracing.End(child, errors.New("test"))
+	tracing.End(root, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 ended spans, got %d", len(spans))
+	}
+
+	childSpan, rootSpan := spans[0], spans[1]
+
+	if rootSpan.Name() != "root" || childSpan.Name() != "child" {
+		t.Fatalf("Expected root and child spans, got %q and %q", rootSpan.Name(), childSpan.Name())
+	}
+
+	if childSpan.Parent().SpanID() != rootSpan.SpanContext().SpanID() {
+		t.Fatal("Expected the child span to be a child of the root span")
+	}
+
+	if len(rootSpan.Attributes()) != 1 || rootSpan.Attributes()[0] != attribute.String("a", "1") {
+		t.Fatalf("Expected root span attribute a=1, got %v", rootSpan.Attributes())
+	}
+
+	if rootSpan.Status().Code != codes.Unset {
+		t.Fatalf("Expected unset root span status, got %v", rootSpan.Status().Code)
+	}
+
+	if childSpan.Status().Code != codes.Error || childSpan.Status().Description != "test" {
+		t.Fatalf("Expected child span error status, got %v", childSpan.Status())
+	}
+}
+
+func TestStartChild(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	// without active span
+	ctx, span := tracing.StartChild(context.Background(), "orphan")
+	if span.IsRecording() {
+		t.Fatal("Expected non-recording span")
+	}
+	if ctx != context.Background() {
+		t.Fatal("Expected the original context to be returned")
+	}
+	tracing.End(span, nil)
+
+	// with active span
+	rootCtx, root := tracing.Start(context.Background(), "root")
+	_, child := tracing.StartChild(rootCtx, "child")
+	if !child.IsRecording() {
+		t.Fatal("Expected recording child span")
+	}
+	tracing.End(child, nil)
+	tracing.End(root, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 ended spans, got %d", len(spans))
+	}
+
+	if spans[0].Name() != "child" {
+		t.Fatalf("Expected child span, got %q", spans[0].Name())
+	}
+}
+
+func TestRecord(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	// without active span
+	tracing.Record(context.Background(), "orphan", time.Second, nil)
+
+	// with active span
+	rootCtx, root := tracing.Start(context.Background(), "root")
+	tracing.Record(rootCtx, "query", 2*time.Second, errors.New("test"))
+	tracing.End(root, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 ended spans, got %d", len(spans))
+	}
+
+	query := spans[0]
+
+	if query.Name() != "query" {
+		t.Fatalf("Expected query span, got %q", query.Name())
+	}
+
+	if d := query.EndTime().Sub(query.StartTime()); d != 2*time.Second {
+		t.Fatalf("Expected span duration 2s, got %v", d)
+	}
+
+	if query.Status().Code != codes.Error {
+		t.Fatalf("Expected error status, got %v", query.Status().Code)
+	}
+}
+
+func TestTraceIdAndSpanId(t *testing.T) {
+	setupRecorder(t)
+
+	if v := tracing.TraceId(nil); v != "" {
+		t.Fatalf("Expected empty trace id for nil context, got %q", v)
+	}
+
+	if v := tracing.SpanId(context.Background()); v != "" {
+		t.Fatalf("Expected empty span id without active span, got %q", v)
+	}
+
+	ctx, span := tracing.Start(context.Background(), "test")
+	defer span.End()
+
+	if v := tracing.TraceId(ctx); v != span.SpanContext().TraceID().String() {
+		t.Fatalf("Expected trace id %q, got %q", span.SpanContext().TraceID(), v)
+	}
+
+	if v := tracing.SpanId(ctx); v != span.SpanContext().SpanID().String() {
+		t.Fatalf("Expected span id %q, got %q", span.SpanContext().SpanID(), v)
+	}
+}